@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// maxAccessTokensPerUser - Giới hạn số token đang hoạt động của mỗi user
+const maxAccessTokensPerUser = 50
+
+// GetAccessTokens - Danh sách personal access token của user hiện tại (không trả token gốc)
+func (h *Handler) GetAccessTokens(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	tokens, err := h.repos.AccessTokens.ListByUser(ctx, c.GetString("user_id"))
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch access tokens", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": len(tokens), "data": tokens})
+}
+
+// CreateAccessToken - Tạo personal access token mới; token gốc chỉ được trả về một lần
+func (h *Handler) CreateAccessToken(c *gin.Context) {
+	var input models.CreateAccessTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	count, err := h.repos.AccessTokens.CountByUser(ctx, userID)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	if count >= maxAccessTokensPerUser {
+		apperr.Respond(c, apperr.Conflict("access_token_limit", "Too many access tokens, revoke an unused one first"))
+		return
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate access token", err))
+		return
+	}
+	raw := models.AccessTokenPrefix + secret
+
+	now := time.Now()
+	token := models.AccessToken{
+		ID:        utils.GenerateID("pat"),
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    raw[:len(models.AccessTokenPrefix)+6],
+		TokenHash: utils.HashToken(raw),
+		Scopes:    uniqueStrings(input.Scopes),
+		CreatedAt: now.Unix(),
+	}
+	if input.ExpiresInDays > 0 {
+		token.ExpiresAt = now.AddDate(0, 0, input.ExpiresInDays).Unix()
+	}
+
+	if err := h.repos.AccessTokens.Create(ctx, &token); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to create access token", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Access token created. Copy it now, it will not be shown again",
+		"token":   raw,
+		"data":    token,
+	})
+}
+
+// DeleteAccessToken - Thu hồi personal access token của chính user
+func (h *Handler) DeleteAccessToken(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	err := h.repos.AccessTokens.Delete(ctx, c.GetString("user_id"), c.Param("id"))
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("access_token_not_found", "Access token not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to revoke access token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access token revoked successfully"})
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+// accessToken - Tạo personal access token với các scope cho user đang đăng nhập bằng JWT
+func accessToken(k *testkit.Kit, jwt string, scopes ...string) string {
+	var created struct {
+		Token string `json:"token"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/me/tokens", jwt, map[string]any{"name": "script", "scopes": scopes}).
+		Status(http.StatusCreated).Decode(&created)
+	return created.Token
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	k := testkit.New(t)
+	_, jwt := k.Login("user")
+	upload := accessToken(k, jwt, "upload")
+	read := accessToken(k, jwt, "read")
+
+	// Scope upload chỉ dùng được cho nhóm route upload
+	k.Upload("/api/v1/upload/image", upload, testkit.NewMultipart().File("image", "photo.jpg", testkit.JPEG(8, 8, nil))).
+		Status(http.StatusOK)
+	k.JSON(http.MethodPost, "/api/v1/albums", upload, map[string]string{"name": "Script"}).
+		Fails(http.StatusForbidden, "insufficient_scope")
+	k.JSON(http.MethodGet, "/api/v1/albums", upload, nil).Fails(http.StatusForbidden, "insufficient_scope")
+
+	k.JSON(http.MethodGet, "/api/v1/albums", read, nil).Status(http.StatusOK)
+	k.Upload("/api/v1/upload/image", read, testkit.NewMultipart().File("image", "photo.jpg", testkit.JPEG(8, 8, nil))).
+		Fails(http.StatusForbidden, "insufficient_scope")
+	k.JSON(http.MethodGet, "/api/v1/me/tokens", read, nil).Fails(http.StatusForbidden, "access_token_not_allowed")
+}
+
+func TestAccessTokensCannotUseAdminRoutes(t *testing.T) {
+	k := testkit.New(t)
+	_, jwt := k.Login("admin")
+	write := accessToken(k, jwt, "write")
+	read := accessToken(k, jwt, "read")
+	user := k.CreateUser()
+
+	// Token của admin có quyền write nhưng vẫn không quản trị được
+	k.JSON(http.MethodPost, "/api/v1/users", write, map[string]string{
+		"name": "Scripted", "email": "scripted@example.test", "password": "s3cret-pass", "role": "admin",
+	}).Fails(http.StatusForbidden, "access_token_not_allowed")
+	k.JSON(http.MethodDelete, "/api/v1/users/"+user.ID, write, nil).Fails(http.StatusForbidden, "access_token_not_allowed")
+	k.JSON(http.MethodDelete, "/api/v1/users/"+user.ID+"/2fa", write, nil).Fails(http.StatusForbidden, "access_token_not_allowed")
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", write, map[string]any{
+		"url": "https://hooks.example.test", "events": []string{"user.created"},
+	}).Fails(http.StatusForbidden, "access_token_not_allowed")
+	for _, path := range []string{"/api/v1/admin/audit", "/api/v1/admin/stats/storage?format=csv"} {
+		k.JSON(http.MethodGet, path, read, nil).Fails(http.StatusForbidden, "access_token_not_allowed")
+		k.JSON(http.MethodGet, path, jwt, nil).Status(http.StatusOK)
+	}
+
+	// Route thường vẫn dùng được với token của admin
+	k.JSON(http.MethodGet, "/api/v1/users/"+user.ID, read, nil).Status(http.StatusOK)
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/mailer"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// issueUserToken - Tạo token một lần cho user, hủy các token cùng mục đích chưa dùng trước đó
+func (h *Handler) issueUserToken(ctx context.Context, userID, purpose, email string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := h.repos.UserTokens.DeleteUnused(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	record := models.UserToken{
+		ID:        utils.GenerateID("tok"),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		Email:     email,
+		ExpiresAt: now.Add(ttl).Unix(),
+		CreatedAt: now.Unix(),
+		PurgeAt:   now.Add(ttl),
+	}
+	if err := h.repos.UserTokens.Create(ctx, &record); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken - Đánh dấu token đã dùng một cách atomic; token hết hạn hoặc đã dùng sẽ bị từ chối
+func (h *Handler) consumeUserToken(ctx context.Context, token, purpose string) (*models.UserToken, error) {
+	record, err := h.repos.UserTokens.Consume(ctx, utils.HashToken(token), purpose, time.Now().Unix())
+	if err == repository.ErrNotFound {
+		return nil, errInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// sendVerificationEmail - Gửi link xác thực tới email (email hiện tại hoặc email mới khi đổi)
+func (h *Handler) sendVerificationEmail(ctx context.Context, user *models.User, email string) error {
+	token, err := h.issueUserToken(ctx, user.ID, models.TokenPurposeEmailVerification, email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	mailer.SendAsync(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email address by opening the link below:\n\n%s/verify-email?token=%s\n\nThis link expires in %d hours.",
+			user.Name, h.cfg.Server.FrontendURL, token, int(emailVerificationTTL.Hours())),
+	})
+	return nil
+}
+
+// VerifyEmail - Xác thực email bằng token đã gửi; áp dụng luôn email mới nếu là yêu cầu đổi email
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var input models.VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	record, err := h.consumeUserToken(ctx, input.Token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		if err == errInvalidUserToken {
+			apperr.Respond(c, apperr.BadRequest("invalid_verification_token", "Invalid or expired verification token"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(ctx, record.UserID)
+	if err != nil {
+		apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
+		return
+	}
+
+	changes := repository.UserChanges{EmailVerified: repository.Ptr(true)}
+	if record.Email != "" && record.Email != user.Email {
+		changes.Email = &record.Email
+		changes.PendingEmail = repository.Ptr("")
+	}
+
+	if err := h.repos.Users.Update(ctx, user.ID, changes); err == repository.ErrDuplicate {
+		apperr.Respond(c, apperr.Conflict("email_taken", "Email already exists"))
+		return
+	} else if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to verify email", err))
+		return
+	}
+	if changes.Email != nil {
+		h.audit(c, ctx, models.AuditEvent{
+			Action: models.AuditEmailChange, ActorID: user.ID, ActorEmail: user.Email, TargetType: "user", TargetID: user.ID,
+			Diff: map[string]models.AuditChange{"email": {From: user.Email, To: record.Email}},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification - Gửi lại email xác thực; luôn trả cùng một thông báo để tránh dò email
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var input models.EmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, err := h.repos.Users.GetByEmail(ctx, input.Email)
+	if err == nil && !user.IsEmailVerified() {
+		if err := h.sendVerificationEmail(ctx, user, user.Email); err != nil {
+			slog.ErrorContext(ctx, "failed to issue verification token", "target_user_id", user.ID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If the email is registered and not yet verified, a verification link has been sent",
+	})
+}
+
+// ForgotPassword - Gửi link đặt lại mật khẩu; luôn trả cùng một thông báo để tránh dò email
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var input models.EmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	if user, err := h.repos.Users.GetByEmail(ctx, input.Email); err == nil {
+		token, err := h.issueUserToken(ctx, user.ID, models.TokenPurposePasswordReset, user.Email, passwordResetTTL)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to issue password reset token", "target_user_id", user.ID, "error", err)
+		} else {
+			mailer.SendAsync(ctx, mailer.Message{
+				To:      user.Email,
+				Subject: "Reset your password",
+				Body: fmt.Sprintf("Hi %s,\n\nSomeone requested a password reset for your account. Open the link below to choose a new password:\n\n%s/reset-password?token=%s\n\nThis link expires in %d minutes. If you did not request this, you can ignore this email.",
+					user.Name, h.cfg.Server.FrontendURL, token, int(passwordResetTTL.Minutes())),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword - Đặt mật khẩu mới bằng token đặt lại mật khẩu
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var input models.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	record, err := h.consumeUserToken(ctx, input.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		if err == errInvalidUserToken {
+			apperr.Respond(c, apperr.BadRequest("invalid_reset_token", "Invalid or expired reset token"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.Password)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to hash password", err))
+		return
+	}
+
+	err = h.repos.Users.Update(ctx, record.UserID, repository.UserChanges{Password: &hashedPassword})
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to reset password", err))
+		return
+	}
+
+	// Đổi mật khẩu thì đăng xuất mọi thiết bị
+	if _, err := h.repos.Sessions.DeleteAll(ctx, record.UserID, ""); err != nil {
+		slog.ErrorContext(ctx, "failed to revoke sessions", "target_user_id", record.UserID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// ChangePassword - Đổi mật khẩu khi đã đăng nhập; mọi phiên (kể cả phiên hiện tại) bị thu hồi
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var input models.ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, ok := h.findCurrentUser(c, ctx)
+	if !ok {
+		return
+	}
+
+	limiter := ratelimit.Default()
+	lockKey := "password:" + user.ID
+	if wait, err := ratelimit.DefaultLockout.Check(ctx, limiter, lockKey); err == nil && wait > 0 {
+		middleware.AbortTooManyRequests(c, wait)
+		return
+	}
+	if err := utils.CheckPassword(user.Password, input.CurrentPassword); err != nil {
+		h.recordLoginFailure(c, ctx, lockKey, models.FailedLogin{Reason: models.LoginFailureCurrentPassword, Email: user.Email, UserID: user.ID})
+		apperr.Respond(c, apperr.Unauthorized("invalid_credentials", "Current password is incorrect"))
+		return
+	}
+	limiter.ResetFailures(ctx, lockKey)
+
+	hashedPassword, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to hash password", err))
+		return
+	}
+
+	if err := h.repos.Users.Update(ctx, user.ID, repository.UserChanges{Password: &hashedPassword}); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to change password", err))
+		return
+	}
+
+	if _, err := h.repos.Sessions.DeleteAll(ctx, user.ID, ""); err != nil {
+		slog.ErrorContext(ctx, "failed to revoke sessions", "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully. Please log in again"})
+}
@@ -1,27 +1,37 @@
 package api
 
 import (
-	"context"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
 	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
 	"github.com/hieu9721/media-store-backend/utils"
 )
 
-func CreateAlbum(c *gin.Context) {
+func (h *Handler) CreateAlbum(c *gin.Context) {
 	var input models.CreateAlbumInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperr.Respond(c, apperr.Validation(err))
 		return
 	}
 
 	userID := c.GetString("user_id")
 
-	collection := config.DB.Collection("albums")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	albumType := models.AlbumTypeRegular
+	if input.Rules != nil {
+		if err := repository.ValidateSmartRule(input.Rules); err != nil {
+			apperr.Respond(c, apperr.BadRequest("invalid_smart_rules", "Invalid smart album rules: "+err.Error()))
+			return
+		}
+		albumType = models.AlbumTypeSmart
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
 	defer cancel()
 
 	albumID := utils.GenerateID("alb")
@@ -31,38 +41,102 @@ func CreateAlbum(c *gin.Context) {
 		UserID:      userID,
 		Name:        input.Name,
 		Description: input.Description,
+		Type:        albumType,
+		Rules:       input.Rules,
 		CreatedAt:   time.Now().Unix(),
 		UpdatedAt:   time.Now().Unix(),
 	}
 
-	_, err := collection.InsertOne(ctx, album)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create album"})
+	if err := h.repos.Albums.Create(ctx, &album); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to create album", err))
 		return
 	}
+	h.emit(ctx, models.WebhookAlbumCreated, album)
 
 	c.JSON(http.StatusCreated, album)
 }
 
-func GetAlbums(c *gin.Context) {
+func (h *Handler) GetAlbums(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	collection := config.DB.Collection("albums")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, gin.H{"user_id": userID})
+	albums, err := h.repos.Albums.ListByUser(ctx, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		apperr.Respond(c, apperr.Internal("Failed to fetch albums", err))
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var albums []models.Album
-	if err = cursor.All(ctx, &albums); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse albums"})
-		return
+	for i := range albums {
+		if albums[i].Type == "" {
+			albums[i].Type = models.AlbumTypeRegular
+		}
 	}
 
 	c.JSON(http.StatusOK, albums)
 }
+
+// GetAlbumMedia - Lấy media trong album với pagination; smart album được tính từ rules tại thời điểm đọc
+func (h *Handler) GetAlbumMedia(c *gin.Context) {
+	userID := c.GetString("user_id")
+	albumID := c.Param("id")
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	album, err := h.repos.Albums.Get(ctx, userID, albumID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			apperr.Respond(c, apperr.NotFound("album_not_found", "Album not found"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Failed to fetch album", err))
+		return
+	}
+
+	page, limit := parsePagination(c)
+	query := repository.MediaQuery{
+		UserID:  userID,
+		AlbumID: album.ID,
+		Skip:    (page - 1) * limit,
+		Limit:   limit,
+	}
+	if album.Type == models.AlbumTypeSmart {
+		if err := repository.ValidateSmartRule(album.Rules); err != nil {
+			apperr.Respond(c, apperr.Unprocessable("invalid_smart_rules", "Invalid smart album rules: "+err.Error()))
+			return
+		}
+		query.Rules = album.Rules
+	}
+
+	media, total, err := h.repos.Media.Find(ctx, query)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch media", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"album": album,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"data":  media,
+	})
+}
+
+// parsePagination - Đọc page/limit từ query, mặc định page=1, limit=20 (tối đa 100)
+func parsePagination(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
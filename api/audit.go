@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// auditVerifyBatch - Số event đọc mỗi lần khi kiểm tra chuỗi hash
+const auditVerifyBatch = 500
+
+// audit - Ghi event vào audit log với IP, user agent và actor của request (nếu event chưa có actor).
+// Lỗi ghi chỉ được log để không làm hỏng thao tác đã thực hiện xong
+func (h *Handler) audit(c *gin.Context, ctx context.Context, event models.AuditEvent) {
+	if event.ActorID == "" {
+		event.ActorID = c.GetString("user_id")
+		event.ActorEmail = c.GetString("email")
+	}
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	event.ID = utils.GenerateID("aud")
+	event.IP = c.ClientIP()
+	event.UserAgent = userAgent
+	event.CreatedAt = time.Now().Unix()
+
+	// Vẫn ghi khi request vừa hết thời gian chờ
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := h.repos.Audit.Append(ctx, &event); err != nil {
+		slog.ErrorContext(ctx, "failed to write audit event", "action", event.Action, "target_id", event.TargetID, "error", err)
+	}
+}
+
+// userDiff - Các field hồ sơ khác nhau giữa hai bản của user; không gồm mật khẩu và secret 2FA
+func userDiff(before, after *models.User) map[string]models.AuditChange {
+	fields := []struct {
+		name          string
+		before, after string
+	}{
+		{"name", before.Name, after.Name},
+		{"email", before.Email, after.Email},
+		{"pending_email", before.PendingEmail, after.PendingEmail},
+		{"email_verified", strconv.FormatBool(before.IsEmailVerified()), strconv.FormatBool(after.IsEmailVerified())},
+		{"role", before.Role, after.Role},
+		{"phone", before.Phone, after.Phone},
+		{"avatar", before.Avatar, after.Avatar},
+		{"strip_metadata", before.StripMetadata, after.StripMetadata},
+	}
+	diff := map[string]models.AuditChange{}
+	for _, f := range fields {
+		if f.before != f.after {
+			diff[f.name] = models.AuditChange{From: f.before, To: f.after}
+		}
+	}
+	return diff
+}
+
+// GetAuditEvents - Audit log mới nhất trước, lọc theo action, actor_id, target_type, target_id, from, to
+func (h *Handler) GetAuditEvents(c *gin.Context) {
+	page, limit := parsePagination(c)
+	query := repository.AuditQuery{
+		Action:     c.Query("action"),
+		ActorID:    c.Query("actor_id"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Skip:       (page - 1) * limit,
+		Limit:      limit,
+	}
+
+	var fields []apperr.FieldError
+	for field, bound := range map[string]*int64{"from": &query.From, "to": &query.To} {
+		value := c.Query(field)
+		if value == "" {
+			continue
+		}
+		t, dateOnly, err := parseDateParam(value)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{
+				Field: field, Code: "date", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+			})
+			continue
+		}
+		// to là ngày cuối cùng được tính
+		if field == "to" && dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		*bound = t.Unix()
+	}
+	if len(fields) > 0 {
+		apperr.Respond(c, apperr.InvalidFields(fields, nil))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	events, total, err := h.repos.Audit.Find(ctx, query)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"data":  events,
+	})
+}
+
+// auditVerification - Kết quả kiểm tra chuỗi hash của audit log
+type auditVerification struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"`
+	// HeadSeq, HeadHash - Event cuối cùng hợp lệ; lưu HeadHash ở nơi khác để phát hiện cả việc viết lại toàn bộ chuỗi
+	HeadSeq  int64  `json:"head_seq"`
+	HeadHash string `json:"head_hash,omitempty"`
+	// BrokenAt - Seq của event đầu tiên không khớp chuỗi
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// verifyAuditChain - Đọc toàn bộ log theo seq, tính lại hash từng event và so với PrevHash của event sau
+func verifyAuditChain(ctx context.Context, audit repository.AuditRepository) (*auditVerification, error) {
+	result := &auditVerification{Valid: true}
+	for {
+		events, err := audit.Chain(ctx, result.HeadSeq, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			reason := ""
+			switch {
+			case e.Seq != result.HeadSeq+1:
+				reason = "sequence_gap"
+			case e.PrevHash != result.HeadHash:
+				reason = "prev_hash_mismatch"
+			case e.Hash != e.ComputeHash():
+				reason = "hash_mismatch"
+			}
+			if reason != "" {
+				result.Valid, result.BrokenAt, result.Reason = false, e.Seq, reason
+				return result, nil
+			}
+			result.Checked++
+			result.HeadSeq, result.HeadHash = e.Seq, e.Hash
+		}
+		if len(events) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
+
+// VerifyAuditLog - Kiểm tra audit log không bị sửa, xóa hoặc chèn event
+func (h *Handler) VerifyAuditLog(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 60*time.Second)
+	defer cancel()
+
+	result, err := verifyAuditChain(ctx, h.repos.Audit)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// AnchorAudit - Ghi log seq và hash của event audit cuối cùng mỗi interval (khi có event mới) tới khi ctx kết thúc.
+// Log được chuyển ra ngoài database là bản sao head hash để phát hiện việc viết lại toàn bộ chuỗi
+func AnchorAudit(ctx context.Context, audit repository.AuditRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var logged int64
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		events, _, err := audit.Find(readCtx, repository.AuditQuery{Limit: 1})
+		cancel()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.ErrorContext(ctx, "failed to read audit head", "error", err)
+		case len(events) == 1 && events[0].Seq != logged:
+			logged = events[0].Seq
+			slog.InfoContext(ctx, "audit head", "seq", events[0].Seq, "hash", events[0].Hash)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
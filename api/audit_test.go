@@ -0,0 +1,127 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+type auditPage struct {
+	Total int64               `json:"total"`
+	Data  []models.AuditEvent `json:"data"`
+}
+
+func TestAuditLogRecordsAdminActions(t *testing.T) {
+	k := testkit.New(t)
+	admin, adminToken := k.Login("admin")
+	_, userToken := k.Login("user")
+
+	var created struct {
+		Data models.User `json:"data"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/users", adminToken, map[string]string{
+		"name": "Audited User", "email": "audited@example.test", "password": "s3cret-pass", "role": "user",
+	}).Status(http.StatusCreated).Decode(&created)
+	target := created.Data.ID
+	k.JSON(http.MethodPut, "/api/v1/users/"+target, adminToken, map[string]string{"name": "Renamed User"}).Status(http.StatusOK)
+	k.JSON(http.MethodDelete, "/api/v1/users/"+target, adminToken, nil).Status(http.StatusOK)
+
+	k.JSON(http.MethodGet, "/api/v1/admin/audit", userToken, nil).Fails(http.StatusForbidden, "admin_required")
+
+	var got auditPage
+	k.JSON(http.MethodGet, "/api/v1/admin/audit?target_id="+target, adminToken, nil).Status(http.StatusOK).Decode(&got)
+	want := []string{models.AuditUserDelete, models.AuditUserUpdate, models.AuditUserCreate}
+	if got.Total != int64(len(want)) || len(got.Data) != len(want) {
+		t.Fatalf("events = %+v, want %v", got.Data, want)
+	}
+	for i, action := range want {
+		e := got.Data[i]
+		if e.Action != action || e.ActorID != admin.ID || e.ActorEmail != admin.Email || e.IP == "" {
+			t.Errorf("event %d = %+v, want %s by %s", i, e, action, admin.ID)
+		}
+	}
+	if diff := got.Data[1].Diff; len(diff) != 1 || diff["name"] != (models.AuditChange{From: "Audited User", To: "Renamed User"}) {
+		t.Errorf("update diff = %+v", diff)
+	}
+	if got.Data[0].Details["email"] != "audited@example.test" {
+		t.Errorf("delete details = %+v, want the deleted email", got.Data[0].Details)
+	}
+
+	k.JSON(http.MethodGet, "/api/v1/admin/audit?action=user.update&actor_id="+admin.ID, adminToken, nil).
+		Status(http.StatusOK).Decode(&got)
+	if got.Total != 1 {
+		t.Errorf("filtered total = %d, want 1", got.Total)
+	}
+}
+
+func TestAuditLogRecordsLogins(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	user := k.CreateUser()
+
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": user.Email, "password": "wrong-password"}).
+		Status(http.StatusUnauthorized)
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": user.Email, "password": testkit.Password}).
+		Status(http.StatusOK)
+
+	var got auditPage
+	k.JSON(http.MethodGet, "/api/v1/admin/audit?target_id="+user.ID, adminToken, nil).Status(http.StatusOK).Decode(&got)
+	if len(got.Data) != 2 {
+		t.Fatalf("events = %+v, want a failed and a successful login", got.Data)
+	}
+	success, failure := got.Data[0], got.Data[1]
+	if success.Action != models.AuditLogin || success.ActorID != user.ID || success.Details["session_id"] == "" {
+		t.Errorf("login event = %+v", success)
+	}
+	if failure.Action != models.AuditLoginFailed || failure.ActorID != "" || failure.Details["reason"] != models.LoginFailureWrongPassword {
+		t.Errorf("failed login event = %+v", failure)
+	}
+}
+
+// tamperedAudit - Audit log mà một event đã bị sửa trực tiếp trong database
+type tamperedAudit struct {
+	repository.AuditRepository
+	seq int64
+}
+
+func (r tamperedAudit) Chain(ctx context.Context, after int64, limit int) ([]models.AuditEvent, error) {
+	events, err := r.AuditRepository.Chain(ctx, after, limit)
+	for i := range events {
+		if events[i].Seq == r.seq {
+			events[i].ActorID = "uid_someone_else"
+		}
+	}
+	return events, err
+}
+
+func TestAuditLogVerify(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	for i := 0; i < 3; i++ {
+		k.JSON(http.MethodPost, "/api/v1/users", adminToken, map[string]string{
+			"name": "Chain User", "email": k.CreateUser().ID + "@example.test", "password": "s3cret-pass", "role": "user",
+		}).Status(http.StatusCreated)
+	}
+
+	var result struct {
+		Valid    bool   `json:"valid"`
+		Checked  int64  `json:"checked"`
+		HeadSeq  int64  `json:"head_seq"`
+		BrokenAt int64  `json:"broken_at"`
+		Reason   string `json:"reason"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/admin/audit/verify", adminToken, nil).Status(http.StatusOK).Decode(&result)
+	if !result.Valid || result.Checked != 3 || result.HeadSeq != 3 {
+		t.Fatalf("verify = %+v, want a valid chain of 3 events", result)
+	}
+
+	k.Repos.Audit = tamperedAudit{AuditRepository: k.Repos.Audit, seq: 2}
+	k.JSON(http.MethodGet, "/api/v1/admin/audit/verify", adminToken, nil).Status(http.StatusOK).Decode(&result)
+	if result.Valid || result.BrokenAt != 2 || result.Reason != "hash_mismatch" || result.Checked != 1 {
+		t.Errorf("verify after tampering = %+v, want broken at 2", result)
+	}
+}
@@ -2,109 +2,196 @@ package api
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
 	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/repository"
 	"github.com/hieu9721/media-store-backend/utils"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func Register(c *gin.Context) {
+// loginAccountLimit - Số lần thử đăng nhập tối đa cho mỗi tài khoản, ngoài giới hạn theo IP
+var loginAccountLimit = ratelimit.Per(10, 15*time.Minute)
+
+// recordLoginFailure - Tăng bộ đếm đăng nhập sai (khóa tài khoản tăng dần) và lưu lần thất bại cho thống kê admin
+func (h *Handler) recordLoginFailure(c *gin.Context, ctx context.Context, accountKey string, failure models.FailedLogin) {
+	if _, err := ratelimit.DefaultLockout.Fail(ctx, ratelimit.Default(), accountKey); err != nil {
+		slog.ErrorContext(ctx, "failed to record login failure", "error", err)
+	}
+
+	now := time.Now()
+	failure.ID = utils.GenerateID("fl")
+	failure.IP = c.ClientIP()
+	failure.CreatedAt = now.Unix()
+	failure.PurgeAt = now.Add(statsRetention)
+	if err := h.repos.FailedLogins.Create(ctx, &failure); err != nil {
+		slog.ErrorContext(ctx, "failed to store login failure", "error", err)
+	}
+
+	event := models.AuditEvent{
+		Action:  models.AuditLoginFailed,
+		Details: map[string]string{"reason": failure.Reason, "email": failure.Email},
+	}
+	if failure.UserID != "" {
+		event.TargetType, event.TargetID = "user", failure.UserID
+	}
+	h.audit(c, ctx, event)
+}
+
+func (h *Handler) Register(c *gin.Context) {
 	var input models.RegisterInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperr.Respond(c, apperr.Validation(err))
 		return
 	}
 
-	collection := config.DB.Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
 	defer cancel()
 
-	var existingUser models.User
-	err := collection.FindOne(ctx, bson.M{"email": input.Email}).Decode(&existingUser)
+	_, err := h.repos.Users.GetByEmail(ctx, input.Email)
 	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		apperr.Respond(c, apperr.Conflict("email_taken", "Email already registered"))
 		return
-	} else if err != mongo.ErrNoDocuments {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+	} else if err != repository.ErrNotFound {
+		apperr.Respond(c, apperr.Internal("Database error", err))
 		return
 	}
 
 	hashedPassword, err := utils.HashPassword(input.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apperr.Respond(c, apperr.Internal("Failed to hash password", err))
 		return
 	}
 
 	userID := utils.GenerateUserID()
+	emailVerified := false
 
 	user := models.User{
-		ID:        userID,
-		Name:      input.Name,
-		Email:     input.Email,
-		Password:  hashedPassword,
-		Role:      "user",
-		Phone:     input.Phone,
-		CreatedAt: time.Now().Unix(),
-		UpdatedAt: time.Now().Unix(),
+		ID:            userID,
+		Name:          input.Name,
+		Email:         input.Email,
+		EmailVerified: &emailVerified,
+		Password:      hashedPassword,
+		Role:          "user",
+		Phone:         input.Phone,
+		CreatedAt:     time.Now().Unix(),
+		UpdatedAt:     time.Now().Unix(),
 	}
 
-	_, err = collection.InsertOne(ctx, user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	err = h.repos.Users.Create(ctx, &user)
+	if err == repository.ErrDuplicate {
+		// Unique index users.email chặn Register chạy song song với cùng email
+		apperr.Respond(c, apperr.Conflict("email_taken", "Email already registered"))
 		return
 	}
-
-	token, err := utils.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apperr.Respond(c, apperr.Internal("Failed to create user", err))
 		return
 	}
 
+	// Tài khoản chỉ đăng nhập được sau khi xác thực email. Tài khoản đã được tạo nên lỗi ở bước này không trả 500
+	// (thử lại sẽ gặp email_taken): client được báo gọi /auth/resend-verification
+	message := "User registered successfully. Please check your email to verify your account"
+	emailSent := true
+	if err := h.sendVerificationEmail(ctx, &user, user.Email); err != nil {
+		slog.ErrorContext(ctx, "failed to issue verification email", "user_id", user.ID, "error", err)
+		message = "User registered successfully, but the verification email could not be sent. Request a new one with /auth/resend-verification"
+		emailSent = false
+	}
+
+	h.audit(c, ctx, models.AuditEvent{
+		Action: models.AuditRegister, ActorID: user.ID, ActorEmail: user.Email, TargetType: "user", TargetID: user.ID,
+	})
+	h.emit(ctx, models.WebhookUserCreated, webhookUser(&user))
+
 	user.Password = ""
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"token":   token,
-		"user":    user,
+		"message":                 message,
+		"user":                    user,
+		"verification_email_sent": emailSent,
 	})
 }
 
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var input models.LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperr.Respond(c, apperr.Validation(err))
 		return
 	}
 
-	collection := config.DB.Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
 	defer cancel()
 
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"email": input.Email}).Decode(&user)
+	// Giới hạn theo tài khoản (kể cả email không tồn tại) để không lộ email nào đã đăng ký
+	limiter := ratelimit.Default()
+	accountKey := "login:" + strings.ToLower(strings.TrimSpace(input.Email))
+	if result, err := limiter.Take(ctx, "account:"+accountKey, loginAccountLimit); err == nil && !result.Allowed {
+		middleware.AbortTooManyRequests(c, result.RetryAfter)
+		return
+	}
+	if wait, err := ratelimit.DefaultLockout.Check(ctx, limiter, accountKey); err == nil && wait > 0 {
+		middleware.AbortTooManyRequests(c, wait)
+		return
+	}
+
+	user, err := h.repos.Users.GetByEmail(ctx, input.Email)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		if err == repository.ErrNotFound {
+			utils.CheckDummyPassword(input.Password)
+			h.recordLoginFailure(c, ctx, accountKey, models.FailedLogin{Reason: models.LoginFailureUnknownEmail, Email: input.Email})
+			apperr.Respond(c, apperr.Unauthorized("invalid_credentials", "Invalid email or password"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apperr.Respond(c, apperr.Internal("Database error", err))
 		return
 	}
 
 	err = utils.CheckPassword(user.Password, input.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		h.recordLoginFailure(c, ctx, accountKey, models.FailedLogin{Reason: models.LoginFailureWrongPassword, Email: user.Email, UserID: user.ID})
+		apperr.Respond(c, apperr.Unauthorized("invalid_credentials", "Invalid email or password"))
+		return
+	}
+
+	if err := limiter.ResetFailures(ctx, accountKey); err != nil {
+		slog.ErrorContext(ctx, "failed to reset login failures", "error", err)
+	}
+
+	if !user.IsEmailVerified() {
+		apperr.Respond(c, apperr.Forbidden("email_not_verified", "Email address has not been verified"))
+		return
+	}
+
+	h.respondLogin(c, user, input.DeviceName)
+}
+
+// respondLogin - Tạo phiên và cấp JWT cho user đã xác thực; tài khoản bật 2FA chỉ nhận challenge token,
+// JWT được cấp sau khi nhập đúng mã
+func (h *Handler) respondLogin(c *gin.Context, user *models.User, deviceName string) {
+	if user.TwoFactorEnabled {
+		challenge, err := utils.GenerateChallengeToken(user.ID)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Failed to generate token", err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor authentication required",
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email, user.Role)
+	token, err := h.issueSessionToken(c, user, deviceName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apperr.Respond(c, apperr.Internal("Failed to generate token", err))
 		return
 	}
 
@@ -117,25 +204,23 @@ func Login(c *gin.Context) {
 	})
 }
 
-func GetCurrentUser(c *gin.Context) {
+func (h *Handler) GetCurrentUser(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		apperr.Respond(c, apperr.Unauthorized("unauthorized", "User not found in context"))
 		return
 	}
 
-	collection := config.DB.Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
 	defer cancel()
 
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	user, err := h.repos.Users.GetByID(ctx, userID.(string))
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		if err == repository.ErrNotFound {
+			apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apperr.Respond(c, apperr.Internal("Database error", err))
 		return
 	}
 
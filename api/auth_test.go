@@ -0,0 +1,242 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/testkit"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+func TestRegisterVerifyAndLogin(t *testing.T) {
+	k := testkit.New(t)
+	credentials := map[string]string{"email": "alice@example.test", "password": "s3cret-pass"}
+
+	k.JSON(http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "Alice", "email": credentials["email"], "password": credentials["password"],
+	}).Status(http.StatusCreated)
+
+	// Chưa xác thực email thì chưa đăng nhập được
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", credentials).Fails(http.StatusForbidden, "email_not_verified")
+
+	msg := k.Mail.Wait(t, credentials["email"])
+	k.JSON(http.MethodPost, "/api/v1/auth/verify-email", "", map[string]string{
+		"token": testkit.LinkToken(t, msg),
+	}).Status(http.StatusOK)
+
+	var login struct {
+		Token string `json:"token"`
+		User  struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		} `json:"user"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", credentials).Status(http.StatusOK).Decode(&login)
+	if login.Token == "" {
+		t.Fatal("login returned no token")
+	}
+	if login.User.Password != "" {
+		t.Error("login response exposes the password hash")
+	}
+
+	var me struct {
+		User struct {
+			Email string `json:"email"`
+		} `json:"user"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/me", login.Token, nil).Status(http.StatusOK).Decode(&me)
+	if me.User.Email != credentials["email"] {
+		t.Errorf("GET /me email = %q, want %q", me.User.Email, credentials["email"])
+	}
+}
+
+// failingTokens - Không tạo được token xác thực (ví dụ database lỗi giữa chừng)
+type failingTokens struct {
+	repository.UserTokenRepository
+}
+
+func (failingTokens) Create(context.Context, *models.UserToken) error {
+	return errors.New("database unavailable")
+}
+
+func TestRegisterSucceedsWhenVerificationCannotBeIssued(t *testing.T) {
+	k := testkit.New(t)
+	tokens := k.Repos.UserTokens
+	k.Repos.UserTokens = failingTokens{tokens}
+	credentials := map[string]string{"email": "bob@example.test", "password": "s3cret-pass"}
+
+	var registered struct {
+		VerificationEmailSent bool `json:"verification_email_sent"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "Bob", "email": credentials["email"], "password": credentials["password"],
+	}).Status(http.StatusCreated).Decode(&registered)
+	if registered.VerificationEmailSent {
+		t.Error("verification_email_sent = true, want false")
+	}
+
+	// Tài khoản đã tồn tại: client gửi lại email xác thực thay vì đăng ký lại
+	k.Repos.UserTokens = tokens
+	k.JSON(http.MethodPost, "/api/v1/auth/resend-verification", "", map[string]string{"email": credentials["email"]}).
+		Status(http.StatusOK)
+	k.JSON(http.MethodPost, "/api/v1/auth/verify-email", "", map[string]string{
+		"token": testkit.LinkToken(t, k.Mail.Wait(t, credentials["email"])),
+	}).Status(http.StatusOK)
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", credentials).Status(http.StatusOK)
+}
+
+func TestRegisterValidation(t *testing.T) {
+	k := testkit.New(t)
+
+	p := k.JSON(http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "A", "email": "not-an-email", "password": "123",
+	}).Fails(http.StatusBadRequest, "validation_failed")
+
+	got := map[string]string{}
+	for _, f := range p.Errors {
+		got[f.Field] = f.Code
+	}
+	for field, code := range map[string]string{"name": "min", "email": "email", "password": "min"} {
+		if got[field] != code {
+			t.Errorf("field %s: code = %q, want %q (errors: %+v)", field, got[field], code, p.Errors)
+		}
+	}
+}
+
+func TestRegisterDuplicateEmail(t *testing.T) {
+	k := testkit.New(t)
+	existing := k.CreateUser()
+
+	k.JSON(http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "Someone", "email": existing.Email, "password": "another-pass",
+	}).Fails(http.StatusConflict, "email_taken")
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	k := testkit.New(t)
+	user := k.CreateUser()
+
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email": user.Email, "password": "wrong-password",
+	}).Fails(http.StatusUnauthorized, "invalid_credentials")
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email": "nobody@example.test", "password": testkit.Password,
+	}).Fails(http.StatusUnauthorized, "invalid_credentials")
+
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email": user.Email, "password": testkit.Password,
+	}).Status(http.StatusOK)
+}
+
+// forwardedLogin - Đăng nhập sai từ cùng kết nối (192.0.2.1) nhưng khai báo IP client khác;
+// mỗi lần một email để giới hạn theo tài khoản không ảnh hưởng
+func forwardedLogin(k *testkit.Kit, clientIP string, attempt int) *testkit.Response {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+		bytes.NewBufferString(fmt.Sprintf(`{"email":"nobody%d@example.test","password":"wrong-password"}`, attempt)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", clientIP)
+	return k.Do(req)
+}
+
+func TestRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	k := testkit.New(t)
+	for i := 0; i < 20; i++ {
+		forwardedLogin(k, fmt.Sprintf("203.0.113.%d", i), i).Fails(http.StatusUnauthorized, "invalid_credentials")
+	}
+	// Header do client tự đặt không tạo bucket mới
+	forwardedLogin(k, "203.0.113.200", 20).Status(http.StatusTooManyRequests)
+
+	// Sau proxy tin cậy, mỗi client có bucket riêng theo X-Forwarded-For
+	k = testkit.New(t, testkit.WithConfig(func(cfg *config.Config) {
+		cfg.Server.TrustedProxies = []string{"192.0.2.0/24"}
+	}))
+	for i := 0; i < 20; i++ {
+		forwardedLogin(k, "203.0.113.1", i).Fails(http.StatusUnauthorized, "invalid_credentials")
+	}
+	forwardedLogin(k, "203.0.113.1", 20).Status(http.StatusTooManyRequests)
+	forwardedLogin(k, "203.0.113.2", 21).Fails(http.StatusUnauthorized, "invalid_credentials")
+}
+
+func TestProtectedRoutesRequireValidSession(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+
+	k.JSON(http.MethodGet, "/api/v1/me", "", nil).Fails(http.StatusUnauthorized, "missing_authorization")
+	k.JSON(http.MethodGet, "/api/v1/me", "not-a-jwt", nil).Fails(http.StatusUnauthorized, "invalid_token")
+	k.JSON(http.MethodGet, "/api/v1/me", token, nil).Status(http.StatusOK)
+
+	// Thu hồi mọi phiên: JWT còn hạn cũng bị từ chối
+	if _, err := k.Repos.Sessions.DeleteAll(k.Context(), user.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	k.JSON(http.MethodGet, "/api/v1/me", token, nil).Fails(http.StatusUnauthorized, "session_revoked")
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	k := testkit.New(t)
+	user := k.CreateUser()
+
+	k.JSON(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": user.Email}).
+		Status(http.StatusOK)
+	token := testkit.LinkToken(t, k.Mail.Wait(t, user.Email))
+
+	k.JSON(http.MethodPost, "/api/v1/auth/reset-password", "", map[string]string{
+		"token": token, "password": "brand-new-pass",
+	}).Status(http.StatusOK)
+	// Token chỉ dùng được một lần
+	k.JSON(http.MethodPost, "/api/v1/auth/reset-password", "", map[string]string{
+		"token": token, "password": "another-pass",
+	}).Fails(http.StatusBadRequest, "invalid_reset_token")
+
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email": user.Email, "password": testkit.Password,
+	}).Fails(http.StatusUnauthorized, "invalid_credentials")
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email": user.Email, "password": "brand-new-pass",
+	}).Status(http.StatusOK)
+}
+
+func TestDisableTwoFactorLocksOutPasswordGuessing(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Repos.Users.Update(k.Context(), user.ID, repository.UserChanges{
+		TwoFactorEnabled: repository.Ptr(true), TwoFactorSecret: &secret,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < ratelimit.DefaultLockout.Threshold; i++ {
+		k.JSON(http.MethodPost, "/api/v1/me/2fa/disable", token, map[string]string{
+			"password": fmt.Sprintf("guess-%d", i), "code": "000000",
+		}).Fails(http.StatusUnauthorized, "invalid_credentials")
+	}
+
+	// Cùng bộ đếm với đổi mật khẩu: đúng mật khẩu cũng bị chặn tới khi hết thời gian khóa
+	k.JSON(http.MethodPost, "/api/v1/me/2fa/disable", token, map[string]string{
+		"password": testkit.Password, "code": "000000",
+	}).Status(http.StatusTooManyRequests)
+	k.JSON(http.MethodPost, "/api/v1/me/password", token, map[string]string{
+		"current_password": testkit.Password, "new_password": "another-pass",
+	}).Status(http.StatusTooManyRequests)
+
+	stored, err := k.Repos.Users.GetByID(k.Context(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.TwoFactorEnabled {
+		t.Error("two-factor authentication was disabled")
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// strippableExtensions - Định dạng ảnh có thể loại bỏ metadata khi trả về
+var strippableExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+}
+
+// ServeUpload - Trả file trong thư mục uploads; ảnh được loại bỏ metadata theo thiết lập của owner
+func (h *Handler) ServeUpload(c *gin.Context) {
+	rel := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+	if rel == "" {
+		apperr.Respond(c, apperr.NotFound("file_not_found", "File not found"))
+		return
+	}
+
+	fullPath := filepath.Join(h.cfg.Storage.UploadDir, filepath.FromSlash(rel))
+	if !strippableExtensions[strings.ToLower(filepath.Ext(rel))] {
+		serveRawFile(c, fullPath)
+		return
+	}
+
+	ownerID := strings.TrimPrefix(strings.SplitN(rel, "/", 2)[0], "uid_")
+	serveImage(c, fullPath, h.ownerStripMode(c, ownerID))
+}
+
+// ownerStripMode - Lấy chế độ strip mặc định của user; lỗi hoặc chưa thiết lập thì mặc định bỏ GPS
+func (h *Handler) ownerStripMode(c *gin.Context, userID string) string {
+	ctx, cancel := middleware.RequestContext(c, 5*time.Second)
+	defer cancel()
+
+	user, err := h.repos.Users.GetByID(ctx, userID)
+	if err != nil || !utils.IsValidStripMode(user.StripMetadata) {
+		return utils.StripMetadataGPS
+	}
+	return user.StripMetadata
+}
+
+func serveRawFile(c *gin.Context, fullPath string) {
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		apperr.Respond(c, apperr.NotFound("file_not_found", "File not found"))
+		return
+	}
+	c.File(fullPath)
+}
+
+func serveImage(c *gin.Context, fullPath, mode string) {
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		apperr.Respond(c, apperr.NotFound("file_not_found", "File not found"))
+		return
+	}
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to read file", err))
+		return
+	}
+
+	// Không trả file gốc nếu không xử lý được, tránh lộ GPS
+	stripped, err := utils.StripImageMetadata(data, mode)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to process image", err))
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-transform")
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), bytes.NewReader(stripped))
+}
+
+// CreateShareLink - Tạo link chia sẻ công khai cho media, có thể ghi đè chế độ strip metadata
+func (h *Handler) CreateShareLink(c *gin.Context) {
+	var input models.CreateShareLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	mediaID := c.Param("id")
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	media, err := h.repos.Media.Get(ctx, mediaID)
+	if err == repository.ErrNotFound || (err == nil && media.UserID != userID) {
+		apperr.Respond(c, apperr.NotFound("media_not_found", "Media not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch media", err))
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate share token", err))
+		return
+	}
+
+	link := models.ShareLink{
+		ID:            token,
+		UserID:        userID,
+		MediaID:       media.ID,
+		StripMetadata: input.StripMetadata,
+		CreatedAt:     time.Now().Unix(),
+	}
+	if input.ExpiresInHours > 0 {
+		link.ExpiresAt = time.Now().Add(time.Duration(input.ExpiresInHours) * time.Hour).Unix()
+	}
+
+	if err := h.repos.ShareLinks.Create(ctx, &link); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to create share link", err))
+		return
+	}
+	// ID của link là token truy cập nên không được ghi vào log
+	details := map[string]string{"strip_metadata": link.StripMetadata}
+	if link.ExpiresAt > 0 {
+		details["expires_at"] = strconv.FormatInt(link.ExpiresAt, 10)
+	}
+	h.audit(c, ctx, models.AuditEvent{Action: models.AuditShareLinkCreate, TargetType: "media", TargetID: media.ID, Details: details})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Share link created successfully",
+		"url":     fmt.Sprintf("%s/s/%s", h.cfg.Server.BaseURL, token),
+		"data":    link,
+	})
+}
+
+// GetSharedMedia - Trả media qua link chia sẻ (public)
+func (h *Handler) GetSharedMedia(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	link, err := h.repos.ShareLinks.Get(ctx, c.Param("token"))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			apperr.Respond(c, apperr.NotFound("share_link_not_found", "Share link not found"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Failed to fetch share link", err))
+		return
+	}
+
+	if link.ExpiresAt > 0 && time.Now().Unix() > link.ExpiresAt {
+		apperr.Respond(c, apperr.Gone("share_link_expired", "Share link has expired"))
+		return
+	}
+
+	media, err := h.repos.Media.Get(ctx, link.MediaID)
+	if err != nil || media.Path == "" {
+		apperr.Respond(c, apperr.NotFound("media_not_found", "Media not found"))
+		return
+	}
+
+	h.notifyShareAccess(ctx, link)
+
+	fullPath := filepath.Join(h.cfg.Storage.UploadDir, filepath.FromSlash(media.Path))
+	if !strippableExtensions[strings.ToLower(filepath.Ext(media.Path))] {
+		serveRawFile(c, fullPath)
+		return
+	}
+
+	mode := link.StripMetadata
+	if !utils.IsValidStripMode(mode) {
+		mode = h.ownerStripMode(c, link.UserID)
+	}
+	serveImage(c, fullPath, mode)
+}
+
+// notifyShareAccess - Báo owner có người mở link chia sẻ, tối đa một sự kiện mỗi phút cho mỗi media
+// (trình xem video gửi nhiều range request cho một lần xem). ID của link là token truy cập nên không
+// được dùng làm key hay đưa vào sự kiện
+func (h *Handler) notifyShareAccess(ctx context.Context, link *models.ShareLink) {
+	result, err := ratelimit.Default().Take(ctx, "share_access:"+link.MediaID, ratelimit.Per(1, time.Minute))
+	if err != nil || !result.Allowed {
+		return
+	}
+	h.publish(ctx, link.UserID, models.EventShareLinkAccessed, gin.H{"media_id": link.MediaID, "accessed_at": time.Now().Unix()})
+}
+
+// DeleteShareLink - Thu hồi link chia sẻ của chính user
+func (h *Handler) DeleteShareLink(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	err := h.repos.ShareLinks.Delete(ctx, c.GetString("user_id"), c.Param("id"))
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("share_link_not_found", "Share link not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to delete share link", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link deleted successfully"})
+}
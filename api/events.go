@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/realtime"
+)
+
+const (
+	// streamRetry - Thời gian (ms) EventSource chờ trước khi tự nối lại
+	streamRetry = 3000
+	// replayBatch - Số sự kiện đọc lại mỗi lần khi client nối lại bằng Last-Event-ID
+	replayBatch = 200
+)
+
+// publish - Ghi sự kiện real-time cho user và báo hub của instance này.
+// Lỗi chỉ được log để không làm hỏng thao tác đã thực hiện xong
+func (h *Handler) publish(ctx context.Context, userID, eventType string, data any) {
+	// Vẫn ghi khi request vừa hết thời gian chờ
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	event, err := realtime.NewEvent(userID, eventType, data, h.cfg.Events.Retention.Duration)
+	if err == nil {
+		err = h.repos.Events.Append(ctx, event)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish event", "event", eventType, "user_id", userID, "error", err)
+		return
+	}
+	realtime.Notify()
+}
+
+// GetEvents - Stream server-sent events của user hiện tại. Client nối lại với Last-Event-ID
+// (hoặc ?last_event_id=) nhận lại các sự kiện đã lỡ còn trong thời gian lưu
+func (h *Handler) GetEvents(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("last_event_id")
+	}
+	after := int64(-1)
+	if resume != "" {
+		seq, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || seq < 0 {
+			apperr.Respond(c, apperr.BadRequest("invalid_last_event_id", "Last-Event-ID must be the id of an event from this stream"))
+			return
+		}
+		after = seq
+	}
+
+	// Subscribe trước khi đọc lại để không lỡ sự kiện ghi trong lúc đọc; sự kiện trùng được bỏ qua theo Seq
+	sub := realtime.Subscribe(userID)
+	if sub == nil {
+		apperr.Respond(c, apperr.New(http.StatusServiceUnavailable, "events_unavailable", "Event stream is not available, retry later"))
+		return
+	}
+	defer sub.Close()
+
+	ctx := c.Request.Context()
+	seq, err := h.repos.Events.LastSeq(ctx)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to read events", err))
+		return
+	}
+	// Seq đã chuyển qua hub: sự kiện sau mốc này tới qua subscription, đọc lại từ repository chỉ tới đây
+	// vì sự kiện có Seq lớn hơn có thể được lưu trước sự kiện Seq nhỏ hơn khi ghi đồng thời
+	committed, err := sub.Position(ctx)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to read events", err))
+		return
+	}
+	if after > seq {
+		// Id từ trước khi seq bị đặt lại: đọc lại mọi sự kiện còn lưu thay vì chờ seq vượt id cũ
+		after = 0
+	}
+	position := after
+	if after < 0 {
+		// Client mới chỉ nhận sự kiện từ bây giờ; id ban đầu cho EventSource mốc để nối lại
+		position = committed
+	}
+
+	// Stream dài hơn WriteTimeout của server; hết MaxStream thì đóng để client nối lại và được xác thực lại
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.WarnContext(ctx, "failed to clear stream write deadline", "error", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Tắt buffer của reverse proxy (nginx) để sự kiện tới client ngay
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\nid: %d\n\n", streamRetry, position)
+	c.Writer.Flush()
+
+	sent := position
+	if after >= 0 {
+	replay:
+		for sent < committed {
+			missed, err := h.repos.Events.After(ctx, userID, sent, replayBatch)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.ErrorContext(ctx, "failed to replay events", "user_id", userID, "error", err)
+				}
+				return
+			}
+			for _, event := range missed {
+				if event.Seq > committed {
+					break replay
+				}
+				if writeEvent(c.Writer, event) != nil {
+					return
+				}
+				sent = event.Seq
+			}
+			if len(missed) < replayBatch {
+				break
+			}
+		}
+		c.Writer.Flush()
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Events.Heartbeat.Duration)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(h.cfg.Events.MaxStream.Duration)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			// Channel đóng khi server tắt hoặc client đọc quá chậm; client nối lại và đọc tiếp từ repository
+			if !ok {
+				return
+			}
+			if event.Seq <= sent {
+				continue
+			}
+			if writeEvent(c.Writer, event) != nil {
+				return
+			}
+			sent = event.Seq
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent - Một sự kiện SSE; Data là JSON một dòng nên không cần tách thành nhiều dòng data
+func writeEvent(w io.Writer, event models.UserEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, event.Data)
+	return err
+}
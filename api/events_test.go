@@ -0,0 +1,192 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// seq - id của sự kiện dạng số để so thứ tự
+func (e sseEvent) seq(t *testing.T) int64 {
+	t.Helper()
+	seq, err := strconv.ParseInt(e.ID, 10, 64)
+	if err != nil {
+		t.Fatalf("event id %q: %v", e.ID, err)
+	}
+	return seq
+}
+
+// eventStream - Client SSE tối giản: đọc từng sự kiện (kể cả khối chỉ có id) vào channel
+type eventStream struct {
+	resp   *http.Response
+	events chan sseEvent
+}
+
+func openStream(t *testing.T, srv *httptest.Server, token, lastEventID string) *eventStream {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	s := &eventStream{resp: resp, events: make(chan sseEvent, 16)}
+	t.Cleanup(s.close)
+	go func() {
+		defer close(s.events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				event.ID = value
+			case "event":
+				event.Event = value
+			case "data":
+				event.Data = value
+			case "":
+				if event != (sseEvent{}) {
+					s.events <- event
+				}
+				event = sseEvent{}
+			}
+		}
+	}()
+	return s
+}
+
+func (s *eventStream) next(t *testing.T) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	return sseEvent{}
+}
+
+func (s *eventStream) close() {
+	s.resp.Body.Close()
+}
+
+func TestEventStreamPushesUserEvents(t *testing.T) {
+	k := testkit.New(t)
+	srv := httptest.NewServer(k.Router)
+	t.Cleanup(srv.Close)
+	user, token := k.Login("user")
+	_, otherToken := k.Login("user")
+	album := k.CreateAlbum(user, "Holiday")
+
+	k.JSON(http.MethodGet, "/api/v1/events", "", nil).Fails(http.StatusUnauthorized, "missing_authorization")
+	k.Do(func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Last-Event-ID", "not-a-number")
+		return req
+	}()).Fails(http.StatusBadRequest, "invalid_last_event_id")
+
+	stream := openStream(t, srv, token, "")
+	if start := stream.next(t); start.ID != "0" || start.Event != "" {
+		t.Fatalf("first block = %+v, want the starting id", start)
+	}
+
+	// Sự kiện của user khác không được gửi tới stream này
+	k.Upload("/api/v1/upload/image", otherToken, testkit.NewMultipart().File("image", "other.jpg", testkit.JPEG(8, 8, nil))).
+		Status(http.StatusOK)
+	var uploaded uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().
+		File("image", "photo.jpg", testkit.JPEG(64, 48, taggedPhoto)).
+		Field("album_id", album.ID)).Status(http.StatusOK).Decode(&uploaded)
+
+	var media models.Media
+	processed := stream.next(t)
+	if processed.Event != models.EventMediaProcessed || json.Unmarshal([]byte(processed.Data), &media) != nil || media.ID != uploaded.MediaID {
+		t.Fatalf("event = %+v, want media.processed for %s", processed, uploaded.MediaID)
+	}
+	var extracted struct {
+		MediaID  string                `json:"media_id"`
+		Metadata *models.ImageMetadata `json:"metadata"`
+	}
+	metadata := stream.next(t)
+	if metadata.Event != models.EventMetadataExtracted || json.Unmarshal([]byte(metadata.Data), &extracted) != nil ||
+		extracted.MediaID != media.ID || extracted.Metadata == nil || extracted.Metadata.CameraModel != "X100V" {
+		t.Fatalf("event = %+v, want media.metadata_extracted", metadata)
+	}
+	added := stream.next(t)
+	if added.Event != models.EventAlbumMediaAdded || !strings.Contains(added.Data, `"album_id":"`+album.ID+`"`) {
+		t.Fatalf("event = %+v, want album.media_added", added)
+	}
+	if processed.seq(t) >= metadata.seq(t) || metadata.seq(t) >= added.seq(t) {
+		t.Errorf("ids %s, %s, %s are not increasing", processed.ID, metadata.ID, added.ID)
+	}
+}
+
+func TestEventStreamResumesWithLastEventID(t *testing.T) {
+	k := testkit.New(t)
+	srv := httptest.NewServer(k.Router)
+	t.Cleanup(srv.Close)
+	user, token := k.Login("user")
+	media := k.CreateMedia(user)
+
+	stream := openStream(t, srv, token, "")
+	lastID := stream.next(t).ID
+	stream.close()
+
+	// Trong lúc mất kết nối: link chia sẻ được mở hai lần, chỉ tạo một sự kiện
+	var share struct {
+		URL string `json:"url"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/media/"+media.ID+"/share", token, nil).Status(http.StatusCreated).Decode(&share)
+	path := strings.TrimPrefix(share.URL, k.Config.Server.BaseURL)
+	for i := 0; i < 2; i++ {
+		k.Do(httptest.NewRequest(http.MethodGet, path, nil)).Status(http.StatusOK)
+	}
+
+	resumed := openStream(t, srv, token, lastID)
+	if start := resumed.next(t); start.ID != lastID {
+		t.Fatalf("first block = %+v, want id %s", start, lastID)
+	}
+	accessed := resumed.next(t)
+	if accessed.Event != models.EventShareLinkAccessed || !strings.Contains(accessed.Data, `"media_id":"`+media.ID+`"`) {
+		t.Fatalf("event = %+v, want the missed share_link.accessed", accessed)
+	}
+	if strings.Contains(accessed.Data, strings.TrimPrefix(path, "/s/")) {
+		t.Errorf("event data %s contains the share token", accessed.Data)
+	}
+
+	// Sự kiện mới sau khi đọc lại vẫn tới qua cùng stream, không lặp lại sự kiện cũ
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "new.jpg", testkit.JPEG(8, 8, nil))).
+		Status(http.StatusOK)
+	if live := resumed.next(t); live.Event != models.EventMediaProcessed || live.seq(t) <= accessed.seq(t) {
+		t.Fatalf("event = %+v, want a new media.processed after %s", live, accessed.ID)
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/repository"
+)
+
+// Handler - Gom dependency của các HTTP handler; mọi truy cập dữ liệu đi qua repository được inject
+type Handler struct {
+	repos *repository.Repositories
+	cfg   *config.Config
+	// renders - Dùng chung giữa các request để giới hạn số ảnh render cùng lúc
+	renders *renderLimiter
+}
+
+// NewHandler - Tạo handler với repository Mongo (production) hoặc in-memory (test) và cấu hình đã kiểm tra
+func NewHandler(repos *repository.Repositories, cfg *config.Config) *Handler {
+	return &Handler{repos: repos, cfg: cfg, renders: newRenderLimiter(cfg.Render.Concurrency)}
+}
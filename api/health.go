@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/health"
+	"github.com/hieu9721/media-store-backend/middleware"
+)
+
+// Livez - Process còn sống và phục vụ được request; không kiểm tra phụ thuộc
+func (h *Handler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readyz - Chạy các check đã đăng ký; 503 khi một check critical lỗi, 200 kèm "degraded" khi chỉ check phụ lỗi
+func (h *Handler) Readyz(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	report := health.Default().Run(ctx)
+	status := http.StatusOK
+	if report.Status == health.StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
+
+// Version - Version, commit và thông tin runtime của binary đang chạy
+func (h *Handler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Build())
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+const maxMediaTags = 30
+
+var errAlbumNotWritable = errors.New("album not found or not a regular album")
+
+// parseTags - Tách chuỗi tags "a, b,c" thành danh sách đã chuẩn hóa, bỏ trùng
+func parseTags(raw string) []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, part := range strings.Split(raw, ",") {
+		tag := strings.ToLower(strings.TrimSpace(part))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxMediaTags {
+			break
+		}
+	}
+	return tags
+}
+
+// checkUploadAlbum - Chỉ cho phép upload vào album thường của chính user
+func (h *Handler) checkUploadAlbum(c *gin.Context, userID, albumID string) error {
+	if albumID == "" {
+		return nil
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	album, err := h.repos.Albums.Get(ctx, userID, albumID)
+	if err == repository.ErrNotFound || (err == nil && album.Type == models.AlbumTypeSmart) {
+		return errAlbumNotWritable
+	}
+	return err
+}
+
+// saveMedia - Lưu bản ghi media sau khi file đã được ghi xuống đĩa
+func (h *Handler) saveMedia(c *gin.Context, media *models.Media) error {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	media.ID = utils.GenerateID("med")
+	media.CreatedAt = time.Now().Unix()
+	media.UpdatedAt = media.CreatedAt
+
+	if err := h.repos.Media.Create(ctx, media); err != nil {
+		return err
+	}
+	h.emit(ctx, models.WebhookMediaCreated, media)
+	h.publish(ctx, media.UserID, models.EventMediaProcessed, media)
+	if media.Metadata != nil {
+		h.publish(ctx, media.UserID, models.EventMetadataExtracted, gin.H{"media_id": media.ID, "metadata": media.Metadata})
+	}
+	if media.AlbumID != "" {
+		h.emit(ctx, models.WebhookAlbumUpdated, gin.H{
+			"id": media.AlbumID, "user_id": media.UserID, "change": "media_added", "media_id": media.ID,
+		})
+		// Upload chỉ vào được album của chính user nên chủ album là người upload
+		h.publish(ctx, media.UserID, models.EventAlbumMediaAdded, gin.H{"album_id": media.AlbumID, "media_id": media.ID})
+	}
+	return nil
+}
+
+// DeleteMedia - Xóa media của chính user cùng file gốc và ảnh render đã cache; share link trỏ tới media
+// trả 404 từ đó. File được xóa sau bản ghi nên lỗi xóa file chỉ để lại file mồ côi, không để lại bản ghi hỏng
+func (h *Handler) DeleteMedia(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	userID := c.GetString("user_id")
+	media, err := h.repos.Media.Get(ctx, c.Param("id"))
+	if err == nil && media.UserID != userID {
+		err = repository.ErrNotFound
+	}
+	if err == nil {
+		err = h.repos.Media.Delete(ctx, userID, media.ID)
+	}
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("media_not_found", "Media not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to delete media", err))
+		return
+	}
+
+	if media.Path != "" {
+		err := os.Remove(filepath.Join(h.cfg.Storage.UploadDir, filepath.FromSlash(media.Path)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.ErrorContext(ctx, "failed to remove media file", "media_id", media.ID, "error", err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(h.cfg.Render.CacheDir, media.ID)); err != nil {
+		slog.ErrorContext(ctx, "failed to remove cached renders", "media_id", media.ID, "error", err)
+	}
+
+	h.audit(c, ctx, models.AuditEvent{Action: models.AuditMediaDelete, TargetType: "media", TargetID: media.ID,
+		Details: map[string]string{"owner_id": media.UserID, "type": media.Type}})
+	h.emit(ctx, models.WebhookMediaDeleted, gin.H{"id": media.ID, "user_id": media.UserID, "album_id": media.AlbumID, "type": media.Type})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
@@ -0,0 +1,570 @@
+package api
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/geocoder"
+	"github.com/hieu9721/media-store-backend/metrics"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/tracing"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Các tag EXIF 2.31 mà goexif chưa hỗ trợ
+const (
+	exifOffsetTime          exif.FieldName = "OffsetTime"
+	exifOffsetTimeOriginal  exif.FieldName = "OffsetTimeOriginal"
+	exifOffsetTimeDigitized exif.FieldName = "OffsetTimeDigitized"
+)
+
+var extraExifFields = map[uint16]exif.FieldName{
+	0x9010: exifOffsetTime,
+	0x9011: exifOffsetTimeOriginal,
+	0x9012: exifOffsetTimeDigitized,
+}
+
+// offsetTimeParser - Nạp thêm các tag OffsetTime* từ Exif sub-IFD
+type offsetTimeParser struct{}
+
+func (offsetTimeParser) Parse(x *exif.Exif) error {
+	pointer, err := x.Get(exif.ExifIFDPointer)
+	if err != nil {
+		return nil
+	}
+	offset, err := pointer.Int64(0)
+	if err != nil {
+		return nil
+	}
+
+	r := bytes.NewReader(x.Raw)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil
+	}
+	dir, _, err := tiff.DecodeDir(r, x.Tiff.Order)
+	if err != nil {
+		return nil
+	}
+	x.LoadTags(dir, extraExifFields, false)
+	return nil
+}
+
+func init() {
+	exif.RegisterParsers(offsetTimeParser{})
+}
+
+var meteringModes = map[int]string{
+	0:   "Unknown",
+	1:   "Average",
+	2:   "Center-weighted average",
+	3:   "Spot",
+	4:   "Multi-spot",
+	5:   "Multi-segment",
+	6:   "Partial",
+	255: "Other",
+}
+
+const (
+	xmpNamespace = "http://ns.adobe.com/xap/1.0/"
+	dcNamespace  = "http://purl.org/dc/elements/1.1/"
+)
+
+var (
+	jpegXMPHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// extractImageMetadata - Trích xuất metadata từ file ảnh (EXIF, XMP, PNG text, kích thước)
+func extractImageMetadata(ctx context.Context, filePath string) *models.ImageMetadata {
+	ctx, span := tracing.Start(ctx, "image.extract_metadata")
+	defer span.End()
+
+	start := time.Now()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+
+	metadata := &models.ImageMetadata{}
+	found := false
+
+	// Kích thước thực tế của ảnh (hỗ trợ cả GIF, PNG không có EXIF)
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		metadata.Format = format
+		metadata.Width = config.Width
+		metadata.Height = config.Height
+		found = true
+	}
+
+	var chunks []pngChunk
+	if bytes.HasPrefix(data, pngSignature) {
+		chunks = readPNGChunks(data)
+	}
+
+	// Đọc EXIF data
+	var exifData *exif.Exif
+	if chunks != nil {
+		for _, chunk := range chunks {
+			if chunk.kind == "eXIf" {
+				exifData, _ = exif.Decode(bytes.NewReader(chunk.data))
+				break
+			}
+		}
+	} else {
+		exifData, _ = exif.Decode(bytes.NewReader(data))
+	}
+	if exifData != nil {
+		applyExif(metadata, exifData)
+		found = true
+	}
+
+	// XMP: APP1 của JPEG hoặc iTXt "XML:com.adobe.xmp" của PNG
+	var packet []byte
+	if chunks != nil {
+		var texts map[string]string
+		texts, packet = pngTextChunks(chunks)
+		if len(texts) > 0 {
+			metadata.TextChunks = texts
+			found = true
+		}
+	} else {
+		packet = findJPEGXMP(data)
+	}
+	if packet != nil && applyXMP(metadata, packet) {
+		found = true
+	}
+	metrics.ObserveSince(metrics.ExifDuration, start)
+
+	// Reverse geocoding gọi dịch vụ bên ngoài nên không tính vào thời gian trích xuất
+	if metadata.Latitude != 0 || metadata.Longitude != 0 {
+		metadata.Location = geocoder.Reverse(ctx, metadata.Latitude, metadata.Longitude)
+	}
+
+	if !found {
+		return nil
+	}
+	return metadata
+}
+
+func applyExif(metadata *models.ImageMetadata, exifData *exif.Exif) {
+	metadata.RawExif = exifData.Raw
+	metadata.ExifTags = map[string]string{}
+	exifData.Walk(exifTagDump(metadata.ExifTags))
+
+	// Lấy thời gian chụp: ưu tiên DateTimeOriginal + OffsetTimeOriginal
+	if dateTime, offset, ok := exifDateTime(exifData); ok {
+		metadata.DateTimeOriginal = dateTime
+		metadata.TimeOffset = offset
+		if t, err := parseExifTime(dateTime, offset); err == nil {
+			metadata.DateTime = t.Unix()
+		}
+	}
+
+	// Lấy tọa độ GPS
+	if lat, long, err := exifData.LatLong(); err == nil {
+		metadata.Latitude = lat
+		metadata.Longitude = long
+	}
+
+	// Độ cao GPS, GPSAltitudeRef = 1 nghĩa là dưới mực nước biển
+	if altitude, ok := exifRational(exifData, exif.GPSAltitude); ok {
+		if ref, err := exifData.Get(exif.GPSAltitudeRef); err == nil && len(ref.Val) > 0 && ref.Val[0] == 1 {
+			altitude = -altitude
+		}
+		metadata.Altitude = &altitude
+	}
+
+	// Hướng chụp
+	if direction, ok := exifRational(exifData, exif.GPSImgDirection); ok {
+		metadata.ImgDirection = &direction
+		metadata.ImgDirectionRef = exifString(exifData, exif.GPSImgDirectionRef)
+	}
+
+	// Lấy thông tin camera và ống kính
+	metadata.CameraMake = exifString(exifData, exif.Make)
+	metadata.CameraModel = exifString(exifData, exif.Model)
+	metadata.LensMake = exifString(exifData, exif.LensMake)
+	metadata.LensModel = exifString(exifData, exif.LensModel)
+
+	// Lấy kích thước ảnh nếu không đọc được từ file
+	if metadata.Width == 0 {
+		metadata.Width, _ = exifInt(exifData, exif.PixelXDimension)
+	}
+	if metadata.Height == 0 {
+		metadata.Height, _ = exifInt(exifData, exif.PixelYDimension)
+	}
+
+	// Lấy orientation
+	metadata.Orientation, _ = exifInt(exifData, exif.Orientation)
+
+	// Lấy thông tin flash
+	if f, ok := exifInt(exifData, exif.Flash); ok {
+		if f&1 == 0 {
+			metadata.Flash = "No Flash"
+		} else {
+			metadata.Flash = "Flash Fired"
+		}
+	}
+
+	// Lấy focal length
+	if focalLength, ok := exifRational(exifData, exif.FocalLength); ok {
+		metadata.FocalLength = fmt.Sprintf("%.1fmm", focalLength)
+	}
+
+	// Lấy F-Number (aperture)
+	if fNumber, ok := exifRational(exifData, exif.FNumber); ok {
+		metadata.FNumber = fmt.Sprintf("f/%.1f", fNumber)
+	}
+
+	// Lấy exposure time
+	if exposureTime, err := exifData.Get(exif.ExposureTime); err == nil {
+		if num, denom, err := exposureTime.Rat2(0); err == nil && denom != 0 && num != 0 {
+			if num < denom {
+				metadata.ExposureTime = fmt.Sprintf("1/%d", denom/num)
+			} else {
+				metadata.ExposureTime = fmt.Sprintf("%d", num/denom)
+			}
+		}
+	}
+
+	// Lấy exposure bias (EV)
+	if bias, ok := exifRational(exifData, exif.ExposureBiasValue); ok {
+		metadata.ExposureBias = fmt.Sprintf("%+.1f EV", bias)
+	}
+
+	// Lấy ISO
+	metadata.ISO, _ = exifInt(exifData, exif.ISOSpeedRatings)
+
+	// Lấy white balance
+	if wb, ok := exifInt(exifData, exif.WhiteBalance); ok {
+		if wb == 0 {
+			metadata.WhiteBalance = "Auto"
+		} else {
+			metadata.WhiteBalance = "Manual"
+		}
+	}
+
+	// Lấy metering mode
+	if mode, ok := exifInt(exifData, exif.MeteringMode); ok {
+		metadata.MeteringMode = meteringModes[mode]
+	}
+
+	// Lấy khoảng cách tới chủ thể (mét)
+	if distance, ok := exifRational(exifData, exif.SubjectDistance); ok {
+		metadata.SubjectDistance = distance
+	}
+}
+
+// exifTagDump - Ghi mọi tag EXIF ra map dạng chuỗi, bỏ MakerNote (nhị phân, đã có trong RawExif)
+type exifTagDump map[string]string
+
+func (d exifTagDump) Walk(name exif.FieldName, tag *tiff.Tag) error {
+	if name == exif.MakerNote {
+		return nil
+	}
+	d[string(name)] = strings.Trim(tag.String(), "\"")
+	return nil
+}
+
+func exifString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(value, "\x00"))
+}
+
+func exifInt(x *exif.Exif, name exif.FieldName) (int, bool) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0, false
+	}
+	value, err := tag.Int(0)
+	return value, err == nil
+}
+
+func exifRational(x *exif.Exif, name exif.FieldName) (float64, bool) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0, false
+	}
+	num, denom, err := tag.Rat2(0)
+	if err != nil || denom == 0 {
+		return 0, false
+	}
+	value := float64(num) / float64(denom)
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, false
+	}
+	return value, true
+}
+
+// exifDateTime - Lấy DateTimeOriginal (kèm OffsetTimeOriginal), nếu không có thì dùng DateTime (kèm OffsetTime)
+func exifDateTime(x *exif.Exif) (string, string, bool) {
+	if value := exifString(x, exif.DateTimeOriginal); value != "" {
+		return value, exifString(x, exifOffsetTimeOriginal), true
+	}
+	if value := exifString(x, exif.DateTime); value != "" {
+		return value, exifString(x, exifOffsetTime), true
+	}
+	return "", "", false
+}
+
+// parseExifTime - Parse "2006:01:02 15:04:05" với offset dạng "+09:00"; không có offset thì dùng giờ local như trước
+func parseExifTime(value, offset string) (time.Time, error) {
+	const layout = "2006:01:02 15:04:05"
+	if offset != "" {
+		if t, err := time.Parse(layout+"-07:00", value+offset); err == nil {
+			return t, nil
+		}
+	}
+	return time.ParseInLocation(layout, value, time.Local)
+}
+
+// findJPEGXMP - Tìm gói XMP trong segment APP1 của JPEG
+func findJPEGXMP(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		payload := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(payload, jpegXMPHeader) {
+			return payload[len(jpegXMPHeader):]
+		}
+		pos = end
+	}
+	return nil
+}
+
+// applyXMP - Đọc rating, title, keywords từ gói XMP
+func applyXMP(metadata *models.ImageMetadata, packet []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	var stack []xml.Name
+	found := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == xmpNamespace && attr.Name.Local == "Rating" {
+					if rating, err := strconv.Atoi(attr.Value); err == nil {
+						metadata.Rating = rating
+						found = true
+					}
+				}
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" || len(stack) == 0 {
+				continue
+			}
+			current := stack[len(stack)-1]
+			switch {
+			case current.Space == xmpNamespace && current.Local == "Rating":
+				if rating, err := strconv.Atoi(text); err == nil {
+					metadata.Rating = rating
+					found = true
+				}
+			case xmpWithin(stack, dcNamespace, "title") && metadata.Title == "":
+				metadata.Title = text
+				found = true
+			case xmpWithin(stack, dcNamespace, "subject") && current.Local == "li":
+				metadata.Keywords = append(metadata.Keywords, text)
+				found = true
+			}
+		}
+	}
+
+	return found
+}
+
+func xmpWithin(stack []xml.Name, space, local string) bool {
+	for _, name := range stack {
+		if name.Space == space && name.Local == local {
+			return true
+		}
+	}
+	return false
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// readPNGChunks - Đọc danh sách chunk của file PNG, dừng ở IEND hoặc khi dữ liệu lỗi
+func readPNGChunks(data []byte) []pngChunk {
+	chunks := []pngChunk{}
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		kind := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			break
+		}
+		chunks = append(chunks, pngChunk{kind: kind, data: data[pos+8 : pos+8+length]})
+		if kind == "IEND" {
+			break
+		}
+		pos = end
+	}
+	return chunks
+}
+
+const (
+	// maxPNGTextChunks - Số chunk text tối đa được đọc trong một file
+	maxPNGTextChunks = 64
+	// maxPNGTextBytes - Tổng số byte text (sau giải nén) tối đa của một file, để nhiều chunk nén nhỏ không thành zip bomb
+	maxPNGTextBytes = 1 << 20
+	// pngXMPKeyword - Keyword của iTXt chứa XMP packet
+	pngXMPKeyword = "XML:com.adobe.xmp"
+)
+
+// errPNGTextLimit - Text giải nén vượt quá phần còn lại của maxPNGTextBytes
+var errPNGTextLimit = errors.New("png text exceeds size limit")
+
+// pngTextChunks - Giải mã các chunk tEXt, zTXt và iTXt thành map keyword -> text cùng XMP packet (nếu có).
+// Dừng khi đã đọc maxPNGTextChunks chunk hoặc tổng text vượt maxPNGTextBytes; keyword không dùng được làm
+// tên field Mongo bị bỏ qua (xem textChunkKey)
+func pngTextChunks(chunks []pngChunk) (map[string]string, []byte) {
+	texts := map[string]string{}
+	var xmp []byte
+	budget, count := maxPNGTextBytes, 0
+	for _, chunk := range chunks {
+		if chunk.kind != "tEXt" && chunk.kind != "zTXt" && chunk.kind != "iTXt" {
+			continue
+		}
+		if count++; count > maxPNGTextChunks {
+			break
+		}
+		keyword, rest, ok := bytes.Cut(chunk.data, []byte{0})
+		if !ok || len(keyword) == 0 {
+			continue
+		}
+
+		var text []byte
+		var err error
+		latin1 := true
+		switch chunk.kind {
+		case "tEXt":
+			text = rest
+		case "zTXt":
+			if len(rest) < 1 {
+				continue
+			}
+			text, err = inflate(rest[1:], budget)
+		case "iTXt":
+			// compression flag, compression method, language tag\0, translated keyword\0, text
+			if len(rest) < 2 {
+				continue
+			}
+			compressed := rest[0] == 1
+			if _, rest, ok = bytes.Cut(rest[2:], []byte{0}); !ok {
+				continue
+			}
+			if _, text, ok = bytes.Cut(rest, []byte{0}); !ok {
+				continue
+			}
+			if compressed {
+				text, err = inflate(text, budget)
+			}
+			latin1 = false
+		}
+		if errors.Is(err, errPNGTextLimit) || len(text) > budget {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		budget -= len(text)
+
+		if chunk.kind == "iTXt" && string(keyword) == pngXMPKeyword {
+			xmp = text
+			continue
+		}
+		key, ok := textChunkKey(keyword)
+		if !ok {
+			continue
+		}
+		if latin1 {
+			texts[key] = latin1ToUTF8(text)
+		} else {
+			texts[key] = string(text)
+		}
+	}
+	return texts, xmp
+}
+
+// textChunkKey - Keyword dùng làm tên field trong metadata.text_chunks: 1-79 byte như chuẩn PNG, không chứa "."
+// (Mongo hiểu là đường dẫn field) và không bắt đầu bằng "$" (toán tử)
+func textChunkKey(keyword []byte) (string, bool) {
+	if len(keyword) > 79 || keyword[0] == '$' || bytes.IndexByte(keyword, '.') >= 0 {
+		return "", false
+	}
+	return latin1ToUTF8(keyword), true
+}
+
+// inflate - Giải nén zlib; errPNGTextLimit khi kết quả dài hơn limit byte
+func inflate(data []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	text, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(text) > limit {
+		return nil, errPNGTextLimit
+	}
+	return text, nil
+}
+
+func latin1ToUTF8(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/render"
+	"github.com/hieu9721/media-store-backend/repository"
+	"golang.org/x/sync/singleflight"
+)
+
+// renderLimiter - Giới hạn số ảnh render cùng lúc trên instance và gộp các request cùng một ảnh chưa có trong cache
+type renderLimiter struct {
+	slots chan struct{}
+	group singleflight.Group
+}
+
+func newRenderLimiter(concurrency int) *renderLimiter {
+	return &renderLimiter{slots: make(chan struct{}, concurrency)}
+}
+
+// do - Chạy fn một lần cho mỗi key đang render; ctx chỉ giới hạn thời gian chờ tới lượt
+func (l *renderLimiter) do(ctx context.Context, key string, fn func() error) error {
+	_, err, _ := l.group.Do(key, func() (any, error) {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-l.slots }()
+		return nil, fn()
+	})
+	return err
+}
+
+// renderSecret - Khóa ký URL render; khi không cấu hình riêng thì suy ra từ JWT secret
+// để chữ ký URL không thể dùng làm chữ ký token và ngược lại
+func (h *Handler) renderSecret() []byte {
+	if h.cfg.Render.Secret != "" {
+		return []byte(h.cfg.Render.Secret)
+	}
+	mac := hmac.New(sha256.New, []byte(h.cfg.JWT.Secret))
+	mac.Write([]byte("media-store render"))
+	return mac.Sum(nil)
+}
+
+// CreateRenderURL - Ký URL ảnh dẫn xuất cho media của chính user. URL công khai (dùng được trong thẻ <img>)
+// và không hết hạn; đổi render.secret để vô hiệu mọi URL đã cấp
+func (h *Handler) CreateRenderURL(c *gin.Context) {
+	var input models.RenderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	opts := render.Options{
+		Width:   input.Width,
+		Height:  input.Height,
+		Fit:     input.Fit,
+		Gravity: input.Gravity,
+		Rotate:  input.Rotate,
+		Format:  input.Format,
+		Quality: input.Quality,
+	}.Normalize()
+	if err := opts.Validate(h.cfg.Render.MaxDimension); err != nil {
+		apperr.Respond(c, apperr.BadRequest("invalid_render_options", err.Error()))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	media, err := h.repos.Media.Get(ctx, c.Param("id"))
+	if err == repository.ErrNotFound || (err == nil && media.UserID != c.GetString("user_id")) {
+		apperr.Respond(c, apperr.NotFound("media_not_found", "Media not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch media", err))
+		return
+	}
+	if !renderable(media) {
+		apperr.Respond(c, apperr.Unprocessable("media_not_renderable", "Only images can be rendered"))
+		return
+	}
+
+	query := opts.Query()
+	query.Set(render.SignatureParam, render.Sign(h.renderSecret(), media.ID, opts))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Render URL created successfully",
+		"url":     h.cfg.Server.BaseURL + "/media/" + url.PathEscape(media.ID) + "/render?" + query.Encode(),
+	})
+}
+
+// RenderMedia - Trả ảnh dẫn xuất theo tham số đã ký; ảnh được render một lần rồi phục vụ từ cache.
+// Kết quả không đổi với cùng URL nên client và CDN được cache vĩnh viễn
+func (h *Handler) RenderMedia(c *gin.Context) {
+	opts, err := render.ParseQuery(c.Request.URL.Query())
+	if err == nil {
+		opts = opts.Normalize()
+		err = opts.Validate(h.cfg.Render.MaxDimension)
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.BadRequest("invalid_render_options", err.Error()))
+		return
+	}
+	mediaID := c.Param("id")
+	if render.Verify(h.renderSecret(), mediaID, opts, c.Query(render.SignatureParam)) != nil {
+		apperr.Respond(c, apperr.Forbidden("invalid_signature", "Render URL signature is missing or invalid"))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	media, err := h.repos.Media.Get(ctx, mediaID)
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("media_not_found", "Media not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch media", err))
+		return
+	}
+	if !renderable(media) {
+		apperr.Respond(c, apperr.Unprocessable("media_not_renderable", "Only images can be rendered"))
+		return
+	}
+
+	key := render.Key(media.ID, opts)
+	cachePath := filepath.Join(h.cfg.Render.CacheDir, media.ID, key)
+	if _, err := os.Stat(cachePath); errors.Is(err, fs.ErrNotExist) {
+		// Chờ tới lượt theo request gốc: client đã ngắt thì không cần render
+		err = h.renders.do(c.Request.Context(), cachePath, func() error {
+			return h.renderFile(media, opts, cachePath)
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, fs.ErrNotExist):
+			apperr.Respond(c, apperr.NotFound("file_not_found", "File not found"))
+			return
+		case errors.Is(err, render.ErrTooLarge):
+			apperr.Respond(c, apperr.Unprocessable("image_too_large", "Image is too large to render"))
+			return
+		case errors.Is(err, render.ErrUnsupported):
+			apperr.Respond(c, apperr.Unprocessable("media_not_renderable", "Image format cannot be rendered"))
+			return
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			apperr.Respond(c, apperr.New(http.StatusServiceUnavailable, "render_busy", "Too many images are being rendered, retry later"))
+			return
+		default:
+			apperr.Respond(c, apperr.Internal("Failed to render image", err))
+			return
+		}
+	} else if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to read rendered image", err))
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", `"`+strings.TrimSuffix(key, filepath.Ext(key))+`"`)
+	c.Header("Content-Type", render.ContentType(opts.Format))
+	c.File(cachePath)
+}
+
+// renderable - Ảnh có file gốc trên đĩa
+func renderable(media *models.Media) bool {
+	return media.Type == "image" && media.Path != ""
+}
+
+// renderFile - Render ảnh gốc của media vào path. File được ghi tạm rồi đổi tên để request đồng thời
+// ở instance khác không đọc phải file dở dang
+func (h *Handler) renderFile(media *models.Media, opts render.Options, path string) error {
+	src, err := os.Open(filepath.Join(h.cfg.Storage.UploadDir, filepath.FromSlash(media.Path)))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	img, err := render.Decode(src, h.cfg.Render.MaxSourcePixels)
+	if err != nil {
+		return err
+	}
+	orientation := 0
+	if media.Metadata != nil {
+		orientation = media.Metadata.Orientation
+	}
+	out := render.Transform(img, orientation, opts)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".render-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := render.Encode(tmp, out, opts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,155 @@
+package api_test
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+// renderURL - Ký URL render cho media, trả path để gửi qua router
+func renderURL(t *testing.T, k *testkit.Kit, token, mediaID string, input map[string]any) string {
+	t.Helper()
+	var signed struct {
+		URL string `json:"url"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/media/"+mediaID+"/render", token, input).Status(http.StatusOK).Decode(&signed)
+	if !strings.HasPrefix(signed.URL, k.Config.Server.BaseURL+"/media/"+mediaID+"/render?") {
+		t.Fatalf("url = %q", signed.URL)
+	}
+	return strings.TrimPrefix(signed.URL, k.Config.Server.BaseURL)
+}
+
+// rendered - Tải và giải mã ảnh render
+func rendered(t *testing.T, k *testkit.Kit, path string) (image.Image, *testkit.Response) {
+	t.Helper()
+	resp := k.Do(httptest.NewRequest(http.MethodGet, path, nil)).Status(http.StatusOK)
+	img, _, err := image.Decode(bytes.NewReader(resp.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("decode render: %v", err)
+	}
+	return img, resp
+}
+
+// red - Kênh đỏ (0-255); ảnh upload có màu đỏ tăng dần từ trái sang phải, xanh lá từ trên xuống
+func red(img image.Image, x, y int) uint32 {
+	r, _, _, _ := img.At(x, y).RGBA()
+	return r >> 8
+}
+
+func green(img image.Image, x, y int) uint32 {
+	_, g, _, _ := img.At(x, y).RGBA()
+	return g >> 8
+}
+
+func TestRenderServesSignedDerivatives(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+	_, otherToken := k.Login("user")
+	var uploaded uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "photo.jpg", testkit.JPEG(64, 48, taggedPhoto))).
+		Status(http.StatusOK).Decode(&uploaded)
+	id := uploaded.MediaID
+
+	input := map[string]any{"width": 16, "height": 16, "fit": "cover", "gravity": "east"}
+	k.JSON(http.MethodPost, "/api/v1/media/"+id+"/render", otherToken, input).Fails(http.StatusNotFound, "media_not_found")
+	k.JSON(http.MethodPost, "/api/v1/media/"+id+"/render", token, map[string]any{"width": 5000}).
+		Fails(http.StatusBadRequest, "invalid_render_options")
+	k.JSON(http.MethodPost, "/api/v1/media/"+id+"/render", token, map[string]any{"width": 16, "fit": "stretch"}).
+		Fails(http.StatusBadRequest, "validation_failed")
+	video := k.CreateMedia(user, func(m *models.Media) { m.Type = "video" })
+	k.JSON(http.MethodPost, "/api/v1/media/"+video.ID+"/render", token, map[string]any{"width": 16}).
+		Fails(http.StatusUnprocessableEntity, "media_not_renderable")
+
+	path := renderURL(t, k, token, id, input)
+	img, resp := rendered(t, k, path)
+	if b := img.Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+		t.Fatalf("size = %v, want 16x16", b.Size())
+	}
+	// Cắt phía đông giữ lại cạnh phải (đỏ đậm) của ảnh 64x48
+	if r := red(img, 0, 8); r < 64 {
+		t.Errorf("left edge red = %d, want the east part of the image", r)
+	}
+	if ct := resp.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cc := resp.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	if bytes.Contains(resp.Body.Bytes(), []byte("X100V")) {
+		t.Error("render kept the EXIF block of the original")
+	}
+
+	// Lần sau phục vụ từ cache; ETag cho phép client kiểm tra lại mà không tải lại
+	files, _ := filepath.Glob(filepath.Join(k.Config.Render.CacheDir, id, "*.jpg"))
+	if len(files) != 1 {
+		t.Fatalf("cache files = %v, want one render", files)
+	}
+	cached, _ := os.Stat(files[0])
+	_, again := rendered(t, k, path)
+	if after, _ := os.Stat(files[0]); !after.ModTime().Equal(cached.ModTime()) || !bytes.Equal(again.Body.Bytes(), resp.Body.Bytes()) {
+		t.Error("second request rendered the image again")
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", resp.Header().Get("ETag"))
+	k.Do(req).Status(http.StatusNotModified)
+
+	// Tham số không có trong chữ ký hoặc bị sửa đều bị từ chối trước khi render
+	u, _ := url.Parse(path)
+	q := u.Query()
+	q.Set("w", "4096")
+	k.Do(httptest.NewRequest(http.MethodGet, u.Path+"?"+q.Encode(), nil)).Fails(http.StatusForbidden, "invalid_signature")
+	q = u.Query()
+	q.Del("sig")
+	k.Do(httptest.NewRequest(http.MethodGet, u.Path+"?"+q.Encode(), nil)).Fails(http.StatusForbidden, "invalid_signature")
+	other := k.CreateMedia(k.CreateUser())
+	k.Do(httptest.NewRequest(http.MethodGet, strings.Replace(path, id, other.ID, 1), nil)).Fails(http.StatusForbidden, "invalid_signature")
+	k.Do(httptest.NewRequest(http.MethodGet, path+"&x=1", nil)).Fails(http.StatusBadRequest, "invalid_render_options")
+}
+
+func TestRenderResizesRotatesAndConverts(t *testing.T) {
+	k := testkit.New(t)
+	_, token := k.Login("user")
+	var uploaded uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "photo.jpg", testkit.JPEG(64, 48, nil))).
+		Status(http.StatusOK).Decode(&uploaded)
+
+	tests := []struct {
+		name          string
+		input         map[string]any
+		width, height int
+		contentType   string
+	}{
+		{"contain keeps the aspect ratio", map[string]any{"width": 32, "height": 32, "fit": "contain", "format": "png"}, 32, 24, "image/png"},
+		{"fill stretches", map[string]any{"width": 10, "height": 30, "fit": "fill"}, 10, 30, "image/jpeg"},
+		{"height only", map[string]any{"height": 12, "quality": 50}, 16, 12, "image/jpeg"},
+		{"rotation swaps the sides", map[string]any{"width": 24, "rotate": 90}, 24, 32, "image/jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, resp := rendered(t, k, renderURL(t, k, token, uploaded.MediaID, tt.input))
+			if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Errorf("size = %v, want %dx%d", b.Size(), tt.width, tt.height)
+			}
+			if ct := resp.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			if tt.input["rotate"] == 90 {
+				// Góc trên trái của ảnh gốc (tối) sang góc trên phải; cạnh trái là cạnh dưới cũ (xanh lá đậm)
+				if green(img, 0, 0) < 128 || green(img, 23, 0) > 64 {
+					t.Errorf("corners green = %d, %d, want the image turned clockwise", green(img, 0, 0), green(img, 23, 0))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// issueSessionToken - Tạo phiên đăng nhập mới cho thiết bị hiện tại và cấp JWT gắn với phiên đó
+func (h *Handler) issueSessionToken(c *gin.Context, user *models.User, deviceName string) (string, error) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	deviceName = strings.TrimSpace(deviceName)
+	if deviceName == "" {
+		deviceName = describeUserAgent(userAgent)
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:         utils.GenerateID("ses"),
+		UserID:     user.ID,
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		IP:         c.ClientIP(),
+		CreatedAt:  now.Unix(),
+		LastSeenAt: now.Unix(),
+		ExpiresAt:  now.Add(utils.TokenTTL).Unix(),
+		PurgeAt:    now.Add(utils.TokenTTL),
+	}
+	if err := h.repos.Sessions.Create(ctx, &session); err != nil {
+		return "", err
+	}
+	h.audit(c, ctx, models.AuditEvent{
+		Action: models.AuditLogin, ActorID: user.ID, ActorEmail: user.Email, TargetType: "user", TargetID: user.ID,
+		Details: map[string]string{"session_id": session.ID, "device_name": deviceName},
+	})
+
+	return utils.GenerateToken(user.ID, user.Email, user.Role, session.ID)
+}
+
+// describeUserAgent - Tên thiết bị dễ đọc (vd. "Chrome on Windows") khi client không gửi device_name
+func describeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	var platform string
+	switch {
+	case strings.Contains(userAgent, "iPhone"):
+		platform = "iPhone"
+	case strings.Contains(userAgent, "iPad"):
+		platform = "iPad"
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+
+	var client string
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		client = "Edge"
+	case strings.Contains(userAgent, "Firefox/"):
+		client = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		client = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		client = "Safari"
+	default:
+		// Client không phải trình duyệt (curl, app...): lấy tên sản phẩm đầu tiên
+		client = strings.SplitN(strings.SplitN(userAgent, " ", 2)[0], "/", 2)[0]
+	}
+
+	if platform == "" {
+		return client
+	}
+	return client + " on " + platform
+}
+
+// GetSessions - Danh sách phiên đăng nhập đang hoạt động của user hiện tại
+func (h *Handler) GetSessions(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	sessions, err := h.repos.Sessions.ListActive(ctx, c.GetString("user_id"), time.Now().Unix())
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch sessions", err))
+		return
+	}
+
+	currentID := c.GetString("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": len(sessions), "data": sessions})
+}
+
+// DeleteSession - Thu hồi một phiên (đăng xuất thiết bị); thu hồi phiên hiện tại tương đương đăng xuất
+func (h *Handler) DeleteSession(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	err := h.repos.Sessions.Delete(ctx, c.GetString("user_id"), c.Param("id"))
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("session_not_found", "Session not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to revoke session", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// DeleteOtherSessions - Thu hồi mọi phiên khác, giữ lại phiên đang dùng
+func (h *Handler) DeleteOtherSessions(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	count, err := h.repos.Sessions.DeleteAll(ctx, c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to revoke sessions", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked successfully", "revoked": count})
+}
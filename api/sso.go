@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/sso"
+	"github.com/hieu9721/media-store-backend/utils"
+	"golang.org/x/oauth2"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// GetOIDCProviders - Danh sách provider đăng nhập ngoài đã cấu hình
+func (h *Handler) GetOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": sso.Default().Names()})
+}
+
+// OIDCLogin - Bắt đầu đăng nhập OIDC: lưu state/nonce/PKCE verifier rồi chuyển hướng tới provider
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	provider, err := sso.Default().Get(ctx, c.Param("provider"))
+	if err != nil {
+		if err == sso.ErrUnknownProvider {
+			apperr.Respond(c, apperr.NotFound("unknown_provider", "Unknown identity provider"))
+			return
+		}
+		slog.ErrorContext(ctx, "OIDC discovery failed", "provider", c.Param("provider"), "error", err)
+		apperr.Respond(c, apperr.BadGateway("idp_unavailable", "Identity provider is unavailable"))
+		return
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to start login", err))
+		return
+	}
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to start login", err))
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	expiresAt := time.Now().Add(oidcStateTTL)
+	record := models.OIDCState{
+		ID:        utils.HashToken(state),
+		Provider:  provider.Name(),
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: expiresAt.Unix(),
+		PurgeAt:   expiresAt,
+	}
+	if err := h.repos.OIDCStates.Create(ctx, &record); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to start login", err))
+		return
+	}
+
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, verifier))
+}
+
+// OIDCCallback - Hoàn tất đăng nhập OIDC: kiểm tra state, đổi code, xác thực ID token,
+// liên kết hoặc tạo tài khoản rồi cấp JWT như Login
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		apperr.Respond(c, apperr.Unauthorized("idp_error", "Identity provider returned an error: "+errCode))
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		apperr.Respond(c, apperr.BadRequest("missing_oidc_params", "Missing code or state"))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 15*time.Second)
+	defer cancel()
+
+	// State chỉ dùng được một lần
+	record, err := h.repos.OIDCStates.Consume(ctx, utils.HashToken(state), c.Param("provider"), time.Now().Unix())
+	if err != nil {
+		apperr.Respond(c, apperr.BadRequest("invalid_oidc_state", "Invalid or expired login state"))
+		return
+	}
+
+	provider, err := sso.Default().Get(ctx, record.Provider)
+	if err != nil {
+		apperr.Respond(c, apperr.BadGateway("idp_unavailable", "Identity provider is unavailable"))
+		return
+	}
+
+	identity, err := provider.Exchange(ctx, code, record.Verifier, record.Nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "OIDC login failed", "provider", record.Provider, "error", err)
+		apperr.Respond(c, apperr.Unauthorized("idp_verification_failed", "Failed to verify identity"))
+		return
+	}
+
+	user, appErr := h.resolveExternalUser(ctx, identity)
+	if appErr != nil {
+		apperr.Respond(c, appErr)
+		return
+	}
+	// Tài khoản liên kết trước đây với email chưa xác thực đăng nhập được sau khi xác thực email, như Login
+	if !user.IsEmailVerified() {
+		apperr.Respond(c, apperr.Forbidden("email_not_verified", "Email address has not been verified"))
+		return
+	}
+
+	h.respondLogin(c, user, "")
+}
+
+// resolveExternalUser - Tìm user theo identity đã liên kết; nếu chưa có thì liên kết theo email đã xác thực
+// hoặc tạo tài khoản mới
+func (h *Handler) resolveExternalUser(ctx context.Context, identity *sso.Identity) (*models.User, *apperr.Error) {
+	now := time.Now().Unix()
+
+	link, err := h.repos.Identities.RecordLogin(ctx, identity.Provider, identity.Subject, identity.Email, now)
+	if err == nil {
+		user, err := h.repos.Users.GetByID(ctx, link.UserID)
+		if err != nil {
+			return nil, apperr.Unauthorized("identity_orphaned", "Linked account no longer exists")
+		}
+		return user, nil
+	}
+	if err != repository.ErrNotFound {
+		return nil, apperr.Internal("Database error", err)
+	}
+
+	if identity.Email == "" {
+		return nil, apperr.BadRequest("idp_missing_email", "Identity provider did not return an email address")
+	}
+
+	user, err := h.repos.Users.GetByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		// Chỉ tự liên kết khi cả provider và tài khoản hiện có đều đã xác thực email,
+		// tránh chiếm tài khoản bằng cách đăng ký trước email của người khác
+		if !identity.EmailVerified || !user.IsEmailVerified() {
+			return nil, apperr.Conflict("email_taken", "An account with this email already exists. Log in with your password first")
+		}
+	case err == repository.ErrNotFound:
+		// Email chưa được provider xác thực thì không tạo tài khoản: tài khoản đó sẽ giữ email
+		// của người khác mà không có cách nào chứng minh quyền sở hữu
+		if !identity.EmailVerified {
+			return nil, apperr.Forbidden("idp_email_unverified", "Identity provider has not verified this email address")
+		}
+		verified := true
+		name := identity.Name
+		if len(name) < 2 {
+			name = identity.Email
+		}
+		user = &models.User{
+			ID:            utils.GenerateUserID(),
+			Name:          name,
+			Email:         identity.Email,
+			EmailVerified: &verified,
+			Role:          "user",
+			Avatar:        identity.Picture,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := h.repos.Users.Create(ctx, user); err == repository.ErrDuplicate {
+			return nil, apperr.Conflict("email_taken", "An account with this email already exists. Log in with your password first")
+		} else if err != nil {
+			return nil, apperr.Internal("Failed to create user", err)
+		}
+		h.emit(ctx, models.WebhookUserCreated, webhookUser(user))
+	default:
+		return nil, apperr.Internal("Database error", err)
+	}
+
+	link = &models.ExternalIdentity{
+		ID:          utils.GenerateID("ext"),
+		UserID:      user.ID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	}
+	if err := h.repos.Identities.Create(ctx, link); err == repository.ErrDuplicate {
+		return nil, apperr.Conflict("identity_linked", "This identity is already linked to an account")
+	} else if err != nil {
+		return nil, apperr.Internal("Failed to link identity", err)
+	}
+
+	return user, nil
+}
+
+// GetMyIdentities - Danh sách identity ngoài đã liên kết với tài khoản hiện tại
+func (h *Handler) GetMyIdentities(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	identities, err := h.repos.Identities.ListByUser(ctx, c.GetString("user_id"))
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch identities", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": len(identities), "data": identities})
+}
+
+// DeleteMyIdentity - Hủy liên kết identity ngoài; không cho hủy phương thức đăng nhập cuối cùng
+func (h *Handler) DeleteMyIdentity(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, ok := h.findCurrentUser(c, ctx)
+	if !ok {
+		return
+	}
+
+	if user.Password == "" {
+		count, err := h.repos.Identities.CountByUser(ctx, user.ID)
+		if err != nil {
+			apperr.Respond(c, apperr.Internal("Database error", err))
+			return
+		}
+		if count <= 1 {
+			apperr.Respond(c, apperr.Conflict("last_sign_in_method", "Set a password before removing your last sign-in method"))
+			return
+		}
+	}
+
+	err := h.repos.Identities.Delete(ctx, user.ID, c.Param("id"))
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("identity_not_found", "Identity not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to unlink identity", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
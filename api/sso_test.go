@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+// oidcLogin - Body của callback đăng nhập thành công
+type oidcLogin struct {
+	Token string `json:"token"`
+	User  struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+// identities - Số identity ngoài đã liên kết với user của token
+func identities(k *testkit.Kit, token string) int {
+	var list struct {
+		Count int `json:"count"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/me/identities", token, nil).Status(http.StatusOK).Decode(&list)
+	return list.Count
+}
+
+func TestOIDCCreatesAccountForVerifiedEmail(t *testing.T) {
+	k := testkit.New(t)
+	idp := testkit.NewOIDCProvider(t, "corp")
+
+	var login oidcLogin
+	idp.Login(k, testkit.OIDCClaims{Subject: "sub-1", Email: "carol@example.test", EmailVerified: true, Name: "Carol"}).
+		Status(http.StatusOK).Decode(&login)
+	if login.Token == "" || login.User.Email != "carol@example.test" {
+		t.Fatalf("login = %+v", login)
+	}
+	if n := identities(k, login.Token); n != 1 {
+		t.Errorf("identities = %d, want 1", n)
+	}
+
+	// Lần sau đăng nhập lại đúng tài khoản đó qua identity đã liên kết
+	var again oidcLogin
+	idp.Login(k, testkit.OIDCClaims{Subject: "sub-1", Email: "carol@example.test", EmailVerified: true}).
+		Status(http.StatusOK).Decode(&again)
+	if again.User.ID != login.User.ID {
+		t.Errorf("second login user = %q, want %q", again.User.ID, login.User.ID)
+	}
+
+	// Provider chưa xác thực email: không tạo tài khoản giữ email đó
+	idp.Login(k, testkit.OIDCClaims{Subject: "sub-2", Email: "dave@example.test"}).
+		Fails(http.StatusForbidden, "idp_email_unverified")
+	if _, err := k.Repos.Users.GetByEmail(k.Context(), "dave@example.test"); err == nil {
+		t.Error("account created for an unverified email")
+	}
+}
+
+func TestOIDCLinksAccountWithVerifiedEmail(t *testing.T) {
+	k := testkit.New(t)
+	idp := testkit.NewOIDCProvider(t, "corp")
+	user := k.CreateUser(testkit.WithEmail("erin@example.test"))
+
+	var login oidcLogin
+	idp.Login(k, testkit.OIDCClaims{Subject: "sub-erin", Email: "erin@example.test", EmailVerified: true}).
+		Status(http.StatusOK).Decode(&login)
+	if login.User.ID != user.ID {
+		t.Fatalf("linked user = %q, want existing account %q", login.User.ID, user.ID)
+	}
+	if n := identities(k, k.Token(user)); n != 1 {
+		t.Errorf("identities = %d, want 1", n)
+	}
+}
+
+func TestOIDCRefusesUnverifiedEmailOfExistingAccount(t *testing.T) {
+	k := testkit.New(t)
+	idp := testkit.NewOIDCProvider(t, "corp")
+	user := k.CreateUser(testkit.WithEmail("frank@example.test"))
+
+	// Người khác đăng ký email của frank ở provider không xác thực email: không được chiếm tài khoản
+	idp.Login(k, testkit.OIDCClaims{Subject: "attacker", Email: "frank@example.test"}).
+		Fails(http.StatusConflict, "email_taken")
+	if n := identities(k, k.Token(user)); n != 0 {
+		t.Errorf("identities = %d, want 0", n)
+	}
+
+	// Tài khoản chưa xác thực email cũng không được tự liên kết
+	pending := k.CreateUser(testkit.WithEmail("grace@example.test"), testkit.Unverified())
+	idp.Login(k, testkit.OIDCClaims{Subject: "sub-grace", Email: pending.Email, EmailVerified: true}).
+		Fails(http.StatusConflict, "email_taken")
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/mailer"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+	"github.com/hieu9721/media-store-backend/webhook"
+)
+
+const (
+	// statsRetention - Thời gian giữ lịch sử đăng nhập lỗi và lần chạy job trước khi TTL index xóa
+	statsRetention = 90 * 24 * time.Hour
+	// statsDefaultDays, statsMaxDays - Khoảng thống kê mặc định và tối đa (tính theo ngày)
+	statsDefaultDays = 30
+	statsMaxDays     = 366
+	// statsTimeout - Aggregation trên khoảng dài có thể chậm hơn truy vấn thường
+	statsTimeout = 30 * time.Second
+)
+
+// JobRecorder - Lưu mỗi lần chạy job nền cho thống kê admin; đăng ký với mailer.Observe
+func JobRecorder(repos *repository.Repositories) func(ctx context.Context, job string, elapsed time.Duration, err error) {
+	return func(ctx context.Context, job string, elapsed time.Duration, err error) {
+		now := time.Now()
+		run := &models.JobRun{
+			ID:         utils.GenerateID("job"),
+			Job:        job,
+			Status:     models.JobStatusOK,
+			DurationMs: float64(elapsed.Microseconds()) / 1000,
+			CreatedAt:  now.Unix(),
+			PurgeAt:    now.Add(statsRetention),
+		}
+		if err != nil {
+			run.Status = models.JobStatusFailed
+			run.Error = err.Error()
+		}
+		// ctx của job có thể đã hết hạn khi job chạy quá lâu
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := repos.JobRuns.Create(ctx, run); err != nil {
+			slog.ErrorContext(ctx, "failed to record job run", "job", job, "error", err)
+		}
+	}
+}
+
+// statsQuery - Tham số chung của các endpoint thống kê
+type statsQuery struct {
+	Range repository.StatsRange
+	Limit int
+	CSV   bool
+}
+
+// parseDateParam - Tham số ngày YYYY-MM-DD (UTC) hoặc thời điểm RFC 3339; dateOnly cho biết giá trị là cả ngày
+func parseDateParam(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// parseStatsQuery - Đọc from/to (to tính cả ngày cuối khi là ngày), format và limit; mặc định 30 ngày gần nhất
+func parseStatsQuery(c *gin.Context) (*statsQuery, bool) {
+	var fields []apperr.FieldError
+	invalidDate := func(field string) {
+		fields = append(fields, apperr.FieldError{
+			Field: field, Code: "date", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+		})
+	}
+
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if value := c.Query("to"); value != "" {
+		t, dateOnly, err := parseDateParam(value)
+		if err != nil {
+			invalidDate("to")
+		} else if dateOnly {
+			to = t.AddDate(0, 0, 1)
+		} else {
+			to = t
+		}
+	}
+	from := to.AddDate(0, 0, -statsDefaultDays)
+	if value := c.Query("from"); value != "" {
+		t, _, err := parseDateParam(value)
+		if err != nil {
+			invalidDate("from")
+		} else {
+			from = t
+		}
+	}
+
+	format := c.Query("format")
+	if format != "" && format != "json" && format != "csv" {
+		fields = append(fields, apperr.FieldError{Field: "format", Code: "oneof", Message: "must be one of: json, csv"})
+	}
+	if len(fields) > 0 {
+		apperr.Respond(c, apperr.InvalidFields(fields, nil))
+		return nil, false
+	}
+
+	if !from.Before(to) {
+		apperr.Respond(c, apperr.BadRequest("invalid_range", "from must be before to"))
+		return nil, false
+	}
+	if to.Sub(from) > statsMaxDays*24*time.Hour {
+		apperr.Respond(c, apperr.BadRequest("invalid_range", fmt.Sprintf("Date range must not exceed %d days", statsMaxDays)))
+		return nil, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	return &statsQuery{
+		Range: repository.StatsRange{From: from.Unix(), To: to.Unix()},
+		Limit: limit,
+		CSV:   format == "csv" || (format == "" && strings.Contains(c.GetHeader("Accept"), "text/csv")),
+	}, true
+}
+
+// respond - JSON {from, to, data, ...extra} hoặc file CSV (header + rows) khi client yêu cầu
+func (q *statsQuery) respond(c *gin.Context, name string, data any, extra gin.H, header []string, rows func() [][]string) {
+	from := time.Unix(q.Range.From, 0).UTC()
+	to := time.Unix(q.Range.To, 0).UTC()
+	if !q.CSV {
+		body := gin.H{"from": from.Format(time.RFC3339), "to": to.Format(time.RFC3339), "data": data}
+		for key, value := range extra {
+			body[key] = value
+		}
+		c.JSON(http.StatusOK, body)
+		return
+	}
+
+	// Tên file dùng ngày cuối cùng có trong khoảng
+	filename := fmt.Sprintf("media-store-%s-%s-%s.csv", name, from.Format(time.DateOnly), to.Add(-time.Second).Format(time.DateOnly))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	for _, row := range rows() {
+		for i, cell := range row {
+			row[i] = csvCell(cell)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to write csv", "report", name, "error", err)
+	}
+}
+
+// csvCell - Thêm dấu ' trước ô bắt đầu bằng ký tự công thức: giá trị do user đặt (tên, EXIF Make/Model)
+// không được chạy như công thức khi mở file bằng Excel hoặc Google Sheets
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func formatMillis(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// StatsUsers - Tổng số user tại cuối khoảng và số đăng ký mới theo ngày
+func (h *Handler) StatsUsers(c *gin.Context) {
+	q, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := middleware.RequestContext(c, statsTimeout)
+	defer cancel()
+
+	stats, err := h.repos.Stats.Users(ctx, q.Range)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	q.respond(c, "users", stats, nil, []string{"date", "signups", "total_users"}, func() [][]string {
+		total := stats.Total - stats.Signups
+		rows := [][]string{}
+		for _, d := range stats.SignupsPerDay {
+			total += d.Count
+			rows = append(rows, []string{d.Date, formatInt(d.Count), formatInt(total)})
+		}
+		return rows
+	})
+}
+
+// StatsUploads - Số file và dung lượng upload theo ngày và loại media
+func (h *Handler) StatsUploads(c *gin.Context) {
+	q, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := middleware.RequestContext(c, statsTimeout)
+	defer cancel()
+
+	uploads, err := h.repos.Stats.Uploads(ctx, q.Range)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	q.respond(c, "uploads", uploads, nil, []string{"date", "type", "count", "bytes"}, func() [][]string {
+		rows := [][]string{}
+		for _, u := range uploads {
+			rows = append(rows, []string{u.Date, u.Type, formatInt(u.Count), formatInt(u.Bytes)})
+		}
+		return rows
+	})
+}
+
+// StatsStorage - Top user theo dung lượng upload trong khoảng
+func (h *Handler) StatsStorage(c *gin.Context) {
+	q, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := middleware.RequestContext(c, statsTimeout)
+	defer cancel()
+
+	usage, err := h.repos.Stats.Storage(ctx, q.Range, q.Limit)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	header := []string{"user_id", "name", "email", "files", "images", "videos", "bytes"}
+	q.respond(c, "storage", usage, nil, header, func() [][]string {
+		rows := [][]string{}
+		for _, u := range usage {
+			rows = append(rows, []string{u.UserID, u.Name, u.Email,
+				formatInt(u.Files), formatInt(u.Images), formatInt(u.Videos), formatInt(u.Bytes)})
+		}
+		return rows
+	})
+}
+
+// StatsCameras - Phân bố model máy ảnh của ảnh upload trong khoảng
+func (h *Handler) StatsCameras(c *gin.Context) {
+	q, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := middleware.RequestContext(c, statsTimeout)
+	defer cancel()
+
+	cameras, err := h.repos.Stats.Cameras(ctx, q.Range, q.Limit)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	q.respond(c, "cameras", cameras, nil, []string{"make", "model", "count"}, func() [][]string {
+		rows := [][]string{}
+		for _, cam := range cameras {
+			rows = append(rows, []string{cam.Make, cam.Model, formatInt(cam.Count)})
+		}
+		return rows
+	})
+}
+
+// StatsFailedLogins - Số lần xác thực thất bại theo ngày và lý do
+func (h *Handler) StatsFailedLogins(c *gin.Context) {
+	q, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := middleware.RequestContext(c, statsTimeout)
+	defer cancel()
+
+	failures, err := h.repos.Stats.FailedLogins(ctx, q.Range)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	q.respond(c, "logins", failures, nil, []string{"date", "reason", "count"}, func() [][]string {
+		rows := [][]string{}
+		for _, f := range failures {
+			rows = append(rows, []string{f.Date, f.Reason, formatInt(f.Count)})
+		}
+		return rows
+	})
+}
+
+// StatsJobs - Số lần chạy, lỗi và thời gian chạy của job nền theo ngày, kèm trạng thái hàng đợi hiện tại
+func (h *Handler) StatsJobs(c *gin.Context) {
+	q, ok := parseStatsQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := middleware.RequestContext(c, statsTimeout)
+	defer cancel()
+
+	runs, err := h.repos.Stats.JobRuns(ctx, q.Range)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	queues := gin.H{
+		"mail":    gin.H{"pending": mailer.Pending(), "last_activity": mailer.LastActivity().Unix()},
+		"webhook": gin.H{"pending": webhook.Pending(), "last_activity": webhook.LastActivity().Unix()},
+	}
+	header := []string{"date", "job", "runs", "failures", "avg_duration_ms", "max_duration_ms"}
+	q.respond(c, "jobs", runs, gin.H{"queues": queues}, header, func() [][]string {
+		rows := [][]string{}
+		for _, r := range runs {
+			rows = append(rows, []string{r.Date, r.Job, formatInt(r.Runs), formatInt(r.Failures),
+				formatMillis(r.AvgDurationMs), formatMillis(r.MaxDurationMs)})
+		}
+		return rows
+	})
+}
@@ -0,0 +1,201 @@
+package api_test
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+func TestStatsRequireAdmin(t *testing.T) {
+	k := testkit.New(t)
+	_, userToken := k.Login("user")
+
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/users", "", nil).Fails(http.StatusUnauthorized, "missing_authorization")
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/users", userToken, nil).Fails(http.StatusForbidden, "admin_required")
+}
+
+func TestStatsUploadsAndStorage(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	alice := k.CreateUser(testkit.WithName("Alice Uploader"))
+	bob := k.CreateUser()
+	photo := models.ImageMetadata{CameraMake: "Fujifilm", CameraModel: "X100V"}
+	k.CreateMedia(alice, testkit.WithMetadata(photo))
+	k.CreateMedia(alice, testkit.WithMetadata(photo))
+	k.CreateMedia(bob)
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	var uploads struct {
+		From string                 `json:"from"`
+		Data []models.UploadsPerDay `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/uploads", adminToken, nil).Status(http.StatusOK).Decode(&uploads)
+	if len(uploads.Data) != 1 || uploads.Data[0].Date != today || uploads.Data[0].Type != "image" || uploads.Data[0].Count != 3 {
+		t.Errorf("uploads = %+v, want 3 images today", uploads.Data)
+	}
+
+	var storage struct {
+		Data []models.StorageUsage `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/storage?limit=1", adminToken, nil).Status(http.StatusOK).Decode(&storage)
+	if len(storage.Data) != 1 || storage.Data[0].UserID != alice.ID || storage.Data[0].Name != "Alice Uploader" || storage.Data[0].Files != 2 {
+		t.Errorf("storage = %+v, want only %s with 2 files", storage.Data, alice.ID)
+	}
+
+	var cameras struct {
+		Data []models.CameraCount `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/cameras", adminToken, nil).Status(http.StatusOK).Decode(&cameras)
+	if len(cameras.Data) != 1 || cameras.Data[0] != (models.CameraCount{Make: "Fujifilm", Model: "X100V", Count: 2}) {
+		t.Errorf("cameras = %+v", cameras.Data)
+	}
+
+	// Khoảng kết thúc hôm qua không có upload nào
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/uploads?to="+yesterday, adminToken, nil).Status(http.StatusOK).Decode(&uploads)
+	if len(uploads.Data) != 0 {
+		t.Errorf("uploads before today = %+v, want none", uploads.Data)
+	}
+}
+
+func TestStatsFailedLoginsCSV(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	user := k.CreateUser()
+
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": user.Email, "password": "wrong-password"}).
+		Status(http.StatusUnauthorized)
+	k.JSON(http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "nobody@example.test", "password": "wrong-password"}).
+		Status(http.StatusUnauthorized)
+
+	weekAgo := time.Now().UTC().AddDate(0, 0, -7).Format(time.DateOnly)
+	today := time.Now().UTC().Format(time.DateOnly)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats/logins?from="+weekAgo+"&to="+today, nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	req.Header.Set("Accept", "text/csv")
+	w := k.Do(req).Status(http.StatusOK)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("content type = %q, want text/csv", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="media-store-logins-`+weekAgo+"-"+today+`.csv"`) {
+		t.Errorf("content disposition = %q", cd)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"date", "reason", "count"},
+		{today, models.LoginFailureUnknownEmail, "1"},
+		{today, models.LoginFailureWrongPassword, "1"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("csv rows = %v, want %v", rows, want)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %v, want %v", i, rows[i], want[i])
+		}
+	}
+}
+
+// statsCSV - Báo cáo dạng CSV đã parse
+func statsCSV(t *testing.T, k *testkit.Kit, path, token string) [][]string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "text/csv")
+	rows, err := csv.NewReader(k.Do(req).Status(http.StatusOK).Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestStatsCSVEscapesFormulas(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	_, token := k.Login("user", testkit.WithName("-2+3 Mallory"))
+	crafted := &testkit.EXIF{Make: `=HYPERLINK("http://evil.test","open")`, Model: "@SUM(A1)"}
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "photo.jpg", testkit.JPEG(8, 8, crafted))).
+		Status(http.StatusOK)
+
+	cameras := statsCSV(t, k, "/api/v1/admin/stats/cameras", adminToken)
+	if len(cameras) != 2 || cameras[1][0] != `'=HYPERLINK("http://evil.test","open")` || cameras[1][1] != "'@SUM(A1)" || cameras[1][2] != "1" {
+		t.Errorf("cameras = %q, want formula cells prefixed with '", cameras)
+	}
+	storage := statsCSV(t, k, "/api/v1/admin/stats/storage", adminToken)
+	if len(storage) != 2 || storage[1][1] != "'-2+3 Mallory" {
+		t.Errorf("storage = %q, want the name prefixed with '", storage)
+	}
+}
+
+func TestStatsJobsRecordsMail(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	user := k.CreateUser()
+
+	k.JSON(http.MethodPost, "/api/v1/auth/forgot-password", "", map[string]string{"email": user.Email}).Status(http.StatusOK)
+	k.Mail.Wait(t, user.Email)
+
+	// Lần chạy được ghi ngay sau khi gửi xong, nên có thể trễ hơn email một chút
+	var jobs struct {
+		Data   []models.JobRunsPerDay `json:"data"`
+		Queues map[string]struct {
+			Pending int `json:"pending"`
+		} `json:"queues"`
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		k.JSON(http.MethodGet, "/api/v1/admin/stats/jobs", adminToken, nil).Status(http.StatusOK).Decode(&jobs)
+		if len(jobs.Data) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(jobs.Data) != 1 || jobs.Data[0].Job != "mail" || jobs.Data[0].Runs != 1 || jobs.Data[0].Failures != 0 {
+		t.Errorf("jobs = %+v, want one successful mail run", jobs.Data)
+	}
+	if _, ok := jobs.Queues["mail"]; !ok {
+		t.Errorf("queues = %+v, want the mail queue", jobs.Queues)
+	}
+}
+
+func TestStatsRangeValidation(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+
+	p := k.JSON(http.MethodGet, "/api/v1/admin/stats/users?from=yesterday&format=xml", adminToken, nil).
+		Fails(http.StatusBadRequest, "validation_failed")
+	got := map[string]string{}
+	for _, f := range p.Errors {
+		got[f.Field] = f.Code
+	}
+	if got["from"] != "date" || got["format"] != "oneof" {
+		t.Errorf("errors = %+v, want date on from and oneof on format", p.Errors)
+	}
+
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/users?from=2024-03-10&to=2024-03-01", adminToken, nil).
+		Fails(http.StatusBadRequest, "invalid_range")
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/users?from=2022-01-01&to=2024-01-01", adminToken, nil).
+		Fails(http.StatusBadRequest, "invalid_range")
+
+	var users struct {
+		From string           `json:"from"`
+		To   string           `json:"to"`
+		Data models.UserStats `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/admin/stats/users", adminToken, nil).Status(http.StatusOK).Decode(&users)
+	if users.Data.Total != 1 || users.Data.Admins != 1 || users.Data.Signups != 1 {
+		t.Errorf("users = %+v, want the admin only", users.Data)
+	}
+	if from, _ := time.Parse(time.RFC3339, users.From); time.Since(from) < 29*24*time.Hour {
+		t.Errorf("default range starts at %s, want 30 days back", users.From)
+	}
+}
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+const recoveryCodeCount = 10
+
+// hashRecoveryCodes - Chỉ lưu hash của mã khôi phục
+func hashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return hashes
+}
+
+// useTOTPCode - Kiểm tra mã TOTP và ghi nhận bước thời gian đã dùng để mã không bị dùng lại
+func (h *Handler) useTOTPCode(ctx context.Context, user *models.User, secret, code string) (bool, error) {
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.repos.Users.AdvanceTOTPStep(ctx, user.ID, step)
+}
+
+// useRecoveryCode - Xóa mã khôi phục khỏi danh sách một cách atomic; mỗi mã chỉ dùng được một lần
+func (h *Handler) useRecoveryCode(ctx context.Context, userID, code string) (bool, error) {
+	hash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	return h.repos.Users.ConsumeRecoveryCode(ctx, userID, hash)
+}
+
+func (h *Handler) findCurrentUser(c *gin.Context, ctx context.Context) (*models.User, bool) {
+	user, err := h.repos.Users.GetByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
+			return nil, false
+		}
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return nil, false
+	}
+	return user, true
+}
+
+// SetupTwoFactor - Bắt đầu đăng ký 2FA: sinh secret chờ xác nhận và URI otpauth để hiển thị QR code
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, ok := h.findCurrentUser(c, ctx)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled {
+		apperr.Respond(c, apperr.Conflict("two_factor_already_enabled", "Two-factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate secret", err))
+		return
+	}
+
+	err = h.repos.Users.Update(ctx, user.ID, repository.UserChanges{TwoFactorPendingSecret: &secret})
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to start two-factor setup", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Scan the QR code with your authenticator app, then confirm with a code",
+		"secret":      secret,
+		"otpauth_url": utils.TOTPProvisioningURI(secret, h.cfg.TOTP.Issuer, user.Email),
+	})
+}
+
+// EnableTwoFactor - Xác nhận mã từ app authenticator, bật 2FA và trả về mã khôi phục (chỉ hiển thị một lần)
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	var input models.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, ok := h.findCurrentUser(c, ctx)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled {
+		apperr.Respond(c, apperr.Conflict("two_factor_already_enabled", "Two-factor authentication is already enabled"))
+		return
+	}
+	if user.TwoFactorPendingSecret == "" {
+		apperr.Respond(c, apperr.BadRequest("two_factor_setup_missing", "Two-factor setup has not been started"))
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(user.TwoFactorPendingSecret, input.Code, time.Now())
+	if !valid {
+		apperr.Respond(c, apperr.BadRequest("invalid_otp", "Invalid authentication code"))
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate recovery codes", err))
+		return
+	}
+
+	hashes := hashRecoveryCodes(codes)
+	err = h.repos.Users.Update(ctx, user.ID, repository.UserChanges{
+		TwoFactorEnabled:       repository.Ptr(true),
+		TwoFactorSecret:        &user.TwoFactorPendingSecret,
+		TwoFactorPendingSecret: repository.Ptr(""),
+		TwoFactorLastStep:      &step,
+		RecoveryCodes:          &hashes,
+	})
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to enable two-factor authentication", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled. Store these recovery codes in a safe place",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor - Tắt 2FA, yêu cầu cả mật khẩu và mã TOTP hiện tại
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var input models.DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, ok := h.findCurrentUser(c, ctx)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		apperr.Respond(c, apperr.BadRequest("two_factor_not_enabled", "Two-factor authentication is not enabled"))
+		return
+	}
+	// Cùng bộ đếm với đổi mật khẩu: token bị lộ không dùng được endpoint này để dò mật khẩu
+	limiter := ratelimit.Default()
+	lockKey := "password:" + user.ID
+	if wait, err := ratelimit.DefaultLockout.Check(ctx, limiter, lockKey); err == nil && wait > 0 {
+		middleware.AbortTooManyRequests(c, wait)
+		return
+	}
+	if utils.CheckPassword(user.Password, input.Password) != nil {
+		h.recordLoginFailure(c, ctx, lockKey, models.FailedLogin{Reason: models.LoginFailureCurrentPassword, Email: user.Email, UserID: user.ID})
+		apperr.Respond(c, apperr.Unauthorized("invalid_credentials", "Invalid password or authentication code"))
+		return
+	}
+	limiter.ResetFailures(ctx, lockKey)
+	if valid, err := h.useTOTPCode(ctx, user, user.TwoFactorSecret, input.Code); err != nil || !valid {
+		apperr.Respond(c, apperr.Unauthorized("invalid_credentials", "Invalid password or authentication code"))
+		return
+	}
+
+	if err := h.clearTwoFactor(ctx, user.ID); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to disable two-factor authentication", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes - Tạo bộ mã khôi phục mới, các mã cũ hết hiệu lực
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var input models.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	user, ok := h.findCurrentUser(c, ctx)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled {
+		apperr.Respond(c, apperr.BadRequest("two_factor_not_enabled", "Two-factor authentication is not enabled"))
+		return
+	}
+	if valid, err := h.useTOTPCode(ctx, user, user.TwoFactorSecret, input.Code); err != nil || !valid {
+		apperr.Respond(c, apperr.Unauthorized("invalid_otp", "Invalid authentication code"))
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate recovery codes", err))
+		return
+	}
+
+	hashes := hashRecoveryCodes(codes)
+	if err := h.repos.Users.Update(ctx, user.ID, repository.UserChanges{RecoveryCodes: &hashes}); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to save recovery codes", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Recovery codes regenerated",
+		"recovery_codes": codes,
+	})
+}
+
+// VerifyTwoFactorLogin - Bước 2 của đăng nhập: đổi challenge token + mã TOTP/mã khôi phục lấy JWT
+func (h *Handler) VerifyTwoFactorLogin(c *gin.Context) {
+	var input models.TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(input.ChallengeToken)
+	if err != nil {
+		apperr.Respond(c, apperr.Unauthorized("invalid_challenge", "Invalid or expired challenge token"))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	limiter := ratelimit.Default()
+	lockKey := "2fa:" + claims.UserID
+	if wait, err := ratelimit.DefaultLockout.Check(ctx, limiter, lockKey); err == nil && wait > 0 {
+		middleware.AbortTooManyRequests(c, wait)
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(ctx, claims.UserID)
+	if err != nil || !user.TwoFactorEnabled {
+		apperr.Respond(c, apperr.Unauthorized("invalid_challenge", "Invalid or expired challenge token"))
+		return
+	}
+
+	var valid bool
+	if input.RecoveryCode != "" {
+		valid, err = h.useRecoveryCode(ctx, user.ID, input.RecoveryCode)
+	} else {
+		valid, err = h.useTOTPCode(ctx, user, user.TwoFactorSecret, input.Code)
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	if !valid {
+		h.recordLoginFailure(c, ctx, lockKey, models.FailedLogin{Reason: models.LoginFailureInvalidOTP, Email: user.Email, UserID: user.ID})
+		apperr.Respond(c, apperr.Unauthorized("invalid_otp", "Invalid authentication code"))
+		return
+	}
+	limiter.ResetFailures(ctx, lockKey)
+
+	token, err := h.issueSessionToken(c, user, input.DeviceName)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate token", err))
+		return
+	}
+
+	user.Password = ""
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"token":   token,
+		"user":    user,
+	})
+}
+
+// ResetUserTwoFactor - Admin tắt 2FA cho user bị mất thiết bị
+func (h *Handler) ResetUserTwoFactor(c *gin.Context) {
+	userID := c.Param("id")
+	if !utils.IsValidUserID(userID) {
+		apperr.Respond(c, apperr.BadRequest("invalid_user_id", "Invalid user ID format"))
+		return
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	if err := h.clearTwoFactor(ctx, userID); err != nil {
+		if err == repository.ErrNotFound {
+			apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Failed to reset two-factor authentication", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset successfully"})
+}
+
+func (h *Handler) clearTwoFactor(ctx context.Context, userID string) error {
+	return h.repos.Users.Update(ctx, userID, repository.UserChanges{
+		TwoFactorEnabled:       repository.Ptr(false),
+		TwoFactorSecret:        repository.Ptr(""),
+		TwoFactorPendingSecret: repository.Ptr(""),
+		TwoFactorLastStep:      repository.Ptr(int64(0)),
+		RecoveryCodes:          &[]string{},
+	})
+}
@@ -2,9 +2,8 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -13,313 +12,120 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/rwcarlsen/goexif/exif"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/metrics"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var allowedImageExtensions = map[string]bool{
-	".jpg":  true,
-	".jpeg": true,
-	".png":  true,
-	".gif":  true,
-}
-
-var allowedVideoExtensions = map[string]bool{
-	".mp4":  true,
-	".avi":  true,
-	".mov":  true,
-	".mkv":  true,
-	".webm": true,
-}
-
-// LocationInfo - Thông tin vị trí chi tiết
-type LocationInfo struct {
-	Country     string `json:"country,omitempty"`
-	State       string `json:"state,omitempty"`
-	City        string `json:"city,omitempty"`
-	District    string `json:"district,omitempty"`
-	Road        string `json:"road,omitempty"`
-	PostalCode  string `json:"postal_code,omitempty"`
-	DisplayName string `json:"display_name,omitempty"`
-}
-
-// ImageMetadata - Thông tin metadata của hình ảnh
-type ImageMetadata struct {
-	DateTime     int64         `json:"date_time,omitempty"`
-	Latitude     float64       `json:"latitude,omitempty"`
-	Longitude    float64       `json:"longitude,omitempty"`
-	Location     *LocationInfo `json:"location,omitempty"`
-	CameraMake   string        `json:"camera_make,omitempty"`
-	CameraModel  string        `json:"camera_model,omitempty"`
-	Width        int           `json:"width,omitempty"`
-	Height       int           `json:"height,omitempty"`
-	Orientation  int           `json:"orientation,omitempty"`
-	Flash        string        `json:"flash,omitempty"`
-	FocalLength  string        `json:"focal_length,omitempty"`
-	FNumber      string        `json:"f_number,omitempty"`
-	ExposureTime string        `json:"exposure_time,omitempty"`
-	ISO          int           `json:"iso,omitempty"`
-}
-
-// NominatimResponse - Response từ Nominatim API
-type NominatimResponse struct {
-	DisplayName string `json:"display_name"`
-	Address     struct {
-		Country    string `json:"country"`
-		State      string `json:"state"`
-		City       string `json:"city"`
-		Town       string `json:"town"`
-		Village    string `json:"village"`
-		County     string `json:"county"`
-		District   string `json:"district"`
-		Road       string `json:"road"`
-		PostalCode string `json:"postcode"`
-	} `json:"address"`
-}
-
-// reverseGeocode - Chuyển đổi tọa độ GPS thành địa chỉ
-func reverseGeocode(lat, lon float64) *LocationInfo {
-	// Sử dụng Nominatim API (OpenStreetMap)
-	url := fmt.Sprintf("https://nominatim.openstreetmap.org/reverse?format=json&lat=%f&lon=%f&zoom=18&addressdetails=1", lat, lon)
-
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil
-	}
-
-	// Nominatim yêu cầu User-Agent
-	req.Header.Set("User-Agent", "MediaStoreBackend/1.0")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil
-	}
-
-	var result NominatimResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil
-	}
-
-	// Xây dựng LocationInfo
-	location := &LocationInfo{
-		Country:     result.Address.Country,
-		State:       result.Address.State,
-		PostalCode:  result.Address.PostalCode,
-		Road:        result.Address.Road,
-		DisplayName: result.DisplayName,
+// allowedExtension - Phần mở rộng nằm trong danh sách được cấu hình
+func allowedExtension(allowed []string, ext string) bool {
+	for _, candidate := range allowed {
+		if candidate == ext {
+			return true
+		}
 	}
+	return false
+}
 
-	// Ưu tiên City > Town > Village
-	if result.Address.City != "" {
-		location.City = result.Address.City
-	} else if result.Address.Town != "" {
-		location.City = result.Address.Town
-	} else if result.Address.Village != "" {
-		location.City = result.Address.Village
+// invalidTypeMessage - Thông báo lỗi liệt kê các định dạng được phép, ví dụ "PNG, JPG and GIF"
+func invalidTypeMessage(allowed []string) string {
+	names := make([]string, len(allowed))
+	for i, ext := range allowed {
+		names[i] = strings.ToUpper(strings.TrimPrefix(ext, "."))
 	}
-
-	// Ưu tiên District > County
-	if result.Address.District != "" {
-		location.District = result.Address.District
-	} else if result.Address.County != "" {
-		location.District = result.Address.County
+	list := names[0]
+	if len(names) > 1 {
+		list = strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
 	}
-
-	return location
+	return "Invalid file type. Only " + list + " are allowed"
 }
 
-// extractImageMetadata - Trích xuất metadata từ file ảnh
-func extractImageMetadata(filePath string) *ImageMetadata {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil
-	}
-	defer file.Close()
+// formFile - Nhận file multipart của field; span ghi lại thời gian nhận/parse body, thường là phần lâu nhất
+func formFile(c *gin.Context, field string) (*multipart.FileHeader, error) {
+	_, span := tracing.Start(c.Request.Context(), "upload.receive_multipart",
+		trace.WithAttributes(attribute.String("upload.field", field)))
+	defer span.End()
 
-	// Đọc EXIF data
-	exifData, err := exif.Decode(file)
+	file, err := c.FormFile(field)
 	if err != nil {
-		return nil // Không có EXIF data hoặc lỗi
-	}
-
-	metadata := &ImageMetadata{}
-
-	// Lấy thời gian chụp
-	if dateTime, err := exifData.DateTime(); err == nil {
-		metadata.DateTime = dateTime.Unix()
-	}
-
-	// Lấy tọa độ GPS
-	if lat, long, err := exifData.LatLong(); err == nil {
-		metadata.Latitude = lat
-		metadata.Longitude = long
-
-		// Thực hiện reverse geocoding để lấy thông tin địa chỉ
-		if location := reverseGeocode(lat, long); location != nil {
-			metadata.Location = location
-		}
-	}
-
-	// Lấy thông tin camera
-	if make, err := exifData.Get(exif.Make); err == nil {
-		if makeStr, err := make.StringVal(); err == nil {
-			metadata.CameraMake = strings.TrimSpace(makeStr)
-		}
-	}
-
-	if model, err := exifData.Get(exif.Model); err == nil {
-		if modelStr, err := model.StringVal(); err == nil {
-			metadata.CameraModel = strings.TrimSpace(modelStr)
-		}
-	}
-
-	// Lấy kích thước ảnh
-	if width, err := exifData.Get(exif.PixelXDimension); err == nil {
-		if w, err := width.Int(0); err == nil {
-			metadata.Width = w
-		}
-	}
-
-	if height, err := exifData.Get(exif.PixelYDimension); err == nil {
-		if h, err := height.Int(0); err == nil {
-			metadata.Height = h
-		}
-	}
-
-	// Lấy orientation
-	if orientation, err := exifData.Get(exif.Orientation); err == nil {
-		if o, err := orientation.Int(0); err == nil {
-			metadata.Orientation = o
-		}
-	}
-
-	// Lấy thông tin flash
-	if flash, err := exifData.Get(exif.Flash); err == nil {
-		if f, err := flash.Int(0); err == nil {
-			if f == 0 {
-				metadata.Flash = "No Flash"
-			} else {
-				metadata.Flash = "Flash Fired"
-			}
-		}
-	}
-
-	// Lấy focal length
-	if focalLength, err := exifData.Get(exif.FocalLength); err == nil {
-		if num, denom, err := focalLength.Rat2(0); err == nil && denom != 0 {
-			metadata.FocalLength = fmt.Sprintf("%.1fmm", float64(num)/float64(denom))
-		}
-	}
-
-	// Lấy F-Number (aperture)
-	if fNumber, err := exifData.Get(exif.FNumber); err == nil {
-		if num, denom, err := fNumber.Rat2(0); err == nil && denom != 0 {
-			metadata.FNumber = fmt.Sprintf("f/%.1f", float64(num)/float64(denom))
-		}
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
+	span.SetAttributes(attribute.Int64("upload.size", file.Size))
+	return file, nil
+}
 
-	// Lấy exposure time
-	if exposureTime, err := exifData.Get(exif.ExposureTime); err == nil {
-		if num, denom, err := exposureTime.Rat2(0); err == nil && denom != 0 {
-			if num < denom {
-				metadata.ExposureTime = fmt.Sprintf("1/%d", denom/num)
-			} else {
-				metadata.ExposureTime = fmt.Sprintf("%d", num/denom)
-			}
-		}
-	}
+// saveUploadedFile - Ghi file đã nhận vào thư mục upload, kèm span
+func saveUploadedFile(c *gin.Context, file *multipart.FileHeader, dst string) error {
+	_, span := tracing.Start(c.Request.Context(), "upload.save_file",
+		trace.WithAttributes(attribute.Int64("upload.size", file.Size)))
+	defer span.End()
 
-	// Lấy ISO
-	if isoSpeed, err := exifData.Get(exif.ISOSpeedRatings); err == nil {
-		if iso, err := isoSpeed.Int(0); err == nil {
-			metadata.ISO = iso
-		}
+	if err := c.SaveUploadedFile(file, dst); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "save failed")
+		return err
 	}
-
-	return metadata
+	return nil
 }
 
 // UploadAvatar - Upload avatar cho user, lưu trong thư mục uploads/uid_xxx/avatars
-func UploadAvatar(c *gin.Context) {
+func (h *Handler) UploadAvatar(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperr.Respond(c, apperr.Unauthorized("unauthorized", "User not authenticated"))
 		return
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		apperr.Respond(c, apperr.Internal("Invalid user ID", nil))
 		return
 	}
 
-	file, err := c.FormFile("image")
+	file, err := formFile(c, "image")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No file uploaded or invalid field name. Use 'image' as field name",
-		})
+		apperr.Respond(c, apperr.BadRequest("missing_file", "No file uploaded or invalid field name. Use 'image' as field name"))
 		return
 	}
 
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !allowedImageExtensions[ext] {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid file type. Only PNG, JPG, JPEG, and GIF are allowed",
-		})
+	if !allowedExtension(h.cfg.Upload.ImageExtensions, ext) {
+		apperr.Respond(c, apperr.BadRequest("invalid_file_type", invalidTypeMessage(h.cfg.Upload.ImageExtensions)))
 		return
 	}
 
-	maxSize := int64(5 * 1024 * 1024) // 5MB
-	if file.Size > maxSize {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "File size exceeds 5MB limit",
-		})
+	maxSize := h.cfg.Upload.MaxAvatarSize
+	if file.Size > int64(maxSize) {
+		apperr.Respond(c, apperr.BadRequest("file_too_large", fmt.Sprintf("File size exceeds %s limit", maxSize)))
 		return
 	}
 
-	uploadDir := fmt.Sprintf("uploads/uid_%s/avatars", userIDStr)
+	uploadDir := filepath.Join(h.cfg.Storage.UploadDir, "uid_"+userIDStr, "avatars")
 	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create upload directory",
-		})
+		apperr.Respond(c, apperr.Internal("Failed to create upload directory", err))
 		return
 	}
 
 	filename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
 	filepath := filepath.Join(uploadDir, filename)
 
-	if err := c.SaveUploadedFile(file, filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save file",
-		})
+	if err := saveUploadedFile(c, file, filepath); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to save file", err))
 		return
 	}
 
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = fmt.Sprintf("http://localhost:%s", os.Getenv("PORT"))
-		if baseURL == "http://localhost:" {
-			baseURL = "http://localhost:8080"
-		}
-	}
-
+	baseURL := h.cfg.Server.BaseURL
 	imageURL := fmt.Sprintf("%s/uploads/uid_%s/avatars/%s", baseURL, userIDStr, filename)
 
+	metrics.RecordUpload("avatar", file.Size)
+
 	// Trích xuất metadata từ ảnh
-	metadata := extractImageMetadata(filepath)
+	metadata := extractImageMetadata(c.Request.Context(), filepath)
 
 	response := gin.H{
 		"message":  "Avatar uploaded successfully",
@@ -338,77 +144,91 @@ func UploadAvatar(c *gin.Context) {
 }
 
 // UploadUserImage - Upload ảnh vào thư viện cá nhân của user, lưu trong thư mục uploads/uid_xxx/gallery
-func UploadUserImage(c *gin.Context) {
+func (h *Handler) UploadUserImage(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperr.Respond(c, apperr.Unauthorized("unauthorized", "User not authenticated"))
 		return
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		apperr.Respond(c, apperr.Internal("Invalid user ID", nil))
 		return
 	}
 
-	file, err := c.FormFile("image")
+	file, err := formFile(c, "image")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No file uploaded or invalid field name. Use 'image' as field name",
-		})
+		apperr.Respond(c, apperr.BadRequest("missing_file", "No file uploaded or invalid field name. Use 'image' as field name"))
 		return
 	}
 
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !allowedImageExtensions[ext] {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid file type. Only PNG, JPG, JPEG, and GIF are allowed",
-		})
+	if !allowedExtension(h.cfg.Upload.ImageExtensions, ext) {
+		apperr.Respond(c, apperr.BadRequest("invalid_file_type", invalidTypeMessage(h.cfg.Upload.ImageExtensions)))
+		return
+	}
+
+	maxSize := h.cfg.Upload.MaxImageSize
+	if file.Size > int64(maxSize) {
+		apperr.Respond(c, apperr.BadRequest("file_too_large", fmt.Sprintf("File size exceeds %s limit", maxSize)))
 		return
 	}
 
-	maxSize := int64(10 * 1024 * 1024) // 10MB for gallery images
-	if file.Size > maxSize {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "File size exceeds 10MB limit",
-		})
+	albumID := c.PostForm("album_id")
+	if err := h.checkUploadAlbum(c, userIDStr, albumID); err != nil {
+		if err == errAlbumNotWritable {
+			apperr.Respond(c, apperr.BadRequest("album_not_writable", "Album not found or is a smart album"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Failed to fetch album", err))
 		return
 	}
 
-	uploadDir := fmt.Sprintf("uploads/uid_%s/gallery", userIDStr)
+	uploadDir := filepath.Join(h.cfg.Storage.UploadDir, "uid_"+userIDStr, "gallery")
 	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create upload directory",
-		})
+		apperr.Respond(c, apperr.Internal("Failed to create upload directory", err))
 		return
 	}
 
 	filename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
 	filepath := filepath.Join(uploadDir, filename)
 
-	if err := c.SaveUploadedFile(file, filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save file",
-		})
+	if err := saveUploadedFile(c, file, filepath); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to save file", err))
 		return
 	}
 
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = fmt.Sprintf("http://localhost:%s", os.Getenv("PORT"))
-		if baseURL == "http://localhost:" {
-			baseURL = "http://localhost:8080"
-		}
-	}
-
+	baseURL := h.cfg.Server.BaseURL
 	imageURL := fmt.Sprintf("%s/uploads/uid_%s/gallery/%s", baseURL, userIDStr, filename)
 
 	// Trích xuất metadata từ ảnh
-	metadata := extractImageMetadata(filepath)
+	metadata := extractImageMetadata(c.Request.Context(), filepath)
+
+	media := models.Media{
+		UserID:      userIDStr,
+		AlbumID:     albumID,
+		Type:        "image",
+		URL:         imageURL,
+		Filename:    filename,
+		Path:        fmt.Sprintf("uid_%s/gallery/%s", userIDStr, filename),
+		Size:        file.Size,
+		Title:       c.PostForm("title"),
+		Description: c.PostForm("description"),
+		Tags:        parseTags(c.PostForm("tags")),
+		Metadata:    metadata,
+	}
+	if err := h.saveMedia(c, &media); err != nil {
+		os.Remove(filepath)
+		apperr.Respond(c, apperr.Internal("Failed to save media", err))
+		return
+	}
+	metrics.RecordUpload("image", file.Size)
 
 	response := gin.H{
 		"message":  "Image uploaded to gallery successfully",
 		"user_id":  userID,
+		"media_id": media.ID,
 		"filename": filename,
 		"url":      imageURL,
 		"size":     file.Size,
@@ -423,76 +243,91 @@ func UploadUserImage(c *gin.Context) {
 }
 
 // UploadVideo - Upload video vào thư viện của user, lưu trong thư mục uploads/uid_xxx/videos
-func UploadVideo(c *gin.Context) {
+func (h *Handler) UploadVideo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperr.Respond(c, apperr.Unauthorized("unauthorized", "User not authenticated"))
 		return
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		apperr.Respond(c, apperr.Internal("Invalid user ID", nil))
 		return
 	}
 
-	file, err := c.FormFile("video")
+	file, err := formFile(c, "video")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No file uploaded or invalid field name. Use 'video' as field name",
-		})
+		apperr.Respond(c, apperr.BadRequest("missing_file", "No file uploaded or invalid field name. Use 'video' as field name"))
 		return
 	}
 
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !allowedVideoExtensions[ext] {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid file type. Only MP4, AVI, MOV, MKV, and WEBM are allowed",
-		})
+	if !allowedExtension(h.cfg.Upload.VideoExtensions, ext) {
+		apperr.Respond(c, apperr.BadRequest("invalid_file_type", invalidTypeMessage(h.cfg.Upload.VideoExtensions)))
+		return
+	}
+
+	maxSize := h.cfg.Upload.MaxVideoSize
+	if file.Size > int64(maxSize) {
+		apperr.Respond(c, apperr.BadRequest("file_too_large", fmt.Sprintf("File size exceeds %s limit", maxSize)))
 		return
 	}
 
-	maxSize := int64(500 * 1024 * 1024) // 100MB for videos
-	if file.Size > maxSize {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "File size exceeds 500MB limit",
-		})
+	albumID := c.PostForm("album_id")
+	if err := h.checkUploadAlbum(c, userIDStr, albumID); err != nil {
+		if err == errAlbumNotWritable {
+			apperr.Respond(c, apperr.BadRequest("album_not_writable", "Album not found or is a smart album"))
+			return
+		}
+		apperr.Respond(c, apperr.Internal("Failed to fetch album", err))
 		return
 	}
 
-	uploadDir := fmt.Sprintf("uploads/uid_%s/videos", userIDStr)
+	uploadDir := filepath.Join(h.cfg.Storage.UploadDir, "uid_"+userIDStr, "videos")
 	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create upload directory",
-		})
+		apperr.Respond(c, apperr.Internal("Failed to create upload directory", err))
 		return
 	}
 
 	filename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
 	filepath := filepath.Join(uploadDir, filename)
 
-	if err := c.SaveUploadedFile(file, filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save file",
-		})
+	if err := saveUploadedFile(c, file, filepath); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to save file", err))
 		return
 	}
 
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL := fmt.Sprintf("http://localhost:%s", os.Getenv("PORT"))
-		if baseURL == "http://localhost:" {
-			baseURL = "http://localhost:8080"
-		}
-	}
-
+	baseURL := h.cfg.Server.BaseURL
 	videoURL := fmt.Sprintf("%s/uploads/uid_%s/videos/%s", baseURL, userIDStr, filename)
 
+	media := models.Media{
+		UserID:      userIDStr,
+		AlbumID:     albumID,
+		Type:        "video",
+		URL:         videoURL,
+		Filename:    filename,
+		Path:        fmt.Sprintf("uid_%s/videos/%s", userIDStr, filename),
+		Size:        file.Size,
+		Title:       c.PostForm("title"),
+		Description: c.PostForm("description"),
+		Tags:        parseTags(c.PostForm("tags")),
+		Duration:    extractVideoDuration(filepath),
+	}
+	if err := h.saveMedia(c, &media); err != nil {
+		os.Remove(filepath)
+		apperr.Respond(c, apperr.Internal("Failed to save media", err))
+		return
+	}
+	metrics.RecordUpload("video", file.Size)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Video uploaded successfully",
 		"user_id":  userID,
+		"media_id": media.ID,
 		"filename": filename,
 		"url":      videoURL,
 		"size":     file.Size,
+		"duration": media.Duration,
 	})
 }
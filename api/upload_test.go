@@ -0,0 +1,281 @@
+package api_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/testkit"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+type uploadResponse struct {
+	MediaID  string                `json:"media_id"`
+	URL      string                `json:"url"`
+	Size     int64                 `json:"size"`
+	Metadata *models.ImageMetadata `json:"metadata"`
+}
+
+var taggedPhoto = &testkit.EXIF{
+	Make:      "Fujifilm",
+	Model:     "X100V",
+	Taken:     time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC),
+	Latitude:  21.028511,
+	Longitude: 105.804817,
+}
+
+func TestUploadImageExtractsMetadata(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+	album := k.CreateAlbum(user, "Holiday")
+
+	form := testkit.NewMultipart().
+		File("image", "photo.jpg", testkit.JPEG(64, 48, taggedPhoto)).
+		Field("title", "Lake").
+		Field("tags", "travel, hanoi").
+		Field("album_id", album.ID)
+	var got uploadResponse
+	k.Upload("/api/v1/upload/image", token, form).Status(http.StatusOK).Decode(&got)
+
+	m := got.Metadata
+	if m == nil {
+		t.Fatal("upload response has no metadata")
+	}
+	if m.CameraMake != "Fujifilm" || m.CameraModel != "X100V" || m.Width != 64 || m.Height != 48 {
+		t.Errorf("camera/size = %q %q %dx%d", m.CameraMake, m.CameraModel, m.Width, m.Height)
+	}
+	if m.DateTimeOriginal != "2024:05:17 09:30:00" {
+		t.Errorf("date_time_original = %q", m.DateTimeOriginal)
+	}
+	if math.Abs(m.Latitude-taggedPhoto.Latitude) > 1e-4 || math.Abs(m.Longitude-taggedPhoto.Longitude) > 1e-4 {
+		t.Errorf("coordinates = %f,%f", m.Latitude, m.Longitude)
+	}
+	if m.Location == nil || m.Location.City != "Hanoi" {
+		t.Errorf("location = %+v, want the stub geocoder's address", m.Location)
+	}
+	if calls := k.Geocoder.Calls(); len(calls) != 1 {
+		t.Errorf("geocoder calls = %d, want 1", len(calls))
+	}
+
+	media, err := k.Repos.Media.Get(k.Context(), got.MediaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.UserID != user.ID || media.AlbumID != album.ID || media.Title != "Lake" || len(media.Tags) != 2 {
+		t.Errorf("stored media = %+v", media)
+	}
+	if _, err := os.Stat(filepath.Join(k.Config.Storage.UploadDir, filepath.FromSlash(media.Path))); err != nil {
+		t.Errorf("uploaded file not on disk: %v", err)
+	}
+}
+
+func TestUploadedImageServedWithoutGPS(t *testing.T) {
+	k := testkit.New(t)
+	_, token := k.Login("user")
+
+	var got uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().
+		File("image", "photo.jpg", testkit.JPEG(32, 32, taggedPhoto))).
+		Status(http.StatusOK).Decode(&got)
+
+	// Mặc định (strip_metadata rỗng) bỏ GPS nhưng giữ các tag khác
+	path := strings.TrimPrefix(got.URL, k.Config.Server.BaseURL)
+	w := k.Do(httptest.NewRequest(http.MethodGet, path, nil)).Status(http.StatusOK)
+	x, err := exif.Decode(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("served image lost its EXIF block: %v", err)
+	}
+	if _, _, err := x.LatLong(); err == nil {
+		t.Error("served image still has GPS coordinates")
+	}
+	if tag, err := x.Get(exif.Model); err != nil || !strings.Contains(tag.String(), "X100V") {
+		t.Errorf("served image lost the camera model: %v", err)
+	}
+}
+
+func TestUploadGeocoderFailureKeepsUpload(t *testing.T) {
+	k := testkit.New(t)
+	_, token := k.Login("user")
+	k.Geocoder.Respond(nil, errors.New("geocoder unavailable"))
+
+	var got uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().
+		File("image", "photo.jpg", testkit.JPEG(32, 32, taggedPhoto))).
+		Status(http.StatusOK).Decode(&got)
+	if got.Metadata == nil || got.Metadata.Latitude == 0 {
+		t.Fatalf("metadata = %+v, want GPS coordinates", got.Metadata)
+	}
+	if got.Metadata.Location != nil {
+		t.Errorf("location = %+v, want none when geocoding fails", got.Metadata.Location)
+	}
+}
+
+func TestUploadBoundsPNGTextChunks(t *testing.T) {
+	k := testkit.New(t)
+	_, token := k.Login("user")
+	big := strings.Repeat("A", 600<<10)
+
+	// Mỗi chunk nén nhỏ nhưng tổng text giải nén bị giới hạn; keyword không làm được tên field Mongo bị bỏ qua
+	var got uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "text.png", testkit.PNG(8, 8,
+		testkit.PNGText{Keyword: "Title", Text: "Lake"},
+		testkit.PNGText{Keyword: "a.b", Text: "dotted"},
+		testkit.PNGText{Keyword: "$where", Text: "operator"},
+		testkit.PNGText{Keyword: "Comment", Text: big, Compressed: true},
+		testkit.PNGText{Keyword: "Bomb", Text: big, Compressed: true},
+	))).Status(http.StatusOK).Decode(&got)
+	if got.Metadata == nil {
+		t.Fatal("upload response has no metadata")
+	}
+	texts := got.Metadata.TextChunks
+	if len(texts) != 2 || texts["Title"] != "Lake" || len(texts["Comment"]) != len(big) {
+		t.Errorf("text chunks = %d keys %v", len(texts), keys(texts))
+	}
+
+	var many []testkit.PNGText
+	for i := 0; i < 100; i++ {
+		many = append(many, testkit.PNGText{Keyword: fmt.Sprintf("Key%d", i), Text: "value"})
+	}
+	var capped uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "many.png", testkit.PNG(8, 8, many...))).
+		Status(http.StatusOK).Decode(&capped)
+	if capped.Metadata == nil {
+		t.Fatal("upload response has no metadata")
+	}
+	if len(capped.Metadata.TextChunks) != 64 || capped.Metadata.TextChunks["Key63"] == "" {
+		t.Errorf("expected the first 64 text chunks, got %d", len(capped.Metadata.TextChunks))
+	}
+}
+
+// keys - Các key của m, để thông báo lỗi không in cả text dài
+func keys(m map[string]string) []string {
+	var out []string
+	for key := range m {
+		out = append(out, key)
+	}
+	return out
+}
+
+func TestUploadRejectsInvalidFiles(t *testing.T) {
+	k := testkit.New(t, testkit.WithConfig(func(cfg *config.Config) {
+		cfg.Upload.MaxImageSize = 1 << 10
+	}))
+	user, token := k.Login("user")
+	other := k.CreateUser()
+	smart := k.CreateAlbum(user, "Smart", testkit.Smart(models.SmartRule{Field: "country", Op: "eq", Value: "Vietnam"}))
+	foreign := k.CreateAlbum(other, "Not mine")
+	small := testkit.JPEG(8, 8, nil)
+
+	k.Upload("/api/v1/upload/image", "", testkit.NewMultipart().File("image", "photo.jpg", small)).
+		Fails(http.StatusUnauthorized, "missing_authorization")
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("file", "photo.jpg", small)).
+		Fails(http.StatusBadRequest, "missing_file")
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "notes.txt", small)).
+		Fails(http.StatusBadRequest, "invalid_file_type")
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "big.jpg", bytes.Repeat([]byte{0}, 2<<10))).
+		Fails(http.StatusBadRequest, "file_too_large")
+
+	for _, album := range []*models.Album{smart, foreign} {
+		k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().
+			File("image", "photo.jpg", small).
+			Field("album_id", album.ID)).
+			Fails(http.StatusBadRequest, "album_not_writable")
+	}
+}
+
+func TestUploadAvatar(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+
+	var got uploadResponse
+	k.Upload("/api/v1/upload/avatar", token, testkit.NewMultipart().
+		File("image", "me.jpg", testkit.JPEG(16, 16, nil))).
+		Status(http.StatusOK).Decode(&got)
+	if !strings.HasPrefix(got.URL, k.Config.Server.BaseURL+"/uploads/uid_"+user.ID+"/avatars/") {
+		t.Fatalf("avatar url = %q", got.URL)
+	}
+
+	// Client lưu URL avatar qua PUT /users/:id
+	k.Do(httptest.NewRequest(http.MethodGet, strings.TrimPrefix(got.URL, k.Config.Server.BaseURL), nil)).
+		Status(http.StatusOK)
+}
+
+func TestAlbumMediaServesFactoryMedia(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+	album := k.CreateAlbum(user, "Trip")
+	media := k.CreateMedia(user, testkit.InAlbum(album))
+	k.CreateMedia(user)
+
+	var got struct {
+		Total int64          `json:"total"`
+		Data  []models.Media `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/albums/"+album.ID+"/media", token, nil).Status(http.StatusOK).Decode(&got)
+	if got.Total != 1 || len(got.Data) != 1 || got.Data[0].ID != media.ID {
+		t.Fatalf("album media = %+v, want only %s", got, media.ID)
+	}
+	k.Do(httptest.NewRequest(http.MethodGet, strings.TrimPrefix(media.URL, k.Config.Server.BaseURL), nil)).
+		Status(http.StatusOK)
+}
+
+func TestDeleteMedia(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	user, token := k.Login("user")
+	_, otherToken := k.Login("user")
+	recv := newReceiver(t)
+	var hook webhookCreated
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", adminToken, map[string]any{
+		"url": recv.URL, "events": []string{models.WebhookMediaDeleted},
+	}).Status(http.StatusCreated).Decode(&hook)
+
+	var uploaded uploadResponse
+	k.Upload("/api/v1/upload/image", token, testkit.NewMultipart().File("image", "photo.jpg", testkit.JPEG(16, 16, nil))).
+		Status(http.StatusOK).Decode(&uploaded)
+	media, err := k.Repos.Media.Get(k.Context(), uploaded.MediaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var share struct {
+		URL string `json:"url"`
+	}
+	k.JSON(http.MethodPost, "/api/v1/media/"+media.ID+"/share", token, nil).Status(http.StatusCreated).Decode(&share)
+	rendered(t, k, renderURL(t, k, token, media.ID, map[string]any{"width": 8}))
+
+	k.JSON(http.MethodDelete, "/api/v1/media/"+media.ID, otherToken, nil).Fails(http.StatusNotFound, "media_not_found")
+	k.JSON(http.MethodDelete, "/api/v1/media/"+media.ID, token, nil).Status(http.StatusOK)
+	k.JSON(http.MethodDelete, "/api/v1/media/"+media.ID, token, nil).Fails(http.StatusNotFound, "media_not_found")
+
+	if _, err := os.Stat(filepath.Join(k.Config.Storage.UploadDir, filepath.FromSlash(media.Path))); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file still on disk: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(k.Config.Render.CacheDir, media.ID)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cached renders still on disk: %v", err)
+	}
+	k.Do(httptest.NewRequest(http.MethodGet, strings.TrimPrefix(share.URL, k.Config.Server.BaseURL), nil)).
+		Fails(http.StatusNotFound, "media_not_found")
+
+	events, _, err := k.Repos.Audit.Find(k.Context(), repository.AuditQuery{Action: models.AuditMediaDelete, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ActorID != user.ID || events[0].TargetID != media.ID {
+		t.Errorf("audit events = %+v", events)
+	}
+	page := waitDeliveries(t, k, adminToken, hook.Data.ID, finished)
+	if page.Total != 1 || page.Data[0].Event != models.WebhookMediaDeleted {
+		t.Errorf("deliveries = %+v, want one media.deleted", page.Data)
+	}
+}
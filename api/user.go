@@ -1,52 +1,56 @@
 package api
 
 import (
-	"context"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
 	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
 	"github.com/hieu9721/media-store-backend/utils"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func getUserCollection() *mongo.Collection {
-	return config.GetCollection("users")
-}
-
 // CreateUser - Tạo user mới
-func CreateUser(c *gin.Context) {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) CreateUser(c *gin.Context) {
+    ctx, cancel := middleware.RequestContext(c, 10*time.Second)
     defer cancel()
 
     var user models.User
 
     // Validate input
     if err := c.ShouldBindJSON(&user); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        apperr.Respond(c, apperr.Validation(err))
         return
     }
 
     // Check email exist
-    var existUser models.User
-    err := getUserCollection().FindOne(ctx, bson.M{"email": user.Email}).Decode(&existUser)
-    if err == nil {
-        c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+    if _, err := h.repos.Users.GetByEmail(ctx, user.Email); err == nil {
+        apperr.Respond(c, apperr.Conflict("email_taken", "Email already exists"))
         return
     }
 
     user.ID = utils.GenerateUserID()
+    user.TwoFactorEnabled = false
     user.CreatedAt = time.Now().Unix()
     user.UpdatedAt = time.Now().Unix()
 
-    _, err = getUserCollection().InsertOne(ctx, user)
+    err := h.repos.Users.Create(ctx, &user)
+    if err == repository.ErrDuplicate {
+        apperr.Respond(c, apperr.Conflict("email_taken", "Email already exists"))
+        return
+    }
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+        apperr.Respond(c, apperr.Internal("Failed to create user", err))
         return
     }
+    h.audit(c, ctx, models.AuditEvent{
+        Action: models.AuditUserCreate, TargetType: "user", TargetID: user.ID,
+        Details: map[string]string{"email": user.Email, "role": user.Role},
+    })
+    h.emit(ctx, models.WebhookUserCreated, webhookUser(&user))
     c.JSON(http.StatusCreated, gin.H{
         "message": "User created successfully",
         "data":    user,
@@ -54,31 +58,15 @@ func CreateUser(c *gin.Context) {
 }
 
 // GetUsers - Lấy danh sách users với pagination
-func GetUsers(c *gin.Context) {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) GetUsers(c *gin.Context) {
+    ctx, cancel := middleware.RequestContext(c, 10*time.Second)
     defer cancel()
 
-    var users []models.User
-
-    cursor, err := getUserCollection().Find(ctx, bson.M{})
+    users, err := h.repos.Users.List(ctx)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+        apperr.Respond(c, apperr.Internal("Failed to fetch users", err))
         return
     }
-    defer cursor.Close(ctx)
-
-    for cursor.Next(ctx) {
-        var user models.User
-        if err := cursor.Decode(&user); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode user"})
-            return
-        }
-        users = append(users, user)
-    }
-
-    if users == nil {
-        users = []models.User{}
-    }
 
     c.JSON(http.StatusOK, gin.H{
         "message": "Users fetched successfully",
@@ -88,24 +76,23 @@ func GetUsers(c *gin.Context) {
 }
 
 // GetUser - Lấy user theo ID
-func GetUser(c *gin.Context) {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) GetUser(c *gin.Context) {
+    ctx, cancel := middleware.RequestContext(c, 10*time.Second)
     defer cancel()
 
     userID := c.Param("id")
     if !utils.IsValidUserID(userID) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+        apperr.Respond(c, apperr.BadRequest("invalid_user_id", "Invalid user ID format"))
         return
     }
 
-    var user models.User
-    err := getUserCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+    user, err := h.repos.Users.GetByID(ctx, userID)
     if err != nil {
-        if err == mongo.ErrNoDocuments {
-            c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+        if err == repository.ErrNotFound {
+            apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
             return
         }
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+        apperr.Respond(c, apperr.Internal("Failed to fetch user", err))
         return
     }
 
@@ -116,100 +103,132 @@ func GetUser(c *gin.Context) {
 }
 
 // UpdateUser - Cập nhật user
-func UpdateUser(c *gin.Context) {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) UpdateUser(c *gin.Context) {
+    ctx, cancel := middleware.RequestContext(c, 10*time.Second)
     defer cancel()
 
     userID := c.Param("id")
     if !utils.IsValidUserID(userID) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+        apperr.Respond(c, apperr.BadRequest("invalid_user_id", "Invalid user ID format"))
+        return
+    }
+
+    // Chỉ chủ tài khoản hoặc admin được sửa: đổi email của người khác là chiếm được tài khoản qua đặt lại mật khẩu
+    if c.GetString("user_id") != userID && c.GetString("role") != "admin" {
+        apperr.Respond(c, apperr.Forbidden("not_account_owner", "You can only update your own account"))
         return
     }
 
     var updateData models.UpdateUser
     if err := c.ShouldBindJSON(&updateData); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        apperr.Respond(c, apperr.Validation(err))
         return
     }
 
     // Check if user exists
-    var existUser models.User
-    err := getUserCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&existUser)
+    existUser, err := h.repos.Users.GetByID(ctx, userID)
     if err != nil {
-        if err == mongo.ErrNoDocuments {
-            c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+        if err == repository.ErrNotFound {
+            apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
             return
         }
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+        apperr.Respond(c, apperr.Internal("Failed to fetch user", err))
         return
     }
 
     // Check if email already used by another user
     if updateData.Email != "" && updateData.Email != existUser.Email {
-        var emailCheck models.User
-        err := getUserCollection().FindOne(ctx, bson.M{"email": updateData.Email}).Decode(&emailCheck)
-        if err == nil {
-            c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+        if _, err := h.repos.Users.GetByEmail(ctx, updateData.Email); err == nil {
+            apperr.Respond(c, apperr.Conflict("email_taken", "Email already exists"))
             return
         }
     }
 
-    // Build update document
-    updateDoc := bson.M{
-        "$set": bson.M{
-            "updated_at": time.Now().Unix(),
-        },
-    }
+    // Build changes
+    var changes repository.UserChanges
 
     if updateData.Name != "" {
-        updateDoc["$set"].(bson.M)["name"] = updateData.Name
+        changes.Name = &updateData.Name
     }
-    if updateData.Email != "" {
-        updateDoc["$set"].(bson.M)["email"] = updateData.Email
+    // Email mới chỉ được áp dụng sau khi xác thực
+    emailChanged := updateData.Email != "" && updateData.Email != existUser.Email
+    if emailChanged {
+        changes.PendingEmail = &updateData.Email
     }
     if updateData.Phone != "" {
-        updateDoc["$set"].(bson.M)["phone"] = updateData.Phone
+        changes.Phone = &updateData.Phone
     }
     if updateData.Avatar != "" {
-        updateDoc["$set"].(bson.M)["avatar"] = updateData.Avatar
+        changes.Avatar = &updateData.Avatar
+    }
+    if updateData.StripMetadata != "" {
+        changes.StripMetadata = &updateData.StripMetadata
     }
 
-    _, err = getUserCollection().UpdateOne(ctx, bson.M{"_id": userID}, updateDoc)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+    if err := h.repos.Users.Update(ctx, userID, changes); err != nil {
+        apperr.Respond(c, apperr.Internal("Failed to update user", err))
         return
     }
 
     // Get updated user
-    var updatedUser models.User
-    getUserCollection().FindOne(ctx, bson.M{"_id": userID}).Decode(&updatedUser)
+    updatedUser, err := h.repos.Users.GetByID(ctx, userID)
+    if err != nil {
+        apperr.Respond(c, apperr.Internal("Failed to fetch user", err))
+        return
+    }
+
+    if diff := userDiff(existUser, updatedUser); len(diff) > 0 {
+        h.audit(c, ctx, models.AuditEvent{Action: models.AuditUserUpdate, TargetType: "user", TargetID: userID, Diff: diff})
+    }
+
+    message := "User updated successfully"
+    if emailChanged {
+        message = "User updated successfully. Please verify the new email address to apply the change"
+        // Thay đổi đã được lưu: không trả lỗi, gửi lại email mới để nhận link xác thực khác
+        if err := h.sendVerificationEmail(ctx, updatedUser, updateData.Email); err != nil {
+            slog.ErrorContext(ctx, "failed to send email change verification", "user_id", userID, "error", err)
+            message = "User updated successfully, but the verification email could not be sent. Submit the new email again to retry"
+        }
+    }
 
     c.JSON(http.StatusOK, gin.H{
-        "message": "User updated successfully",
+        "message": message,
         "data":    updatedUser,
     })
 }
 
 // DeleteUser - Xóa user
-func DeleteUser(c *gin.Context) {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) DeleteUser(c *gin.Context) {
+    ctx, cancel := middleware.RequestContext(c, 10*time.Second)
     defer cancel()
 
     userID := c.Param("id")
     if !utils.IsValidUserID(userID) {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+        apperr.Respond(c, apperr.BadRequest("invalid_user_id", "Invalid user ID format"))
         return
     }
 
-    result, err := getUserCollection().DeleteOne(ctx, bson.M{"_id": userID})
+    // Giữ email và role của user bị xóa cho audit log
+    target, err := h.repos.Users.GetByID(ctx, userID)
+    if err == nil {
+        err = h.repos.Users.Delete(ctx, userID)
+    }
+    if err == repository.ErrNotFound {
+        apperr.Respond(c, apperr.NotFound("user_not_found", "User not found"))
+        return
+    }
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+        apperr.Respond(c, apperr.Internal("Failed to delete user", err))
         return
     }
+    h.audit(c, ctx, models.AuditEvent{
+        Action: models.AuditUserDelete, TargetType: "user", TargetID: userID,
+        Details: map[string]string{"email": target.Email, "role": target.Role},
+    })
+    h.emit(ctx, models.WebhookUserDeleted, webhookUser(target))
 
-    if result.DeletedCount == 0 {
-        c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-        return
+    if _, err := h.repos.Sessions.DeleteAll(ctx, userID, ""); err != nil {
+        slog.ErrorContext(ctx, "failed to revoke sessions", "target_user_id", userID, "error", err)
     }
 
     c.JSON(http.StatusOK, gin.H{
@@ -218,42 +237,21 @@ func DeleteUser(c *gin.Context) {
 }
 
 // SearchUsers - Tìm kiếm users
-func SearchUsers(c *gin.Context) {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) SearchUsers(c *gin.Context) {
+    ctx, cancel := middleware.RequestContext(c, 10*time.Second)
     defer cancel()
 
     searchTerm := c.Query("q")
     if searchTerm == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Search term is required"})
+        apperr.Respond(c, apperr.BadRequest("missing_search_term", "Search term is required"))
         return
     }
 
-    filter := bson.M{
-        "$or": []bson.M{
-            {"name": bson.M{"$regex": searchTerm, "$options": "i"}},
-            {"email": bson.M{"$regex": searchTerm, "$options": "i"}},
-        },
-    }
-
-    var users []models.User
-    cursor, err := getUserCollection().Find(ctx, filter)
+    users, err := h.repos.Users.Search(ctx, searchTerm)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+        apperr.Respond(c, apperr.Internal("Failed to search users", err))
         return
     }
-    defer cursor.Close(ctx)
-
-    for cursor.Next(ctx) {
-        var user models.User
-        if err := cursor.Decode(&user); err != nil {
-            continue
-        }
-        users = append(users, user)
-    }
-
-    if users == nil {
-        users = []models.User{}
-    }
 
     c.JSON(http.StatusOK, gin.H{
         "message": "Search completed",
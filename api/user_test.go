@@ -0,0 +1,147 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/testkit"
+)
+
+func TestGetUser(t *testing.T) {
+	k := testkit.New(t)
+	_, token := k.Login("user")
+	other := k.CreateUser(testkit.WithName("Bob Builder"))
+
+	var got struct {
+		Data models.User `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/users/"+other.ID, token, nil).Status(http.StatusOK).Decode(&got)
+	if got.Data.ID != other.ID || got.Data.Email != other.Email {
+		t.Errorf("GET user = %s %s, want %s %s", got.Data.ID, got.Data.Email, other.ID, other.Email)
+	}
+
+	k.JSON(http.MethodGet, "/api/v1/users/not-a-user-id", token, nil).Fails(http.StatusBadRequest, "invalid_user_id")
+	k.JSON(http.MethodGet, "/api/v1/users/uid_missing", token, nil).Fails(http.StatusNotFound, "user_not_found")
+}
+
+func TestSearchUsers(t *testing.T) {
+	k := testkit.New(t)
+	_, token := k.Login("user")
+	k.CreateUser(testkit.WithName("Carol Danvers"))
+	k.CreateUser(testkit.WithName("Dave Grohl"))
+
+	var got struct {
+		Count int           `json:"count"`
+		Data  []models.User `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/users/search?q=carol", token, nil).Status(http.StatusOK).Decode(&got)
+	if got.Count != 1 || len(got.Data) != 1 || got.Data[0].Name != "Carol Danvers" {
+		t.Errorf("search carol = %+v", got)
+	}
+
+	// q là bắt buộc theo tài liệu OpenAPI
+	p := k.JSON(http.MethodGet, "/api/v1/users/search", token, nil).Fails(http.StatusBadRequest, "validation_failed")
+	if len(p.Errors) != 1 || p.Errors[0].Field != "q" {
+		t.Errorf("errors = %+v, want one error for q", p.Errors)
+	}
+}
+
+func TestUpdateUserEmailNeedsVerification(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+
+	var got struct {
+		Data models.User `json:"data"`
+	}
+	k.JSON(http.MethodPut, "/api/v1/users/"+user.ID, token, map[string]string{
+		"name": "Renamed User", "email": "renamed@example.test", "strip_metadata": "all",
+	}).Status(http.StatusOK).Decode(&got)
+	if got.Data.Name != "Renamed User" || got.Data.StripMetadata != "all" {
+		t.Errorf("updated user = %+v", got.Data)
+	}
+	// Email mới chỉ được áp dụng sau khi xác thực
+	if got.Data.Email != user.Email || got.Data.PendingEmail != "renamed@example.test" {
+		t.Errorf("email = %q, pending = %q; want %q pending renamed@example.test", got.Data.Email, got.Data.PendingEmail, user.Email)
+	}
+
+	k.JSON(http.MethodPost, "/api/v1/auth/verify-email", "", map[string]string{
+		"token": testkit.LinkToken(t, k.Mail.Wait(t, "renamed@example.test")),
+	}).Status(http.StatusOK)
+	stored, err := k.Repos.Users.GetByID(k.Context(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != "renamed@example.test" || stored.PendingEmail != "" {
+		t.Errorf("after verification email = %q, pending = %q", stored.Email, stored.PendingEmail)
+	}
+
+	p := k.JSON(http.MethodPut, "/api/v1/users/"+user.ID, token, map[string]string{"strip_metadata": "some"}).
+		Fails(http.StatusBadRequest, "validation_failed")
+	if len(p.Errors) != 1 || p.Errors[0].Field != "strip_metadata" || p.Errors[0].Code != "oneof" {
+		t.Errorf("errors = %+v, want oneof on strip_metadata", p.Errors)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	k := testkit.New(t)
+	_, userToken := k.Login("user")
+	_, adminToken := k.Login("admin")
+	target, targetToken := k.Login("user")
+
+	k.JSON(http.MethodDelete, "/api/v1/users/"+target.ID, userToken, nil).Fails(http.StatusForbidden, "admin_required")
+
+	k.JSON(http.MethodDelete, "/api/v1/users/"+target.ID, adminToken, nil).Status(http.StatusOK)
+	k.JSON(http.MethodDelete, "/api/v1/users/"+target.ID, adminToken, nil).Fails(http.StatusNotFound, "user_not_found")
+	// Xóa user thu hồi luôn các phiên của user đó
+	k.JSON(http.MethodGet, "/api/v1/me", targetToken, nil).Fails(http.StatusUnauthorized, "session_revoked")
+}
+
+func TestUpdateUserRequiresOwnerOrAdmin(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	victim := k.CreateUser()
+	_, attackerToken := k.Login("user")
+	write := accessToken(k, attackerToken, "write")
+	takeover := map[string]string{"email": "attacker@example.test", "strip_metadata": "none", "name": "Owned"}
+
+	for _, token := range []string{attackerToken, write} {
+		k.JSON(http.MethodPut, "/api/v1/users/"+victim.ID, token, takeover).Fails(http.StatusForbidden, "not_account_owner")
+	}
+	stored, err := k.Repos.Users.GetByID(k.Context(), victim.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.PendingEmail != "" || stored.StripMetadata != victim.StripMetadata || stored.Name != victim.Name {
+		t.Errorf("victim changed: %+v", stored)
+	}
+	if msgs := k.Mail.Messages(); len(msgs) != 0 {
+		t.Errorf("sent %d emails, want none", len(msgs))
+	}
+
+	var got struct {
+		Data models.User `json:"data"`
+	}
+	k.JSON(http.MethodPut, "/api/v1/users/"+victim.ID, adminToken, map[string]string{"strip_metadata": "all"}).
+		Status(http.StatusOK).Decode(&got)
+	if got.Data.StripMetadata != "all" {
+		t.Errorf("admin update = %+v", got.Data)
+	}
+}
+
+func TestUpdateUserKeepsChangeWhenVerificationCannotBeIssued(t *testing.T) {
+	k := testkit.New(t)
+	user, token := k.Login("user")
+	k.Repos.UserTokens = failingTokens{k.Repos.UserTokens}
+
+	var got struct {
+		Message string      `json:"message"`
+		Data    models.User `json:"data"`
+	}
+	k.JSON(http.MethodPut, "/api/v1/users/"+user.ID, token, map[string]string{"email": "renamed@example.test"}).
+		Status(http.StatusOK).Decode(&got)
+	if got.Data.PendingEmail != "renamed@example.test" || !strings.Contains(got.Message, "could not be sent") {
+		t.Errorf("response = %+v", got)
+	}
+}
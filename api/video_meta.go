@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// extractVideoDuration - Đọc thời lượng (giây) từ box mvhd của file MP4/MOV.
+// Trả về 0 nếu không đọc được hoặc định dạng không hỗ trợ (AVI, MKV, WEBM).
+func extractVideoDuration(filePath string) float64 {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+
+	moovOffset, moovSize, ok := findBox(file, 0, info.Size(), "moov")
+	if !ok {
+		return 0
+	}
+	mvhdOffset, mvhdSize, ok := findBox(file, moovOffset, moovOffset+moovSize, "mvhd")
+	if !ok || mvhdSize < 20 {
+		return 0
+	}
+
+	header := make([]byte, 32)
+	n, err := file.ReadAt(header, mvhdOffset)
+	if err != nil && err != io.EOF {
+		return 0
+	}
+	header = header[:n]
+
+	var timescale uint32
+	var duration uint64
+	if len(header) >= 20 && header[0] == 0 {
+		timescale = binary.BigEndian.Uint32(header[12:16])
+		duration = uint64(binary.BigEndian.Uint32(header[16:20]))
+	} else if len(header) >= 32 && header[0] == 1 {
+		timescale = binary.BigEndian.Uint32(header[20:24])
+		duration = binary.BigEndian.Uint64(header[24:32])
+	}
+	if timescale == 0 {
+		return 0
+	}
+
+	return float64(duration) / float64(timescale)
+}
+
+// findBox - Tìm box ISO-BMFF có kiểu boxType trong khoảng [start, end), trả về offset và kích thước phần nội dung
+func findBox(r io.ReaderAt, start, end int64, boxType string) (int64, int64, bool) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return 0, 0, false
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, 0, false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return 0, 0, false
+		}
+		if string(header[4:8]) == boxType {
+			return offset + headerSize, size - headerSize, true
+		}
+		offset += size
+	}
+	return 0, 0, false
+}
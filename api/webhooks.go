@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+	"github.com/hieu9721/media-store-backend/webhook"
+)
+
+// emit - Tạo delivery cho mọi subscription đang bật của event và đánh thức worker.
+// Lỗi chỉ được log để không làm hỏng thao tác đã thực hiện xong
+func (h *Handler) emit(ctx context.Context, eventType string, data any) {
+	// Vẫn ghi khi request vừa hết thời gian chờ
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	hooks, err := h.repos.Webhooks.ListForEvent(ctx, eventType)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list webhooks", "event", eventType, "error", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	event, payload, err := webhook.NewEvent(eventType, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to encode webhook event", "event", eventType, "error", err)
+		return
+	}
+	for _, hook := range hooks {
+		delivery := webhook.NewDelivery(hook.ID, event.ID, eventType, payload)
+		if err := h.repos.WebhookDeliveries.Create(ctx, delivery); err != nil {
+			slog.ErrorContext(ctx, "failed to queue webhook delivery", "event", eventType, "webhook_id", hook.ID, "error", err)
+			continue
+		}
+		webhook.Notify()
+	}
+}
+
+// webhookUser - User trong payload webhook: chỉ thông tin hồ sơ, không có mật khẩu hay trạng thái bảo mật
+func webhookUser(user *models.User) gin.H {
+	return gin.H{
+		"id":         user.ID,
+		"name":       user.Name,
+		"email":      user.Email,
+		"role":       user.Role,
+		"created_at": user.CreatedAt,
+	}
+}
+
+// GetWebhooks - Danh sách subscription, mới tạo trước (không gồm secret)
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	hooks, err := h.repos.Webhooks.List(ctx)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to fetch webhooks", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": len(hooks), "data": hooks, "events": models.WebhookEvents})
+}
+
+// CreateWebhook - Tạo subscription; secret ký payload chỉ được trả về một lần
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var input models.CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+	if err := webhook.CheckURL(input.URL, h.cfg.Webhooks.AllowPrivate); err != nil {
+		apperr.Respond(c, apperr.BadRequest("webhook_url_not_allowed", err.Error()))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to generate webhook secret", err))
+		return
+	}
+
+	now := time.Now().Unix()
+	hook := models.Webhook{
+		ID:          utils.GenerateID("whk"),
+		URL:         input.URL,
+		Events:      uniqueStrings(input.Events),
+		Description: strings.TrimSpace(input.Description),
+		Secret:      secret,
+		Enabled:     true,
+		CreatedBy:   c.GetString("user_id"),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	if err := h.repos.Webhooks.Create(ctx, &hook); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to create webhook", err))
+		return
+	}
+	h.audit(c, ctx, models.AuditEvent{
+		Action: models.AuditWebhookCreate, TargetType: "webhook", TargetID: hook.ID,
+		Details: map[string]string{"url": hook.URL, "events": strings.Join(hook.Events, ",")},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created. Copy the signing secret now, it will not be shown again",
+		"secret":  secret,
+		"data":    hook,
+	})
+}
+
+// UpdateWebhook - Đổi URL, event, mô tả hoặc bật/tắt; bật lại thì xóa lý do tắt và bộ đếm lỗi
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	var input models.UpdateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apperr.Respond(c, apperr.Validation(err))
+		return
+	}
+	if input.URL != nil {
+		if err := webhook.CheckURL(*input.URL, h.cfg.Webhooks.AllowPrivate); err != nil {
+			apperr.Respond(c, apperr.BadRequest("webhook_url_not_allowed", err.Error()))
+			return
+		}
+	}
+
+	changes := repository.WebhookChanges{URL: input.URL, Enabled: input.Enabled}
+	if input.Events != nil {
+		changes.Events = repository.Ptr(uniqueStrings(*input.Events))
+	}
+	if input.Description != nil {
+		changes.Description = repository.Ptr(strings.TrimSpace(*input.Description))
+	}
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+	before, err := h.repos.Webhooks.Get(ctx, id)
+	var after *models.Webhook
+	if err == nil {
+		after, err = h.repos.Webhooks.Update(ctx, id, changes)
+	}
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("webhook_not_found", "Webhook not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to update webhook", err))
+		return
+	}
+	if diff := webhookDiff(before, after); len(diff) > 0 {
+		h.audit(c, ctx, models.AuditEvent{Action: models.AuditWebhookUpdate, TargetType: "webhook", TargetID: id, Diff: diff})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook updated successfully", "data": after})
+}
+
+// webhookDiff - Các field cấu hình khác nhau giữa hai bản của subscription
+func webhookDiff(before, after *models.Webhook) map[string]models.AuditChange {
+	diff := map[string]models.AuditChange{}
+	for name, values := range map[string][2]string{
+		"url":         {before.URL, after.URL},
+		"events":      {strings.Join(before.Events, ","), strings.Join(after.Events, ",")},
+		"description": {before.Description, after.Description},
+		"enabled":     {strconv.FormatBool(before.Enabled), strconv.FormatBool(after.Enabled)},
+	} {
+		if values[0] != values[1] {
+			diff[name] = models.AuditChange{From: values[0], To: values[1]}
+		}
+	}
+	return diff
+}
+
+// DeleteWebhook - Xóa subscription cùng log delivery của nó
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+	hook, err := h.repos.Webhooks.Get(ctx, id)
+	if err == nil {
+		err = h.repos.Webhooks.Delete(ctx, id)
+	}
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("webhook_not_found", "Webhook not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to delete webhook", err))
+		return
+	}
+	h.audit(c, ctx, models.AuditEvent{
+		Action: models.AuditWebhookDelete, TargetType: "webhook", TargetID: id,
+		Details: map[string]string{"url": hook.URL},
+	})
+
+	if err := h.repos.WebhookDeliveries.DeleteByWebhook(ctx, id); err != nil {
+		slog.ErrorContext(ctx, "failed to delete webhook deliveries", "webhook_id", id, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries - Log delivery của subscription, mới nhất trước, kèm lịch sử từng lần gửi
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	page, limit := parsePagination(c)
+
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+	if _, err := h.repos.Webhooks.Get(ctx, id); err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("webhook_not_found", "Webhook not found"))
+		return
+	} else if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+
+	deliveries, total, err := h.repos.WebhookDeliveries.ListByWebhook(ctx, id, (page-1)*limit, limit)
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"data":  deliveries,
+	})
+}
+
+// RedeliverWebhook - Gửi lại payload của một delivery (cùng event ID) dưới dạng delivery mới
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	ctx, cancel := middleware.RequestContext(c, 10*time.Second)
+	defer cancel()
+
+	hook, err := h.repos.Webhooks.Get(ctx, c.Param("id"))
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("webhook_not_found", "Webhook not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+	if !hook.Enabled {
+		apperr.Respond(c, apperr.Conflict("webhook_disabled", "Webhook is disabled, enable it before redelivering"))
+		return
+	}
+
+	original, err := h.repos.WebhookDeliveries.Get(ctx, hook.ID, c.Param("delivery_id"))
+	if err == repository.ErrNotFound {
+		apperr.Respond(c, apperr.NotFound("delivery_not_found", "Delivery not found"))
+		return
+	}
+	if err != nil {
+		apperr.Respond(c, apperr.Internal("Database error", err))
+		return
+	}
+
+	delivery := webhook.NewDelivery(hook.ID, original.EventID, original.Event, original.Payload)
+	delivery.RedeliveryOf = original.ID
+	if err := h.repos.WebhookDeliveries.Create(ctx, delivery); err != nil {
+		apperr.Respond(c, apperr.Internal("Failed to queue delivery", err))
+		return
+	}
+	webhook.Notify()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued", "data": delivery})
+}
@@ -0,0 +1,265 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/testkit"
+	"github.com/hieu9721/media-store-backend/webhook"
+)
+
+// receiverBody - Body mọi response của receiver, như trang lỗi của một dịch vụ nội bộ
+const receiverBody = "internal service details"
+
+// receiver - Subscriber giả: lưu mọi request nhận được và trả status đặt trước
+type receiver struct {
+	*httptest.Server
+	status   atomic.Int32
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.status.Store(http.StatusNoContent)
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+		w.WriteHeader(int(r.status.Load()))
+		w.Write([]byte(receiverBody))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+type webhookCreated struct {
+	Secret string         `json:"secret"`
+	Data   models.Webhook `json:"data"`
+}
+
+type deliveryPage struct {
+	Total int64                    `json:"total"`
+	Data  []models.WebhookDelivery `json:"data"`
+}
+
+// waitDeliveries - Chờ tới khi done trả về true cho log delivery của webhook (worker gửi ở background)
+func waitDeliveries(t *testing.T, k *testkit.Kit, token, webhookID string, done func(deliveryPage) bool) deliveryPage {
+	t.Helper()
+	var page deliveryPage
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		k.JSON(http.MethodGet, "/api/v1/admin/webhooks/"+webhookID+"/deliveries", token, nil).Status(http.StatusOK).Decode(&page)
+		if done(page) {
+			return page
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries = %+v", page.Data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func finished(page deliveryPage) bool {
+	for _, d := range page.Data {
+		if d.Status == models.WebhookDeliveryPending {
+			return false
+		}
+	}
+	return len(page.Data) > 0
+}
+
+func TestWebhookDeliversSignedEvents(t *testing.T) {
+	k := testkit.New(t)
+	_, adminToken := k.Login("admin")
+	_, userToken := k.Login("user")
+	recv := newReceiver(t)
+
+	input := map[string]any{"url": recv.URL, "events": []string{models.WebhookUserCreated}}
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", userToken, input).Fails(http.StatusForbidden, "admin_required")
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", adminToken, map[string]any{"url": recv.URL, "events": []string{"media.updated"}}).
+		Fails(http.StatusBadRequest, "validation_failed")
+
+	var created webhookCreated
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", adminToken, input).Status(http.StatusCreated).Decode(&created)
+	if created.Secret == "" || !created.Data.Enabled {
+		t.Fatalf("created = %+v", created)
+	}
+
+	// Sự kiện không đăng ký thì không tạo delivery
+	k.JSON(http.MethodPost, "/api/v1/albums", userToken, map[string]string{"name": "Not subscribed"}).Status(http.StatusCreated)
+	k.JSON(http.MethodPost, "/api/v1/users", adminToken, map[string]string{
+		"name": "Hooked User", "email": "hooked@example.test", "password": "s3cret-pass", "role": "user",
+	}).Status(http.StatusCreated)
+
+	page := waitDeliveries(t, k, adminToken, created.Data.ID, finished)
+	if page.Total != 1 || page.Data[0].Status != models.WebhookDeliverySucceeded || len(page.Data[0].Attempts) != 1 {
+		t.Fatalf("deliveries = %+v, want one successful delivery", page.Data)
+	}
+	if recv.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", recv.count())
+	}
+
+	req, body := recv.requests[0], recv.bodies[0]
+	if err := webhook.Verify(created.Secret, req.Header.Get(webhook.SignatureHeader), body, time.Now(), 5*time.Minute); err != nil {
+		t.Errorf("signature: %v", err)
+	}
+	if err := webhook.Verify("whsec_other", req.Header.Get(webhook.SignatureHeader), body, time.Now(), 5*time.Minute); err == nil {
+		t.Error("signature verified with the wrong secret")
+	}
+	var event struct {
+		ID   string         `json:"id"`
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != models.WebhookUserCreated || event.Data["email"] != "hooked@example.test" || event.Data["password"] != nil {
+		t.Errorf("event = %+v", event)
+	}
+	if req.Header.Get(webhook.EventHeader) != event.Type || req.Header.Get(webhook.EventIDHeader) != event.ID ||
+		req.Header.Get(webhook.DeliveryIDHeader) != page.Data[0].ID {
+		t.Errorf("headers = %v", req.Header)
+	}
+}
+
+func TestWebhookRetriesAndDisables(t *testing.T) {
+	k := testkit.New(t, testkit.WithConfig(func(cfg *config.Config) {
+		cfg.Webhooks.MaxAttempts = 3
+		cfg.Webhooks.DisableAfter = 2
+	}))
+	_, adminToken := k.Login("admin")
+	user := k.CreateUser()
+	recv := newReceiver(t)
+	recv.status.Store(http.StatusInternalServerError)
+
+	var created webhookCreated
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", adminToken, map[string]any{
+		"url": recv.URL, "events": []string{models.WebhookAlbumCreated},
+	}).Status(http.StatusCreated).Decode(&created)
+	id := created.Data.ID
+
+	for _, name := range []string{"First", "Second"} {
+		k.JSON(http.MethodPost, "/api/v1/albums", k.Token(user), map[string]string{"name": name}).Status(http.StatusCreated)
+		waitDeliveries(t, k, adminToken, id, finished)
+	}
+	page := waitDeliveries(t, k, adminToken, id, finished)
+	for _, d := range page.Data {
+		if d.Status != models.WebhookDeliveryFailed || len(d.Attempts) != 3 || d.Attempts[0].StatusCode != http.StatusInternalServerError {
+			t.Errorf("delivery = %+v, want 3 failed attempts", d)
+		}
+		// Receiver ở 127.0.0.1: body lỗi của địa chỉ không công khai không được lưu
+		if d.Attempts[0].Response != "" {
+			t.Errorf("attempt response = %q, want it hidden", d.Attempts[0].Response)
+		}
+	}
+
+	var hooks struct {
+		Data []models.Webhook `json:"data"`
+	}
+	k.JSON(http.MethodGet, "/api/v1/admin/webhooks", adminToken, nil).Status(http.StatusOK).Decode(&hooks)
+	if len(hooks.Data) != 1 || hooks.Data[0].Enabled || hooks.Data[0].DisabledReason == "" {
+		t.Fatalf("webhooks = %+v, want the subscription disabled", hooks.Data)
+	}
+
+	// Subscription đã tắt: không nhận sự kiện mới và không gửi lại được
+	k.JSON(http.MethodPost, "/api/v1/albums", k.Token(user), map[string]string{"name": "Dropped"}).Status(http.StatusCreated)
+	original := page.Data[0]
+	redeliver := "/api/v1/admin/webhooks/" + id + "/deliveries/" + original.ID + "/redeliver"
+	k.JSON(http.MethodPost, redeliver, adminToken, nil).Fails(http.StatusConflict, "webhook_disabled")
+
+	recv.status.Store(http.StatusOK)
+	var updated struct {
+		Data models.Webhook `json:"data"`
+	}
+	k.JSON(http.MethodPut, "/api/v1/admin/webhooks/"+id, adminToken, map[string]bool{"enabled": true}).
+		Status(http.StatusOK).Decode(&updated)
+	if !updated.Data.Enabled || updated.Data.ConsecutiveFailures != 0 || updated.Data.DisabledReason != "" {
+		t.Fatalf("re-enabled webhook = %+v", updated.Data)
+	}
+
+	var queued struct {
+		Data models.WebhookDelivery `json:"data"`
+	}
+	k.JSON(http.MethodPost, redeliver, adminToken, nil).Status(http.StatusAccepted).Decode(&queued)
+	page = waitDeliveries(t, k, adminToken, id, finished)
+	if page.Total != 3 {
+		t.Fatalf("deliveries = %+v, want the redelivery added", page.Data)
+	}
+	// Các delivery tạo cùng giây không có thứ tự cố định
+	for _, d := range page.Data {
+		if d.ID == queued.Data.ID && (d.Status != models.WebhookDeliverySucceeded ||
+			d.EventID != original.EventID || d.RedeliveryOf != original.ID) {
+			t.Errorf("redelivery = %+v, want a successful copy of %s", d, original.ID)
+		}
+	}
+	if recv.count() != 7 {
+		t.Errorf("receiver got %d requests, want 6 failed attempts and the redelivery", recv.count())
+	}
+
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks/"+id+"/deliveries/whd_missing/redeliver", adminToken, nil).
+		Fails(http.StatusNotFound, "delivery_not_found")
+	k.JSON(http.MethodDelete, "/api/v1/admin/webhooks/"+id, adminToken, nil).Status(http.StatusOK)
+	k.JSON(http.MethodGet, "/api/v1/admin/webhooks/"+id+"/deliveries", adminToken, nil).Fails(http.StatusNotFound, "webhook_not_found")
+}
+
+func TestWebhookRejectsPrivateDestinations(t *testing.T) {
+	k := testkit.New(t, testkit.WithConfig(func(cfg *config.Config) {
+		cfg.Webhooks.AllowPrivate = false
+		cfg.Webhooks.MaxAttempts = 1
+	}))
+	_, adminToken := k.Login("admin")
+	recv := newReceiver(t)
+	events := []string{models.WebhookUserCreated}
+
+	for _, url := range []string{
+		"http://hooks.example.test/in",
+		"https://127.0.0.1/in",
+		"https://10.1.2.3/in",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/in",
+		"https://[::ffff:192.168.0.1]/in",
+	} {
+		k.JSON(http.MethodPost, "/api/v1/admin/webhooks", adminToken, map[string]any{"url": url, "events": events}).
+			Fails(http.StatusBadRequest, "webhook_url_not_allowed")
+	}
+
+	// Tên miền chỉ được kiểm tra khi kết nối: localhost phân giải ra loopback nên không bao giờ được gửi tới
+	local := strings.Replace(recv.URL, "http://127.0.0.1", "https://localhost", 1)
+	var created webhookCreated
+	k.JSON(http.MethodPost, "/api/v1/admin/webhooks", adminToken, map[string]any{"url": local, "events": events}).
+		Status(http.StatusCreated).Decode(&created)
+	k.JSON(http.MethodPut, "/api/v1/admin/webhooks/"+created.Data.ID, adminToken, map[string]string{"url": recv.URL}).
+		Fails(http.StatusBadRequest, "webhook_url_not_allowed")
+
+	k.JSON(http.MethodPost, "/api/v1/users", adminToken, map[string]string{
+		"name": "Hooked User", "email": "hooked@example.test", "password": "s3cret-pass", "role": "user",
+	}).Status(http.StatusCreated)
+	page := waitDeliveries(t, k, adminToken, created.Data.ID, finished)
+	attempt := page.Data[0].Attempts[0]
+	if page.Data[0].Status != models.WebhookDeliveryFailed || !strings.Contains(attempt.Error, webhook.ErrDestinationNotAllowed.Error()) ||
+		strings.Contains(attempt.Error, "127.0.0.1") {
+		t.Errorf("attempt = %+v, want the destination refused", attempt)
+	}
+	if recv.count() != 0 {
+		t.Errorf("receiver got %d requests, want none", recv.count())
+	}
+}
@@ -0,0 +1,117 @@
+// Package apperr định nghĩa lỗi ứng dụng có mã ổn định (machine-readable) và trả về dạng
+// application/problem+json (RFC 7807) kèm request ID
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Các mã lỗi dùng chung; mã riêng của từng endpoint được khai báo tại chỗ dùng
+const (
+	CodeValidation      = "validation_failed"
+	CodeInvalidBody     = "invalid_body"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeRouteNotFound   = "route_not_found"
+	CodeTooManyRequests = "too_many_requests"
+	CodeInternal        = "internal_error"
+)
+
+// Error - Lỗi trả về client: HTTP status, mã ổn định, thông báo cho người đọc và lỗi theo từng field.
+// Cause chỉ dùng để ghi log, không bao giờ được trả về client.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []FieldError
+	Cause   error
+}
+
+// FieldError - Lỗi validation của một field trong body/query
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is - Hai lỗi cùng mã được coi là một, để dùng errors.Is với các lỗi khai báo sẵn
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code && other.Status == e.Status
+}
+
+// New - Tạo lỗi với status, mã và thông báo
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithCause - Bản sao của lỗi kèm nguyên nhân gốc (để log)
+func (e *Error) WithCause(cause error) *Error {
+	clone := *e
+	clone.Cause = cause
+	return &clone
+}
+
+func BadRequest(code, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(http.StatusUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(http.StatusForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+func Gone(code, message string) *Error {
+	return New(http.StatusGone, code, message)
+}
+
+func Unprocessable(code, message string) *Error {
+	return New(http.StatusUnprocessableEntity, code, message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeTooManyRequests, message)
+}
+
+func BadGateway(code, message string) *Error {
+	return New(http.StatusBadGateway, code, message)
+}
+
+// Internal - Lỗi 500; message phải là câu chung chung an toàn để hiển thị, chi tiết nằm trong cause
+func Internal(message string, cause error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: message, Cause: cause}
+}
+
+// From - Chuyển lỗi bất kỳ thành *Error; lỗi không phải *Error thành 500 không lộ chi tiết
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("Internal server error", err)
+}
@@ -0,0 +1,63 @@
+package apperr
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// ContentType - Media type của response lỗi theo RFC 7807
+const ContentType = "application/problem+json"
+
+// TypePrefix - Tiền tố của member "type"; phần sau là mã lỗi, ví dụ urn:problem-type:media-store:email_taken
+const TypePrefix = "urn:problem-type:media-store:"
+
+// Problem - Body của response lỗi. "error" giữ lại cho client cũ, có cùng nội dung với "detail".
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Error     string       `json:"error"`
+}
+
+// NewProblem - Problem của lỗi cho request hiện tại
+func NewProblem(c *gin.Context, e *Error) Problem {
+	return Problem{
+		Type:      TypePrefix + e.Code,
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      e.Code,
+		RequestID: c.GetString("request_id"),
+		Errors:    e.Fields,
+		Error:     e.Message,
+	}
+}
+
+// Respond - Ghi lỗi ra response dạng problem+json và dừng chuỗi handler.
+// Lỗi 5xx được log kèm nguyên nhân; client chỉ nhận thông báo chung và request ID để tra log.
+func Respond(c *gin.Context, err error) {
+	e := From(err)
+	if e.Status >= http.StatusInternalServerError && e.Cause != nil {
+		slog.ErrorContext(c.Request.Context(), "request failed",
+			"code", e.Code,
+			"route", c.FullPath(),
+			"error", e.Cause)
+	}
+	// Access log ghi lại c.Errors
+	if last := c.Errors.Last(); last == nil || last.Err != err {
+		c.Error(e)
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.Render(e.Status, render.JSON{Data: NewProblem(c, e)})
+	c.Abort()
+}
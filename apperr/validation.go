@@ -0,0 +1,130 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Lỗi validation dùng tên field theo JSON (hoặc form) thay cho tên field Go, ví dụ "email" thay vì "Email"
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// Validation - Chuyển lỗi của c.ShouldBind* thành lỗi 400 có danh sách lỗi theo field,
+// không trả nguyên văn thông báo của validator ("Key: 'RegisterInput.Email' Error:...")
+func Validation(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return InvalidFields(fields, err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		appErr := BadRequest(CodeInvalidBody, "Request body has a field of the wrong type")
+		appErr.Fields = []FieldError{{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be of type " + typeErr.Type.String(),
+		}}
+		appErr.Cause = err
+		return appErr
+	}
+
+	if errors.Is(err, io.EOF) {
+		return BadRequest(CodeInvalidBody, "Request body is required").WithCause(err)
+	}
+	return BadRequest(CodeInvalidBody, "Request body is not valid JSON").WithCause(err)
+}
+
+// InvalidFields - Lỗi validation_failed (400) với danh sách field không hợp lệ
+func InvalidFields(fields []FieldError, cause error) *Error {
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidation,
+		Message: "One or more fields are invalid",
+		Fields:  fields,
+		Cause:   cause,
+	}
+}
+
+// fieldPath - Đường dẫn field bỏ tên struct gốc: "RegisterInput.email" -> "email", "rules.conditions[0].field"
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required", "required_without", "required_with":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "len":
+		if isString {
+			return fmt.Sprintf("must be exactly %s characters", fe.Param())
+		}
+		return "must have length " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "gt", "gte", "lt", "lte":
+		ops := map[string]string{"gt": "greater than", "gte": "at least", "lt": "less than", "lte": "at most"}
+		return fmt.Sprintf("must be %s %s", ops[fe.Tag()], fe.Param())
+	case "numeric", "number":
+		return "must be numeric"
+	case "alphanum":
+		return "must contain only letters and digits"
+	default:
+		return "is invalid"
+	}
+}
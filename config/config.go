@@ -0,0 +1,622 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config - Toàn bộ cấu hình của ứng dụng. Thứ tự ưu tiên: flag > biến môi trường > file cấu hình > mặc định
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	Storage   StorageConfig   `yaml:"storage" toml:"storage"`
+	Upload    UploadConfig    `yaml:"upload" toml:"upload"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	TOTP      TOTPConfig      `yaml:"totp" toml:"totp"`
+	OIDC      OIDCConfig      `yaml:"oidc" toml:"oidc"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	API       APIConfig       `yaml:"api" toml:"api"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks" toml:"webhooks"`
+	Events    EventsConfig    `yaml:"events" toml:"events"`
+	Render    RenderConfig    `yaml:"render" toml:"render"`
+}
+
+type ServerConfig struct {
+	Port int `yaml:"port" toml:"port"`
+	// BaseURL - URL công khai của API, dùng trong link file và link chia sẻ (mặc định http://localhost:<port>)
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+	// FrontendURL - URL của web client trong link gửi qua email (mặc định bằng BaseURL)
+	FrontendURL string `yaml:"frontend_url" toml:"frontend_url"`
+	// TrustedProxies - IP hoặc CIDR của reverse proxy được tin header X-Forwarded-For/X-Real-IP.
+	// Mặc định không tin proxy nào: IP client là địa chỉ kết nối, client không tự đặt IP được
+	TrustedProxies    []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes    ByteSize `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	ShutdownTimeout   Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	URI            string   `yaml:"uri" toml:"uri"`
+	Name           string   `yaml:"name" toml:"name"`
+	ConnectTimeout Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	MigrateOnStart bool     `yaml:"migrate_on_start" toml:"migrate_on_start"`
+}
+
+type JWTConfig struct {
+	// Secret - Khóa ký HS256, tối thiểu 32 ký tự (tạo bằng `make gen-secret`)
+	Secret string `yaml:"secret" toml:"secret"`
+	// TTL - Thời hạn access token, cũng là thời hạn phiên đăng nhập
+	TTL Duration `yaml:"ttl" toml:"ttl"`
+}
+
+type StorageConfig struct {
+	// UploadDir - Thư mục gốc lưu file upload
+	UploadDir string `yaml:"upload_dir" toml:"upload_dir"`
+	// MinFreeSpace - /readyz báo lỗi khi ổ chứa UploadDir còn trống ít hơn mức này
+	MinFreeSpace ByteSize `yaml:"min_free_space" toml:"min_free_space"`
+}
+
+type UploadConfig struct {
+	MaxAvatarSize   ByteSize `yaml:"max_avatar_size" toml:"max_avatar_size"`
+	MaxImageSize    ByteSize `yaml:"max_image_size" toml:"max_image_size"`
+	MaxVideoSize    ByteSize `yaml:"max_video_size" toml:"max_video_size"`
+	ImageExtensions []string `yaml:"image_extensions" toml:"image_extensions"`
+	VideoExtensions []string `yaml:"video_extensions" toml:"video_extensions"`
+}
+
+type MailConfig struct {
+	// Driver - "file" (ghi .eml vào Dir hoặc log) hoặc "smtp"
+	Driver       string `yaml:"driver" toml:"driver"`
+	Dir          string `yaml:"dir" toml:"dir"`
+	From         string `yaml:"from" toml:"from"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+}
+
+type RateLimitConfig struct {
+	// Backend - "memory" (một instance) hoặc "mongo" (dùng chung giữa nhiều instance)
+	Backend string `yaml:"backend" toml:"backend"`
+}
+
+type TOTPConfig struct {
+	// Issuer - Tên hiển thị trong app authenticator
+	Issuer string `yaml:"issuer" toml:"issuer"`
+}
+
+type OIDCConfig struct {
+	// Providers - Identity provider OpenID Connect cho đăng nhập SSO; trống thì tắt SSO
+	Providers []OIDCProviderConfig `yaml:"providers" toml:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	// Name - Tên trong URL /api/v1/auth/oidc/<name>/login và trong biến môi trường OIDC_<NAME>_*
+	Name         string `yaml:"name" toml:"name"`
+	Issuer       string `yaml:"issuer" toml:"issuer"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// RedirectURL - Mặc định <base_url>/api/v1/auth/oidc/<name>/callback
+	RedirectURL string `yaml:"redirect_url" toml:"redirect_url"`
+	// Scopes - Mặc định openid, email, profile
+	Scopes []string `yaml:"scopes" toml:"scopes"`
+}
+
+type LogConfig struct {
+	// Level - debug, info, warn hoặc error
+	Level string `yaml:"level" toml:"level"`
+	// Format - "json" cho log pipeline, "text" dễ đọc khi phát triển
+	Format string `yaml:"format" toml:"format"`
+}
+
+type MetricsConfig struct {
+	// Enabled - Bật endpoint /metrics (Prometheus)
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Token - Nếu đặt, scraper phải gửi "Authorization: Bearer <token>"
+	Token string `yaml:"token" toml:"token"`
+}
+
+type TracingConfig struct {
+	// Exporter - "none" (mặc định, no-op) hoặc "otlp" (OTLP/HTTP tới collector)
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint - host:port của collector (mặc định localhost:4318); rỗng thì dùng OTEL_EXPORTER_OTLP_* chuẩn
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// Insecure - Gửi qua HTTP thay vì HTTPS, dùng cho collector chạy local
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+}
+
+type APIConfig struct {
+	// Docs - Phục vụ tài liệu OpenAPI ở /openapi.json và Swagger UI ở /docs
+	Docs bool `yaml:"docs" toml:"docs"`
+	// ValidateRequests - Kiểm tra parameter và body JSON theo tài liệu OpenAPI trước khi vào handler
+	ValidateRequests bool `yaml:"validate_requests" toml:"validate_requests"`
+}
+
+type WebhooksConfig struct {
+	// Timeout - Thời gian chờ tối đa cho mỗi lần gửi tới endpoint của subscriber
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+	// MaxAttempts - Số lần gửi tối đa của một delivery trước khi đánh dấu thất bại
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts"`
+	// RetryBackoff - Thời gian chờ trước lần thử lại đầu tiên, gấp đôi sau mỗi lần (tối đa RetryBackoffMax)
+	RetryBackoff    Duration `yaml:"retry_backoff" toml:"retry_backoff"`
+	RetryBackoffMax Duration `yaml:"retry_backoff_max" toml:"retry_backoff_max"`
+	// DisableAfter - Tắt subscription sau chừng này delivery liên tiếp thất bại hết số lần thử
+	DisableAfter int `yaml:"disable_after" toml:"disable_after"`
+	// PollInterval - Chu kỳ worker tìm delivery đến hạn (delivery mới được gửi ngay, không chờ chu kỳ)
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval"`
+	// AllowPrivate - Chỉ dùng khi phát triển: cho phép URL http:// và gửi tới loopback/mạng nội bộ.
+	// Mặc định URL phải là https và mọi địa chỉ không công khai (gồm metadata cloud) bị chặn khi kết nối
+	AllowPrivate bool `yaml:"allow_private" toml:"allow_private"`
+}
+
+type EventsConfig struct {
+	// PollInterval - Chu kỳ mỗi instance đọc sự kiện mới từ database (sự kiện của chính instance được đẩy ngay)
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval"`
+	// Heartbeat - Chu kỳ gửi comment giữ kết nối qua proxy khi không có sự kiện
+	Heartbeat Duration `yaml:"heartbeat" toml:"heartbeat"`
+	// MaxStream - Thời gian tối đa của một kết nối; client nối lại bằng Last-Event-ID và được xác thực lại
+	MaxStream Duration `yaml:"max_stream" toml:"max_stream"`
+	// Retention - Thời gian giữ sự kiện để client nối lại không bị mất sự kiện
+	Retention Duration `yaml:"retention" toml:"retention"`
+}
+
+type RenderConfig struct {
+	// Secret - Khóa HMAC ký tham số của URL render (tối thiểu 32 ký tự); để trống thì suy ra từ jwt.secret
+	Secret string `yaml:"secret" toml:"secret"`
+	// CacheDir - Thư mục lưu ảnh đã render; phải nằm ngoài UploadDir vì /uploads phục vụ công khai không cần chữ ký
+	CacheDir string `yaml:"cache_dir" toml:"cache_dir"`
+	// MaxDimension - Chiều rộng/cao tối đa của ảnh kết quả
+	MaxDimension int `yaml:"max_dimension" toml:"max_dimension"`
+	// MaxSourcePixels - Ảnh gốc có số điểm ảnh (rộng × cao) lớn hơn không được render
+	MaxSourcePixels int `yaml:"max_source_pixels" toml:"max_source_pixels"`
+	// Concurrency - Số ảnh render cùng lúc trên một instance; request khác chờ tới lượt
+	Concurrency int `yaml:"concurrency" toml:"concurrency"`
+}
+
+// Default - Cấu hình mặc định, giữ nguyên giới hạn upload trước đây (avatar 5MB, ảnh 10MB, video 500MB)
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: Duration{10 * time.Second},
+			ReadTimeout:       Duration{10 * time.Minute},
+			WriteTimeout:      Duration{10 * time.Minute},
+			IdleTimeout:       Duration{2 * time.Minute},
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   Duration{2 * time.Minute},
+		},
+		Database: DatabaseConfig{
+			URI:            "mongodb://localhost:27017",
+			Name:           "media_store",
+			ConnectTimeout: Duration{10 * time.Second},
+			MigrateOnStart: true,
+		},
+		JWT: JWTConfig{
+			TTL: Duration{24 * time.Hour},
+		},
+		Storage: StorageConfig{
+			UploadDir:    "uploads",
+			MinFreeSpace: 1 << 30,
+		},
+		Upload: UploadConfig{
+			MaxAvatarSize:   5 << 20,
+			MaxImageSize:    10 << 20,
+			MaxVideoSize:    500 << 20,
+			ImageExtensions: []string{".jpg", ".jpeg", ".png", ".gif"},
+			VideoExtensions: []string{".mp4", ".avi", ".mov", ".mkv", ".webm"},
+		},
+		Mail: MailConfig{
+			Driver:   "file",
+			SMTPPort: 587,
+		},
+		RateLimit: RateLimitConfig{
+			Backend: "memory",
+		},
+		TOTP: TOTPConfig{
+			Issuer: "MediaStore",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "media-store-backend",
+		},
+		API: APIConfig{
+			Docs:             true,
+			ValidateRequests: true,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:         Duration{10 * time.Second},
+			MaxAttempts:     8,
+			RetryBackoff:    Duration{30 * time.Second},
+			RetryBackoffMax: Duration{time.Hour},
+			DisableAfter:    5,
+			PollInterval:    Duration{5 * time.Second},
+		},
+		Events: EventsConfig{
+			PollInterval: Duration{time.Second},
+			Heartbeat:    Duration{25 * time.Second},
+			MaxStream:    Duration{30 * time.Minute},
+			Retention:    Duration{24 * time.Hour},
+		},
+		Render: RenderConfig{
+			CacheDir:        "cache/renders",
+			MaxDimension:    4096,
+			MaxSourcePixels: 50_000_000,
+			Concurrency:     4,
+		},
+	}
+}
+
+// minJWTSecretLength - 32 ký tự, tương đương 256 bit với secret ngẫu nhiên dạng hex/base64
+const minJWTSecretLength = 32
+
+// weakJWTSecrets - Giá trị mẫu trong tài liệu/.env.example, không bao giờ được dùng thật
+var weakJWTSecrets = []string{
+	"your-super-secret-jwt-key-change-this-in-production",
+	"secret",
+	"changeme",
+}
+
+// oidcProviderName - Tên provider xuất hiện trong URL callback và tên biến môi trường
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// OIDCEnvPrefix - Tiền tố biến môi trường của provider: "my-idp" thành "OIDC_MY_IDP_"
+func OIDCEnvPrefix(name string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+// normalize - Điền các giá trị suy ra từ field khác và chuẩn hóa danh sách phần mở rộng
+func (c *Config) normalize() {
+	c.Server.BaseURL = strings.TrimRight(c.Server.BaseURL, "/")
+	if c.Server.BaseURL == "" {
+		c.Server.BaseURL = "http://localhost:" + strconv.Itoa(c.Server.Port)
+	}
+	c.Server.FrontendURL = strings.TrimRight(c.Server.FrontendURL, "/")
+	if c.Server.FrontendURL == "" {
+		c.Server.FrontendURL = c.Server.BaseURL
+	}
+	var proxies []string
+	for _, proxy := range c.Server.TrustedProxies {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	c.Server.TrustedProxies = proxies
+	for i := range c.OIDC.Providers {
+		provider := &c.OIDC.Providers[i]
+		provider.Name = strings.ToLower(strings.TrimSpace(provider.Name))
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = c.Server.BaseURL + "/api/v1/auth/oidc/" + provider.Name + "/callback"
+		}
+		var scopes []string
+		for _, scope := range provider.Scopes {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		provider.Scopes = scopes
+	}
+	c.Upload.ImageExtensions = normalizeExtensions(c.Upload.ImageExtensions)
+	c.Upload.VideoExtensions = normalizeExtensions(c.Upload.VideoExtensions)
+	c.Mail.Driver = strings.ToLower(c.Mail.Driver)
+	c.RateLimit.Backend = strings.ToLower(c.RateLimit.Backend)
+	c.Log.Level = strings.ToLower(c.Log.Level)
+	if c.Log.Level == "warning" {
+		c.Log.Level = "warn"
+	}
+	c.Log.Format = strings.ToLower(c.Log.Format)
+	c.Tracing.Exporter = strings.ToLower(c.Tracing.Exporter)
+}
+
+func normalizeExtensions(exts []string) []string {
+	var out []string
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		out = append(out, ext)
+	}
+	return out
+}
+
+// Validate - Kiểm tra toàn bộ cấu hình, trả về tất cả lỗi cùng lúc
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if err := validateJWTSecret(c.JWT.Secret); err != nil {
+		errs = append(errs, err)
+	}
+	if c.JWT.TTL.Duration <= 0 {
+		fail("jwt.ttl (JWT_EXPIRY) must be positive")
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("server.port (PORT) must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	for name, raw := range map[string]string{"server.base_url (BASE_URL)": c.Server.BaseURL, "server.frontend_url (FRONTEND_URL)": c.Server.FrontendURL} {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("%s must be an absolute http(s) URL, got %q", name, raw)
+		}
+	}
+	for name, d := range map[string]Duration{
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+		"database.connect_timeout":   c.Database.ConnectTimeout,
+		"webhooks.timeout":           c.Webhooks.Timeout,
+		"webhooks.retry_backoff":     c.Webhooks.RetryBackoff,
+		"webhooks.retry_backoff_max": c.Webhooks.RetryBackoffMax,
+		"webhooks.poll_interval":     c.Webhooks.PollInterval,
+		"events.poll_interval":       c.Events.PollInterval,
+		"events.heartbeat":           c.Events.Heartbeat,
+		"events.max_stream":          c.Events.MaxStream,
+		"events.retention":           c.Events.Retention,
+	} {
+		if d.Duration <= 0 {
+			fail("%s must be positive", name)
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			fail("server.trusted_proxies (TRUSTED_PROXIES) must contain IP addresses or CIDR ranges, got %q", proxy)
+		}
+	}
+	if c.Server.MaxHeaderBytes < 4<<10 {
+		fail("server.max_header_bytes must be at least 4KB")
+	}
+
+	if c.Database.URI == "" {
+		fail("database.uri (MONGODB_URI) is required")
+	} else if !strings.HasPrefix(c.Database.URI, "mongodb://") && !strings.HasPrefix(c.Database.URI, "mongodb+srv://") {
+		fail("database.uri (MONGODB_URI) must start with mongodb:// or mongodb+srv://")
+	}
+	if c.Database.Name == "" {
+		fail("database.name (DATABASE_NAME) is required")
+	}
+
+	if c.Storage.UploadDir == "" {
+		fail("storage.upload_dir (UPLOAD_DIR) is required")
+	}
+	if c.Storage.MinFreeSpace < 0 {
+		fail("storage.min_free_space (STORAGE_MIN_FREE_SPACE) must not be negative")
+	}
+
+	for name, size := range map[string]ByteSize{
+		"upload.max_avatar_size": c.Upload.MaxAvatarSize,
+		"upload.max_image_size":  c.Upload.MaxImageSize,
+		"upload.max_video_size":  c.Upload.MaxVideoSize,
+	} {
+		if size <= 0 {
+			fail("%s must be positive", name)
+		}
+	}
+	if len(c.Upload.ImageExtensions) == 0 {
+		fail("upload.image_extensions must not be empty")
+	}
+	if len(c.Upload.VideoExtensions) == 0 {
+		fail("upload.video_extensions must not be empty")
+	}
+
+	switch c.Mail.Driver {
+	case "file":
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.From == "" {
+			fail("mail.smtp_host (SMTP_HOST) and mail.from (MAIL_FROM) are required for the smtp driver")
+		}
+		if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+			fail("mail.smtp_port (SMTP_PORT) must be between 1 and 65535")
+		}
+	default:
+		fail("mail.driver (MAIL_DRIVER) must be file or smtp, got %q", c.Mail.Driver)
+	}
+
+	switch c.RateLimit.Backend {
+	case "memory", "mongo":
+	default:
+		fail("rate_limit.backend (RATE_LIMIT_BACKEND) must be memory or mongo, got %q", c.RateLimit.Backend)
+	}
+
+	names := map[string]bool{}
+	for i, provider := range c.OIDC.Providers {
+		if !oidcProviderName.MatchString(provider.Name) {
+			fail("oidc.providers[%d].name (OIDC_PROVIDERS) must contain only letters, digits and dashes, got %q", i, provider.Name)
+			continue
+		}
+		if names[provider.Name] {
+			fail("oidc.providers[%d].name (OIDC_PROVIDERS) %q is configured twice", i, provider.Name)
+		}
+		names[provider.Name] = true
+		prefix := OIDCEnvPrefix(provider.Name)
+		if u, err := url.Parse(provider.Issuer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("oidc.providers[%d].issuer (%sISSUER) must be an absolute http(s) URL, got %q", i, prefix, provider.Issuer)
+		}
+		if provider.ClientID == "" {
+			fail("oidc.providers[%d].client_id (%sCLIENT_ID) is required", i, prefix)
+		}
+		if u, err := url.Parse(provider.RedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("oidc.providers[%d].redirect_url (%sREDIRECT_URL) must be an absolute http(s) URL, got %q", i, prefix, provider.RedirectURL)
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		fail("log.format (LOG_FORMAT) must be json or text, got %q", c.Log.Format)
+	}
+
+	if c.Metrics.Token != "" && len(c.Metrics.Token) < 16 {
+		fail("metrics.token (METRICS_TOKEN) must be at least 16 characters")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp":
+	default:
+		fail("tracing.exporter (TRACING_EXPORTER) must be none or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+	}
+	if c.Tracing.ServiceName == "" {
+		fail("tracing.service_name (TRACING_SERVICE_NAME) is required")
+	}
+
+	if c.Webhooks.MaxAttempts < 1 {
+		fail("webhooks.max_attempts (WEBHOOK_MAX_ATTEMPTS) must be at least 1")
+	}
+	if c.Webhooks.DisableAfter < 1 {
+		fail("webhooks.disable_after (WEBHOOK_DISABLE_AFTER) must be at least 1")
+	}
+
+	if c.Render.Secret != "" && len(c.Render.Secret) < minJWTSecretLength {
+		fail("render.secret (RENDER_SECRET) must be at least %d characters", minJWTSecretLength)
+	}
+	if c.Render.CacheDir == "" {
+		fail("render.cache_dir (RENDER_CACHE_DIR) is required")
+	} else if within(c.Render.CacheDir, c.Storage.UploadDir) {
+		fail("render.cache_dir (RENDER_CACHE_DIR) must be outside storage.upload_dir (UPLOAD_DIR)")
+	}
+	for name, n := range map[string]int{
+		"render.max_dimension (RENDER_MAX_DIMENSION)":         c.Render.MaxDimension,
+		"render.max_source_pixels (RENDER_MAX_SOURCE_PIXELS)": c.Render.MaxSourcePixels,
+		"render.concurrency (RENDER_CONCURRENCY)":             c.Render.Concurrency,
+	} {
+		if n < 1 {
+			fail("%s must be at least 1", name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// within - dir nằm trong (hoặc trùng) parent
+func within(dir, parent string) bool {
+	dir, err1 := filepath.Abs(dir)
+	parent, err2 := filepath.Abs(parent)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// validateJWTSecret - Từ chối secret thiếu, ngắn, là giá trị mẫu hoặc quá ít ký tự khác nhau
+func validateJWTSecret(secret string) error {
+	if secret == "" {
+		return errors.New("jwt.secret (JWT_SECRET) is required; generate one with `make gen-secret`")
+	}
+	for _, weak := range weakJWTSecrets {
+		if strings.EqualFold(secret, weak) {
+			return errors.New("jwt.secret (JWT_SECRET) is a placeholder value; generate one with `make gen-secret`")
+		}
+	}
+	if len(secret) < minJWTSecretLength {
+		return fmt.Errorf("jwt.secret (JWT_SECRET) must be at least %d characters, got %d", minJWTSecretLength, len(secret))
+	}
+	distinct := map[rune]bool{}
+	for _, r := range secret {
+		distinct[r] = true
+	}
+	if len(distinct) < 10 {
+		return errors.New("jwt.secret (JWT_SECRET) is too predictable; generate one with `make gen-secret`")
+	}
+	return nil
+}
+
+// Duration - time.Duration đọc được từ chuỗi như "10m" trong env, YAML và TOML
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// ByteSize - Kích thước tính bằng byte, nhận số nguyên hoặc chuỗi có đơn vị KB/MB/GB (hệ 1024)
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	raw := strings.ToUpper(strings.TrimSpace(string(text)))
+	factor := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(raw, unit.suffix) {
+			raw = strings.TrimSpace(strings.TrimSuffix(raw, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", string(text))
+	}
+	*s = ByteSize(n * factor)
+	return nil
+}
+
+// String - Dạng dễ đọc dùng trong thông báo lỗi, ví dụ "500MB"
+func (s ByteSize) String() string {
+	switch {
+	case s >= 1<<30 && s%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", s>>30)
+	case s >= 1<<20 && s%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", s>>20)
+	case s >= 1<<10 && s%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", s>>10)
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
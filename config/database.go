@@ -3,45 +3,75 @@ package config
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
-	"time"
+	"log/slog"
 
+	"github.com/hieu9721/media-store-backend/metrics"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var DB *mongo.Database
 
-func ConnectDB() {
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ConnectDB - Kết nối MongoDB theo cấu hình và kiểm tra bằng ping
+func ConnectDB(cfg DatabaseConfig) error {
+    ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout.Duration)
     defer cancel()
 
-    mongoURI := os.Getenv("MONGODB_URI")
-    if mongoURI == "" {
-        mongoURI = "mongodb://localhost:27017"
-    }
-
-    clientOptions := options.Client().ApplyURI(mongoURI)
+    // Command monitoring: latency metrics and a child span per command
+    monitor := combineMonitors(metrics.MongoMonitor(), otelmongo.NewMonitor())
+    clientOptions := options.Client().ApplyURI(cfg.URI).SetMonitor(monitor)
     client, err := mongo.Connect(ctx, clientOptions)
     if err != nil {
-        log.Fatal("Failed to connect to MongoDB:", err)
+        return fmt.Errorf("failed to connect to MongoDB: %w", err)
     }
 
     err = client.Ping(ctx, nil)
     if err != nil {
-        log.Fatal("Failed to ping MongoDB:", err)
+        return fmt.Errorf("failed to ping MongoDB: %w", err)
     }
 
-    dbName := os.Getenv("DATABASE_NAME")
-    if dbName == "" {
-        dbName = "media_store"
-    }
+    DB = client.Database(cfg.Name)
+    slog.Info("connected to MongoDB", "database", cfg.Name)
+    return nil
+}
 
-    DB = client.Database(dbName)
-    fmt.Println("✅ Connected to MongoDB!")
+// combineMonitors - Driver chỉ nhận một CommandMonitor nên gọi lần lượt từng monitor
+func combineMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+    return &event.CommandMonitor{
+        Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+            for _, m := range monitors {
+                if m.Started != nil {
+                    m.Started(ctx, e)
+                }
+            }
+        },
+        Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+            for _, m := range monitors {
+                if m.Succeeded != nil {
+                    m.Succeeded(ctx, e)
+                }
+            }
+        },
+        Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+            for _, m := range monitors {
+                if m.Failed != nil {
+                    m.Failed(ctx, e)
+                }
+            }
+        },
+    }
 }
 
 func GetCollection(collectionName string) *mongo.Collection {
     return DB.Collection(collectionName)
 }
+
+// DisconnectDB - Đóng kết nối MongoDB khi tắt server
+func DisconnectDB(ctx context.Context) error {
+    if DB == nil {
+        return nil
+    }
+    return DB.Client().Disconnect(ctx)
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// Load - Đọc cấu hình: mặc định, rồi file (-config hoặc CONFIG_FILE, .yaml/.yml/.toml), rồi biến môi trường,
+// cuối cùng là flag dòng lệnh. Trả về các tham số còn lại sau flag (ví dụ "migrate up").
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("media-store-backend", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	port := fs.Int("port", 0, "HTTP port")
+	baseURL := fs.String("base-url", "", "public base URL of the API")
+	dbURI := fs.String("mongodb-uri", "", "MongoDB connection URI")
+	dbName := fs.String("database-name", "", "MongoDB database name")
+	uploadDir := fs.String("upload-dir", "", "directory for uploaded files")
+	migrateOnStart := fs.Bool("migrate-on-start", true, "apply pending migrations at startup")
+	logLevel := fs.String("log-level", "", "log level: debug, info, warn or error")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("invalid flags: %w", err)
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := applyEnv(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = *port
+		case "base-url":
+			cfg.Server.BaseURL = *baseURL
+		case "mongodb-uri":
+			cfg.Database.URI = *dbURI
+		case "database-name":
+			cfg.Database.Name = *dbName
+		case "upload-dir":
+			cfg.Storage.UploadDir = *uploadDir
+		case "migrate-on-start":
+			cfg.Database.MigrateOnStart = *migrateOnStart
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		}
+	})
+
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile - Field không có trong file giữ giá trị mặc định; key không xác định bị báo lỗi để tránh gõ nhầm
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalWithOptions(data, cfg, yaml.Strict())
+	case ".toml":
+		decoder := toml.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("config file %s: unsupported format (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv - Ghi đè bằng biến môi trường đã đặt (kể cả từ .env); biến rỗng được bỏ qua
+func applyEnv(cfg *Config) error {
+	var errs []error
+	str := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			*dst = v
+		}
+	}
+	text := func(key string, dst encoding.TextUnmarshaler) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+		}
+	}
+	integer := func(key string, dst *int) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid integer %q", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	boolean := func(key string, dst *bool) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid boolean %q", key, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	number := func(key string, dst *float64) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid number %q", key, v))
+				return
+			}
+			*dst = f
+		}
+	}
+	list := func(key string, dst *[]string) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			*dst = strings.Split(v, ",")
+		}
+	}
+
+	integer("PORT", &cfg.Server.Port)
+	str("BASE_URL", &cfg.Server.BaseURL)
+	str("FRONTEND_URL", &cfg.Server.FrontendURL)
+	list("TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+	text("HTTP_READ_HEADER_TIMEOUT", &cfg.Server.ReadHeaderTimeout)
+	text("HTTP_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	text("HTTP_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	text("HTTP_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	text("HTTP_MAX_HEADER_BYTES", &cfg.Server.MaxHeaderBytes)
+	text("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	str("MONGODB_URI", &cfg.Database.URI)
+	str("DATABASE_NAME", &cfg.Database.Name)
+	text("MONGODB_CONNECT_TIMEOUT", &cfg.Database.ConnectTimeout)
+	boolean("MIGRATE_ON_START", &cfg.Database.MigrateOnStart)
+
+	str("JWT_SECRET", &cfg.JWT.Secret)
+	text("JWT_EXPIRY", &cfg.JWT.TTL)
+
+	str("UPLOAD_DIR", &cfg.Storage.UploadDir)
+	text("STORAGE_MIN_FREE_SPACE", &cfg.Storage.MinFreeSpace)
+	text("UPLOAD_MAX_AVATAR_SIZE", &cfg.Upload.MaxAvatarSize)
+	text("UPLOAD_MAX_IMAGE_SIZE", &cfg.Upload.MaxImageSize)
+	text("UPLOAD_MAX_VIDEO_SIZE", &cfg.Upload.MaxVideoSize)
+	list("UPLOAD_IMAGE_EXTENSIONS", &cfg.Upload.ImageExtensions)
+	list("UPLOAD_VIDEO_EXTENSIONS", &cfg.Upload.VideoExtensions)
+
+	str("MAIL_DRIVER", &cfg.Mail.Driver)
+	str("MAIL_DIR", &cfg.Mail.Dir)
+	str("MAIL_FROM", &cfg.Mail.From)
+	str("SMTP_HOST", &cfg.Mail.SMTPHost)
+	integer("SMTP_PORT", &cfg.Mail.SMTPPort)
+	str("SMTP_USERNAME", &cfg.Mail.SMTPUsername)
+	str("SMTP_PASSWORD", &cfg.Mail.SMTPPassword)
+
+	str("RATE_LIMIT_BACKEND", &cfg.RateLimit.Backend)
+	str("TOTP_ISSUER", &cfg.TOTP.Issuer)
+
+	// OIDC_PROVIDERS=google,keycloak thay danh sách provider (provider cùng tên trong file giữ các field của nó);
+	// mỗi provider, kể cả provider chỉ khai báo trong file, đọc thêm OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+	// _REDIRECT_URL và _SCOPES
+	if v, ok := os.LookupEnv("OIDC_PROVIDERS"); ok && v != "" {
+		var providers []OIDCProviderConfig
+		for _, name := range strings.Split(v, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			provider := OIDCProviderConfig{Name: name}
+			for _, existing := range cfg.OIDC.Providers {
+				if strings.EqualFold(strings.TrimSpace(existing.Name), name) {
+					provider = existing
+				}
+			}
+			providers = append(providers, provider)
+		}
+		cfg.OIDC.Providers = providers
+	}
+	for i := range cfg.OIDC.Providers {
+		provider := &cfg.OIDC.Providers[i]
+		prefix := OIDCEnvPrefix(strings.TrimSpace(provider.Name))
+		str(prefix+"ISSUER", &provider.Issuer)
+		str(prefix+"CLIENT_ID", &provider.ClientID)
+		str(prefix+"CLIENT_SECRET", &provider.ClientSecret)
+		str(prefix+"REDIRECT_URL", &provider.RedirectURL)
+		list(prefix+"SCOPES", &provider.Scopes)
+	}
+
+	str("LOG_LEVEL", &cfg.Log.Level)
+	str("LOG_FORMAT", &cfg.Log.Format)
+
+	boolean("METRICS_ENABLED", &cfg.Metrics.Enabled)
+	str("METRICS_TOKEN", &cfg.Metrics.Token)
+
+	str("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	str("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	boolean("TRACING_INSECURE", &cfg.Tracing.Insecure)
+	number("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+	str("TRACING_SERVICE_NAME", &cfg.Tracing.ServiceName)
+
+	boolean("API_DOCS", &cfg.API.Docs)
+	boolean("API_VALIDATE_REQUESTS", &cfg.API.ValidateRequests)
+
+	text("WEBHOOK_TIMEOUT", &cfg.Webhooks.Timeout)
+	integer("WEBHOOK_MAX_ATTEMPTS", &cfg.Webhooks.MaxAttempts)
+	text("WEBHOOK_RETRY_BACKOFF", &cfg.Webhooks.RetryBackoff)
+	text("WEBHOOK_RETRY_BACKOFF_MAX", &cfg.Webhooks.RetryBackoffMax)
+	integer("WEBHOOK_DISABLE_AFTER", &cfg.Webhooks.DisableAfter)
+	text("WEBHOOK_POLL_INTERVAL", &cfg.Webhooks.PollInterval)
+	boolean("WEBHOOK_ALLOW_PRIVATE", &cfg.Webhooks.AllowPrivate)
+
+	text("EVENTS_POLL_INTERVAL", &cfg.Events.PollInterval)
+	text("EVENTS_HEARTBEAT", &cfg.Events.Heartbeat)
+	text("EVENTS_MAX_STREAM", &cfg.Events.MaxStream)
+	text("EVENTS_RETENTION", &cfg.Events.Retention)
+
+	str("RENDER_SECRET", &cfg.Render.Secret)
+	str("RENDER_CACHE_DIR", &cfg.Render.CacheDir)
+	integer("RENDER_MAX_DIMENSION", &cfg.Render.MaxDimension)
+	integer("RENDER_MAX_SOURCE_PIXELS", &cfg.Render.MaxSourcePixels)
+	integer("RENDER_CONCURRENCY", &cfg.Render.Concurrency)
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,36 @@
+// Package geocoder chuyển tọa độ GPS thành địa chỉ (reverse geocoding) qua driver có thể thay thế
+package geocoder
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/metrics"
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+// Geocoder - Driver reverse geocoding
+type Geocoder interface {
+	Reverse(ctx context.Context, lat, lon float64) (*models.LocationInfo, error)
+}
+
+var current Geocoder = NewNominatim(NominatimURL)
+
+// SetGeocoder - Thay driver hiện tại, dùng cho test
+func SetGeocoder(g Geocoder) {
+	current = g
+}
+
+// Reverse - Địa chỉ của tọa độ qua driver hiện tại; nil khi lỗi để upload vẫn thành công, chỉ thiếu địa chỉ
+func Reverse(ctx context.Context, lat, lon float64) *models.LocationInfo {
+	start := time.Now()
+	location, err := current.Reverse(ctx, lat, lon)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		slog.DebugContext(ctx, "reverse geocoding failed", "error", err)
+	}
+	metrics.ObserveSince(metrics.GeocodeDuration.WithLabelValues(outcome), start)
+	return location
+}
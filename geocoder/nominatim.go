@@ -0,0 +1,106 @@
+package geocoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/health"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/tracing"
+)
+
+// NominatimURL - Dịch vụ reverse geocoding (OpenStreetMap Nominatim)
+const NominatimURL = "https://nominatim.openstreetmap.org"
+
+// Nominatim - Driver gọi API reverse của Nominatim
+type Nominatim struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewNominatim - Driver Nominatim; mỗi lần gọi là một span client và gửi kèm traceparent
+func NewNominatim(baseURL string) *Nominatim {
+	return &Nominatim{
+		baseURL: baseURL,
+		client:  tracing.HTTPClient(http.Client{Timeout: 5 * time.Second}),
+	}
+}
+
+// Check - Check không critical cho Nominatim; kết quả được cache để không gọi quá nhiều
+func (n *Nominatim) Check() health.Check {
+	return health.HTTPCheck("geocoder", n.baseURL+"/status", n.client)
+}
+
+// nominatimResponse - Response từ Nominatim API
+type nominatimResponse struct {
+	DisplayName string `json:"display_name"`
+	Address     struct {
+		Country    string `json:"country"`
+		State      string `json:"state"`
+		City       string `json:"city"`
+		Town       string `json:"town"`
+		Village    string `json:"village"`
+		County     string `json:"county"`
+		District   string `json:"district"`
+		Road       string `json:"road"`
+		PostalCode string `json:"postcode"`
+	} `json:"address"`
+}
+
+// Reverse - Chuyển đổi tọa độ GPS thành địa chỉ
+func (n *Nominatim) Reverse(ctx context.Context, lat, lon float64) (*models.LocationInfo, error) {
+	url := fmt.Sprintf("%s/reverse?format=json&lat=%f&lon=%f&zoom=18&addressdetails=1", n.baseURL, lat, lon)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nominatim yêu cầu User-Agent
+	req.Header.Set("User-Agent", "MediaStoreBackend/1.0")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim returned %s", resp.Status)
+	}
+
+	var result nominatimResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	// Xây dựng LocationInfo
+	location := &models.LocationInfo{
+		Country:     result.Address.Country,
+		State:       result.Address.State,
+		PostalCode:  result.Address.PostalCode,
+		Road:        result.Address.Road,
+		DisplayName: result.DisplayName,
+	}
+
+	// Ưu tiên City > Town > Village
+	if result.Address.City != "" {
+		location.City = result.Address.City
+	} else if result.Address.Town != "" {
+		location.City = result.Address.Town
+	} else if result.Address.Village != "" {
+		location.City = result.Address.Village
+	}
+
+	// Ưu tiên District > County
+	if result.Address.District != "" {
+		location.District = result.Address.District
+	} else if result.Address.County != "" {
+		location.District = result.Address.County
+	}
+
+	return location, nil
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// MongoCheck - Ping primary của MongoDB
+func MongoCheck(db *mongo.Database) Check {
+	return Check{
+		Name:     "mongo",
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			return db.Client().Ping(ctx, readpref.Primary())
+		},
+	}
+}
+
+// StorageCheck - Thư mục upload ghi được và còn ít nhất minFree byte trống
+func StorageCheck(dir string, minFree int64) Check {
+	return Check{
+		Name:     "storage",
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				return fmt.Errorf("upload directory unavailable: %w", err)
+			}
+
+			f, err := os.CreateTemp(dir, ".readyz-*")
+			if err != nil {
+				return fmt.Errorf("upload directory not writable: %w", err)
+			}
+			_, writeErr := f.Write([]byte("ok"))
+			closeErr := f.Close()
+			os.Remove(f.Name())
+			if writeErr != nil || closeErr != nil {
+				return fmt.Errorf("upload directory not writable: %w", firstError(writeErr, closeErr))
+			}
+
+			free, err := freeSpace(dir)
+			if err != nil {
+				return fmt.Errorf("cannot read free space: %w", err)
+			}
+			if free < uint64(minFree) {
+				return fmt.Errorf("only %d MB free, need at least %d MB", free>>20, minFree>>20)
+			}
+			return nil
+		},
+	}
+}
+
+// HTTPCheck - GET url phải trả 2xx; dùng cho dịch vụ bên ngoài như geocoder (thường không critical)
+func HTTPCheck(name, url string, client *http.Client) Check {
+	return Check{
+		Name:     name,
+		Timeout:  5 * time.Second,
+		CacheFor: time.Minute,
+		Run: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("User-Agent", "MediaStoreBackend/1.0")
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// WorkerCheck - Heartbeat của worker nền: lỗi khi còn việc chờ (pending > 0) mà worker
+// không có hoạt động nào (lastActivity) trong maxAge
+func WorkerCheck(name string, pending func() int, lastActivity func() time.Time, maxAge time.Duration) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			n := pending()
+			if n == 0 {
+				return nil
+			}
+			if idle := time.Since(lastActivity()); idle > maxAge {
+				return fmt.Errorf("%d jobs pending, no progress for %s", n, idle.Round(time.Second))
+			}
+			return nil
+		},
+	}
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package health
+
+import "golang.org/x/sys/windows"
+
+// freeSpace - Số byte trống mà user hiện tại dùng được trên ổ chứa dir
+func freeSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var available, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return available, nil
+}
@@ -0,0 +1,160 @@
+// Package health chạy các kiểm tra phụ thuộc (MongoDB, ổ lưu upload, geocoder, worker nền) cho /readyz
+// và cung cấp thông tin build cho /version
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Status - Trạng thái của một check hoặc của cả báo cáo
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// StatusDegraded - Chỉ check không critical bị lỗi; server vẫn nhận request
+	StatusDegraded Status = "degraded"
+	// StatusFail - Có check critical bị lỗi; /readyz trả 503
+	StatusFail Status = "fail"
+)
+
+const defaultCheckTimeout = 2 * time.Second
+
+// Check - Một kiểm tra cắm thêm được. Run trả lỗi khi phụ thuộc không dùng được.
+type Check struct {
+	Name string
+	// Critical - Lỗi làm server không sẵn sàng; false thì chỉ báo degraded
+	Critical bool
+	// Timeout - Thời gian tối đa cho một lần chạy, mặc định 2 giây
+	Timeout time.Duration
+	// CacheFor - Dùng lại kết quả trong khoảng này, tránh gọi dịch vụ bên ngoài mỗi lần probe
+	CacheFor time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Result - Kết quả của một check trong báo cáo
+type Result struct {
+	Status    Status  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	// Error - Chỉ ghi vào log, không trả qua /readyz: lỗi có thể lộ địa chỉ và cấu hình hạ tầng
+	Error     string `json:"-"`
+	CheckedAt int64  `json:"checked_at"`
+}
+
+// Report - Trạng thái tổng hợp và kết quả từng check
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type registeredCheck struct {
+	Check
+	mu       sync.Mutex
+	last     Result
+	lastTime time.Time
+}
+
+// Checker - Tập các check, chạy song song khi được hỏi
+type Checker struct {
+	mu     sync.RWMutex
+	checks []*registeredCheck
+}
+
+// New - Tạo Checker rỗng (báo cáo luôn ok)
+func New() *Checker {
+	return &Checker{}
+}
+
+var defaultChecker = New()
+
+// Default - Checker dùng cho /readyz
+func Default() *Checker {
+	return defaultChecker
+}
+
+// Register - Thêm check vào Checker mặc định
+func Register(check Check) {
+	defaultChecker.Register(check)
+}
+
+// Register - Thêm check; check trùng tên thay thế check cũ
+func (c *Checker) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = defaultCheckTimeout
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.checks {
+		if existing.Name == check.Name {
+			c.checks[i] = &registeredCheck{Check: check}
+			return
+		}
+	}
+	c.checks = append(c.checks, &registeredCheck{Check: check})
+}
+
+// Run - Chạy mọi check song song và tổng hợp trạng thái
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]*registeredCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, check := range checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		switch {
+		case result.Status == StatusOK:
+		case check.Critical:
+			report.Status = StatusFail
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (rc *registeredCheck) run(ctx context.Context) Result {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.CacheFor > 0 && !rc.lastTime.IsZero() && time.Since(rc.lastTime) < rc.CacheFor {
+		return rc.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rc.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := rc.Run(ctx)
+	result := Result{
+		Status:    StatusOK,
+		Critical:  rc.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start.Unix(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		slog.WarnContext(ctx, "health check failed", "check", rc.Name, "critical", rc.Critical, "error", err)
+	}
+
+	rc.last = result
+	rc.lastTime = start
+	return result
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// BuildInfo - Thông tin build trả về ở /version
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	// Modified - Binary được build từ working tree có thay đổi chưa commit
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	StartedAt int64  `json:"started_at"`
+}
+
+var build = readBuildInfo()
+
+// SetVersion - Ghi nhận version gắn lúc build bằng -ldflags "-X main.Version=..."; gọi một lần khi khởi động
+func SetVersion(version string) {
+	if version != "" {
+		build.Version = version
+	}
+}
+
+// Build - Thông tin build hiện tại
+func Build() BuildInfo {
+	return build
+}
+
+// readBuildInfo - Commit và thời điểm commit lấy từ thông tin VCS mà Go nhúng vào binary
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   "dev",
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		StartedAt: time.Now().Unix(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.CommitTime = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
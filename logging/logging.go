@@ -0,0 +1,98 @@
+// Package logging cấu hình log/slog cho toàn server (JSON hoặc text) và tự gắn request ID, user ID, trace ID
+// lấy từ context vào mọi dòng log ghi bằng các hàm *Context của slog
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/hieu9721/media-store-backend/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	userIDKey
+)
+
+// New - Tạo logger ghi ra w theo cfg.Format ("json" hoặc "text") và cfg.Level
+func New(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Setup - Đặt logger mặc định (slog và cả package log chuẩn) ghi ra stdout
+func Setup(cfg config.LogConfig) {
+	slog.SetDefault(New(os.Stdout, cfg))
+}
+
+// Fatal - Ghi log mức error rồi thoát với mã 1, thay cho log.Fatal
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// WithRequestID - Gắn request ID vào context để các dòng log sau đó mang theo
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID - Request ID trong context, rỗng nếu không có
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithUserID - Gắn ID của user đã xác thực vào context
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// UserID - User ID trong context, rỗng nếu request chưa xác thực
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey).(string)
+	return id
+}
+
+// contextHandler - Thêm request_id, user_id và trace_id/span_id (khi có tracing) từ context vào record trước khi ghi
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if id := UserID(ctx); id != "" {
+			r.AddAttrs(slog.String("user_id", id))
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// asyncTimeout - Thời gian tối đa cho một email gửi ở background
+const asyncTimeout = 30 * time.Second
+
+var (
+	pendingMu sync.Mutex
+	pending   sync.WaitGroup
+	draining  bool
+	queued    atomic.Int64
+	// lastActivity - Thời điểm (UnixNano) email gần nhất gửi xong hoặc hàng đợi bắt đầu có việc
+	lastActivity atomic.Int64
+	observer     atomic.Pointer[Observer]
+)
+
+// Observer - Nhận kết quả mỗi lần gửi email ở background (job "mail"), ví dụ để lưu thống kê
+type Observer func(ctx context.Context, job string, elapsed time.Duration, err error)
+
+// Observe - Đăng ký observer cho các lần gửi nền; nil để bỏ
+func Observe(fn Observer) {
+	if fn == nil {
+		observer.Store(nil)
+		return
+	}
+	observer.Store(&fn)
+}
+
+// SendAsync - Gửi email ở background để thời gian phản hồi không lộ việc email có tồn tại hay không.
+// Sau khi Drain đã được gọi, email được gửi ngay trong goroutine của caller để không bị mất khi tắt server.
+// ctx chỉ dùng để giữ request ID cho log; việc gửi không bị hủy theo ctx.
+func SendAsync(ctx context.Context, msg Message) {
+	ctx = context.WithoutCancel(ctx)
+
+	pendingMu.Lock()
+	if draining {
+		pendingMu.Unlock()
+		sendLogged(ctx, msg)
+		return
+	}
+	pending.Add(1)
+	if queued.Add(1) == 1 {
+		lastActivity.Store(time.Now().UnixNano())
+	}
+	pendingMu.Unlock()
+
+	go func() {
+		defer pending.Done()
+		defer queued.Add(-1)
+		sendLogged(ctx, msg)
+		lastActivity.Store(time.Now().UnixNano())
+	}()
+}
+
+// Pending - Số email đang chờ gửi ở background
+func Pending() int {
+	return int(queued.Load())
+}
+
+// LastActivity - Heartbeat của việc gửi nền, dùng cho readiness check
+func LastActivity() time.Time {
+	return time.Unix(0, lastActivity.Load())
+}
+
+func sendLogged(ctx context.Context, msg Message) {
+	ctx, cancel := context.WithTimeout(ctx, asyncTimeout)
+	defer cancel()
+	start := time.Now()
+	err := Send(ctx, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to send mail", "subject", msg.Subject, "to", msg.To, "error", err)
+	}
+	if fn := observer.Load(); fn != nil {
+		(*fn)(ctx, "mail", time.Since(start), err)
+	}
+}
+
+// Drain - Chờ các email đang gửi ở background hoàn tất, tối đa tới khi ctx hết hạn
+func Drain(ctx context.Context) error {
+	pendingMu.Lock()
+	draining = true
+	pendingMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,45 @@
+// Package mailer gửi email giao dịch (xác thực email, đặt lại mật khẩu) qua driver có thể thay thế
+package mailer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/hieu9721/media-store-backend/config"
+)
+
+// Message - Nội dung một email dạng text
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer - Driver gửi email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var current Mailer = &FileMailer{}
+
+// Setup - Chọn driver theo cfg.Driver: "smtp" hoặc "file" (mặc định, ghi log nếu không có Dir)
+func Setup(cfg config.MailConfig) {
+	switch cfg.Driver {
+	case "smtp":
+		current = NewSMTPMailer(cfg)
+		slog.Info("mailer configured", "driver", "smtp", "host", cfg.SMTPHost)
+	default:
+		current = &FileMailer{Dir: cfg.Dir}
+		slog.Info("mailer configured", "driver", "file", "dir", cfg.Dir, "note", "emails are not delivered")
+	}
+}
+
+// SetMailer - Thay driver hiện tại, dùng cho test
+func SetMailer(m Mailer) {
+	current = m
+}
+
+// Send - Gửi email qua driver hiện tại
+func Send(ctx context.Context, msg Message) error {
+	return current.Send(ctx, msg)
+}
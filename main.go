@@ -1,35 +1,164 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/api"
 	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/geocoder"
+	"github.com/hieu9721/media-store-backend/health"
+	"github.com/hieu9721/media-store-backend/logging"
+	"github.com/hieu9721/media-store-backend/mailer"
+	"github.com/hieu9721/media-store-backend/metrics"
+	"github.com/hieu9721/media-store-backend/migrations"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/realtime"
+	"github.com/hieu9721/media-store-backend/repository"
 	"github.com/hieu9721/media-store-backend/routes"
+	"github.com/hieu9721/media-store-backend/server"
+	"github.com/hieu9721/media-store-backend/sso"
+	"github.com/hieu9721/media-store-backend/tracing"
+	"github.com/hieu9721/media-store-backend/utils"
+	"github.com/hieu9721/media-store-backend/webhook"
 	"github.com/joho/godotenv"
 )
 
+// Version - Set at build time with -ldflags "-X main.Version=..." (see Makefile)
+var Version = "dev"
+
 func main() {
+    health.SetVersion(Version)
+
+    // JSON logs from the start; level/format are applied once configuration is loaded
+    logging.Setup(config.Default().Log)
+
     // Load .env file
-    if err := godotenv.Load(); err != nil {
-        log.Println("No .env file found")
+    envErr := godotenv.Load()
+
+    // Load and validate configuration (defaults < config file < env < flags); fail fast on invalid values
+    cfg, args, err := config.Load(os.Args[1:])
+    if err != nil {
+        logging.Fatal("invalid configuration", "error", err)
+    }
+    logging.Setup(cfg.Log)
+    if envErr != nil {
+        slog.Debug("no .env file found")
+    }
+    utils.ConfigureJWT(cfg.JWT.Secret, cfg.JWT.TTL.Duration)
+
+    // Gin's own debug output goes through slog; release mode unless debugging
+    if cfg.Log.Level != "debug" {
+        gin.SetMode(gin.ReleaseMode)
+    }
+    gin.DebugPrintFunc = func(format string, values ...any) {
+        slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)), "component", "gin")
+    }
+
+    // OpenTelemetry: W3C trace-context propagation, OTLP exporter when enabled (no-op by default)
+    shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+    if err != nil {
+        logging.Fatal("tracing setup failed", "error", err)
     }
 
     // Connect to MongoDB
-    config.ConnectDB()
+    if err := config.ConnectDB(cfg.Database); err != nil {
+        logging.Fatal("database connection failed", "error", err)
+    }
+
+    // Migration command: media-store-backend [flags] migrate [up|down [n]|status]
+    if len(args) > 0 && args[0] == "migrate" {
+        if err := migrations.RunCommand(config.DB, args[1:], os.Stdout); err != nil {
+            logging.Fatal("migration failed", "error", err)
+        }
+        config.DisconnectDB(context.Background())
+        return
+    }
+
+    // Apply pending migrations (indexes, validators, backfills) unless disabled
+    if cfg.Database.MigrateOnStart {
+        if err := migrations.RunCommand(config.DB, []string{"up"}, os.Stdout); err != nil {
+            logging.Fatal("migration failed", "error", err)
+        }
+    }
+
+    // Setup mail driver
+    mailer.Setup(cfg.Mail)
+
+    // Expose background mail queue depth as a metric
+    metrics.RegisterQueue("mail", mailer.Pending)
+
+    // Record every background mail send for the admin job statistics
+    repos := repository.NewMongo(config.DB)
+    mailer.Observe(api.JobRecorder(repos))
+
+    // Background webhook delivery; pending deliveries survive restarts in the database
+    webhooks := webhook.NewWorker(repos, cfg.Webhooks)
+    webhooks.Observe(api.JobRecorder(repos))
+    webhook.SetWorker(webhooks)
+    webhooks.Start()
+    metrics.RegisterQueue("webhook", webhook.Pending)
+
+    // Real-time event stream; every instance tails the shared event log
+    hub := realtime.NewHub(repos.Events, cfg.Events)
+    realtime.SetHub(hub)
+    hub.Start()
+
+    // Setup rate limiter backend (memory or mongo)
+    ratelimit.Setup(cfg.RateLimit.Backend, config.DB)
+
+    // Setup OpenID Connect providers
+    sso.Setup(cfg.OIDC)
+
+    // Readiness checks for /readyz: database and upload storage are critical,
+    // the geocoder and the background mail and webhook workers only degrade the report
+    health.Register(health.MongoCheck(config.DB))
+    health.Register(health.StorageCheck(cfg.Storage.UploadDir, int64(cfg.Storage.MinFreeSpace)))
+    health.Register(geocoder.NewNominatim(geocoder.NominatimURL).Check())
+    health.Register(health.WorkerCheck("mail_worker", mailer.Pending, mailer.LastActivity, 2*time.Minute))
+    health.Register(health.WorkerCheck("webhook_worker", webhook.Pending, webhook.LastActivity, 2*time.Minute))
 
     // Setup routes
-    router := routes.SetupRoutes()
+    router := routes.SetupRoutes(repos, cfg)
+
+    // HTTP server with explicit timeouts; SIGTERM/SIGINT triggers a graceful shutdown
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+    go func() {
+        // A second signal kills the process immediately
+        <-ctx.Done()
+        stop()
+    }()
+
+    // Log the audit chain head hourly so shipped logs keep a copy of it outside the database
+    go api.AnchorAudit(ctx, repos.Audit, time.Hour)
 
-    // Get port from env
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "8080"
+    opts := server.Options{
+        Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+        ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Duration,
+        ReadTimeout:       cfg.Server.ReadTimeout.Duration,
+        WriteTimeout:      cfg.Server.WriteTimeout.Duration,
+        IdleTimeout:       cfg.Server.IdleTimeout.Duration,
+        MaxHeaderBytes:    int(cfg.Server.MaxHeaderBytes),
+        ShutdownTimeout:   cfg.Server.ShutdownTimeout.Duration,
     }
+    srv := server.New(router, opts)
+    srv.BeforeDrain("events", hub.Stop)
+    srv.OnShutdown("mailer", mailer.Drain)
+    srv.OnShutdown("webhooks", webhooks.Stop)
+    srv.OnShutdown("database", config.DisconnectDB)
+    srv.OnShutdown("tracing", shutdownTracing)
 
-    // Start server
-    log.Printf("🚀 Server starting on port %s...", port)
-    if err := router.Run(":" + port); err != nil {
-        log.Fatal("Failed to start server:", err)
+    slog.Info("server starting", "addr", opts.Addr, "base_url", cfg.Server.BaseURL)
+    if err := srv.Run(ctx); err != nil {
+        logging.Fatal("server error", "error", err)
     }
 }
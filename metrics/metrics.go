@@ -0,0 +1,130 @@
+// Package metrics khai báo các metric Prometheus của server (HTTP, upload, xử lý metadata, MongoDB, hàng đợi nền)
+// trên một registry riêng, phục vụ qua endpoint /metrics
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "media_store"
+
+// Registry - Registry riêng thay cho registry mặc định để /metrics chỉ chứa metric của server và runtime Go
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests - Số request theo method, route template và status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration - Thời gian xử lý request; bucket tới 5 phút vì upload video có thể rất lâu
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"method", "route", "status"})
+
+	// UploadedBytes - Tổng số byte đã lưu theo loại media (avatar, image, video)
+	UploadedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes of successfully stored uploads by media type.",
+	}, []string{"type"})
+
+	// UploadedFiles - Số file đã lưu theo loại media
+	UploadedFiles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_files_total",
+		Help:      "Successfully stored uploads by media type.",
+	}, []string{"type"})
+
+	// UploadsInFlight - Số request upload đang xử lý (đang nhận file hoặc trích xuất metadata)
+	UploadsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "uploads_in_flight",
+		Help:      "Upload requests currently being processed.",
+	})
+
+	// EventStreams - Số kết nối SSE /api/v1/events đang mở trên instance
+	EventStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_streams_open",
+		Help:      "Open real-time event streams on this instance.",
+	})
+
+	// ExifDuration - Thời gian trích xuất metadata ảnh (EXIF, XMP, PNG text), không gồm reverse geocoding
+	ExifDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "exif_extraction_duration_seconds",
+		Help:      "Time spent extracting image metadata, excluding reverse geocoding.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	})
+
+	// GeocodeDuration - Thời gian gọi dịch vụ reverse geocoding, result là ok hoặc error
+	GeocodeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reverse_geocode_duration_seconds",
+		Help:      "Reverse geocoding latency by result (ok or error).",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"result"})
+
+	// MongoDuration - Thời gian mỗi lệnh MongoDB theo tên lệnh (find, insert, update...) và kết quả
+	MongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_command_duration_seconds",
+		Help:      "MongoDB command latency by command name and result (ok or error).",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"command", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		UploadedBytes,
+		UploadedFiles,
+		UploadsInFlight,
+		EventStreams,
+		ExifDuration,
+		GeocodeDuration,
+		MongoDuration,
+	)
+}
+
+// Handler - HTTP handler xuất metric theo định dạng Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RecordUpload - Ghi nhận một file upload đã lưu thành công
+func RecordUpload(mediaType string, size int64) {
+	UploadedFiles.WithLabelValues(mediaType).Inc()
+	UploadedBytes.WithLabelValues(mediaType).Add(float64(size))
+}
+
+// ObserveSince - Ghi thời gian từ start vào histogram, dùng với defer
+func ObserveSince(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// RegisterQueue - Xuất độ dài hàng đợi nền (ví dụ email chờ gửi) dưới dạng gauge media_store_queue_depth{queue="name"}
+func RegisterQueue(name string, depth func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "queue_depth",
+		Help:        "Items waiting or in progress in background queues.",
+		ConstLabels: prometheus.Labels{"queue": name},
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// Loại xác thực của request, lưu trong context với key "auth_type"
+const (
+	AuthTypeJWT         = "jwt"
+	AuthTypeAccessToken = "access_token"
+)
+
+var errInvalidAccessToken = errors.New("invalid or expired access token")
+
+// authenticateAccessToken - Tìm personal access token theo hash, kiểm tra hạn và lấy user sở hữu
+func authenticateAccessToken(c *gin.Context, repos *repository.Repositories, raw string) error {
+	ctx, cancel := RequestContext(c, 5*time.Second)
+	defer cancel()
+
+	token, err := repos.AccessTokens.GetByHash(ctx, utils.HashToken(raw))
+	if err != nil {
+		return errInvalidAccessToken
+	}
+
+	now := time.Now().Unix()
+	if token.ExpiresAt > 0 && now > token.ExpiresAt {
+		return errInvalidAccessToken
+	}
+
+	user, err := repos.Users.GetByID(ctx, token.UserID)
+	if err != nil {
+		return errInvalidAccessToken
+	}
+
+	// Ghi nhận lần dùng gần nhất, tối đa mỗi phút một lần để tránh ghi DB mỗi request
+	if now-token.LastUsedAt >= 60 {
+		if err := repos.AccessTokens.Touch(ctx, token.ID, c.ClientIP(), now); err != nil {
+			slog.ErrorContext(ctx, "failed to update access token usage", "token_id", token.ID, "error", err)
+		}
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("email", user.Email)
+	c.Set("role", user.Role)
+	c.Set("auth_type", AuthTypeAccessToken)
+	c.Set("token_id", token.ID)
+	c.Set("scopes", token.Scopes)
+	setLogUser(c, user.ID)
+	return nil
+}
+
+// MethodScope - Scope mặc định personal access token cần theo method: read cho GET/HEAD, còn lại là write.
+// Nhóm route cần scope khác (upload) khai báo qua AuthRequired
+func MethodScope(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return models.ScopeRead
+	}
+	return models.ScopeWrite
+}
+
+// hasScope - Scope write bao gồm mọi quyền
+func hasScope(scopes []string, required string) bool {
+	for _, scope := range scopes {
+		if scope == required || scope == models.ScopeWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTOnly - Chặn personal access token ở các route nhạy cảm (quản lý token, 2FA, liên kết tài khoản, quản trị)
+func JWTOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") == AuthTypeAccessToken {
+			apperr.Respond(c, apperr.Forbidden("access_token_not_allowed", "This endpoint cannot be used with a personal access token"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,22 +1,33 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
 	"github.com/hieu9721/media-store-backend/utils"
 )
 
+// ErrorHandler - Handler có thể gọi c.Error(err) rồi return thay vì tự ghi response: lỗi cuối cùng
+// được trả dạng problem+json (*apperr.Error giữ status và mã, lỗi khác thành 500 không lộ chi tiết).
+// Response đã được ghi (apperr.Respond, c.JSON...) thì giữ nguyên.
 func ErrorHandler() gin.HandlerFunc {
     return func(c *gin.Context) {
         c.Next()
 
-        if len(c.Errors) > 0 {
-            c.JSON(http.StatusInternalServerError, gin.H{
-                "errors": c.Errors.Errors(),
-            })
+        if len(c.Errors) == 0 || c.Writer.Written() {
+            return
         }
+        apperr.Respond(c, c.Errors.Last().Err)
+    }
+}
+
+// NotFound - Route không tồn tại
+func NotFound() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        apperr.Respond(c, apperr.NotFound(apperr.CodeRouteNotFound, "Route not found"))
     }
 }
 
@@ -24,7 +35,8 @@ func CORS() gin.HandlerFunc {
     return func(c *gin.Context) {
         c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
         c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-        c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+        c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+        c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
         c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
         if c.Request.Method == "OPTIONS" {
@@ -36,39 +48,64 @@ func CORS() gin.HandlerFunc {
     }
 }
 
-func AuthRequired() gin.HandlerFunc {
+// AuthRequired - Xác thực JWT hoặc personal access token. scope là scope token cần cho mọi route của nhóm;
+// rỗng thì theo method (MethodScope)
+func AuthRequired(repos *repository.Repositories, scope string) gin.HandlerFunc {
     return func(c *gin.Context) {
         authHeader := c.GetHeader("Authorization")
         if authHeader == "" {
-            c.JSON(http.StatusUnauthorized, gin.H{
-                "error": "Authorization header is required",
-            })
-            c.Abort()
+            apperr.Respond(c, apperr.Unauthorized("missing_authorization", "Authorization header is required"))
             return
         }
 
         parts := strings.Split(authHeader, " ")
         if len(parts) != 2 || parts[0] != "Bearer" {
-            c.JSON(http.StatusUnauthorized, gin.H{
-                "error": "Invalid authorization header format. Expected: Bearer <token>",
-            })
-            c.Abort()
+            apperr.Respond(c, apperr.Unauthorized("invalid_authorization_header", "Invalid authorization header format. Expected: Bearer <token>"))
             return
         }
 
         token := parts[1]
+
+        // Personal access token (msp_...) cho script, giới hạn theo scope
+        if strings.HasPrefix(token, models.AccessTokenPrefix) {
+            if err := authenticateAccessToken(c, repos, token); err != nil {
+                apperr.Respond(c, apperr.Unauthorized("invalid_token", "Invalid or expired token"))
+                return
+            }
+
+            scopes, _ := c.Get("scopes")
+            tokenScopes, _ := scopes.([]string)
+            required := scope
+            if required == "" {
+                required = MethodScope(c.Request.Method)
+            }
+            if !hasScope(tokenScopes, required) {
+                apperr.Respond(c, apperr.Forbidden("insufficient_scope", "Access token is missing the '"+required+"' scope"))
+                return
+            }
+
+            c.Next()
+            return
+        }
+
         claims, err := utils.ValidateToken(token)
         if err != nil {
-            c.JSON(http.StatusUnauthorized, gin.H{
-                "error": "Invalid or expired token",
-            })
-            c.Abort()
+            apperr.Respond(c, apperr.Unauthorized("invalid_token", "Invalid or expired token"))
+            return
+        }
+
+        // Token của phiên đã bị thu hồi (đăng xuất thiết bị, đổi mật khẩu) không còn dùng được
+        if err := checkSession(c, repos, claims.UserID, claims.SessionID); err != nil {
+            apperr.Respond(c, apperr.Unauthorized("session_revoked", "Session has been revoked"))
             return
         }
 
         c.Set("user_id", claims.UserID)
         c.Set("email", claims.Email)
         c.Set("role", claims.Role)
+        c.Set("auth_type", AuthTypeJWT)
+        c.Set("session_id", claims.SessionID)
+        setLogUser(c, claims.UserID)
         c.Next()
     }
 }
@@ -77,18 +114,12 @@ func AdminRequired() gin.HandlerFunc {
     return func(c *gin.Context) {
         role, exists := c.Get("role")
         if !exists {
-            c.JSON(http.StatusUnauthorized, gin.H{
-                "error": "Unauthorized: No role found",
-            })
-            c.Abort()
+            apperr.Respond(c, apperr.Unauthorized("unauthorized", "Unauthorized: No role found"))
             return
         }
 
         if role != "admin" {
-            c.JSON(http.StatusForbidden, gin.H{
-                "error": "Forbidden: Admin access required",
-            })
-            c.Abort()
+            apperr.Respond(c, apperr.Forbidden("admin_required", "Forbidden: Admin access required"))
             return
         }
 
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics - Đếm request và đo latency theo route template (không theo path thật để giới hạn số time series)
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// InFlight - Tăng gauge trong lúc request đang được xử lý (dùng cho nhóm route upload)
+func InFlight(gauge prometheus.Gauge) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gauge.Inc()
+		defer gauge.Dec()
+		c.Next()
+	}
+}
+
+// MetricsAuth - Yêu cầu "Authorization: Bearer <token>" cho /metrics; token rỗng thì không bảo vệ
+func MetricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		expected := []byte("Bearer " + token)
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			apperr.Respond(c, apperr.Unauthorized("invalid_metrics_token", "Invalid or missing metrics token"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/openapi"
+)
+
+// ValidateRequest - Kiểm tra path/query parameter và body JSON theo tài liệu OpenAPI trước khi vào handler,
+// trả lỗi validation_failed với danh sách field giống lỗi binding. Route không có trong tài liệu được bỏ qua;
+// body multipart (upload) không được đọc ở đây để không phải giữ cả file trong bộ nhớ.
+func ValidateRequest(doc *openapi3.T) gin.HandlerFunc {
+	// Lỗi trả về và ghi log không kèm toàn bộ schema
+	openapi3.SchemaErrorDetailsDisabled = true
+	// Format email/uri kiểm tra bằng đúng rule của validator mà binding dùng
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		for format, tag := range map[string]string{"email": "email", "uri": "url"} {
+			openapi3.DefineStringFormatCallback(format, func(value string) error {
+				return v.Var(value, tag)
+			})
+		}
+	}
+	options := openapi3filter.Options{
+		MultiError:          true,
+		SkipSettingDefaults: true,
+		// Xác thực do AuthRequired đảm nhiệm
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(c *gin.Context) {
+		path := openapi.Path(c.FullPath())
+		item := doc.Paths.Value(path)
+		if item == nil || item.GetOperation(c.Request.Method) == nil {
+			c.Next()
+			return
+		}
+
+		opts := options
+		opts.ExcludeRequestBody = c.ContentType() != "application/json"
+
+		params := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			params[param.Key] = param.Value
+		}
+
+		ctx, cancel := RequestContext(c, 5*time.Second)
+		defer cancel()
+		err := openapi3filter.ValidateRequest(ctx, &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route: &routers.Route{
+				Spec:      doc,
+				Path:      path,
+				PathItem:  item,
+				Method:    c.Request.Method,
+				Operation: item.GetOperation(c.Request.Method),
+			},
+			Options: &opts,
+		})
+		if err != nil {
+			apperr.Respond(c, requestValidationError(err))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestValidationError - Gom lỗi của openapi3filter thành một lỗi validation_failed có danh sách field
+func requestValidationError(err error) *apperr.Error {
+	var fields []apperr.FieldError
+	for _, e := range flattenErrors(err) {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(e, &requestErr) {
+			continue
+		}
+
+		var parseErr *openapi3filter.ParseError
+		if errors.As(requestErr.Err, &parseErr) && requestErr.RequestBody != nil {
+			return apperr.BadRequest(apperr.CodeInvalidBody, "Request body is not valid JSON").WithCause(err)
+		}
+
+		prefix := ""
+		if requestErr.Parameter != nil {
+			prefix = requestErr.Parameter.Name
+		}
+		schemaErrs := flattenErrors(requestErr.Err)
+		if len(schemaErrs) == 0 {
+			fields = append(fields, apperr.FieldError{Field: prefix, Code: "invalid", Message: requestErr.Reason})
+		}
+		for _, se := range schemaErrs {
+			var schemaErr *openapi3.SchemaError
+			if !errors.As(se, &schemaErr) {
+				fields = append(fields, apperr.FieldError{Field: prefix, Code: "invalid", Message: se.Error()})
+				continue
+			}
+			fields = append(fields, schemaFieldError(prefix, schemaErr))
+		}
+	}
+
+	if len(fields) == 0 {
+		return apperr.BadRequest(apperr.CodeValidation, "Request does not match the API specification").WithCause(err)
+	}
+	return apperr.InvalidFields(fields, err)
+}
+
+// schemaFieldError - Dùng cùng tên rule và câu thông báo như lỗi binding (required, min, max, oneof, email, type)
+func schemaFieldError(prefix string, err *openapi3.SchemaError) apperr.FieldError {
+	path := err.JSONPointer()
+	if prefix != "" {
+		path = append([]string{prefix}, path...)
+	}
+
+	code, message := err.SchemaField, err.Reason
+	schema := err.Schema
+	if schema == nil {
+		schema = &openapi3.Schema{}
+	}
+	switch err.SchemaField {
+	case "required":
+		// Reason dạng: property "email" is missing; pointer có thể đã trỏ tới field bị thiếu
+		if name, ok := quoted(err.Reason); ok && (len(path) == 0 || path[len(path)-1] != name) {
+			path = append(path, name)
+		}
+		message = "is required"
+	case "minLength":
+		code, message = "min", fmt.Sprintf("must be at least %d characters", schema.MinLength)
+	case "maxLength":
+		code, message = "max", fmt.Sprintf("must be at most %d characters", derefUint(schema.MaxLength))
+	case "minItems":
+		code, message = "min", fmt.Sprintf("must contain at least %d items", schema.MinItems)
+	case "maxItems":
+		code, message = "max", fmt.Sprintf("must contain at most %d items", derefUint(schema.MaxItems))
+	case "minimum":
+		code, message = "min", "must be at least "+formatNumber(schema.Min)
+	case "maximum":
+		code, message = "max", "must be at most "+formatNumber(schema.Max)
+	case "enum":
+		values := make([]string, 0, len(schema.Enum))
+		for _, v := range schema.Enum {
+			values = append(values, fmt.Sprint(v))
+		}
+		code, message = "oneof", "must be one of: "+strings.Join(values, ", ")
+	case "type":
+		if types := schema.Type.Slice(); len(types) > 0 {
+			message = "must be of type " + types[0]
+		}
+	case "format":
+		switch schema.Format {
+		case "email":
+			code, message = "email", "must be a valid email address"
+		case "uri":
+			code, message = "url", "must be a valid URL"
+		}
+	}
+	return apperr.FieldError{Field: strings.Join(path, "."), Code: code, Message: message}
+}
+
+func derefUint(n *uint64) uint64 {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
+
+func formatNumber(n *float64) string {
+	if n == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*n, 'f', -1, 64)
+}
+
+func quoted(s string) (string, bool) {
+	_, rest, ok := strings.Cut(s, `"`)
+	if !ok {
+		return "", false
+	}
+	name, _, ok := strings.Cut(rest, `"`)
+	return name, ok
+}
+
+// flattenErrors - Trải phẳng MultiError lồng nhau; không unwrap RequestError để giữ được parameter/body của lỗi
+func flattenErrors(err error) []error {
+	multi, ok := err.(openapi3.MultiError)
+	if !ok {
+		if err == nil {
+			return nil
+		}
+		return []error{err}
+	}
+	var list []error
+	for _, e := range multi {
+		list = append(list, flattenErrors(e)...)
+	}
+	return list
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+)
+
+// RateLimit - Giới hạn số request theo IP cho từng nhóm endpoint (token bucket)
+func RateLimit(name string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := RequestContext(c, 2*time.Second)
+		defer cancel()
+
+		result, err := ratelimit.Default().Take(ctx, "ip:"+name+":"+c.ClientIP(), limit)
+		if err != nil {
+			// Không chặn user khi backend rate limit lỗi
+			slog.ErrorContext(ctx, "rate limiter error", "limit", name, "error", err)
+			c.Next()
+			return
+		}
+
+		if !result.Allowed {
+			AbortTooManyRequests(c, result.RetryAfter)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AbortTooManyRequests - Trả 429 kèm header Retry-After (giây)
+func AbortTooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(retryAfter.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	apperr.Respond(c, apperr.TooManyRequests("Too many requests. Please try again later"))
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/logging"
+)
+
+// RequestIDHeader - Header nhận/trả request ID để đối chiếu log giữa client, proxy và server
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// RequestID - Nhận X-Request-ID hợp lệ từ client/proxy hoặc tạo mới, trả lại trong response header,
+// gắn vào context của request (cho log) và vào response lỗi (apperr.Respond đọc từ c.Get("request_id"))
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// RequestContext - Context cho thao tác DB/IO của handler: giữ request ID và user ID của request để log,
+// nhưng không bị hủy khi client ngắt kết nối (tránh ghi dở dang), chỉ hết hạn sau timeout
+func RequestContext(c *gin.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(c.Request.Context()), timeout)
+}
+
+// setLogUser - Gắn user đã xác thực vào context của request để access log và log của handler có user_id
+func setLogUser(c *gin.Context, userID string) {
+	c.Request = c.Request.WithContext(logging.WithUserID(c.Request.Context(), userID))
+}
+
+// validRequestID - Chỉ nhận ID ngắn gồm chữ, số và - _ . : để không thể chèn nội dung vào log hay JSON
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_.:", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
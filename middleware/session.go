@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/repository"
+)
+
+var errSessionRevoked = errors.New("session has been revoked")
+
+// checkSession - Kiểm tra phiên của JWT còn hiệu lực và cập nhật last_seen (tối đa mỗi phút một lần)
+func checkSession(c *gin.Context, repos *repository.Repositories, userID, sessionID string) error {
+	if sessionID == "" {
+		return errSessionRevoked
+	}
+
+	ctx, cancel := RequestContext(c, 5*time.Second)
+	defer cancel()
+
+	session, err := repos.Sessions.Get(ctx, userID, sessionID)
+	if err != nil {
+		return errSessionRevoked
+	}
+
+	now := time.Now().Unix()
+	if session.ExpiresAt > 0 && now > session.ExpiresAt {
+		return errSessionRevoked
+	}
+
+	if now-session.LastSeenAt >= 60 {
+		if err := repos.Sessions.Touch(ctx, session.ID, c.ClientIP(), now); err != nil {
+			slog.ErrorContext(ctx, "failed to update session activity", "session_id", session.ID, "error", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Tracing - Tạo server span cho mỗi request (tên theo route template), nhận traceparent từ client/proxy.
+// Bỏ qua /metrics và các endpoint health để không làm nhiễu trace bởi scraper và probe.
+func Tracing(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithFilter(func(r *http.Request) bool {
+		return !untracedPaths[r.URL.Path]
+	}))
+}
+
+var untracedPaths = map[string]bool{
+	"/metrics": true,
+	"/health":  true,
+	"/livez":   true,
+	"/readyz":  true,
+}
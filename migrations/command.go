@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Timeout - Thời gian tối đa cho một lần chạy migration (bao gồm chờ lock)
+const Timeout = 10 * time.Minute
+
+// RunCommand - Xử lý lệnh `migrate up | down [n] | status`
+func RunCommand(db *mongo.Database, args []string, out io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		count, err := Up(ctx, db)
+		fmt.Fprintf(out, "Applied %d migration(s)\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		count, err := Down(ctx, db, steps)
+		fmt.Fprintf(out, "Reverted %d migration(s)\n", count)
+		return err
+	case "status":
+		statuses, err := List(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%03d_%-30s %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down [n] or status", command)
+	}
+}
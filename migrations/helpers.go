@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// createIndexes - Tạo index (bỏ qua nếu đã tồn tại với cùng định nghĩa)
+func createIndexes(ctx context.Context, db *mongo.Database, collection string, indexes ...mongo.IndexModel) error {
+	if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("%s: %w", collection, err)
+	}
+	return nil
+}
+
+// dropIndexes - Xóa index theo tên; index không tồn tại được bỏ qua
+func dropIndexes(ctx context.Context, db *mongo.Database, collection string, names ...string) error {
+	for _, name := range names {
+		_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+		if err != nil && !isCommandError(err, "IndexNotFound", "NamespaceNotFound") {
+			return fmt.Errorf("%s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// setValidator - Gắn $jsonSchema validator cho collection (tạo collection nếu chưa có); schema nil để gỡ validator.
+// validationLevel moderate: document cũ không hợp lệ vẫn được cập nhật, document mới phải hợp lệ
+func setValidator(ctx context.Context, db *mongo.Database, collection string, schema bson.M) error {
+	if err := ensureCollection(ctx, db, collection); err != nil {
+		return err
+	}
+
+	validator := bson.M{}
+	level := "off"
+	if schema != nil {
+		validator = bson.M{"$jsonSchema": schema}
+		level = "moderate"
+	}
+
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: level},
+		{Key: "validationAction", Value: "error"},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", collection, err)
+	}
+	return nil
+}
+
+func ensureCollection(ctx context.Context, db *mongo.Database, collection string) error {
+	err := db.CreateCollection(ctx, collection)
+	if err != nil && !isCommandError(err, "NamespaceExists") {
+		return fmt.Errorf("%s: %w", collection, err)
+	}
+	return nil
+}
+
+// findDuplicates - Liệt kê giá trị bị trùng của field, dùng để báo lỗi rõ ràng trước khi tạo unique index
+func findDuplicates(ctx context.Context, db *mongo.Database, collection, field string) ([]string, error) {
+	cursor, err := db.Collection(collection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{field: bson.M{"$exists": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$limit", Value: 20}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Value interface{} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(groups))
+	for _, group := range groups {
+		values = append(values, fmt.Sprint(group.Value))
+	}
+	return values, nil
+}
+
+// requireUnique - Trả lỗi kèm danh sách giá trị trùng để người vận hành xử lý trước khi chạy lại migration
+func requireUnique(ctx context.Context, db *mongo.Database, collection, field string) error {
+	duplicates, err := findDuplicates(ctx, db, collection, field)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%s.%s has duplicate values, resolve them before migrating: %s",
+			collection, field, strings.Join(duplicates, ", "))
+	}
+	return nil
+}
+
+func isCommandError(err error, names ...string) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, name := range names {
+		if cmdErr.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// Package migrations quản lý schema MongoDB theo phiên bản: index, validator và backfill dữ liệu.
+// Mỗi migration có version tăng dần, được ghi lại trong collection schema_migrations sau khi chạy.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	collectionName = "schema_migrations"
+	lockID         = "lock"
+	// staleLockAfter - Lock cũ hơn thời gian này coi như instance giữ lock đã chết
+	staleLockAfter = 15 * time.Minute
+)
+
+// Step - Một bước up hoặc down của migration
+type Step func(ctx context.Context, db *mongo.Database) error
+
+// Migration - Một thay đổi schema; Down nil nghĩa là không có gì cần hoàn tác
+type Migration struct {
+	Version int
+	Name    string
+	Up      Step
+	Down    Step
+}
+
+// Record - Migration đã chạy, lưu trong schema_migrations
+type Record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Status - Trạng thái của một migration
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// All - Danh sách migration đã sắp xếp theo version
+func All() []Migration {
+	list := append([]Migration(nil), registry...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Up - Chạy các migration chưa áp dụng theo thứ tự; trả về số migration đã chạy
+func Up(ctx context.Context, db *mongo.Database) (int, error) {
+	release, err := acquireLock(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range All() {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		slog.Info("applying migration", "version", m.Version, "name", m.Name)
+		if err := m.Up(ctx, db); err != nil {
+			return count, fmt.Errorf("migration %03d_%s failed: %w", m.Version, m.Name, err)
+		}
+		record := Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}
+		if _, err := db.Collection(collectionName).InsertOne(ctx, record); err != nil {
+			return count, fmt.Errorf("failed to record migration %03d_%s: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down - Hoàn tác steps migration gần nhất theo thứ tự ngược lại
+func Down(ctx context.Context, db *mongo.Database, steps int) (int, error) {
+	release, err := acquireLock(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	list := All()
+	count := 0
+	for i := len(list) - 1; i >= 0 && count < steps; i-- {
+		m := list[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		slog.Info("reverting migration", "version", m.Version, "name", m.Name)
+		if m.Down != nil {
+			if err := m.Down(ctx, db); err != nil {
+				return count, fmt.Errorf("revert of %03d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		if _, err := db.Collection(collectionName).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+			return count, fmt.Errorf("failed to unrecord migration %03d_%s: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// List - Trạng thái của mọi migration
+func List(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Status
+	for _, m := range All() {
+		status := Status{Migration: m}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+func appliedVersions(ctx context.Context, db *mongo.Database) (map[int]Record, error) {
+	cursor, err := db.Collection(collectionName).Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// acquireLock - Chỉ một instance chạy migration tại một thời điểm; các instance khác chờ tới khi lock được nhả
+func acquireLock(ctx context.Context, db *mongo.Database) (func(), error) {
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+	collection := db.Collection(collectionName)
+
+	for {
+		_, err := collection.InsertOne(ctx, bson.M{"_id": lockID, "owner": owner, "locked_at": time.Now().UTC()})
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		// Lock bị bỏ lại bởi instance đã chết
+		if _, err := collection.DeleteOne(ctx, bson.M{
+			"_id":       lockID,
+			"locked_at": bson.M{"$lt": time.Now().Add(-staleLockAfter).UTC()},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("timed out waiting for migration lock held by another instance")
+		case <-time.After(time.Second):
+		}
+	}
+
+	return func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := collection.DeleteOne(releaseCtx, bson.M{"_id": lockID, "owner": owner}); err != nil {
+			slog.Error("failed to release migration lock", "error", err)
+		}
+	}, nil
+}
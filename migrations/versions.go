@@ -0,0 +1,334 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// registry - Thêm migration mới vào cuối với version lớn hơn; không sửa migration đã phát hành
+var registry = []Migration{
+	{Version: 1, Name: "create_core_indexes", Up: upCoreIndexes, Down: downCoreIndexes},
+	{Version: 2, Name: "add_expiry_ttl_indexes", Up: upExpiryTTL, Down: downExpiryTTL},
+	{Version: 3, Name: "backfill_album_type", Up: upAlbumType},
+	{Version: 4, Name: "add_schema_validators", Up: upValidators, Down: downValidators},
+	{Version: 5, Name: "add_stats_indexes", Up: upStatsIndexes, Down: downStatsIndexes},
+	{Version: 6, Name: "create_audit_log", Up: upAuditLog, Down: downAuditLog},
+	{Version: 7, Name: "create_webhooks", Up: upWebhooks, Down: downWebhooks},
+	{Version: 8, Name: "create_user_events", Up: upUserEvents, Down: downUserEvents},
+	{Version: 9, Name: "create_user_event_counter", Up: upUserEventCounter, Down: downUserEventCounter},
+}
+
+func index(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+}
+
+func uniqueIndex(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name).SetUnique(true)}
+}
+
+func ttlIndex(name, field string) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetName(name).SetExpireAfterSeconds(0),
+	}
+}
+
+// 001 - Unique email (chặn Register trùng khi chạy song song) và index cho các truy vấn thường dùng
+func upCoreIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := requireUnique(ctx, db, "users", "email"); err != nil {
+		return err
+	}
+
+	steps := []struct {
+		collection string
+		indexes    []mongo.IndexModel
+	}{
+		{"users", []mongo.IndexModel{
+			uniqueIndex("email_unique", bson.D{{Key: "email", Value: 1}}),
+		}},
+		{"media", []mongo.IndexModel{
+			index("user_created", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}),
+			index("album_created", bson.D{{Key: "album_id", Value: 1}, {Key: "created_at", Value: -1}}),
+			index("user_captured", bson.D{{Key: "user_id", Value: 1}, {Key: "metadata.date_time", Value: -1}}),
+			index("user_tags", bson.D{{Key: "user_id", Value: 1}, {Key: "tags", Value: 1}}),
+		}},
+		{"albums", []mongo.IndexModel{
+			index("user_created", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}),
+		}},
+		{"share_links", []mongo.IndexModel{
+			index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+		}},
+		{"external_identities", []mongo.IndexModel{
+			uniqueIndex("provider_subject_unique", bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}}),
+			index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+		}},
+		{"user_tokens", []mongo.IndexModel{
+			uniqueIndex("token_hash_unique", bson.D{{Key: "token_hash", Value: 1}}),
+			index("user_purpose", bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}),
+		}},
+		{"access_tokens", []mongo.IndexModel{
+			uniqueIndex("token_hash_unique", bson.D{{Key: "token_hash", Value: 1}}),
+			index("user_created", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}),
+		}},
+		{"sessions", []mongo.IndexModel{
+			index("user_last_seen", bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}),
+		}},
+	}
+
+	for _, step := range steps {
+		if err := createIndexes(ctx, db, step.collection, step.indexes...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downCoreIndexes(ctx context.Context, db *mongo.Database) error {
+	drops := map[string][]string{
+		"users":               {"email_unique"},
+		"media":               {"user_created", "album_created", "user_captured", "user_tags"},
+		"albums":              {"user_created"},
+		"share_links":         {"user_id"},
+		"external_identities": {"provider_subject_unique", "user_id"},
+		"user_tokens":         {"token_hash_unique", "user_purpose"},
+		"access_tokens":       {"token_hash_unique", "user_created"},
+		"sessions":            {"user_last_seen"},
+	}
+	for collection, names := range drops {
+		if err := dropIndexes(ctx, db, collection, names...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeCollections - Collection lưu expires_at dạng unix timestamp; TTL index cần kiểu Date nên dùng thêm purge_at
+var purgeCollections = []string{"user_tokens", "oidc_states", "sessions"}
+
+// 002 - Tự xóa token, OIDC state, phiên và bộ đếm rate limit đã hết hạn
+func upExpiryTTL(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range purgeCollections {
+		// Backfill purge_at cho document tạo trước migration
+		_, err := db.Collection(collection).UpdateMany(ctx,
+			bson.M{"purge_at": bson.M{"$exists": false}, "expires_at": bson.M{"$type": "number"}},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{
+				"purge_at": bson.M{"$toDate": bson.M{"$multiply": bson.A{"$expires_at", 1000}}},
+			}}}},
+		)
+		if err != nil {
+			return err
+		}
+		if err := createIndexes(ctx, db, collection, ttlIndex("purge_ttl", "purge_at")); err != nil {
+			return err
+		}
+	}
+
+	for _, collection := range []string{"rate_limits", "login_failures"} {
+		if err := createIndexes(ctx, db, collection, ttlIndex("expires_ttl", "expires_at")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downExpiryTTL(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range purgeCollections {
+		if err := dropIndexes(ctx, db, collection, "purge_ttl"); err != nil {
+			return err
+		}
+	}
+	for _, collection := range []string{"rate_limits", "login_failures"} {
+		if err := dropIndexes(ctx, db, collection, "expires_ttl"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 003 - Album tạo trước khi có smart album là album thường
+func upAlbumType(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("albums").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"type": bson.M{"$exists": false}}, bson.M{"type": ""}}},
+		bson.M{"$set": bson.M{"type": "regular"}},
+	)
+	return err
+}
+
+var (
+	numberTypes = bson.A{"int", "long", "double"}
+
+	usersSchema = bson.M{
+		"bsonType": "object",
+		"required": bson.A{"_id", "email", "role", "created_at"},
+		"properties": bson.M{
+			"_id":                bson.M{"bsonType": "string", "pattern": "^uid_"},
+			"name":               bson.M{"bsonType": "string"},
+			"email":              bson.M{"bsonType": "string", "minLength": 3},
+			"email_verified":     bson.M{"bsonType": "bool"},
+			"password":           bson.M{"bsonType": "string"},
+			"role":               bson.M{"enum": bson.A{"user", "admin"}},
+			"two_factor_enabled": bson.M{"bsonType": "bool"},
+			"strip_metadata":     bson.M{"enum": bson.A{"none", "gps", "all"}},
+			"created_at":         bson.M{"bsonType": numberTypes},
+			"updated_at":         bson.M{"bsonType": numberTypes},
+		},
+	}
+
+	mediaSchema = bson.M{
+		"bsonType": "object",
+		"required": bson.A{"_id", "user_id", "type", "url", "created_at"},
+		"properties": bson.M{
+			"_id":        bson.M{"bsonType": "string"},
+			"user_id":    bson.M{"bsonType": "string"},
+			"album_id":   bson.M{"bsonType": "string"},
+			"type":       bson.M{"enum": bson.A{"image", "video"}},
+			"url":        bson.M{"bsonType": "string"},
+			"size":       bson.M{"bsonType": numberTypes, "minimum": 0},
+			"duration":   bson.M{"bsonType": numberTypes, "minimum": 0},
+			"tags":       bson.M{"bsonType": "array", "items": bson.M{"bsonType": "string"}},
+			"created_at": bson.M{"bsonType": numberTypes},
+		},
+	}
+
+	albumsSchema = bson.M{
+		"bsonType": "object",
+		"required": bson.A{"_id", "user_id", "name", "type"},
+		"properties": bson.M{
+			"_id":     bson.M{"bsonType": "string"},
+			"user_id": bson.M{"bsonType": "string"},
+			"name":    bson.M{"bsonType": "string", "minLength": 1},
+			"type":    bson.M{"enum": bson.A{"regular", "smart"}},
+			"rules":   bson.M{"bsonType": "object"},
+		},
+	}
+)
+
+// 004 - Validator $jsonSchema cho các collection chính
+func upValidators(ctx context.Context, db *mongo.Database) error {
+	for collection, schema := range map[string]bson.M{"users": usersSchema, "media": mediaSchema, "albums": albumsSchema} {
+		if err := setValidator(ctx, db, collection, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downValidators(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range []string{"users", "media", "albums"} {
+		if err := setValidator(ctx, db, collection, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsCollections - Lịch sử đăng nhập lỗi và lần chạy job, giữ lại cho thống kê admin đến purge_at
+var statsCollections = []string{"failed_logins", "job_runs"}
+
+// 005 - Index created_at cho pipeline thống kê theo khoảng thời gian và TTL cho dữ liệu thống kê
+func upStatsIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range []string{"users", "media"} {
+		if err := createIndexes(ctx, db, collection, index("created_at", bson.D{{Key: "created_at", Value: 1}})); err != nil {
+			return err
+		}
+	}
+	for _, collection := range statsCollections {
+		if err := createIndexes(ctx, db, collection,
+			index("created_at", bson.D{{Key: "created_at", Value: 1}}),
+			ttlIndex("purge_ttl", "purge_at"),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downStatsIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range []string{"users", "media"} {
+		if err := dropIndexes(ctx, db, collection, "created_at"); err != nil {
+			return err
+		}
+	}
+	for _, collection := range statsCollections {
+		if err := dropIndexes(ctx, db, collection, "created_at", "purge_ttl"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 006 - Audit log: seq duy nhất giữ chuỗi hash không rẽ nhánh khi nhiều instance ghi; không có TTL
+func upAuditLog(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db, "audit_events",
+		uniqueIndex("seq_unique", bson.D{{Key: "seq", Value: 1}}),
+		index("action_seq", bson.D{{Key: "action", Value: 1}, {Key: "seq", Value: -1}}),
+		index("actor_seq", bson.D{{Key: "actor_id", Value: 1}, {Key: "seq", Value: -1}}),
+		index("target_seq", bson.D{{Key: "target_id", Value: 1}, {Key: "seq", Value: -1}}),
+		index("created_at", bson.D{{Key: "created_at", Value: 1}}),
+	)
+}
+
+func downAuditLog(ctx context.Context, db *mongo.Database) error {
+	return dropIndexes(ctx, db, "audit_events", "seq_unique", "action_seq", "actor_seq", "target_seq", "created_at")
+}
+
+// 007 - Webhook: tìm subscription theo event, worker lấy delivery đến hạn, log delivery theo subscription và TTL
+func upWebhooks(ctx context.Context, db *mongo.Database) error {
+	if err := createIndexes(ctx, db, "webhooks",
+		index("enabled_events", bson.D{{Key: "enabled", Value: 1}, {Key: "events", Value: 1}}),
+	); err != nil {
+		return err
+	}
+	return createIndexes(ctx, db, "webhook_deliveries",
+		index("status_next_attempt", bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}),
+		index("webhook_created", bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}),
+		ttlIndex("purge_ttl", "purge_at"),
+	)
+}
+
+func downWebhooks(ctx context.Context, db *mongo.Database) error {
+	if err := dropIndexes(ctx, db, "webhooks", "enabled_events"); err != nil {
+		return err
+	}
+	return dropIndexes(ctx, db, "webhook_deliveries", "status_next_attempt", "webhook_created", "purge_ttl")
+}
+
+// 008 - Sự kiện real-time: seq duy nhất để các instance đọc tiếp không bỏ sót, nối lại theo user và TTL
+func upUserEvents(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db, "user_events",
+		uniqueIndex("seq_unique", bson.D{{Key: "seq", Value: 1}}),
+		index("user_seq", bson.D{{Key: "user_id", Value: 1}, {Key: "seq", Value: 1}}),
+		ttlIndex("purge_ttl", "purge_at"),
+	)
+}
+
+func downUserEvents(ctx context.Context, db *mongo.Database) error {
+	return dropIndexes(ctx, db, "user_events", "seq_unique", "user_seq", "purge_ttl")
+}
+
+// 009 - Counter cấp seq cho sự kiện real-time: nằm ngoài user_events nên không bị TTL xóa. Khởi tạo bằng seq lớn nhất
+// hiện có để sự kiện mới không trùng seq với sự kiện chưa hết hạn
+func upUserEventCounter(ctx context.Context, db *mongo.Database) error {
+	var last struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}}).SetProjection(bson.M{"seq": 1})
+	err := db.Collection("user_events").FindOne(ctx, bson.M{}, opts).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	_, err = db.Collection("counters").UpdateOne(ctx,
+		bson.M{"_id": "user_events"},
+		bson.M{"$max": bson.M{"seq": last.Seq}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func downUserEventCounter(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("counters").DeleteOne(ctx, bson.M{"_id": "user_events"})
+	return err
+}
@@ -0,0 +1,31 @@
+package models
+
+// Scope của personal access token
+const (
+	ScopeRead   = "read"
+	ScopeUpload = "upload"
+	ScopeWrite  = "write"
+)
+
+// AccessTokenPrefix - Tiền tố nhận diện personal access token trong header Authorization
+const AccessTokenPrefix = "msp_"
+
+// AccessToken - Personal access token cho script/NAS sync; chỉ lưu hash SHA-256
+type AccessToken struct {
+	ID         string   `json:"id" bson:"_id"`
+	UserID     string   `json:"user_id" bson:"user_id"`
+	Name       string   `json:"name" bson:"name"`
+	Prefix     string   `json:"prefix" bson:"prefix"`
+	TokenHash  string   `json:"-" bson:"token_hash"`
+	Scopes     []string `json:"scopes" bson:"scopes"`
+	ExpiresAt  int64    `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt int64    `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	LastUsedIP string   `json:"last_used_ip,omitempty" bson:"last_used_ip,omitempty"`
+	CreatedAt  int64    `json:"created_at" bson:"created_at"`
+}
+
+type CreateAccessTokenInput struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=read upload write"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=3650"`
+}
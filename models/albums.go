@@ -1,12 +1,19 @@
 package models
 
+const (
+	AlbumTypeRegular = "regular"
+	AlbumTypeSmart   = "smart"
+)
+
 type Album struct {
-	ID          string `json:"id" bson:"_id"`
-	UserID      string `json:"user_id" bson:"user_id" binding:"required"`
-	Name 	  	string `json:"name" bson:"name" binding:"required,min=2,max=200"`
-	Description string `json:"description,omitempty" bson:"description,omitempty"`
-	CreatedAt   int64  `json:"created_at" bson:"created_at"`
-	UpdatedAt   int64  `json:"updated_at" bson:"updated_at"`
+	ID          string     `json:"id" bson:"_id"`
+	UserID      string     `json:"user_id" bson:"user_id" binding:"required"`
+	Name 	  	string     `json:"name" bson:"name" binding:"required,min=2,max=200"`
+	Description string     `json:"description,omitempty" bson:"description,omitempty"`
+	Type        string     `json:"type" bson:"type"`
+	Rules       *SmartRule `json:"rules,omitempty" bson:"rules,omitempty"`
+	CreatedAt   int64      `json:"created_at" bson:"created_at"`
+	UpdatedAt   int64      `json:"updated_at" bson:"updated_at"`
 }
 
 type UpdateAlbum struct {
@@ -15,11 +22,23 @@ type UpdateAlbum struct {
 }
 
 type CreateAlbumInput struct {
-	Name        string `json:"name" binding:"required,min=2,max=200"`
-	Description string `json:"description,omitempty"`
+	Name        string     `json:"name" binding:"required,min=2,max=200"`
+	Description string     `json:"description,omitempty"`
+	Rules       *SmartRule `json:"rules,omitempty"`
 }
 
 type UpdateAlbumInput struct {
 	Name        string `json:"name,omitempty" binding:"omitempty,min=2,max=200"`
 	Description string `json:"description,omitempty"`
 }
+
+// SmartRule - Một node trong cây điều kiện của smart album.
+// Node nhóm dùng Match ("all", "any", "none") cùng danh sách Rules con;
+// node lá dùng Field, Op và Value, ví dụ {"field": "country", "op": "eq", "value": "Japan"}.
+type SmartRule struct {
+	Match string      `json:"match,omitempty" bson:"match,omitempty"`
+	Rules []SmartRule `json:"rules,omitempty" bson:"rules,omitempty"`
+	Field string      `json:"field,omitempty" bson:"field,omitempty"`
+	Op    string      `json:"op,omitempty" bson:"op,omitempty"`
+	Value interface{} `json:"value,omitempty" bson:"value,omitempty"`
+}
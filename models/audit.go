@@ -0,0 +1,66 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Hành động được ghi vào audit log
+const (
+	AuditLogin           = "auth.login"
+	AuditLoginFailed     = "auth.login_failed"
+	AuditRegister        = "user.register"
+	AuditEmailChange     = "user.email_change"
+	AuditUserUpdate      = "user.update"
+	AuditUserCreate      = "user.create"
+	AuditUserDelete      = "user.delete"
+	AuditShareLinkCreate = "share_link.create"
+	AuditMediaDelete     = "media.delete"
+	AuditWebhookCreate   = "webhook.create"
+	AuditWebhookUpdate   = "webhook.update"
+	AuditWebhookDelete   = "webhook.delete"
+)
+
+// AuditChange - Giá trị cũ và mới của một field
+type AuditChange struct {
+	From string `json:"from" bson:"from"`
+	To   string `json:"to" bson:"to"`
+}
+
+// AuditEvent - Một sự kiện trong audit log. Log chỉ được ghi thêm; mỗi event chứa hash của event trước
+// (PrevHash) nên sửa hoặc xóa một event làm hỏng chuỗi từ event đó trở đi
+type AuditEvent struct {
+	ID  string `json:"id" bson:"_id"`
+	Seq int64  `json:"seq" bson:"seq"`
+	// Action - Một trong các hằng Audit*
+	Action string `json:"action" bson:"action"`
+	// ActorID - User thực hiện; rỗng khi chưa xác thực (ví dụ đăng nhập sai)
+	ActorID    string `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ActorEmail string `json:"actor_email,omitempty" bson:"actor_email,omitempty"`
+	TargetType string `json:"target_type,omitempty" bson:"target_type,omitempty"`
+	TargetID   string `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	IP         string `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	// Diff - Field đã đổi (không bao giờ chứa mật khẩu hay secret)
+	Diff map[string]AuditChange `json:"diff,omitempty" bson:"diff,omitempty"`
+	// Details - Thông tin thêm theo action, ví dụ lý do đăng nhập lỗi
+	Details   map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt int64             `json:"created_at" bson:"created_at"`
+	PrevHash  string            `json:"prev_hash" bson:"prev_hash"`
+	Hash      string            `json:"hash" bson:"hash"`
+}
+
+// ComputeHash - SHA-256 (hex) của mọi field trừ Hash. JSON của struct có thứ tự field cố định và map được
+// sắp theo key, map rỗng và nil cho cùng kết quả nên hash không đổi sau khi đọc lại từ database
+func (e *AuditEvent) ComputeHash() string {
+	payload := *e
+	payload.Hash = ""
+	data, err := json.Marshal(payload)
+	if err != nil {
+		// Chỉ gồm string, int64 và map string nên không thể lỗi
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Sự kiện real-time gửi tới user qua GET /api/v1/events
+const (
+	// EventMediaProcessed - Upload đã xử lý xong và media đã được lưu
+	EventMediaProcessed = "media.processed"
+	// EventMetadataExtracted - Đọc được metadata (EXIF, GPS) của ảnh vừa upload
+	EventMetadataExtracted = "media.metadata_extracted"
+	// EventAlbumMediaAdded - Album của user có thêm media
+	EventAlbumMediaAdded = "album.media_added"
+	// EventShareLinkAccessed - Có người mở link chia sẻ của user (gộp tối đa một sự kiện mỗi phút cho mỗi media)
+	EventShareLinkAccessed = "share_link.accessed"
+)
+
+// UserEvent - Sự kiện gửi tới một user. Seq tăng dần trên toàn bộ sự kiện (mọi user, mọi instance)
+// và là id của sự kiện trong stream để client nối lại bằng Last-Event-ID
+type UserEvent struct {
+	ID     string `json:"-" bson:"_id"`
+	Seq    int64  `json:"seq" bson:"seq"`
+	UserID string `json:"-" bson:"user_id"`
+	Type   string `json:"type" bson:"type"`
+	// Data - JSON đã mã hóa, gửi nguyên trong field data của sự kiện
+	Data      string `json:"data" bson:"data"`
+	CreatedAt int64  `json:"created_at" bson:"created_at"`
+	// PurgeAt - Mốc TTL index tự xóa sự kiện cũ
+	PurgeAt time.Time `json:"-" bson:"purge_at,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ExternalIdentity - Liên kết giữa tài khoản và một identity của OIDC provider (provider + subject là duy nhất)
+type ExternalIdentity struct {
+	ID          string `json:"id" bson:"_id"`
+	UserID      string `json:"user_id" bson:"user_id"`
+	Provider    string `json:"provider" bson:"provider"`
+	Subject     string `json:"subject" bson:"subject"`
+	Email       string `json:"email,omitempty" bson:"email,omitempty"`
+	CreatedAt   int64  `json:"created_at" bson:"created_at"`
+	LastLoginAt int64  `json:"last_login_at,omitempty" bson:"last_login_at,omitempty"`
+}
+
+// OIDCState - Trạng thái một lần đăng nhập OIDC đang chờ callback (state, nonce, PKCE verifier)
+type OIDCState struct {
+	ID        string    `bson:"_id"`
+	Provider  string    `bson:"provider"`
+	Nonce     string    `bson:"nonce"`
+	Verifier  string    `bson:"verifier"`
+	ExpiresAt int64     `bson:"expires_at"`
+	PurgeAt   time.Time `bson:"purge_at,omitempty"`
+}
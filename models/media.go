@@ -1,15 +1,21 @@
 package models
 
 type Media struct {
-	ID          string `json:"id" bson:"_id"`
-	UserID      string `json:"user_id" bson:"user_id" binding:"required"`
-	AlbumID     string `json:"album_id,omitempty" bson:"album_id,omitempty"`
-	Type        string `json:"type" bson:"type" binding:"required,oneof=image video"`
-	URL         string `json:"url" bson:"url" binding:"required,url"`
-	Title       string `json:"title,omitempty" bson:"title,omitempty"`
-	Description string `json:"description,omitempty" bson:"description,omitempty"`
-	CreatedAt   int64  `json:"created_at" bson:"created_at"`
-	UpdatedAt   int64  `json:"updated_at" bson:"updated_at"`
+	ID          string         `json:"id" bson:"_id"`
+	UserID      string         `json:"user_id" bson:"user_id" binding:"required"`
+	AlbumID     string         `json:"album_id,omitempty" bson:"album_id,omitempty"`
+	Type        string         `json:"type" bson:"type" binding:"required,oneof=image video"`
+	URL         string         `json:"url" bson:"url" binding:"required,url"`
+	Filename    string         `json:"filename,omitempty" bson:"filename,omitempty"`
+	Path        string         `json:"-" bson:"path,omitempty"`
+	Size        int64          `json:"size,omitempty" bson:"size,omitempty"`
+	Title       string         `json:"title,omitempty" bson:"title,omitempty"`
+	Description string         `json:"description,omitempty" bson:"description,omitempty"`
+	Tags        []string       `json:"tags,omitempty" bson:"tags,omitempty"`
+	Duration    float64        `json:"duration,omitempty" bson:"duration,omitempty"`
+	Metadata    *ImageMetadata `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	CreatedAt   int64          `json:"created_at" bson:"created_at"`
+	UpdatedAt   int64          `json:"updated_at" bson:"updated_at"`
 }
 
 type CreatedImageInput struct {
@@ -0,0 +1,54 @@
+package models
+
+// LocationInfo - Thông tin vị trí chi tiết
+type LocationInfo struct {
+	Country     string `json:"country,omitempty" bson:"country,omitempty"`
+	State       string `json:"state,omitempty" bson:"state,omitempty"`
+	City        string `json:"city,omitempty" bson:"city,omitempty"`
+	District    string `json:"district,omitempty" bson:"district,omitempty"`
+	Road        string `json:"road,omitempty" bson:"road,omitempty"`
+	PostalCode  string `json:"postal_code,omitempty" bson:"postal_code,omitempty"`
+	DisplayName string `json:"display_name,omitempty" bson:"display_name,omitempty"`
+}
+
+// ImageMetadata - Thông tin metadata của hình ảnh
+type ImageMetadata struct {
+	Format           string        `json:"format,omitempty" bson:"format,omitempty"`
+	DateTime         int64         `json:"date_time,omitempty" bson:"date_time,omitempty"`
+	DateTimeOriginal string        `json:"date_time_original,omitempty" bson:"date_time_original,omitempty"`
+	TimeOffset       string        `json:"time_offset,omitempty" bson:"time_offset,omitempty"`
+	Latitude         float64       `json:"latitude,omitempty" bson:"latitude,omitempty"`
+	Longitude        float64       `json:"longitude,omitempty" bson:"longitude,omitempty"`
+	Altitude         *float64      `json:"altitude,omitempty" bson:"altitude,omitempty"`
+	ImgDirection     *float64      `json:"img_direction,omitempty" bson:"img_direction,omitempty"`
+	ImgDirectionRef  string        `json:"img_direction_ref,omitempty" bson:"img_direction_ref,omitempty"`
+	Location         *LocationInfo `json:"location,omitempty" bson:"location,omitempty"`
+	CameraMake       string        `json:"camera_make,omitempty" bson:"camera_make,omitempty"`
+	CameraModel      string        `json:"camera_model,omitempty" bson:"camera_model,omitempty"`
+	LensMake         string        `json:"lens_make,omitempty" bson:"lens_make,omitempty"`
+	LensModel        string        `json:"lens_model,omitempty" bson:"lens_model,omitempty"`
+	Width            int           `json:"width,omitempty" bson:"width,omitempty"`
+	Height           int           `json:"height,omitempty" bson:"height,omitempty"`
+	Orientation      int           `json:"orientation,omitempty" bson:"orientation,omitempty"`
+	Flash            string        `json:"flash,omitempty" bson:"flash,omitempty"`
+	FocalLength      string        `json:"focal_length,omitempty" bson:"focal_length,omitempty"`
+	FNumber          string        `json:"f_number,omitempty" bson:"f_number,omitempty"`
+	ExposureTime     string        `json:"exposure_time,omitempty" bson:"exposure_time,omitempty"`
+	ExposureBias     string        `json:"exposure_bias,omitempty" bson:"exposure_bias,omitempty"`
+	ISO              int           `json:"iso,omitempty" bson:"iso,omitempty"`
+	WhiteBalance     string        `json:"white_balance,omitempty" bson:"white_balance,omitempty"`
+	MeteringMode     string        `json:"metering_mode,omitempty" bson:"metering_mode,omitempty"`
+	SubjectDistance  float64       `json:"subject_distance,omitempty" bson:"subject_distance,omitempty"`
+
+	// Các field lấy từ XMP
+	Rating   int      `json:"rating,omitempty" bson:"rating,omitempty"`
+	Title    string   `json:"title,omitempty" bson:"title,omitempty"`
+	Keywords []string `json:"keywords,omitempty" bson:"keywords,omitempty"`
+
+	// TextChunks - Các chunk tEXt/iTXt/zTXt của file PNG
+	TextChunks map[string]string `json:"text_chunks,omitempty" bson:"text_chunks,omitempty"`
+
+	// ExifTags - Dump toàn bộ tag EXIF dạng chuỗi; RawExif giữ nguyên block TIFF để xử lý lại sau này
+	ExifTags map[string]string `json:"exif_tags,omitempty" bson:"exif_tags,omitempty"`
+	RawExif  []byte            `json:"-" bson:"raw_exif,omitempty"`
+}
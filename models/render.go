@@ -0,0 +1,13 @@
+package models
+
+// RenderInput - Tham số ảnh dẫn xuất cần ký URL. Cần width hoặc height (tối đa render.max_dimension);
+// thiếu một chiều thì tính theo tỉ lệ ảnh gốc
+type RenderInput struct {
+	Width   int    `json:"width,omitempty" binding:"omitempty,min=1"`
+	Height  int    `json:"height,omitempty" binding:"omitempty,min=1"`
+	Fit     string `json:"fit,omitempty" binding:"omitempty,oneof=contain cover fill"`
+	Gravity string `json:"gravity,omitempty" binding:"omitempty,oneof=center north south east west northeast northwest southeast southwest"`
+	Rotate  int    `json:"rotate,omitempty" binding:"omitempty,oneof=90 180 270"`
+	Format  string `json:"format,omitempty" binding:"omitempty,oneof=jpeg jpg png"`
+	Quality int    `json:"quality,omitempty" binding:"omitempty,min=1,max=100"`
+}
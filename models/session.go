@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Session - Một phiên đăng nhập (thiết bị); JWT mang session ID và bị từ chối khi phiên bị thu hồi
+type Session struct {
+	ID         string `json:"id" bson:"_id"`
+	UserID     string `json:"user_id" bson:"user_id"`
+	DeviceName string `json:"device_name" bson:"device_name"`
+	UserAgent  string `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	IP         string `json:"ip,omitempty" bson:"ip,omitempty"`
+	CreatedAt  int64  `json:"created_at" bson:"created_at"`
+	LastSeenAt int64  `json:"last_seen_at" bson:"last_seen_at"`
+	ExpiresAt  int64  `json:"expires_at" bson:"expires_at"`
+	// PurgeAt - Mốc TTL index tự xóa phiên hết hạn
+	PurgeAt time.Time `json:"-" bson:"purge_at,omitempty"`
+	Current bool      `json:"current" bson:"-"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
@@ -0,0 +1,16 @@
+package models
+
+type ShareLink struct {
+	ID      string `json:"id" bson:"_id"`
+	UserID  string `json:"user_id" bson:"user_id"`
+	MediaID string `json:"media_id" bson:"media_id"`
+	// StripMetadata - Ghi đè thiết lập mặc định của owner cho riêng link này (rỗng = theo owner)
+	StripMetadata string `json:"strip_metadata,omitempty" bson:"strip_metadata,omitempty"`
+	ExpiresAt     int64  `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt     int64  `json:"created_at" bson:"created_at"`
+}
+
+type CreateShareLinkInput struct {
+	StripMetadata  string `json:"strip_metadata,omitempty" binding:"omitempty,oneof=none gps all"`
+	ExpiresInHours int    `json:"expires_in_hours,omitempty" binding:"omitempty,min=1,max=8760"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Lý do của một lần xác thực thất bại
+const (
+	LoginFailureUnknownEmail    = "unknown_email"
+	LoginFailureWrongPassword   = "wrong_password"
+	LoginFailureInvalidOTP      = "invalid_otp"
+	LoginFailureCurrentPassword = "current_password"
+)
+
+// FailedLogin - Một lần xác thực thất bại (đăng nhập, mã 2FA, mật khẩu hiện tại khi đổi mật khẩu), giữ lại để thống kê
+type FailedLogin struct {
+	ID        string `json:"id" bson:"_id"`
+	Reason    string `json:"reason" bson:"reason"`
+	Email     string `json:"email,omitempty" bson:"email,omitempty"`
+	UserID    string `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	IP        string `json:"ip,omitempty" bson:"ip,omitempty"`
+	CreatedAt int64  `json:"created_at" bson:"created_at"`
+	// PurgeAt - Mốc TTL index tự xóa bản ghi cũ
+	PurgeAt time.Time `json:"-" bson:"purge_at,omitempty"`
+}
+
+// Kết quả một lần chạy job nền
+const (
+	JobStatusOK     = "ok"
+	JobStatusFailed = "failed"
+)
+
+// JobRun - Một lần chạy job nền (ví dụ gửi một email), giữ lại để theo dõi sức khỏe job
+type JobRun struct {
+	ID         string  `json:"id" bson:"_id"`
+	Job        string  `json:"job" bson:"job"`
+	Status     string  `json:"status" bson:"status"`
+	Error      string  `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms" bson:"duration_ms"`
+	CreatedAt  int64   `json:"created_at" bson:"created_at"`
+	// PurgeAt - Mốc TTL index tự xóa bản ghi cũ
+	PurgeAt time.Time `json:"-" bson:"purge_at,omitempty"`
+}
+
+// UserStats - Số user tại cuối khoảng thời gian và số đăng ký mới trong khoảng
+type UserStats struct {
+	Total      int64 `json:"total"`
+	Admins     int64 `json:"admins"`
+	Unverified int64 `json:"unverified"`
+	TwoFactor  int64 `json:"two_factor"`
+	Signups    int64 `json:"signups"`
+	// SignupsPerDay - Chỉ gồm các ngày có đăng ký
+	SignupsPerDay []DailyCount `json:"signups_per_day"`
+}
+
+// DailyCount - Số lượng theo ngày (UTC, YYYY-MM-DD)
+type DailyCount struct {
+	Date  string `json:"date" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// UploadsPerDay - Số file và dung lượng upload trong một ngày theo loại media
+type UploadsPerDay struct {
+	Date  string `json:"date" bson:"date"`
+	Type  string `json:"type" bson:"type"`
+	Count int64  `json:"count" bson:"count"`
+	Bytes int64  `json:"bytes" bson:"bytes"`
+}
+
+// StorageUsage - Dung lượng một user đã upload
+type StorageUsage struct {
+	UserID string `json:"user_id" bson:"_id"`
+	Name   string `json:"name,omitempty" bson:"name,omitempty"`
+	Email  string `json:"email,omitempty" bson:"email,omitempty"`
+	Files  int64  `json:"files" bson:"files"`
+	Images int64  `json:"images" bson:"images"`
+	Videos int64  `json:"videos" bson:"videos"`
+	Bytes  int64  `json:"bytes" bson:"bytes"`
+}
+
+// CameraCount - Số ảnh chụp bằng một model máy ảnh
+type CameraCount struct {
+	Make  string `json:"make" bson:"make"`
+	Model string `json:"model" bson:"model"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// FailedLoginsPerDay - Số lần xác thực thất bại trong một ngày theo lý do
+type FailedLoginsPerDay struct {
+	Date   string `json:"date" bson:"date"`
+	Reason string `json:"reason" bson:"reason"`
+	Count  int64  `json:"count" bson:"count"`
+}
+
+// JobRunsPerDay - Số lần chạy, số lần lỗi và thời gian chạy của một job trong một ngày
+type JobRunsPerDay struct {
+	Date          string  `json:"date" bson:"date"`
+	Job           string  `json:"job" bson:"job"`
+	Runs          int64   `json:"runs" bson:"runs"`
+	Failures      int64   `json:"failures" bson:"failures"`
+	AvgDurationMs float64 `json:"avg_duration_ms" bson:"avg_duration_ms"`
+	MaxDurationMs float64 `json:"max_duration_ms" bson:"max_duration_ms"`
+}
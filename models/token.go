@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Mục đích sử dụng của UserToken
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// UserToken - Token dùng một lần, chỉ lưu hash SHA-256 của token gửi cho user
+type UserToken struct {
+	ID        string `json:"id" bson:"_id"`
+	UserID    string `json:"user_id" bson:"user_id"`
+	Purpose   string `json:"purpose" bson:"purpose"`
+	TokenHash string `json:"-" bson:"token_hash"`
+	// Email - Địa chỉ cần xác thực (email mới khi user đổi email)
+	Email     string `json:"email,omitempty" bson:"email,omitempty"`
+	ExpiresAt int64  `json:"expires_at" bson:"expires_at"`
+	UsedAt    int64  `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt int64  `json:"created_at" bson:"created_at"`
+	// PurgeAt - Mốc TTL index tự xóa token (TTL cần kiểu Date)
+	PurgeAt time.Time `json:"-" bson:"purge_at,omitempty"`
+}
@@ -1,22 +1,39 @@
 package models
 
 type User struct {
-	ID        string `json:"id" bson:"_id"`
-	Name      string `json:"name" bson:"name" binding:"required,min=2,max=100"`
-	Email     string `json:"email" bson:"email" binding:"required,email"`
-	Password  string `json:"password,omitempty" bson:"password" binding:"required,min=6"`
-	Role      string `json:"role" bson:"role" binding:"required,oneof=admin user"`
-	Phone     string `json:"phone,omitempty" bson:"phone,omitempty"`
-	Avatar    string `json:"avatar,omitempty" bson:"avatar,omitempty"`
-	CreatedAt int64  `json:"created_at" bson:"created_at"`
-	UpdatedAt int64  `json:"updated_at" bson:"updated_at"`
+	ID    string `json:"id" bson:"_id"`
+	Name  string `json:"name" bson:"name" binding:"required,min=2,max=100"`
+	Email string `json:"email" bson:"email" binding:"required,email"`
+	// EmailVerified - nil với tài khoản cũ tạo trước khi có xác thực email, coi như đã xác thực
+	EmailVerified *bool  `json:"email_verified,omitempty" bson:"email_verified,omitempty"`
+	PendingEmail  string `json:"pending_email,omitempty" bson:"pending_email,omitempty"`
+	Password      string `json:"password,omitempty" bson:"password" binding:"required,min=6"`
+	Role          string `json:"role" bson:"role" binding:"required,oneof=admin user"`
+	Phone         string `json:"phone,omitempty" bson:"phone,omitempty"`
+	Avatar        string `json:"avatar,omitempty" bson:"avatar,omitempty"`
+	// Xác thực hai lớp (TOTP); secret và hash mã khôi phục không bao giờ trả về client
+	TwoFactorEnabled       bool     `json:"two_factor_enabled" bson:"two_factor_enabled,omitempty"`
+	TwoFactorSecret        string   `json:"-" bson:"two_factor_secret,omitempty"`
+	TwoFactorPendingSecret string   `json:"-" bson:"two_factor_pending_secret,omitempty"`
+	TwoFactorLastStep      int64    `json:"-" bson:"two_factor_last_step,omitempty"`
+	RecoveryCodes          []string `json:"-" bson:"recovery_codes,omitempty"`
+	// StripMetadata - Mặc định xử lý metadata khi trả ảnh: none | gps | all (rỗng = gps)
+	StripMetadata string `json:"strip_metadata,omitempty" bson:"strip_metadata,omitempty" binding:"omitempty,oneof=none gps all"`
+	CreatedAt     int64  `json:"created_at" bson:"created_at"`
+	UpdatedAt     int64  `json:"updated_at" bson:"updated_at"`
+}
+
+// IsEmailVerified - Tài khoản đã xác thực email (hoặc là tài khoản cũ)
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerified == nil || *u.EmailVerified
 }
 
 type UpdateUser struct {
-	Name   string `json:"name,omitempty" bson:"name,omitempty" binding:"omitempty,min=2,max=100"`
-	Email  string `json:"email,omitempty" bson:"email,omitempty" binding:"omitempty,email"`
-	Phone  string `json:"phone,omitempty" bson:"phone,omitempty"`
-	Avatar string `json:"avatar,omitempty" bson:"avatar,omitempty"`
+	Name          string `json:"name,omitempty" bson:"name,omitempty" binding:"omitempty,min=2,max=100"`
+	Email         string `json:"email,omitempty" bson:"email,omitempty" binding:"omitempty,email"`
+	Phone         string `json:"phone,omitempty" bson:"phone,omitempty"`
+	Avatar        string `json:"avatar,omitempty" bson:"avatar,omitempty"`
+	StripMetadata string `json:"strip_metadata,omitempty" bson:"strip_metadata,omitempty" binding:"omitempty,oneof=none gps all"`
 }
 
 type RegisterInput struct {
@@ -27,11 +44,41 @@ type RegisterInput struct {
 }
 
 type LoginInput struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name,omitempty" binding:"omitempty,max=100"`
 }
 
 type TokenResponse struct {
 	Token string `json:"token"`
 	User  User   `json:"user"`
 }
+
+type EmailInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code,omitempty" binding:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+	DeviceName     string `json:"device_name,omitempty" binding:"omitempty,max=100"`
+}
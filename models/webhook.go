@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// Sự kiện gửi tới webhook
+const (
+	WebhookMediaCreated = "media.created"
+	WebhookMediaDeleted = "media.deleted"
+	WebhookAlbumCreated = "album.created"
+	WebhookAlbumUpdated = "album.updated"
+	WebhookUserCreated  = "user.created"
+	WebhookUserDeleted  = "user.deleted"
+)
+
+// WebhookEvents - Các sự kiện có thể đăng ký (giữ khớp với rule oneof của CreateWebhookInput và UpdateWebhookInput)
+var WebhookEvents = []string{
+	WebhookMediaCreated,
+	WebhookMediaDeleted,
+	WebhookAlbumCreated,
+	WebhookAlbumUpdated,
+	WebhookUserCreated,
+	WebhookUserDeleted,
+}
+
+// Trạng thái của một delivery
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook - Subscription nhận sự kiện qua HTTP POST, payload được ký HMAC-SHA256 bằng Secret
+type Webhook struct {
+	ID          string   `json:"id" bson:"_id"`
+	URL         string   `json:"url" bson:"url"`
+	Events      []string `json:"events" bson:"events"`
+	Description string   `json:"description,omitempty" bson:"description,omitempty"`
+	// Secret - Khóa ký payload; chỉ trả về một lần khi tạo subscription
+	Secret  string `json:"-" bson:"secret"`
+	Enabled bool   `json:"enabled" bson:"enabled"`
+	// DisabledReason - Lý do tự động tắt sau nhiều delivery thất bại liên tiếp
+	DisabledReason string `json:"disabled_reason,omitempty" bson:"disabled_reason,omitempty"`
+	// ConsecutiveFailures - Số delivery liên tiếp đã hết lượt thử mà vẫn lỗi; về 0 khi có lần gửi thành công
+	ConsecutiveFailures int    `json:"consecutive_failures" bson:"consecutive_failures"`
+	CreatedBy           string `json:"created_by" bson:"created_by"`
+	CreatedAt           int64  `json:"created_at" bson:"created_at"`
+	UpdatedAt           int64  `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateWebhookInput struct {
+	URL         string   `json:"url" binding:"required,http_url,max=2048"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=media.created media.deleted album.created album.updated user.created user.deleted"`
+	Description string   `json:"description,omitempty" binding:"max=500"`
+}
+
+// UpdateWebhookInput - Field bỏ trống giữ nguyên; enabled=true bật lại subscription đã bị tự động tắt
+type UpdateWebhookInput struct {
+	URL         *string   `json:"url,omitempty" binding:"omitempty,http_url,max=2048"`
+	Events      *[]string `json:"events,omitempty" binding:"omitempty,min=1,dive,oneof=media.created media.deleted album.created album.updated user.created user.deleted"`
+	Description *string   `json:"description,omitempty" binding:"omitempty,max=500"`
+	Enabled     *bool     `json:"enabled,omitempty"`
+}
+
+// WebhookAttempt - Kết quả một lần gửi delivery
+type WebhookAttempt struct {
+	At int64 `json:"at" bson:"at"`
+	// StatusCode - 0 khi không nhận được response (lỗi kết nối, timeout)
+	StatusCode int    `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+	// Response - Phần đầu body response, giúp subscriber tìm lỗi
+	Response   string  `json:"response,omitempty" bson:"response,omitempty"`
+	DurationMs float64 `json:"duration_ms" bson:"duration_ms"`
+}
+
+// WebhookDelivery - Một sự kiện cần gửi tới một subscription cùng lịch sử các lần gửi
+type WebhookDelivery struct {
+	ID        string `json:"id" bson:"_id"`
+	WebhookID string `json:"webhook_id" bson:"webhook_id"`
+	// EventID - Giống nhau giữa các lần gửi lại để subscriber bỏ qua sự kiện trùng
+	EventID string `json:"event_id" bson:"event_id"`
+	Event   string `json:"event" bson:"event"`
+	// Payload - Body JSON đúng như được ký và gửi đi
+	Payload  string           `json:"payload" bson:"payload"`
+	Status   string           `json:"status" bson:"status"`
+	Attempts []WebhookAttempt `json:"attempts" bson:"attempts"`
+	// NextAttemptAt - Thời điểm gửi tiếp theo khi Status là pending
+	NextAttemptAt int64 `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	// RedeliveryOf - Delivery gốc khi được admin gửi lại thủ công
+	RedeliveryOf string `json:"redelivery_of,omitempty" bson:"redelivery_of,omitempty"`
+	CreatedAt    int64  `json:"created_at" bson:"created_at"`
+	UpdatedAt    int64  `json:"updated_at" bson:"updated_at"`
+	DeliveredAt  int64  `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	// PurgeAt - Mốc TTL index tự xóa bản ghi cũ
+	PurgeAt time.Time `json:"-" bson:"purge_at,omitempty"`
+}
@@ -0,0 +1,31 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed docs.html
+var docsPage string
+
+// SpecHandler - Trả tài liệu dưới dạng JSON (/openapi.json)
+func SpecHandler(doc *openapi3.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(doc)
+	})
+}
+
+// DocsHandler - Trang Swagger UI đọc tài liệu từ specURL
+func DocsHandler(specURL string) http.Handler {
+	page := []byte(strings.Replace(docsPage, "{{SPEC_URL}}", specURL, 1))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+}
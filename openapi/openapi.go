@@ -0,0 +1,254 @@
+// Package openapi sinh tài liệu OpenAPI 3 từ bảng route và các struct trong models:
+// schema lấy từ tag json/binding nên tài liệu luôn khớp với validation của handler
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/hieu9721/media-store-backend/apperr"
+)
+
+// Auth - Cách xác thực của một route
+type Auth int
+
+const (
+	// Public - Không cần đăng nhập
+	Public Auth = iota
+	// User - JWT hoặc personal access token có scope phù hợp
+	User
+	// JWTOnly - Chỉ JWT của phiên đăng nhập, không nhận personal access token
+	JWTOnly
+	// Admin - Như JWTOnly và user phải có role admin
+	Admin
+)
+
+const (
+	bearerScheme      = "bearerAuth"
+	accessTokenScheme = "accessToken"
+)
+
+// File - Field file trong multipart/form-data
+var File = openapi3.NewStringSchema().WithFormat("binary")
+
+// Param - Query parameter
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	// Schema - Giá trị mẫu như Body, mặc định là string
+	Schema any
+}
+
+// Operation - Mô tả một route cho tài liệu. Path viết theo cú pháp của gin (/users/:id, /uploads/*filepath).
+type Operation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Auth        Auth
+	// Scope - Scope personal access token cần (read, upload, write); chỉ dùng khi Auth là User
+	Scope string
+	Query []Param
+	// Body - Mẫu body JSON (struct của models hoặc Object); nil nếu không có body
+	Body         any
+	BodyOptional bool
+	// Form - Field của body multipart/form-data (dùng File cho field file)
+	Form Object
+	// Status - Status khi thành công, mặc định 200
+	Status int
+	// Response - Mẫu body JSON khi thành công; nil nếu không có body JSON
+	Response any
+	// Also - Response JSON khác ngoài Status, ví dụ /readyz trả 503 cùng dạng body
+	Also map[int]any
+	// Produces - Content type của response không phải JSON (file); đi cùng Response khi route trả được cả JSON lẫn file (CSV)
+	Produces []string
+	// Errors - Các status lỗi ngoài những lỗi suy ra được (400 khi có input, 401/403 khi cần đăng nhập, 500)
+	Errors []int
+	// RateLimited - Route có giới hạn số request theo IP (trả 429)
+	RateLimited bool
+}
+
+// Path - Chuyển route của gin sang path template của OpenAPI: /users/:id -> /users/{id}
+func Path(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Build - Tạo tài liệu OpenAPI từ danh sách operation
+func Build(info openapi3.Info, ops []Operation) *openapi3.T {
+	s := newSchemas()
+	problem := s.of(apperr.Problem{})
+
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info:    &info,
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: s.components,
+			SecuritySchemes: openapi3.SecuritySchemes{
+				bearerScheme: &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme().
+					WithDescription("JWT from /api/v1/auth/login")},
+				accessTokenScheme: &openapi3.SecuritySchemeRef{Value: &openapi3.SecurityScheme{
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "Personal access token (msp_...) from /api/v1/me/tokens. See x-token-scope on each operation.",
+				}},
+			},
+		},
+	}
+
+	tags := map[string]bool{}
+	for _, op := range ops {
+		path := Path(op.Path)
+		item := doc.Paths.Value(path)
+		if item == nil {
+			item = &openapi3.PathItem{}
+			doc.Paths.Set(path, item)
+		}
+		item.SetOperation(op.Method, s.operation(op, path, problem))
+		if op.Tag != "" && !tags[op.Tag] {
+			tags[op.Tag] = true
+			doc.Tags = append(doc.Tags, &openapi3.Tag{Name: op.Tag})
+		}
+	}
+	return doc
+}
+
+func (s *schemas) operation(op Operation, path string, problem *openapi3.SchemaRef) *openapi3.Operation {
+	operation := openapi3.NewOperation()
+	operation.Summary = op.Summary
+	operation.Description = op.Description
+	operation.OperationID = operationID(op.Method, path)
+	if op.Tag != "" {
+		operation.Tags = []string{op.Tag}
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			name := strings.Trim(segment, "{}")
+			operation.AddParameter(openapi3.NewPathParameter(name).WithSchema(openapi3.NewStringSchema()))
+		}
+	}
+	for _, param := range op.Query {
+		schema := s.of(param.Schema)
+		if param.Schema == nil {
+			schema = openapi3.NewSchemaRef("", openapi3.NewStringSchema())
+		}
+		p := openapi3.NewQueryParameter(param.Name).WithDescription(param.Description).WithRequired(param.Required)
+		p.Schema = schema
+		operation.AddParameter(p)
+	}
+
+	switch {
+	case op.Body != nil:
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+			WithRequired(!op.BodyOptional).
+			WithJSONSchemaRef(s.of(op.Body))}
+	case op.Form != nil:
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+			WithRequired(true).
+			WithFormDataSchemaRef(s.of(op.Form))}
+	}
+
+	switch op.Auth {
+	case User:
+		operation.Security = &openapi3.SecurityRequirements{
+			openapi3.NewSecurityRequirement().Authenticate(bearerScheme),
+			openapi3.NewSecurityRequirement().Authenticate(accessTokenScheme),
+		}
+		if op.Scope != "" {
+			operation.Extensions = map[string]any{"x-token-scope": op.Scope}
+		}
+	case JWTOnly, Admin:
+		operation.Security = &openapi3.SecurityRequirements{
+			openapi3.NewSecurityRequirement().Authenticate(bearerScheme),
+		}
+	default:
+		operation.Security = openapi3.NewSecurityRequirements()
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := openapi3.NewResponse().WithDescription(http.StatusText(status))
+	if op.Response != nil {
+		success.WithJSONSchemaRef(s.of(op.Response))
+	}
+	if len(op.Produces) > 0 {
+		if success.Content == nil {
+			success.WithContent(openapi3.Content{})
+		}
+		for _, contentType := range op.Produces {
+			success.Content[contentType] = openapi3.NewMediaType().WithSchema(File)
+		}
+	}
+	operation.Responses = openapi3.NewResponses(openapi3.WithStatus(status, &openapi3.ResponseRef{Value: success}))
+	for code, body := range op.Also {
+		operation.Responses.Set(strconv.Itoa(code), &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription(http.StatusText(code)).
+			WithJSONSchemaRef(s.of(body))})
+	}
+
+	for _, code := range errorStatuses(op) {
+		operation.Responses.Set(strconv.Itoa(code), &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription(http.StatusText(code)).
+			WithContent(openapi3.Content{apperr.ContentType: openapi3.NewMediaType().WithSchemaRef(problem)})})
+	}
+	return operation
+}
+
+// errorStatuses - Lỗi có thể trả về: suy ra từ input và cách xác thực, cộng thêm op.Errors
+func errorStatuses(op Operation) []int {
+	codes := map[int]bool{http.StatusInternalServerError: true}
+	if op.Body != nil || op.Form != nil || len(op.Query) > 0 {
+		codes[http.StatusBadRequest] = true
+	}
+	if op.Auth != Public {
+		// 403: thiếu scope, personal access token ở route chỉ nhận JWT, hoặc không phải admin
+		codes[http.StatusUnauthorized] = true
+		codes[http.StatusForbidden] = true
+	}
+	if op.RateLimited {
+		codes[http.StatusTooManyRequests] = true
+	}
+	for _, code := range op.Errors {
+		codes[code] = true
+	}
+
+	list := make([]int, 0, len(codes))
+	for code := range codes {
+		list = append(list, code)
+	}
+	sort.Ints(list)
+	return list
+}
+
+// operationID - get /api/v1/users/{id} -> getUsersById
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/api/v1"), "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			b.WriteString("By")
+			segment = strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
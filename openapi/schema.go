@@ -0,0 +1,236 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type optional struct{ value any }
+
+// Optional - Đánh dấu key không bắt buộc trong Object, ví dụ Object{"metadata": Optional(models.ImageMetadata{})}
+func Optional(value any) any {
+	return optional{value}
+}
+
+// Object - Mô tả body dạng object không có struct riêng (các gin.H mà handler trả về).
+// Giá trị là mẫu Go của field: "" là string, 0 là integer, []models.User{} là mảng User...
+type Object map[string]any
+
+// OneOf - Body có thể là một trong các dạng, ví dụ Login trả token hoặc yêu cầu bước 2FA
+type OneOf []any
+
+// schemas - Sinh schema từ kiểu Go; struct có tên được đưa vào components và tham chiếu bằng $ref
+type schemas struct {
+	components openapi3.Schemas
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: openapi3.Schemas{}, names: map[reflect.Type]string{}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of - Schema cho một giá trị mẫu (Object, OneOf, Optional hoặc giá trị Go bất kỳ)
+func (s *schemas) of(value any) *openapi3.SchemaRef {
+	switch v := value.(type) {
+	case nil:
+		return openapi3.NewSchemaRef("", &openapi3.Schema{})
+	case Object:
+		schema := openapi3.NewObjectSchema()
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := v[key]
+			if opt, ok := field.(optional); ok {
+				field = opt.value
+			} else {
+				schema.Required = append(schema.Required, key)
+			}
+			schema.Properties[key] = s.of(field)
+		}
+		return openapi3.NewSchemaRef("", schema)
+	case OneOf:
+		schema := &openapi3.Schema{}
+		for _, alt := range v {
+			schema.OneOf = append(schema.OneOf, s.of(alt))
+		}
+		return openapi3.NewSchemaRef("", schema)
+	case *openapi3.Schema:
+		return openapi3.NewSchemaRef("", v)
+	}
+	return s.typeRef(reflect.TypeOf(value))
+}
+
+func (s *schemas) typeRef(t reflect.Type) *openapi3.SchemaRef {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t != timeType && t.Name() != "" {
+		name, ok := s.names[t]
+		if !ok {
+			name = t.Name()
+			s.names[t] = name
+			// Đăng ký trước khi sinh để struct đệ quy (SmartRule.Rules) tham chiếu được chính nó
+			s.components[name] = openapi3.NewSchemaRef("", &openapi3.Schema{})
+			s.components[name].Value = s.structSchema(t)
+		}
+		return openapi3.NewSchemaRef("#/components/schemas/"+name, s.components[name].Value)
+	}
+	return openapi3.NewSchemaRef("", s.typeSchema(t))
+}
+
+func (s *schemas) typeSchema(t reflect.Type) *openapi3.Schema {
+	switch {
+	case t == timeType:
+		return openapi3.NewDateTimeSchema()
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return openapi3.NewBytesSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.typeSchema(t.Elem())
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return openapi3.NewInt32Schema()
+	case reflect.Int64, reflect.Uint64:
+		return openapi3.NewInt64Schema()
+	case reflect.Float32, reflect.Float64:
+		return openapi3.NewFloat64Schema()
+	case reflect.String:
+		return openapi3.NewStringSchema()
+	case reflect.Slice, reflect.Array:
+		schema := openapi3.NewArraySchema()
+		schema.Items = s.typeRef(t.Elem())
+		return schema
+	case reflect.Map:
+		schema := openapi3.NewObjectSchema()
+		schema.AdditionalProperties = openapi3.AdditionalProperties{Schema: s.typeRef(t.Elem())}
+		return schema
+	case reflect.Struct:
+		return s.structSchema(t)
+	default:
+		// interface{}: giá trị bất kỳ (SmartRule.Value)
+		return &openapi3.Schema{}
+	}
+}
+
+// structSchema - Field theo tag json; ràng buộc lấy từ tag binding giống như validator của gin
+func (s *schemas) structSchema(t reflect.Type) *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		ref := s.typeRef(field.Type)
+		if binding := field.Tag.Get("binding"); binding != "" {
+			var required bool
+			ref, required = applyBinding(ref, field.Type, binding)
+			if required {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		schema.Properties[name] = ref
+	}
+	return schema
+}
+
+// applyBinding - Chuyển rule của validator (required, min, max, oneof, email, dive...) thành ràng buộc JSON Schema.
+// Rule không biểu diễn được (required_without...) chỉ được kiểm tra bởi binding của gin.
+func applyBinding(ref *openapi3.SchemaRef, t reflect.Type, binding string) (*openapi3.SchemaRef, bool) {
+	if ref.Ref != "" {
+		// Không sửa schema dùng chung trong components
+		required := false
+		for _, rule := range strings.Split(binding, ",") {
+			required = required || rule == "required"
+		}
+		return ref, required
+	}
+
+	// Field tùy chọn dạng con trỏ (*string, *[]string) dùng ràng buộc của kiểu bên trong
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema := ref.Value
+	target, kind := schema, t.Kind()
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = target == schema
+		case "dive":
+			// Các rule sau dive áp dụng cho từng phần tử của mảng
+			if schema.Items == nil || schema.Items.Ref != "" {
+				return ref, required
+			}
+			target, kind = schema.Items.Value, t.Elem().Kind()
+		case "email":
+			target.Format = "email"
+		case "url", "http_url":
+			target.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				// Số so khớp với giá trị JSON dạng số, không phải chuỗi
+				if n, err := strconv.ParseFloat(v, 64); err == nil && kind != reflect.String {
+					target.Enum = append(target.Enum, n)
+				} else {
+					target.Enum = append(target.Enum, v)
+				}
+			}
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			applyLimit(target, kind, name, n)
+		}
+	}
+	return ref, required
+}
+
+func applyLimit(schema *openapi3.Schema, kind reflect.Kind, rule string, n float64) {
+	u := uint64(n)
+	switch kind {
+	case reflect.String:
+		if rule != "max" {
+			schema.MinLength = u
+		}
+		if rule != "min" {
+			schema.MaxLength = &u
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rule != "max" {
+			schema.MinItems = u
+		}
+		if rule != "min" {
+			schema.MaxItems = &u
+		}
+	default:
+		if rule != "max" {
+			schema.Min = &n
+		}
+		if rule != "min" {
+			schema.Max = &n
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type memoryFailures struct {
+	count int
+	last  time.Time
+}
+
+// MemoryStore - Backend trong bộ nhớ cho một instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	failures  map[string]*memoryFailures
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*memoryBucket{},
+		failures:  map[string]*memoryFailures{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.Rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return Result{Allowed: false, RetryAfter: retryAfter(bucket.tokens, limit)}, nil
+	}
+	bucket.tokens--
+	return Result{Allowed: true}, nil
+}
+
+func (s *MemoryStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	entry, ok := s.failures[key]
+	if !ok || now.Sub(entry.last) > window {
+		entry = &memoryFailures{}
+		s.failures[key] = entry
+	}
+	entry.count++
+	entry.last = now
+	return entry.count, nil
+}
+
+func (s *MemoryStore) Failures(ctx context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.failures[key]
+	if !ok {
+		return 0, time.Time{}, nil
+	}
+	return entry.count, entry.last, nil
+}
+
+func (s *MemoryStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// sweep - Dọn bucket đã đầy lại (không hoạt động quá 1 giờ) và bộ đếm thất bại quá 24 giờ, tối đa mỗi phút một lần
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+	for key, entry := range s.failures {
+		if now.Sub(entry.last) > 24*time.Hour {
+			delete(s.failures, key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore - Backend dùng chung giữa nhiều instance; mỗi thao tác là một update atomic
+type MongoStore struct {
+	buckets  *mongo.Collection
+	failures *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{
+		buckets:  db.Collection("rate_limits"),
+		failures: db.Collection("login_failures"),
+	}
+}
+
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+func (s *MongoStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := float64(time.Now().UnixNano()) / 1e9
+	burst := float64(limit.Burst)
+
+	// Nạp lại token theo thời gian đã trôi qua rồi trừ 1 token nếu đủ, trong cùng một update
+	refilled := bson.M{"$min": bson.A{
+		burst,
+		bson.M{"$add": bson.A{
+			bson.M{"$ifNull": bson.A{"$tokens", burst}},
+			bson.M{"$multiply": bson.A{
+				bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated", now}}}},
+				limit.Rate,
+			}},
+		}},
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "updated": now}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+			"tokens": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{"$tokens", 1}},
+				bson.M{"$subtract": bson.A{"$tokens", 1}},
+				"$tokens",
+			}},
+			"expires_at": time.Now().Add(time.Hour),
+		}}},
+	}
+
+	var bucket mongoBucket
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := s.buckets.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket); err != nil {
+		return Result{}, err
+	}
+
+	if !bucket.Allowed {
+		return Result{Allowed: false, RetryAfter: retryAfter(bucket.Tokens, limit)}, nil
+	}
+	return Result{Allowed: true}, nil
+}
+
+type mongoFailures struct {
+	Count int       `bson:"count"`
+	Last  time.Time `bson:"last"`
+}
+
+func (s *MongoStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now()
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"count": bson.M{"$cond": bson.A{
+				bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$last", time.Time{}}}, now.Add(-window)}},
+				1,
+				bson.M{"$add": bson.A{"$count", 1}},
+			}},
+			"last":       now,
+			"expires_at": now.Add(window),
+		}}},
+	}
+
+	var entry mongoFailures
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := s.failures.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&entry); err != nil {
+		return 0, err
+	}
+	return entry.Count, nil
+}
+
+func (s *MongoStore) Failures(ctx context.Context, key string) (int, time.Time, error) {
+	var entry mongoFailures
+	err := s.failures.FindOne(ctx, bson.M{"_id": key}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return entry.Count, entry.Last, nil
+}
+
+func (s *MongoStore) ResetFailures(ctx context.Context, key string) error {
+	_, err := s.failures.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
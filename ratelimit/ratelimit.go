@@ -0,0 +1,129 @@
+// Package ratelimit cung cấp token bucket và khóa tài khoản tăng dần cho các endpoint xác thực
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Limit - Token bucket: Burst token tối đa, nạp lại Rate token mỗi giây
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Per - Limit cho phép n request trong khoảng thời gian d
+func Per(n int, d time.Duration) Limit {
+	return Limit{Rate: float64(n) / d.Seconds(), Burst: n}
+}
+
+// Result - Kết quả lấy token; RetryAfter > 0 khi bị từ chối
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Store - Backend lưu trạng thái bucket và số lần đăng nhập sai
+type Store interface {
+	// Take - Lấy một token từ bucket key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// RecordFailure - Tăng số lần thất bại, reset về 1 nếu lần thất bại trước đã quá window
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// Failures - Số lần thất bại hiện tại và thời điểm thất bại gần nhất
+	Failures(ctx context.Context, key string) (int, time.Time, error)
+	// ResetFailures - Xóa bộ đếm thất bại (sau khi đăng nhập thành công)
+	ResetFailures(ctx context.Context, key string) error
+}
+
+var store Store = NewMemoryStore()
+
+// Setup - Chọn backend: "memory" (mặc định) hoặc "mongo" (lưu trong db) khi chạy nhiều instance
+func Setup(backend string, db *mongo.Database) {
+	switch backend {
+	case "mongo":
+		store = NewMongoStore(db)
+		slog.Info("rate limiter configured", "backend", "mongo")
+	default:
+		store = NewMemoryStore()
+		slog.Info("rate limiter configured", "backend", "memory")
+	}
+}
+
+// SetStore - Thay backend hiện tại, dùng cho test
+func SetStore(s Store) {
+	store = s
+}
+
+// Default - Backend hiện tại
+func Default() Store {
+	return store
+}
+
+// retryAfter - Thời gian chờ tới khi bucket có đủ 1 token
+func retryAfter(tokens float64, limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return time.Hour
+	}
+	wait := (1 - tokens) / limit.Rate
+	return time.Duration(math.Ceil(wait)) * time.Second
+}
+
+// Lockout - Khóa tài khoản tăng dần: từ lần sai thứ Threshold, khóa Base, mỗi lần sai tiếp theo nhân đôi, tối đa Max
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// DefaultLockout - 5 lần sai thì khóa 30s, nhân đôi tới tối đa 1 giờ; bộ đếm reset sau 24 giờ không sai
+var DefaultLockout = Lockout{
+	Threshold: 5,
+	Base:      30 * time.Second,
+	Max:       time.Hour,
+	Window:    24 * time.Hour,
+}
+
+// Duration - Thời gian khóa ứng với số lần thất bại
+func (l Lockout) Duration(failures int) time.Duration {
+	if failures < l.Threshold {
+		return 0
+	}
+	lock := l.Base
+	for i := l.Threshold; i < failures && lock < l.Max; i++ {
+		lock *= 2
+	}
+	if lock > l.Max {
+		lock = l.Max
+	}
+	return lock
+}
+
+// Check - Trả về thời gian còn bị khóa (0 nếu không bị khóa)
+func (l Lockout) Check(ctx context.Context, s Store, key string) (time.Duration, error) {
+	failures, last, err := s.Failures(ctx, key)
+	if err != nil || failures == 0 {
+		return 0, err
+	}
+	if time.Since(last) > l.Window {
+		return 0, nil
+	}
+	remaining := time.Until(last.Add(l.Duration(failures)))
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining.Round(time.Second) + time.Second, nil
+}
+
+// Fail - Ghi nhận một lần thất bại, trả về thời gian bị khóa nếu vượt ngưỡng
+func (l Lockout) Fail(ctx context.Context, s Store, key string) (time.Duration, error) {
+	failures, err := s.RecordFailure(ctx, key, l.Window)
+	if err != nil {
+		return 0, err
+	}
+	return l.Duration(failures), nil
+}
@@ -0,0 +1,292 @@
+// Package realtime đẩy sự kiện của user tới các kết nối SSE đang mở. Sự kiện được ghi vào repository trước,
+// mỗi instance đọc tiếp theo Seq và chuyển tới subscriber của mình: sự kiện phát ở instance này tới được client
+// đang nối vào instance khác, và client nối lại bằng Last-Event-ID đọc lại phần bị lỡ từ repository.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+const (
+	// buffer - Số sự kiện chờ gửi của một subscriber; đầy thì đóng subscription để client nối lại
+	// và đọc phần còn thiếu từ repository thay vì làm chậm mọi stream khác
+	buffer = 64
+	// batch - Số sự kiện tối đa đọc mỗi lần
+	batch = 500
+	// gapGrace - Thời gian chờ một seq đã cấp nhưng chưa được lưu (ghi đồng thời) trước khi coi lần ghi đó đã thất bại
+	gapGrace = 5 * time.Second
+)
+
+// Subscription - Sự kiện của một user trên instance này; channel bị đóng khi hub dừng hoặc subscriber quá chậm
+type Subscription struct {
+	hub    *Hub
+	userID string
+	events chan models.UserEvent
+}
+
+// Events - Sự kiện theo thứ tự Seq tăng dần
+func (s *Subscription) Events() <-chan models.UserEvent {
+	return s.events
+}
+
+// Position - Xem Hub.Position
+func (s *Subscription) Position(ctx context.Context) (int64, error) {
+	return s.hub.Position(ctx)
+}
+
+// Close - Bỏ subscription khi stream kết thúc; gọi nhiều lần hoặc sau khi channel đã bị đóng đều an toàn
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+// Hub - Đọc sự kiện mới từ repository và chuyển tới subscriber theo user
+type Hub struct {
+	repo repository.EventRepository
+	cfg  config.EventsConfig
+
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	closed bool
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	// feed - Chỉ goroutine poll dùng; nil khi chưa đọc được từ repository
+	feed *repository.EventFeed
+	// last - Mọi sự kiện có Seq <= last đã được chuyển tới subscriber; -1 khi chưa đọc được từ repository.
+	// Chỉ tăng khi đang giữ mu
+	last atomic.Int64
+}
+
+// NewHub - Hub dùng cấu hình events
+func NewHub(repo repository.EventRepository, cfg config.EventsConfig) *Hub {
+	h := &Hub{
+		repo: repo,
+		cfg:  cfg,
+		subs: map[string]map[*Subscription]struct{}{},
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	h.last.Store(-1)
+	return h
+}
+
+// Start - Đọc sự kiện ở background tới khi Stop được gọi
+func (h *Hub) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go func() {
+		defer close(h.done)
+		h.run(ctx)
+	}()
+}
+
+// Stop - Ngừng đọc sự kiện và đóng mọi subscription để các stream kết thúc; client nối lại vào instance khác
+func (h *Hub) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		for _, subs := range h.subs {
+			for sub := range subs {
+				close(sub.events)
+			}
+		}
+		h.subs = map[string]map[*Subscription]struct{}{}
+	}
+	h.mu.Unlock()
+
+	if h.cancel == nil {
+		return nil
+	}
+	h.cancel()
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe - Nhận sự kiện mới của user; nil khi hub đã dừng. Gọi Close khi stream kết thúc
+func (h *Hub) Subscribe(userID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	sub := &Subscription{hub: h, userID: userID, events: make(chan models.UserEvent, buffer)}
+	if h.subs[userID] == nil {
+		h.subs[userID] = map[*Subscription]struct{}{}
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// drop - Đóng và bỏ subscription; gọi khi đang giữ mu
+func (h *Hub) drop(sub *Subscription) {
+	subs, ok := h.subs[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	close(sub.events)
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+}
+
+// Notify - Đọc sự kiện mới ngay thay vì chờ chu kỳ poll
+func (h *Hub) Notify() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *Hub) run(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.PollInterval.Duration)
+	defer ticker.Stop()
+	for {
+		h.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-h.wake:
+		}
+	}
+}
+
+// poll - Chuyển các sự kiện mới tới subscriber. Lần đầu chỉ ghi nhận Seq hiện tại: sự kiện cũ hơn được
+// stream đọc lại từ repository theo Last-Event-ID
+func (h *Hub) poll(ctx context.Context) {
+	if h.feed == nil {
+		last, err := h.Position(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to read last event", "error", err)
+			}
+			return
+		}
+		h.feed = repository.NewEventFeed(h.repo, last, gapGrace)
+		return
+	}
+
+	for {
+		events, err := h.feed.Next(ctx, batch)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to read events", "error", err)
+			}
+			return
+		}
+		// Cập nhật last cùng lúc chuyển sự kiện: subscriber mới hoặc nhận sự kiện, hoặc thấy nó <= Position
+		h.mu.Lock()
+		for _, event := range events {
+			for sub := range h.subs[event.UserID] {
+				select {
+				case sub.events <- event:
+				default:
+					h.drop(sub)
+				}
+			}
+		}
+		h.last.Store(h.feed.Position())
+		h.mu.Unlock()
+		if len(events) == 0 {
+			h.checkReset(ctx)
+		}
+		if len(events) < batch {
+			return
+		}
+	}
+}
+
+// Position - Mọi sự kiện có Seq <= Position đã được chuyển tới subscriber (hoặc seq đó không bao giờ được lưu);
+// sự kiện sau đó tới qua Subscription. Stream đọc lại từ repository tới mốc này, không xa hơn: sự kiện có Seq
+// lớn hơn đã đọc được chưa chắc đã đủ các sự kiện trước nó
+func (h *Hub) Position(ctx context.Context) (int64, error) {
+	if last := h.last.Load(); last >= 0 {
+		return last, nil
+	}
+	seq, err := h.repo.LastSeq(ctx)
+	if err != nil {
+		return 0, err
+	}
+	h.last.CompareAndSwap(-1, seq)
+	return h.last.Load(), nil
+}
+
+// NewEvent - Sự kiện mới cho user, data được mã hóa JSON; Seq được gán khi ghi vào repository
+func NewEvent(userID, eventType string, data any, retention time.Duration) (*models.UserEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &models.UserEvent{
+		ID:        utils.GenerateID("uev"),
+		UserID:    userID,
+		Type:      eventType,
+		Data:      string(payload),
+		CreatedAt: now.Unix(),
+		PurgeAt:   now.Add(retention),
+	}, nil
+}
+
+// current - Hub đang chạy; handler subscribe và đánh thức nó sau khi ghi sự kiện
+var current atomic.Pointer[Hub]
+
+// SetHub - Đăng ký hub cho Subscribe và Notify; nil để bỏ
+func SetHub(h *Hub) {
+	current.Store(h)
+}
+
+// Subscribe - Subscription trên hub hiện tại; nil khi không có hub hoặc hub đã dừng
+func Subscribe(userID string) *Subscription {
+	if h := current.Load(); h != nil {
+		return h.Subscribe(userID)
+	}
+	return nil
+}
+
+// Notify - Báo hub có sự kiện mới để chuyển ngay tới subscriber trên instance này
+func Notify() {
+	if h := current.Load(); h != nil {
+		h.Notify()
+	}
+}
+
+// checkReset - Seq đã cấp nhỏ hơn mốc đang đọc khi counter bị tạo lại (database khôi phục từ bản sao lưu, xóa tay):
+// đọc tiếp từ seq hiện tại, nếu không hub sẽ bỏ qua mọi sự kiện tới khi seq vượt lại mốc cũ
+func (h *Hub) checkReset(ctx context.Context) {
+	seq, err := h.repo.LastSeq(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to read last event", "error", err)
+		}
+		return
+	}
+	if last := h.feed.Position(); seq < last {
+		slog.WarnContext(ctx, "event sequence went backwards, resetting", "from", last, "to", seq)
+		h.feed.Reset(seq)
+		h.mu.Lock()
+		h.last.Store(seq)
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Cách đưa ảnh vào khung Width × Height
+const (
+	// FitContain - Giữ tỉ lệ, nằm gọn trong khung (ảnh kết quả có thể nhỏ hơn khung một chiều)
+	FitContain = "contain"
+	// FitCover - Giữ tỉ lệ, phủ kín khung rồi cắt phần thừa theo Gravity
+	FitCover = "cover"
+	// FitFill - Kéo giãn đúng bằng khung, không giữ tỉ lệ
+	FitFill = "fill"
+)
+
+// Định dạng ảnh kết quả
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// DefaultQuality - Chất lượng JPEG khi không chỉ định
+const DefaultQuality = 85
+
+// SignatureParam - Tham số query chứa chữ ký
+const SignatureParam = "sig"
+
+// version - Nằm trong phần được ký và trong key cache; đổi khi thuật toán render thay đổi kết quả
+const version = "v1"
+
+// ErrInvalidSignature - Chữ ký thiếu hoặc không khớp với tham số
+var ErrInvalidSignature = errors.New("invalid render signature")
+
+// gravities - Vị trí giữ lại khi cắt với FitCover, theo hướng la bàn: tỉ lệ x, y của vùng cắt trong ảnh
+var gravities = map[string][2]float64{
+	"center":    {0.5, 0.5},
+	"north":     {0.5, 0},
+	"south":     {0.5, 1},
+	"east":      {1, 0.5},
+	"west":      {0, 0.5},
+	"northeast": {1, 0},
+	"northwest": {0, 0},
+	"southeast": {1, 1},
+	"southwest": {0, 1},
+}
+
+// Options - Tham số render. Width hoặc Height bằng 0 thì tính theo tỉ lệ ảnh gốc; Rotate là số độ xoay
+// theo chiều kim đồng hồ, áp dụng sau khi ảnh đã được xoay đúng theo EXIF
+type Options struct {
+	Width   int
+	Height  int
+	Fit     string
+	Gravity string
+	Rotate  int
+	Format  string
+	Quality int
+}
+
+// Normalize - Điền giá trị mặc định và bỏ tham số không có tác dụng, để hai tập tham số cho cùng một ảnh
+// có cùng chữ ký và cùng key cache
+func (o Options) Normalize() Options {
+	if o.Format == "" || o.Format == "jpg" {
+		o.Format = FormatJPEG
+	}
+	switch {
+	case o.Format != FormatJPEG:
+		o.Quality = 0
+	case o.Quality == 0:
+		o.Quality = DefaultQuality
+	}
+	switch {
+	case o.Width == 0 || o.Height == 0:
+		o.Fit = ""
+	case o.Fit == "":
+		o.Fit = FitContain
+	}
+	switch {
+	case o.Fit != FitCover:
+		o.Gravity = ""
+	case o.Gravity == "":
+		o.Gravity = "center"
+	}
+	return o
+}
+
+// Validate - Kiểm tra tham số đã Normalize; maxDimension giới hạn chiều rộng/cao của ảnh kết quả
+func (o Options) Validate(maxDimension int) error {
+	if o.Width == 0 && o.Height == 0 {
+		return errors.New("width or height is required")
+	}
+	if o.Width < 0 || o.Width > maxDimension || o.Height < 0 || o.Height > maxDimension {
+		return fmt.Errorf("width and height must be between 1 and %d", maxDimension)
+	}
+	switch o.Fit {
+	case "", FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("fit must be %s, %s or %s", FitContain, FitCover, FitFill)
+	}
+	if _, ok := gravities[o.Gravity]; o.Gravity != "" && !ok {
+		return errors.New("gravity must be center or a compass direction (north, southeast, ...)")
+	}
+	switch o.Rotate {
+	case 0, 90, 180, 270:
+	default:
+		return errors.New("rotate must be 0, 90, 180 or 270")
+	}
+	switch o.Format {
+	case FormatJPEG:
+		if o.Quality < 1 || o.Quality > 100 {
+			return errors.New("quality must be between 1 and 100")
+		}
+	case FormatPNG:
+	default:
+		return fmt.Errorf("format must be %s or %s", FormatJPEG, FormatPNG)
+	}
+	return nil
+}
+
+// Query - Tham số dạng query, bỏ giá trị rỗng; ParseQuery đọc lại được
+func (o Options) Query() url.Values {
+	q := url.Values{}
+	set := func(key string, n int) {
+		if n != 0 {
+			q.Set(key, strconv.Itoa(n))
+		}
+	}
+	set("w", o.Width)
+	set("h", o.Height)
+	set("rotate", o.Rotate)
+	set("q", o.Quality)
+	for key, value := range map[string]string{"fit": o.Fit, "gravity": o.Gravity, "format": o.Format} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	return q
+}
+
+// ParseQuery - Options từ query của URL render (chưa Normalize); SignatureParam được bỏ qua
+func ParseQuery(q url.Values) (Options, error) {
+	var o Options
+	ints := map[string]*int{"w": &o.Width, "h": &o.Height, "rotate": &o.Rotate, "q": &o.Quality}
+	strs := map[string]*string{"fit": &o.Fit, "gravity": &o.Gravity, "format": &o.Format}
+	for key, values := range q {
+		if key == SignatureParam {
+			continue
+		}
+		if len(values) != 1 {
+			return o, fmt.Errorf("%s must be given once", key)
+		}
+		if dst, ok := ints[key]; ok {
+			n, err := strconv.Atoi(values[0])
+			if err != nil {
+				return o, fmt.Errorf("%s must be an integer", key)
+			}
+			*dst = n
+		} else if dst, ok := strs[key]; ok {
+			*dst = values[0]
+		} else {
+			return o, fmt.Errorf("unknown parameter %s", key)
+		}
+	}
+	return o, nil
+}
+
+// canonical - Chuỗi được ký cho ảnh mediaID; Encode sắp xếp khóa nên không phụ thuộc thứ tự trong URL
+func (o Options) canonical(mediaID string) string {
+	return version + "\n" + mediaID + "\n" + o.Normalize().Query().Encode()
+}
+
+// Sign - Chữ ký hex HMAC-SHA256 của tham số render cho ảnh mediaID
+func Sign(secret []byte, mediaID string, o Options) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(o.canonical(mediaID)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify - Kiểm tra chữ ký do Sign tạo
+func Verify(secret []byte, mediaID string, o Options, signature string) error {
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(Sign(secret, mediaID, o))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Key - Tên file cache của ảnh render (không gồm thư mục): giống nhau với mọi tập tham số tương đương
+func Key(mediaID string, o Options) string {
+	sum := sha256.Sum256([]byte(o.canonical(mediaID)))
+	return hex.EncodeToString(sum[:]) + "." + Extension(o.Normalize().Format)
+}
+
+// Extension - Phần mở rộng file của định dạng
+func Extension(format string) string {
+	if format == FormatPNG {
+		return "png"
+	}
+	return "jpg"
+}
+
+// ContentType - MIME type của định dạng
+func ContentType(format string) string {
+	if format == FormatPNG {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
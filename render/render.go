@@ -0,0 +1,245 @@
+// Package render tạo ảnh dẫn xuất (đổi kích thước, cắt, xoay, đổi định dạng) từ ảnh gốc chỉ bằng thư viện chuẩn.
+// Tham số được ký HMAC để client không tự tạo tổ hợp tùy ý làm cạn CPU; kết quả được cache theo Key.
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	// Decoder GIF cho ảnh gốc; kết quả lấy khung hình đầu tiên
+	_ "image/gif"
+)
+
+// Lỗi khi đọc ảnh gốc
+var (
+	ErrUnsupported = errors.New("unsupported source image")
+	ErrTooLarge    = errors.New("source image has too many pixels")
+)
+
+// Decode - Đọc ảnh gốc; kích thước được kiểm tra từ header trước khi giải mã để ảnh quá lớn
+// không chiếm bộ nhớ. maxPixels giới hạn rộng × cao
+func Decode(r io.ReadSeeker, maxPixels int) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, ErrTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	return img, nil
+}
+
+// Transform - Xoay ảnh đúng theo orientation EXIF (1-8, 0 = không có), rồi áp dụng Options đã Normalize
+func Transform(src image.Image, orientation int, o Options) *image.NRGBA {
+	img := toNRGBA(src)
+	img = orient(img, orientation)
+	switch o.Rotate {
+	case 90:
+		img = orient(img, 6)
+	case 180:
+		img = orient(img, 3)
+	case 270:
+		img = orient(img, 8)
+	}
+
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	w, h := o.Width, o.Height
+	switch {
+	case h == 0:
+		h = scaled(srcH, w, srcW)
+	case w == 0:
+		w = scaled(srcW, h, srcH)
+	case o.Fit == FitContain:
+		if srcW*h > w*srcH {
+			h = scaled(srcH, w, srcW)
+		} else {
+			w = scaled(srcW, h, srcH)
+		}
+	case o.Fit == FitCover:
+		img = crop(img, w, h, gravities[o.Gravity])
+	}
+	return resize(img, w, h)
+}
+
+// Encode - Ghi ảnh theo định dạng của Options đã Normalize. Ảnh mới không mang metadata nào của ảnh gốc;
+// JPEG không có kênh alpha nên phần trong suốt được phủ nền trắng
+func Encode(w io.Writer, img *image.NRGBA, o Options) error {
+	if o.Format == FormatPNG {
+		return png.Encode(w, img)
+	}
+	opaque := image.NewRGBA(img.Bounds())
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+	return jpeg.Encode(w, opaque, &jpeg.Options{Quality: o.Quality})
+}
+
+// scaled - n * num / den làm tròn, tối thiểu 1
+func scaled(n, num, den int) int {
+	return max(1, int(math.Round(float64(n)*float64(num)/float64(den))))
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// orient - Biến đổi theo giá trị EXIF Orientation: 2-4 lật/xoay 180, 5-8 đổi chiều rộng và cao
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // lật ngang
+				dx, dy = w-1-x, y
+			case 3: // xoay 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // lật dọc
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // xoay 90 theo chiều kim đồng hồ
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // xoay 270 theo chiều kim đồng hồ
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// crop - Vùng lớn nhất có tỉ lệ w:h, đặt theo gravity; resize sau đó phủ kín đúng khung
+func crop(src *image.NRGBA, w, h int, gravity [2]float64) *image.NRGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	cw, ch := srcW, srcH
+	if srcW*h > w*srcH {
+		cw = min(srcW, scaled(srcH, w, h))
+	} else {
+		ch = min(srcH, scaled(srcW, h, w))
+	}
+	x := int(math.Round(float64(srcW-cw) * gravity[0]))
+	y := int(math.Round(float64(srcH-ch) * gravity[1]))
+	return src.SubImage(image.Rect(x, y, x+cw, y+ch)).(*image.NRGBA)
+}
+
+// contribution - Các điểm ảnh nguồn góp vào một điểm ảnh đích và trọng số (tổng bằng 1)
+type contribution struct {
+	start   int
+	weights []float64
+}
+
+// contributions - Bộ lọc tam giác (bilinear) giãn theo tỉ lệ thu nhỏ: khi thu nhỏ mỗi điểm đích lấy trung bình
+// cả vùng nguồn tương ứng thay vì bỏ qua điểm ảnh, nên không bị răng cưa
+func contributions(srcLen, dstLen int) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	support := max(scale, 1)
+	out := make([]contribution, dstLen)
+	for i := range out {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := max(0, int(math.Ceil(center-support)))
+		end := min(srcLen-1, int(math.Floor(center+support)))
+		var weights []float64
+		var sum float64
+		for j := start; j <= end; j++ {
+			wt := 1 - math.Abs(float64(j)-center)/support
+			if wt < 0 {
+				wt = 0
+			}
+			weights = append(weights, wt)
+			sum += wt
+		}
+		if sum == 0 {
+			// Điểm đích nằm ngoài mọi điểm nguồn (ảnh nguồn 1 điểm ảnh): lấy điểm gần nhất
+			start = min(max(0, int(math.Round(center))), srcLen-1)
+			weights, sum = []float64{1}, 1
+		}
+		for k := range weights {
+			weights[k] /= sum
+		}
+		out[i] = contribution{start: start, weights: weights}
+	}
+	return out
+}
+
+// resize - Đổi kích thước theo hai lượt (ngang rồi dọc); màu được nhân với alpha khi lấy trung bình
+// để viền vùng trong suốt không bị sẫm
+func resize(src *image.NRGBA, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		return toNRGBA(src)
+	}
+
+	cols := contributions(b.Dx(), w)
+	tmp := make([]float64, w*b.Dy()*4)
+	for y := 0; y < b.Dy(); y++ {
+		for x, c := range cols {
+			var px [4]float64
+			for k, wt := range c.weights {
+				accumulate(&px, src.Pix[src.PixOffset(b.Min.X+c.start+k, b.Min.Y+y):], wt)
+			}
+			copy(tmp[(y*w+x)*4:], px[:])
+		}
+	}
+
+	rows := contributions(b.Dy(), h)
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y, c := range rows {
+		for x := 0; x < w; x++ {
+			var px [4]float64
+			for k, wt := range c.weights {
+				i := ((c.start+k)*w + x) * 4
+				px[0] += tmp[i] * wt
+				px[1] += tmp[i+1] * wt
+				px[2] += tmp[i+2] * wt
+				px[3] += tmp[i+3] * wt
+			}
+			i := dst.PixOffset(x, y)
+			if px[3] > 0 {
+				dst.Pix[i] = clamp(px[0] / px[3] * 255)
+				dst.Pix[i+1] = clamp(px[1] / px[3] * 255)
+				dst.Pix[i+2] = clamp(px[2] / px[3] * 255)
+			}
+			dst.Pix[i+3] = clamp(px[3])
+		}
+	}
+	return dst
+}
+
+// accumulate - Cộng điểm ảnh NRGBA vào px dạng premultiplied (alpha 0-255)
+func accumulate(px *[4]float64, pix []uint8, wt float64) {
+	a := float64(pix[3]) * wt
+	px[0] += float64(pix[0]) * a / 255
+	px[1] += float64(pix[1]) * a / 255
+	px[2] += float64(pix[2]) * a / 255
+	px[3] += a
+}
+
+func clamp(v float64) uint8 {
+	return uint8(min(255, max(0, math.Round(v))))
+}
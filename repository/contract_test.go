@@ -0,0 +1,1101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// runContract - Bộ test chung mà mọi implementation của Repositories phải qua.
+// newRepos trả về một bộ repository rỗng, độc lập cho mỗi subtest.
+func runContract(t *testing.T, newRepos func(t *testing.T) *Repositories) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repos *Repositories)
+	}{
+		{"UsersCRUD", testUsersCRUD},
+		{"UsersUniqueEmail", testUsersUniqueEmail},
+		{"UsersUnsetOptionalFields", testUsersUnsetOptionalFields},
+		{"UsersSearch", testUsersSearch},
+		{"UsersTwoFactor", testUsersTwoFactor},
+		{"AlbumsOwnership", testAlbumsOwnership},
+		{"MediaFindAlbum", testMediaFindAlbum},
+		{"MediaFindSmartRules", testMediaFindSmartRules},
+		{"ShareLinks", testShareLinks},
+		{"UserTokens", testUserTokens},
+		{"Sessions", testSessions},
+		{"AccessTokens", testAccessTokens},
+		{"Identities", testIdentities},
+		{"OIDCStates", testOIDCStates},
+		{"Stats", testStats},
+		{"AuditChain", testAuditChain},
+		{"AuditConcurrentAppend", testAuditConcurrentAppend},
+		{"Webhooks", testWebhooks},
+		{"WebhookFailures", testWebhookFailures},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"Events", testEvents},
+		{"EventsAfterPurge", testEventsAfterPurge},
+		{"EventFeedGaps", testEventFeedGaps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepos(t))
+		})
+	}
+}
+
+func contractContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func mustNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func expectError(t *testing.T, got, want error) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("expected error %v, got %v", want, got)
+	}
+}
+
+func newTestUser(id, name, email string) *models.User {
+	return &models.User{
+		ID:        id,
+		Name:      name,
+		Email:     email,
+		Password:  "hash",
+		Role:      "user",
+		CreatedAt: 1000,
+		UpdatedAt: 1000,
+	}
+}
+
+func testUsersCRUD(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	user := newTestUser("uid_1", "Alice", "alice@example.com")
+	mustNoError(t, repos.Users.Create(ctx, user))
+	expectError(t, repos.Users.Create(ctx, user), ErrDuplicate)
+
+	got, err := repos.Users.GetByID(ctx, "uid_1")
+	mustNoError(t, err)
+	if got.Email != "alice@example.com" || got.Name != "Alice" {
+		t.Fatalf("unexpected user: %+v", got)
+	}
+	got, err = repos.Users.GetByEmail(ctx, "alice@example.com")
+	mustNoError(t, err)
+	if got.ID != "uid_1" {
+		t.Fatalf("GetByEmail returned %q", got.ID)
+	}
+	_, err = repos.Users.GetByID(ctx, "uid_missing")
+	expectError(t, err, ErrNotFound)
+	_, err = repos.Users.GetByEmail(ctx, "missing@example.com")
+	expectError(t, err, ErrNotFound)
+
+	mustNoError(t, repos.Users.Update(ctx, "uid_1", UserChanges{Name: Ptr("Alice B"), Phone: Ptr("123")}))
+	got, err = repos.Users.GetByID(ctx, "uid_1")
+	mustNoError(t, err)
+	if got.Name != "Alice B" || got.Phone != "123" || got.Email != "alice@example.com" {
+		t.Fatalf("update not applied: %+v", got)
+	}
+	if got.UpdatedAt <= 1000 {
+		t.Fatalf("updated_at not refreshed: %d", got.UpdatedAt)
+	}
+	expectError(t, repos.Users.Update(ctx, "uid_missing", UserChanges{Name: Ptr("x")}), ErrNotFound)
+
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_2", "Bob", "bob@example.com")))
+	users, err := repos.Users.List(ctx)
+	mustNoError(t, err)
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+
+	mustNoError(t, repos.Users.Delete(ctx, "uid_1"))
+	expectError(t, repos.Users.Delete(ctx, "uid_1"), ErrNotFound)
+	_, err = repos.Users.GetByID(ctx, "uid_1")
+	expectError(t, err, ErrNotFound)
+}
+
+func testUsersUniqueEmail(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_1", "Alice", "alice@example.com")))
+	expectError(t, repos.Users.Create(ctx, newTestUser("uid_2", "Other", "alice@example.com")), ErrDuplicate)
+
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_3", "Bob", "bob@example.com")))
+	expectError(t, repos.Users.Update(ctx, "uid_3", UserChanges{Email: Ptr("alice@example.com")}), ErrDuplicate)
+
+	got, err := repos.Users.GetByID(ctx, "uid_3")
+	mustNoError(t, err)
+	if got.Email != "bob@example.com" {
+		t.Fatalf("failed update must not change email, got %q", got.Email)
+	}
+}
+
+func testUsersUnsetOptionalFields(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	user := newTestUser("uid_1", "Alice", "alice@example.com")
+	user.PendingEmail = "new@example.com"
+	user.Phone = "123"
+	mustNoError(t, repos.Users.Create(ctx, user))
+
+	mustNoError(t, repos.Users.Update(ctx, "uid_1", UserChanges{
+		PendingEmail:  Ptr(""),
+		Phone:         Ptr(""),
+		EmailVerified: Ptr(true),
+	}))
+	got, err := repos.Users.GetByID(ctx, "uid_1")
+	mustNoError(t, err)
+	if got.PendingEmail != "" || got.Phone != "" {
+		t.Fatalf("optional fields not cleared: %+v", got)
+	}
+	if got.EmailVerified == nil || !*got.EmailVerified {
+		t.Fatalf("email_verified not set: %+v", got.EmailVerified)
+	}
+}
+
+func testUsersSearch(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_1", "Alice Nguyen", "alice@example.com")))
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_2", "Bob", "bob@nguyen.dev")))
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_3", "Carol", "carol@example.com")))
+
+	found, err := repos.Users.Search(ctx, "NGUYEN")
+	mustNoError(t, err)
+	if len(found) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(found))
+	}
+
+	// Ký tự đặc biệt của regex được hiểu theo nghĩa đen
+	found, err = repos.Users.Search(ctx, ".*")
+	mustNoError(t, err)
+	if len(found) != 0 {
+		t.Fatalf("expected no matches for literal pattern, got %d", len(found))
+	}
+}
+
+func testUsersTwoFactor(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.Users.Create(ctx, newTestUser("uid_1", "Alice", "alice@example.com")))
+
+	ok, err := repos.Users.AdvanceTOTPStep(ctx, "uid_1", 100)
+	mustNoError(t, err)
+	if !ok {
+		t.Fatal("first step should be accepted")
+	}
+	if ok, _ := repos.Users.AdvanceTOTPStep(ctx, "uid_1", 100); ok {
+		t.Fatal("same step must not be accepted twice")
+	}
+	if ok, _ := repos.Users.AdvanceTOTPStep(ctx, "uid_1", 99); ok {
+		t.Fatal("older step must be rejected")
+	}
+	if ok, _ := repos.Users.AdvanceTOTPStep(ctx, "uid_1", 101); !ok {
+		t.Fatal("newer step should be accepted")
+	}
+
+	mustNoError(t, repos.Users.Update(ctx, "uid_1", UserChanges{RecoveryCodes: &[]string{"h1", "h2"}}))
+	if ok, _ := repos.Users.ConsumeRecoveryCode(ctx, "uid_1", "h1"); !ok {
+		t.Fatal("recovery code should be consumed")
+	}
+	if ok, _ := repos.Users.ConsumeRecoveryCode(ctx, "uid_1", "h1"); ok {
+		t.Fatal("recovery code must only work once")
+	}
+	if ok, _ := repos.Users.ConsumeRecoveryCode(ctx, "uid_1", "unknown"); ok {
+		t.Fatal("unknown recovery code must be rejected")
+	}
+
+	// Tắt 2FA xóa toàn bộ trạng thái liên quan
+	mustNoError(t, repos.Users.Update(ctx, "uid_1", UserChanges{
+		TwoFactorEnabled:  Ptr(false),
+		TwoFactorSecret:   Ptr(""),
+		TwoFactorLastStep: Ptr(int64(0)),
+		RecoveryCodes:     &[]string{},
+	}))
+	got, err := repos.Users.GetByID(ctx, "uid_1")
+	mustNoError(t, err)
+	if got.TwoFactorLastStep != 0 || len(got.RecoveryCodes) != 0 {
+		t.Fatalf("two-factor state not cleared: %+v", got)
+	}
+	if ok, _ := repos.Users.AdvanceTOTPStep(ctx, "uid_1", 50); !ok {
+		t.Fatal("step should be accepted after reset")
+	}
+}
+
+func testAlbumsOwnership(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.Albums.Create(ctx, &models.Album{ID: "alb_1", UserID: "uid_1", Name: "Trip", Type: models.AlbumTypeRegular}))
+	mustNoError(t, repos.Albums.Create(ctx, &models.Album{ID: "alb_2", UserID: "uid_2", Name: "Other"}))
+
+	album, err := repos.Albums.Get(ctx, "uid_1", "alb_1")
+	mustNoError(t, err)
+	if album.Name != "Trip" {
+		t.Fatalf("unexpected album: %+v", album)
+	}
+	_, err = repos.Albums.Get(ctx, "uid_1", "alb_2")
+	expectError(t, err, ErrNotFound)
+
+	albums, err := repos.Albums.ListByUser(ctx, "uid_1")
+	mustNoError(t, err)
+	if len(albums) != 1 || albums[0].ID != "alb_1" {
+		t.Fatalf("unexpected albums: %+v", albums)
+	}
+	albums, err = repos.Albums.ListByUser(ctx, "uid_none")
+	mustNoError(t, err)
+	if albums == nil || len(albums) != 0 {
+		t.Fatalf("expected empty, non-nil list, got %#v", albums)
+	}
+}
+
+func testMediaFindAlbum(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	for i, captured := range []int64{300, 100, 0, 200} {
+		media := &models.Media{
+			ID:        "med_" + string(rune('a'+i)),
+			UserID:    "uid_1",
+			AlbumID:   "alb_1",
+			Type:      "image",
+			URL:       "http://example.com/x.jpg",
+			CreatedAt: int64(10 + i),
+		}
+		if captured > 0 {
+			media.Metadata = &models.ImageMetadata{DateTime: captured}
+		}
+		mustNoError(t, repos.Media.Create(ctx, media))
+	}
+	mustNoError(t, repos.Media.Create(ctx, &models.Media{ID: "med_other", UserID: "uid_1", AlbumID: "alb_2", Type: "image"}))
+	mustNoError(t, repos.Media.Create(ctx, &models.Media{ID: "med_foreign", UserID: "uid_2", AlbumID: "alb_1", Type: "image"}))
+
+	media, total, err := repos.Media.Find(ctx, MediaQuery{UserID: "uid_1", AlbumID: "alb_1", Limit: 10})
+	mustNoError(t, err)
+	if total != 4 {
+		t.Fatalf("expected total 4, got %d", total)
+	}
+	want := []string{"med_a", "med_d", "med_b", "med_c"}
+	for i, id := range want {
+		if media[i].ID != id {
+			t.Fatalf("position %d: expected %s, got %s", i, id, media[i].ID)
+		}
+	}
+
+	page, total, err := repos.Media.Find(ctx, MediaQuery{UserID: "uid_1", AlbumID: "alb_1", Skip: 2, Limit: 1})
+	mustNoError(t, err)
+	if total != 4 || len(page) != 1 || page[0].ID != "med_b" {
+		t.Fatalf("unexpected page: total=%d %+v", total, page)
+	}
+
+	got, err := repos.Media.Get(ctx, "med_a")
+	mustNoError(t, err)
+	if got.Metadata == nil || got.Metadata.DateTime != 300 {
+		t.Fatalf("unexpected media: %+v", got)
+	}
+	_, err = repos.Media.Get(ctx, "med_missing")
+	expectError(t, err, ErrNotFound)
+
+	expectError(t, repos.Media.Delete(ctx, "uid_2", "med_a"), ErrNotFound)
+	mustNoError(t, repos.Media.Delete(ctx, "uid_1", "med_a"))
+	_, err = repos.Media.Get(ctx, "med_a")
+	expectError(t, err, ErrNotFound)
+}
+
+func testMediaFindSmartRules(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	items := []*models.Media{
+		{ID: "med_1", UserID: "uid_1", Type: "image", Tags: []string{"beach", "family"}, Metadata: &models.ImageMetadata{
+			DateTime: 1700000000, ISO: 100, Location: &models.LocationInfo{Country: "Japan", City: "Tokyo"},
+		}},
+		{ID: "med_2", UserID: "uid_1", Type: "image", Tags: []string{"beach"}, Metadata: &models.ImageMetadata{
+			DateTime: 1600000000, ISO: 800, Location: &models.LocationInfo{Country: "Vietnam"},
+		}},
+		{ID: "med_3", UserID: "uid_1", Type: "video", Duration: 30},
+		{ID: "med_4", UserID: "uid_2", Type: "image", Metadata: &models.ImageMetadata{Location: &models.LocationInfo{Country: "Japan"}}},
+	}
+	for _, m := range items {
+		mustNoError(t, repos.Media.Create(ctx, m))
+	}
+
+	cases := []struct {
+		name string
+		rule models.SmartRule
+		want []string
+	}{
+		{"eq", models.SmartRule{Field: "country", Op: "eq", Value: "Japan"}, []string{"med_1"}},
+		{"ne matches missing", models.SmartRule{Field: "country", Op: "ne", Value: "Japan"}, []string{"med_2", "med_3"}},
+		{"tags all", models.SmartRule{Field: "tag", Op: "all", Value: []interface{}{"beach", "family"}}, []string{"med_1"}},
+		{"number gte", models.SmartRule{Field: "iso", Op: "gte", Value: 400}, []string{"med_2"}},
+		{"exists false", models.SmartRule{Field: "captured_at", Op: "exists", Value: false}, []string{"med_3"}},
+		{"any", models.SmartRule{Match: "any", Rules: []models.SmartRule{
+			{Field: "type", Op: "eq", Value: "video"},
+			{Field: "city", Op: "contains", Value: "tok"},
+		}}, []string{"med_1", "med_3"}},
+		{"none", models.SmartRule{Match: "none", Rules: []models.SmartRule{
+			{Field: "tag", Op: "eq", Value: "beach"},
+		}}, []string{"med_3"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := tc.rule
+			media, total, err := repos.Media.Find(ctx, MediaQuery{UserID: "uid_1", Rules: &rule, Limit: 10})
+			mustNoError(t, err)
+			if int(total) != len(tc.want) || len(media) != len(tc.want) {
+				t.Fatalf("expected %v, got total=%d %+v", tc.want, total, media)
+			}
+			got := map[string]bool{}
+			for _, m := range media {
+				got[m.ID] = true
+			}
+			for _, id := range tc.want {
+				if !got[id] {
+					t.Fatalf("expected %s in result, got %+v", id, media)
+				}
+			}
+		})
+	}
+
+	_, _, err := repos.Media.Find(ctx, MediaQuery{UserID: "uid_1", Rules: &models.SmartRule{Field: "unknown", Op: "eq", Value: "x"}, Limit: 10})
+	if err == nil {
+		t.Fatal("invalid rule must be rejected")
+	}
+}
+
+func testShareLinks(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.ShareLinks.Create(ctx, &models.ShareLink{ID: "share_1", UserID: "uid_1", MediaID: "med_1", CreatedAt: 1}))
+
+	link, err := repos.ShareLinks.Get(ctx, "share_1")
+	mustNoError(t, err)
+	if link.MediaID != "med_1" {
+		t.Fatalf("unexpected link: %+v", link)
+	}
+	expectError(t, repos.ShareLinks.Delete(ctx, "uid_2", "share_1"), ErrNotFound)
+	mustNoError(t, repos.ShareLinks.Delete(ctx, "uid_1", "share_1"))
+	_, err = repos.ShareLinks.Get(ctx, "share_1")
+	expectError(t, err, ErrNotFound)
+}
+
+func testUserTokens(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	now := time.Now().Unix()
+	newToken := func(id, hash, purpose string, expiresAt int64) *models.UserToken {
+		return &models.UserToken{ID: id, UserID: "uid_1", Purpose: purpose, TokenHash: hash, ExpiresAt: expiresAt, CreatedAt: now}
+	}
+	mustNoError(t, repos.UserTokens.Create(ctx, newToken("tok_1", "hash1", models.TokenPurposePasswordReset, now+3600)))
+	mustNoError(t, repos.UserTokens.Create(ctx, newToken("tok_2", "hash2", models.TokenPurposePasswordReset, now-1)))
+
+	_, err := repos.UserTokens.Consume(ctx, "hash1", models.TokenPurposeEmailVerification, now)
+	expectError(t, err, ErrNotFound)
+	_, err = repos.UserTokens.Consume(ctx, "hash2", models.TokenPurposePasswordReset, now)
+	expectError(t, err, ErrNotFound)
+
+	token, err := repos.UserTokens.Consume(ctx, "hash1", models.TokenPurposePasswordReset, now)
+	mustNoError(t, err)
+	if token.ID != "tok_1" || token.UsedAt != now {
+		t.Fatalf("unexpected token: %+v", token)
+	}
+	_, err = repos.UserTokens.Consume(ctx, "hash1", models.TokenPurposePasswordReset, now)
+	expectError(t, err, ErrNotFound)
+
+	mustNoError(t, repos.UserTokens.Create(ctx, newToken("tok_3", "hash3", models.TokenPurposeEmailVerification, now+3600)))
+	mustNoError(t, repos.UserTokens.DeleteUnused(ctx, "uid_1", models.TokenPurposeEmailVerification))
+	_, err = repos.UserTokens.Consume(ctx, "hash3", models.TokenPurposeEmailVerification, now)
+	expectError(t, err, ErrNotFound)
+}
+
+func testSessions(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	now := time.Now().Unix()
+	for i, seen := range []int64{now - 30, now - 10, now - 20} {
+		mustNoError(t, repos.Sessions.Create(ctx, &models.Session{
+			ID:         "ses_" + string(rune('a'+i)),
+			UserID:     "uid_1",
+			CreatedAt:  now - 100,
+			LastSeenAt: seen,
+			ExpiresAt:  now + 3600,
+		}))
+	}
+	mustNoError(t, repos.Sessions.Create(ctx, &models.Session{ID: "ses_expired", UserID: "uid_1", ExpiresAt: now - 1}))
+	mustNoError(t, repos.Sessions.Create(ctx, &models.Session{ID: "ses_other", UserID: "uid_2", ExpiresAt: now + 3600}))
+
+	active, err := repos.Sessions.ListActive(ctx, "uid_1", now)
+	mustNoError(t, err)
+	if len(active) != 3 || active[0].ID != "ses_b" || active[2].ID != "ses_a" {
+		t.Fatalf("unexpected active sessions: %+v", active)
+	}
+
+	_, err = repos.Sessions.Get(ctx, "uid_2", "ses_a")
+	expectError(t, err, ErrNotFound)
+
+	mustNoError(t, repos.Sessions.Touch(ctx, "ses_a", "10.0.0.1", now))
+	session, err := repos.Sessions.Get(ctx, "uid_1", "ses_a")
+	mustNoError(t, err)
+	if session.LastSeenAt != now || session.IP != "10.0.0.1" {
+		t.Fatalf("touch not applied: %+v", session)
+	}
+
+	expectError(t, repos.Sessions.Delete(ctx, "uid_2", "ses_a"), ErrNotFound)
+	mustNoError(t, repos.Sessions.Delete(ctx, "uid_1", "ses_a"))
+
+	deleted, err := repos.Sessions.DeleteAll(ctx, "uid_1", "ses_b")
+	mustNoError(t, err)
+	if deleted != 2 {
+		t.Fatalf("expected 2 deleted sessions, got %d", deleted)
+	}
+	if _, err := repos.Sessions.Get(ctx, "uid_1", "ses_b"); err != nil {
+		t.Fatalf("excepted session must survive: %v", err)
+	}
+	deleted, err = repos.Sessions.DeleteAll(ctx, "uid_1", "")
+	mustNoError(t, err)
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted session, got %d", deleted)
+	}
+	if _, err := repos.Sessions.Get(ctx, "uid_2", "ses_other"); err != nil {
+		t.Fatalf("other user's session must survive: %v", err)
+	}
+}
+
+func testAccessTokens(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.AccessTokens.Create(ctx, &models.AccessToken{ID: "pat_1", UserID: "uid_1", TokenHash: "h1", Scopes: []string{"read"}, CreatedAt: 1}))
+	mustNoError(t, repos.AccessTokens.Create(ctx, &models.AccessToken{ID: "pat_2", UserID: "uid_1", TokenHash: "h2", Scopes: []string{"upload"}, CreatedAt: 2}))
+	expectError(t, repos.AccessTokens.Create(ctx, &models.AccessToken{ID: "pat_3", UserID: "uid_2", TokenHash: "h1", CreatedAt: 3}), ErrDuplicate)
+
+	token, err := repos.AccessTokens.GetByHash(ctx, "h2")
+	mustNoError(t, err)
+	if token.ID != "pat_2" || len(token.Scopes) != 1 || token.Scopes[0] != "upload" {
+		t.Fatalf("unexpected token: %+v", token)
+	}
+	_, err = repos.AccessTokens.GetByHash(ctx, "missing")
+	expectError(t, err, ErrNotFound)
+
+	tokens, err := repos.AccessTokens.ListByUser(ctx, "uid_1")
+	mustNoError(t, err)
+	if len(tokens) != 2 || tokens[0].ID != "pat_2" {
+		t.Fatalf("expected newest token first, got %+v", tokens)
+	}
+	count, err := repos.AccessTokens.CountByUser(ctx, "uid_1")
+	mustNoError(t, err)
+	if count != 2 {
+		t.Fatalf("expected 2 tokens, got %d", count)
+	}
+
+	mustNoError(t, repos.AccessTokens.Touch(ctx, "pat_1", "10.0.0.1", 500))
+	token, err = repos.AccessTokens.GetByHash(ctx, "h1")
+	mustNoError(t, err)
+	if token.LastUsedAt != 500 || token.LastUsedIP != "10.0.0.1" {
+		t.Fatalf("touch not applied: %+v", token)
+	}
+
+	expectError(t, repos.AccessTokens.Delete(ctx, "uid_2", "pat_1"), ErrNotFound)
+	mustNoError(t, repos.AccessTokens.Delete(ctx, "uid_1", "pat_1"))
+	_, err = repos.AccessTokens.GetByHash(ctx, "h1")
+	expectError(t, err, ErrNotFound)
+}
+
+func testIdentities(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	identity := &models.ExternalIdentity{ID: "ext_1", UserID: "uid_1", Provider: "google", Subject: "sub-1", Email: "old@example.com", CreatedAt: 1}
+	mustNoError(t, repos.Identities.Create(ctx, identity))
+	expectError(t, repos.Identities.Create(ctx, &models.ExternalIdentity{ID: "ext_2", UserID: "uid_2", Provider: "google", Subject: "sub-1"}), ErrDuplicate)
+	mustNoError(t, repos.Identities.Create(ctx, &models.ExternalIdentity{ID: "ext_3", UserID: "uid_1", Provider: "github", Subject: "sub-1"}))
+
+	link, err := repos.Identities.RecordLogin(ctx, "google", "sub-1", "new@example.com", 99)
+	mustNoError(t, err)
+	if link.UserID != "uid_1" {
+		t.Fatalf("unexpected identity: %+v", link)
+	}
+	_, err = repos.Identities.RecordLogin(ctx, "google", "sub-unknown", "", 99)
+	expectError(t, err, ErrNotFound)
+
+	identities, err := repos.Identities.ListByUser(ctx, "uid_1")
+	mustNoError(t, err)
+	if len(identities) != 2 {
+		t.Fatalf("expected 2 identities, got %d", len(identities))
+	}
+	for _, item := range identities {
+		if item.ID == "ext_1" && (item.Email != "new@example.com" || item.LastLoginAt != 99) {
+			t.Fatalf("login not recorded: %+v", item)
+		}
+	}
+	count, err := repos.Identities.CountByUser(ctx, "uid_1")
+	mustNoError(t, err)
+	if count != 2 {
+		t.Fatalf("expected 2 identities, got %d", count)
+	}
+
+	expectError(t, repos.Identities.Delete(ctx, "uid_2", "ext_1"), ErrNotFound)
+	mustNoError(t, repos.Identities.Delete(ctx, "uid_1", "ext_1"))
+}
+
+func testOIDCStates(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	now := time.Now().Unix()
+	mustNoError(t, repos.OIDCStates.Create(ctx, &models.OIDCState{ID: "state_1", Provider: "google", Nonce: "n", Verifier: "v", ExpiresAt: now + 600}))
+	mustNoError(t, repos.OIDCStates.Create(ctx, &models.OIDCState{ID: "state_2", Provider: "google", ExpiresAt: now - 1}))
+
+	_, err := repos.OIDCStates.Consume(ctx, "state_1", "github", now)
+	expectError(t, err, ErrNotFound)
+	_, err = repos.OIDCStates.Consume(ctx, "state_2", "google", now)
+	expectError(t, err, ErrNotFound)
+
+	state, err := repos.OIDCStates.Consume(ctx, "state_1", "google", now)
+	mustNoError(t, err)
+	if state.Nonce != "n" || state.Verifier != "v" {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	_, err = repos.OIDCStates.Consume(ctx, "state_1", "google", now)
+	expectError(t, err, ErrNotFound)
+}
+
+func testStats(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	// 2024-03-01 00:00 UTC; khoảng thống kê là hai ngày 01 và 02
+	day1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	day2 := day1 + 86400
+	r := StatsRange{From: day1, To: day2 + 86400}
+
+	users := []*models.User{
+		newTestUser("uid_old", "Old", "old@example.com"),
+		newTestUser("uid_1", "Alice", "alice@example.com"),
+		newTestUser("uid_2", "Bob", "bob@example.com"),
+		newTestUser("uid_3", "Carol", "carol@example.com"),
+		newTestUser("uid_late", "Late", "late@example.com"),
+	}
+	users[1].CreatedAt, users[1].Role, users[1].TwoFactorEnabled = day1+10, "admin", true
+	users[2].CreatedAt, users[2].EmailVerified = day2+10, Ptr(false)
+	users[3].CreatedAt = day2 + 20
+	users[4].CreatedAt = r.To
+	for _, u := range users {
+		mustNoError(t, repos.Users.Create(ctx, u))
+	}
+
+	camera := func(make, model string) *models.ImageMetadata {
+		return &models.ImageMetadata{CameraMake: make, CameraModel: model}
+	}
+	for _, m := range []*models.Media{
+		{ID: "med_1", UserID: "uid_1", Type: "image", Size: 100, CreatedAt: day1 + 1, Metadata: camera("Fujifilm", "X100V")},
+		{ID: "med_2", UserID: "uid_1", Type: "image", Size: 200, CreatedAt: day1 + 2, Metadata: camera("Fujifilm", "X100V")},
+		{ID: "med_3", UserID: "uid_1", Type: "video", Size: 1000, CreatedAt: day2 + 1},
+		{ID: "med_4", UserID: "uid_2", Type: "image", Size: 50, CreatedAt: day2 + 2, Metadata: camera("Canon", "R6")},
+		{ID: "med_5", UserID: "uid_gone", Type: "image", Size: 10, CreatedAt: day2 + 3, Metadata: camera("", "")},
+		{ID: "med_old", UserID: "uid_2", Type: "image", Size: 5000, CreatedAt: day1 - 1, Metadata: camera("Canon", "R6")},
+	} {
+		mustNoError(t, repos.Media.Create(ctx, m))
+	}
+
+	userStats, err := repos.Stats.Users(ctx, r)
+	mustNoError(t, err)
+	if userStats.Total != 4 || userStats.Admins != 1 || userStats.Unverified != 1 || userStats.TwoFactor != 1 || userStats.Signups != 3 {
+		t.Fatalf("unexpected user stats: %+v", userStats)
+	}
+	if len(userStats.SignupsPerDay) != 2 || userStats.SignupsPerDay[0] != (models.DailyCount{Date: "2024-03-01", Count: 1}) ||
+		userStats.SignupsPerDay[1] != (models.DailyCount{Date: "2024-03-02", Count: 2}) {
+		t.Fatalf("unexpected signups per day: %+v", userStats.SignupsPerDay)
+	}
+
+	uploads, err := repos.Stats.Uploads(ctx, r)
+	mustNoError(t, err)
+	wantUploads := []models.UploadsPerDay{
+		{Date: "2024-03-01", Type: "image", Count: 2, Bytes: 300},
+		{Date: "2024-03-02", Type: "image", Count: 2, Bytes: 60},
+		{Date: "2024-03-02", Type: "video", Count: 1, Bytes: 1000},
+	}
+	if len(uploads) != len(wantUploads) {
+		t.Fatalf("unexpected uploads: %+v", uploads)
+	}
+	for i, want := range wantUploads {
+		if uploads[i] != want {
+			t.Fatalf("uploads[%d]: expected %+v, got %+v", i, want, uploads[i])
+		}
+	}
+
+	storage, err := repos.Stats.Storage(ctx, r, 2)
+	mustNoError(t, err)
+	if len(storage) != 2 {
+		t.Fatalf("expected top 2 uploaders, got %+v", storage)
+	}
+	want := models.StorageUsage{UserID: "uid_1", Name: "Alice", Email: "alice@example.com", Files: 3, Images: 2, Videos: 1, Bytes: 1300}
+	if storage[0] != want || storage[1].UserID != "uid_2" || storage[1].Bytes != 50 {
+		t.Fatalf("unexpected storage: %+v", storage)
+	}
+	storage, err = repos.Stats.Storage(ctx, r, 10)
+	mustNoError(t, err)
+	if len(storage) != 3 || storage[2].UserID != "uid_gone" || storage[2].Name != "" {
+		t.Fatalf("expected deleted uploader without name last, got %+v", storage)
+	}
+
+	cameras, err := repos.Stats.Cameras(ctx, r, 10)
+	mustNoError(t, err)
+	if len(cameras) != 2 || cameras[0] != (models.CameraCount{Make: "Fujifilm", Model: "X100V", Count: 2}) ||
+		cameras[1] != (models.CameraCount{Make: "Canon", Model: "R6", Count: 1}) {
+		t.Fatalf("unexpected cameras: %+v", cameras)
+	}
+
+	for i, f := range []models.FailedLogin{
+		{Reason: models.LoginFailureWrongPassword, CreatedAt: day1 + 5},
+		{Reason: models.LoginFailureWrongPassword, CreatedAt: day1 + 6},
+		{Reason: models.LoginFailureUnknownEmail, CreatedAt: day1 + 7},
+		{Reason: models.LoginFailureInvalidOTP, CreatedAt: day2 + 5},
+		{Reason: models.LoginFailureInvalidOTP, CreatedAt: r.To + 5},
+	} {
+		f.ID = "fl_" + string(rune('a'+i))
+		mustNoError(t, repos.FailedLogins.Create(ctx, &f))
+	}
+	logins, err := repos.Stats.FailedLogins(ctx, r)
+	mustNoError(t, err)
+	wantLogins := []models.FailedLoginsPerDay{
+		{Date: "2024-03-01", Reason: models.LoginFailureUnknownEmail, Count: 1},
+		{Date: "2024-03-01", Reason: models.LoginFailureWrongPassword, Count: 2},
+		{Date: "2024-03-02", Reason: models.LoginFailureInvalidOTP, Count: 1},
+	}
+	if len(logins) != len(wantLogins) {
+		t.Fatalf("unexpected failed logins: %+v", logins)
+	}
+	for i, want := range wantLogins {
+		if logins[i] != want {
+			t.Fatalf("failed logins[%d]: expected %+v, got %+v", i, want, logins[i])
+		}
+	}
+
+	for i, run := range []models.JobRun{
+		{Job: "mail", Status: models.JobStatusOK, DurationMs: 10, CreatedAt: day1 + 1},
+		{Job: "mail", Status: models.JobStatusFailed, Error: "smtp down", DurationMs: 30, CreatedAt: day1 + 2},
+		{Job: "mail", Status: models.JobStatusOK, DurationMs: 5, CreatedAt: day2 + 1},
+	} {
+		run.ID = "job_" + string(rune('a'+i))
+		mustNoError(t, repos.JobRuns.Create(ctx, &run))
+	}
+	jobs, err := repos.Stats.JobRuns(ctx, r)
+	mustNoError(t, err)
+	if len(jobs) != 2 || jobs[0] != (models.JobRunsPerDay{Date: "2024-03-01", Job: "mail", Runs: 2, Failures: 1, AvgDurationMs: 20, MaxDurationMs: 30}) ||
+		jobs[1].Runs != 1 || jobs[1].Failures != 0 {
+		t.Fatalf("unexpected job runs: %+v", jobs)
+	}
+
+	empty := StatsRange{From: 0, To: 1}
+	none, err := repos.Stats.Uploads(ctx, empty)
+	mustNoError(t, err)
+	if none == nil || len(none) != 0 {
+		t.Fatalf("expected empty non-nil uploads, got %#v", none)
+	}
+}
+
+func testAuditChain(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	events := []models.AuditEvent{
+		{ID: "aud_1", Action: models.AuditRegister, ActorID: "uid_1", TargetType: "user", TargetID: "uid_1", CreatedAt: 100},
+		{ID: "aud_2", Action: models.AuditUserUpdate, ActorID: "uid_1", TargetType: "user", TargetID: "uid_1", CreatedAt: 200,
+			Diff: map[string]models.AuditChange{"name": {From: "Old", To: "New"}}},
+		{ID: "aud_3", Action: models.AuditUserDelete, ActorID: "uid_admin", TargetType: "user", TargetID: "uid_1", CreatedAt: 300,
+			Details: map[string]string{"email": "a@example.com"}},
+	}
+	for i := range events {
+		mustNoError(t, repos.Audit.Append(ctx, &events[i]))
+		if events[i].Seq != int64(i+1) {
+			t.Fatalf("event %d: expected seq %d, got %d", i, i+1, events[i].Seq)
+		}
+	}
+
+	chain, err := repos.Audit.Chain(ctx, 0, 0)
+	mustNoError(t, err)
+	if len(chain) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(chain))
+	}
+	prev := ""
+	for _, e := range chain {
+		if e.PrevHash != prev || e.Hash == "" || e.Hash != e.ComputeHash() {
+			t.Fatalf("broken chain at seq %d: %+v", e.Seq, e)
+		}
+		prev = e.Hash
+	}
+	if chain[1].Diff["name"].To != "New" || chain[2].Details["email"] != "a@example.com" {
+		t.Fatalf("diff/details not stored: %+v", chain)
+	}
+
+	page, err := repos.Audit.Chain(ctx, 1, 1)
+	mustNoError(t, err)
+	if len(page) != 1 || page[0].ID != "aud_2" {
+		t.Fatalf("unexpected chain page: %+v", page)
+	}
+
+	found, total, err := repos.Audit.Find(ctx, AuditQuery{TargetID: "uid_1", Limit: 2})
+	mustNoError(t, err)
+	if total != 3 || len(found) != 2 || found[0].ID != "aud_3" || found[1].ID != "aud_2" {
+		t.Fatalf("unexpected find page: total=%d %+v", total, found)
+	}
+	found, total, err = repos.Audit.Find(ctx, AuditQuery{ActorID: "uid_1", From: 150, To: 300})
+	mustNoError(t, err)
+	if total != 1 || found[0].ID != "aud_2" {
+		t.Fatalf("unexpected actor/time filter: %+v", found)
+	}
+	found, total, err = repos.Audit.Find(ctx, AuditQuery{Action: models.AuditUserDelete, TargetType: "user"})
+	mustNoError(t, err)
+	if total != 1 || found[0].ID != "aud_3" {
+		t.Fatalf("unexpected action filter: %+v", found)
+	}
+}
+
+func testAuditConcurrentAppend(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	// Đủ nhiều để các lần ghi trùng seq phải thử lại nhiều lần; không sự kiện nào được bỏ
+	const writers = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- repos.Audit.Append(ctx, &models.AuditEvent{ID: fmt.Sprintf("aud_%d", i), Action: models.AuditLogin, CreatedAt: 100})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		mustNoError(t, err)
+	}
+
+	chain, err := repos.Audit.Chain(ctx, 0, 0)
+	mustNoError(t, err)
+	if len(chain) != writers {
+		t.Fatalf("expected %d events, got %d", writers, len(chain))
+	}
+	prev := ""
+	for i, e := range chain {
+		if e.Seq != int64(i+1) || e.PrevHash != prev {
+			t.Fatalf("chain forked at position %d: %+v", i, e)
+		}
+		prev = e.Hash
+	}
+}
+
+func testWebhooks(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	hooks := []models.Webhook{
+		{ID: "whk_1", URL: "https://a.example.com/hook", Events: []string{models.WebhookMediaCreated}, Secret: "s1", Enabled: true, CreatedAt: 100},
+		{ID: "whk_2", URL: "https://b.example.com/hook", Events: []string{models.WebhookMediaCreated, models.WebhookUserDeleted},
+			Secret: "s2", Enabled: true, CreatedAt: 200},
+		{ID: "whk_3", URL: "https://c.example.com/hook", Events: []string{models.WebhookMediaCreated}, Secret: "s3", Enabled: false, CreatedAt: 300},
+	}
+	for i := range hooks {
+		mustNoError(t, repos.Webhooks.Create(ctx, &hooks[i]))
+	}
+
+	got, err := repos.Webhooks.Get(ctx, "whk_2")
+	mustNoError(t, err)
+	if got.Secret != "s2" || len(got.Events) != 2 {
+		t.Fatalf("unexpected webhook: %+v", got)
+	}
+	_, err = repos.Webhooks.Get(ctx, "whk_missing")
+	expectError(t, err, ErrNotFound)
+
+	list, err := repos.Webhooks.List(ctx)
+	mustNoError(t, err)
+	if len(list) != 3 || list[0].ID != "whk_3" || list[2].ID != "whk_1" {
+		t.Fatalf("unexpected list order: %+v", list)
+	}
+
+	subscribed, err := repos.Webhooks.ListForEvent(ctx, models.WebhookMediaCreated)
+	mustNoError(t, err)
+	if len(subscribed) != 2 {
+		t.Fatalf("expected 2 enabled subscribers, got %+v", subscribed)
+	}
+	subscribed, err = repos.Webhooks.ListForEvent(ctx, models.WebhookUserDeleted)
+	mustNoError(t, err)
+	if len(subscribed) != 1 || subscribed[0].ID != "whk_2" {
+		t.Fatalf("unexpected user.deleted subscribers: %+v", subscribed)
+	}
+
+	updated, err := repos.Webhooks.Update(ctx, "whk_1", WebhookChanges{
+		URL:    Ptr("https://a.example.com/v2"),
+		Events: Ptr([]string{models.WebhookAlbumCreated}),
+	})
+	mustNoError(t, err)
+	if updated.URL != "https://a.example.com/v2" || len(updated.Events) != 1 || updated.Events[0] != models.WebhookAlbumCreated ||
+		updated.Secret != "s1" || !updated.Enabled {
+		t.Fatalf("unexpected update result: %+v", updated)
+	}
+	_, err = repos.Webhooks.Update(ctx, "whk_missing", WebhookChanges{Enabled: Ptr(true)})
+	expectError(t, err, ErrNotFound)
+
+	mustNoError(t, repos.Webhooks.Delete(ctx, "whk_1"))
+	expectError(t, repos.Webhooks.Delete(ctx, "whk_1"), ErrNotFound)
+}
+
+func testWebhookFailures(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	mustNoError(t, repos.Webhooks.Create(ctx, &models.Webhook{
+		ID: "whk_1", URL: "https://a.example.com/hook", Events: []string{models.WebhookMediaCreated}, Secret: "s", Enabled: true,
+	}))
+
+	disabled, err := repos.Webhooks.RecordFailure(ctx, "whk_1", 3, "too many failures")
+	mustNoError(t, err)
+	mustNoError(t, repos.Webhooks.RecordSuccess(ctx, "whk_1"))
+	for i := 1; i <= 3; i++ {
+		disabled, err = repos.Webhooks.RecordFailure(ctx, "whk_1", 3, "too many failures")
+		mustNoError(t, err)
+		if disabled != (i == 3) {
+			t.Fatalf("failure %d: disabled = %v", i, disabled)
+		}
+	}
+	got, err := repos.Webhooks.Get(ctx, "whk_1")
+	mustNoError(t, err)
+	if got.Enabled || got.DisabledReason != "too many failures" || got.ConsecutiveFailures != 3 {
+		t.Fatalf("expected disabled webhook, got %+v", got)
+	}
+
+	// Đã tắt thì lần lỗi sau không báo tắt lần nữa
+	disabled, err = repos.Webhooks.RecordFailure(ctx, "whk_1", 3, "too many failures")
+	mustNoError(t, err)
+	if disabled {
+		t.Fatal("expected an already disabled webhook not to be reported again")
+	}
+
+	got, err = repos.Webhooks.Update(ctx, "whk_1", WebhookChanges{Enabled: Ptr(true)})
+	mustNoError(t, err)
+	if !got.Enabled || got.DisabledReason != "" || got.ConsecutiveFailures != 0 {
+		t.Fatalf("expected re-enabling to reset failures, got %+v", got)
+	}
+
+	mustNoError(t, repos.Webhooks.RecordSuccess(ctx, "whk_missing"))
+	_, err = repos.Webhooks.RecordFailure(ctx, "whk_missing", 3, "x")
+	expectError(t, err, ErrNotFound)
+}
+
+func testWebhookDeliveries(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	deliveries := []models.WebhookDelivery{
+		{ID: "whd_1", WebhookID: "whk_1", EventID: "evt_1", Event: models.WebhookMediaCreated, Payload: `{"a":1}`,
+			Status: models.WebhookDeliveryPending, NextAttemptAt: 100, CreatedAt: 100},
+		{ID: "whd_2", WebhookID: "whk_1", EventID: "evt_2", Event: models.WebhookMediaCreated, Payload: `{"a":2}`,
+			Status: models.WebhookDeliveryPending, NextAttemptAt: 50, CreatedAt: 200},
+		{ID: "whd_3", WebhookID: "whk_2", EventID: "evt_1", Event: models.WebhookMediaCreated, Payload: `{"a":1}`,
+			Status: models.WebhookDeliveryPending, NextAttemptAt: 500, CreatedAt: 100},
+	}
+	for i := range deliveries {
+		mustNoError(t, repos.WebhookDeliveries.Create(ctx, &deliveries[i]))
+	}
+
+	_, err := repos.WebhookDeliveries.Get(ctx, "whk_2", "whd_1")
+	expectError(t, err, ErrNotFound)
+
+	due, err := repos.WebhookDeliveries.CountDue(ctx, 150)
+	mustNoError(t, err)
+	if due != 2 {
+		t.Fatalf("expected 2 due deliveries, got %d", due)
+	}
+
+	// Sớm nhất trước; delivery đã lấy bị dời lịch nên không bị lấy lại
+	claimed, err := repos.WebhookDeliveries.ClaimDue(ctx, 150, 1000)
+	mustNoError(t, err)
+	if claimed.ID != "whd_2" || claimed.NextAttemptAt != 1000 || claimed.Payload != `{"a":2}` {
+		t.Fatalf("unexpected first claim: %+v", claimed)
+	}
+	claimed, err = repos.WebhookDeliveries.ClaimDue(ctx, 150, 1000)
+	mustNoError(t, err)
+	if claimed.ID != "whd_1" {
+		t.Fatalf("unexpected second claim: %+v", claimed)
+	}
+	_, err = repos.WebhookDeliveries.ClaimDue(ctx, 150, 1000)
+	expectError(t, err, ErrNotFound)
+
+	mustNoError(t, repos.WebhookDeliveries.Record(ctx, "whd_1", WebhookDeliveryResult{
+		Status:        models.WebhookDeliveryPending,
+		Attempt:       models.WebhookAttempt{At: 150, StatusCode: 500, Error: "HTTP 500", DurationMs: 12.5},
+		NextAttemptAt: 180,
+		Now:           150,
+	}))
+	mustNoError(t, repos.WebhookDeliveries.Record(ctx, "whd_1", WebhookDeliveryResult{
+		Status:  models.WebhookDeliverySucceeded,
+		Attempt: models.WebhookAttempt{At: 180, StatusCode: 204, DurationMs: 3},
+		Now:     180,
+	}))
+	got, err := repos.WebhookDeliveries.Get(ctx, "whk_1", "whd_1")
+	mustNoError(t, err)
+	if got.Status != models.WebhookDeliverySucceeded || got.DeliveredAt != 180 || got.NextAttemptAt != 0 || len(got.Attempts) != 2 ||
+		got.Attempts[0].StatusCode != 500 || got.Attempts[0].Error != "HTTP 500" || got.Attempts[1].StatusCode != 204 {
+		t.Fatalf("unexpected recorded delivery: %+v", got)
+	}
+
+	mustNoError(t, repos.WebhookDeliveries.Record(ctx, "whd_2", WebhookDeliveryResult{
+		Status:  models.WebhookDeliveryFailed,
+		Attempt: models.WebhookAttempt{At: 150, Error: "connection refused"},
+		Now:     150,
+	}))
+	got, err = repos.WebhookDeliveries.Get(ctx, "whk_1", "whd_2")
+	mustNoError(t, err)
+	if got.Status != models.WebhookDeliveryFailed || len(got.Attempts) != 1 || got.Attempts[0].StatusCode != 0 || got.DeliveredAt != 0 {
+		t.Fatalf("unexpected finished delivery: %+v", got)
+	}
+	expectError(t, repos.WebhookDeliveries.Record(ctx, "whd_missing", WebhookDeliveryResult{Status: models.WebhookDeliveryFailed}), ErrNotFound)
+
+	page, total, err := repos.WebhookDeliveries.ListByWebhook(ctx, "whk_1", 0, 1)
+	mustNoError(t, err)
+	if total != 2 || len(page) != 1 || page[0].ID != "whd_2" {
+		t.Fatalf("unexpected delivery page: total=%d %+v", total, page)
+	}
+
+	mustNoError(t, repos.WebhookDeliveries.DeleteByWebhook(ctx, "whk_1"))
+	_, total, err = repos.WebhookDeliveries.ListByWebhook(ctx, "whk_1", 0, 0)
+	mustNoError(t, err)
+	if total != 0 {
+		t.Fatalf("expected deliveries to be deleted, got %d", total)
+	}
+	if _, err := repos.WebhookDeliveries.Get(ctx, "whk_2", "whd_3"); err != nil {
+		t.Fatalf("expected other webhook's deliveries to remain: %v", err)
+	}
+}
+
+func testEvents(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	last, err := repos.Events.LastSeq(ctx)
+	mustNoError(t, err)
+	if last != 0 {
+		t.Fatalf("expected no events, got last seq %d", last)
+	}
+
+	// Ghi đồng thời vẫn cho seq liên tiếp, không trùng
+	const writers = 6
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := []string{"uid_1", "uid_2"}[i%2]
+			errs <- repos.Events.Append(ctx, &models.UserEvent{
+				ID: fmt.Sprintf("evt_%d", i), UserID: userID, Type: models.EventMediaProcessed, Data: `{}`, CreatedAt: 100,
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		mustNoError(t, err)
+	}
+
+	all, err := repos.Events.After(ctx, "", 0, 0)
+	mustNoError(t, err)
+	if len(all) != writers {
+		t.Fatalf("expected %d events, got %d", writers, len(all))
+	}
+	for i, e := range all {
+		if e.Seq != int64(i+1) {
+			t.Fatalf("expected seq %d at %d, got %+v", i+1, i, all)
+		}
+	}
+	last, err = repos.Events.LastSeq(ctx)
+	mustNoError(t, err)
+	if last != writers {
+		t.Fatalf("expected last seq %d, got %d", writers, last)
+	}
+
+	mine, err := repos.Events.After(ctx, "uid_1", 0, 0)
+	mustNoError(t, err)
+	if len(mine) != writers/2 {
+		t.Fatalf("expected %d events for uid_1, got %+v", writers/2, mine)
+	}
+	page, err := repos.Events.After(ctx, "uid_1", mine[0].Seq, 1)
+	mustNoError(t, err)
+	if len(page) != 1 || page[0].Seq != mine[1].Seq || page[0].UserID != "uid_1" || page[0].Data != `{}` {
+		t.Fatalf("unexpected page after seq %d: %+v", mine[0].Seq, page)
+	}
+	rest, err := repos.Events.After(ctx, "", last, 0)
+	mustNoError(t, err)
+	if len(rest) != 0 {
+		t.Fatalf("expected no events after the last seq, got %+v", rest)
+	}
+}
+
+// purgeEvents - Xóa mọi sự kiện như TTL index làm khi tất cả đã hết hạn
+func purgeEvents(t *testing.T, ctx context.Context, repos *Repositories) {
+	t.Helper()
+	switch events := repos.Events.(type) {
+	case *memoryEvents:
+		events.table.remove(func(models.UserEvent) bool { return true }, 0)
+	case *mongoEvents:
+		_, err := events.col.DeleteMany(ctx, bson.M{})
+		mustNoError(t, err)
+	default:
+		t.Fatalf("cannot purge %T", events)
+	}
+}
+
+func testEventsAfterPurge(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	for i := 0; i < 3; i++ {
+		mustNoError(t, repos.Events.Append(ctx, &models.UserEvent{
+			ID: fmt.Sprintf("evt_old_%d", i), UserID: "uid_1", Type: models.EventMediaProcessed, Data: `{}`, CreatedAt: 100,
+		}))
+	}
+	purgeEvents(t, ctx, repos)
+
+	// Seq không quay lại từ đầu: client nối lại với id 3 vẫn nhận sự kiện mới
+	last, err := repos.Events.LastSeq(ctx)
+	mustNoError(t, err)
+	if last != 3 {
+		t.Fatalf("expected last seq 3 after purge, got %d", last)
+	}
+	event := &models.UserEvent{ID: "evt_new", UserID: "uid_1", Type: models.EventMediaProcessed, Data: `{}`, CreatedAt: 200}
+	mustNoError(t, repos.Events.Append(ctx, event))
+	if event.Seq != 4 {
+		t.Fatalf("expected seq 4 after purge, got %d", event.Seq)
+	}
+	resumed, err := repos.Events.After(ctx, "uid_1", 3, 0)
+	mustNoError(t, err)
+	if len(resumed) != 1 || resumed[0].ID != "evt_new" {
+		t.Fatalf("expected the new event after seq 3, got %+v", resumed)
+	}
+}
+
+// storeEvent - Lưu sự kiện với seq đã định sẵn như một lần Append đã lấy seq nhưng chưa ghi xong
+func storeEvent(t *testing.T, ctx context.Context, repos *Repositories, seq int64) {
+	t.Helper()
+	event := models.UserEvent{
+		ID: fmt.Sprintf("evt_%d", seq), UserID: "uid_1", Type: models.EventMediaProcessed, Data: `{}`, CreatedAt: 100, Seq: seq,
+	}
+	switch events := repos.Events.(type) {
+	case *memoryEvents:
+		mustNoError(t, events.table.insert(event, nil))
+	case *mongoEvents:
+		_, err := events.col.InsertOne(ctx, event)
+		mustNoError(t, err)
+	default:
+		t.Fatalf("cannot store into %T", events)
+	}
+}
+
+func testEventFeedGaps(t *testing.T, repos *Repositories) {
+	ctx := contractContext(t)
+	storeEvent(t, ctx, repos, 1)
+	storeEvent(t, ctx, repos, 3)
+
+	// Seq 2 chưa được lưu: feed dừng ở trước nó thay vì trả 3 rồi bỏ lỡ 2
+	feed := NewEventFeed(repos.Events, 0, time.Minute)
+	events, err := feed.Next(ctx, 10)
+	mustNoError(t, err)
+	if len(events) != 1 || events[0].Seq != 1 || feed.Position() != 1 {
+		t.Fatalf("expected to stop before the gap, got %+v at %d", events, feed.Position())
+	}
+	storeEvent(t, ctx, repos, 2)
+	events, err = feed.Next(ctx, 10)
+	mustNoError(t, err)
+	if len(events) != 2 || events[0].Seq != 2 || events[1].Seq != 3 || feed.Position() != 3 {
+		t.Fatalf("expected seq 2 and 3 once the gap filled, got %+v at %d", events, feed.Position())
+	}
+
+	// Seq 4 không bao giờ được lưu (lần ghi thất bại): bỏ qua sau grace
+	storeEvent(t, ctx, repos, 5)
+	feed = NewEventFeed(repos.Events, 3, 0)
+	events, err = feed.Next(ctx, 10)
+	mustNoError(t, err)
+	if len(events) != 1 || events[0].Seq != 5 || feed.Position() != 5 {
+		t.Fatalf("expected to skip the abandoned seq, got %+v at %d", events, feed.Position())
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+// EventFeed - Đọc sự kiện của mọi user theo Seq tăng dần mà không bỏ sót. Seq được cấp trước khi sự kiện được lưu,
+// nên khi ghi đồng thời sự kiện N+1 có thể đọc được trước N: feed dừng ở chỗ thiếu tới khi N được lưu. Seq đã cấp
+// nhưng lần ghi thất bại thì không bao giờ xuất hiện, nên chỗ thiếu tồn tại quá grace được bỏ qua.
+// Không an toàn khi dùng từ nhiều goroutine
+type EventFeed struct {
+	repo     EventRepository
+	grace    time.Duration
+	position int64
+	// gap - Seq đang thiếu, gapSince - lúc phát hiện nó
+	gap      int64
+	gapSince time.Time
+}
+
+// NewEventFeed - Feed đọc các sự kiện có Seq > position
+func NewEventFeed(repo EventRepository, position int64, grace time.Duration) *EventFeed {
+	return &EventFeed{repo: repo, grace: grace, position: position}
+}
+
+// Position - Mọi sự kiện có Seq <= Position đã được Next trả về hoặc đã bị bỏ qua
+func (f *EventFeed) Position() int64 {
+	return f.position
+}
+
+// Reset - Đọc tiếp từ position (seq bị đặt lại)
+func (f *EventFeed) Reset(position int64) {
+	f.position = position
+	f.gap = 0
+}
+
+// Next - Tối đa limit sự kiện liền mạch tiếp theo; ít hơn limit khi đã hết hoặc đang chờ một seq còn thiếu
+func (f *EventFeed) Next(ctx context.Context, limit int) ([]models.UserEvent, error) {
+	events, err := f.repo.After(ctx, "", f.position, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, event := range events {
+		if missing := f.position + 1; event.Seq > missing {
+			if f.gap != missing {
+				f.gap, f.gapSince = missing, time.Now()
+			}
+			if time.Since(f.gapSince) < f.grace {
+				return events[:i], nil
+			}
+			slog.WarnContext(ctx, "skipping missing event sequence", "from", missing, "to", event.Seq-1)
+		}
+		f.position = event.Seq
+	}
+	return events, nil
+}
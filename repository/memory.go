@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"sync"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+// NewMemory - Repository lưu trong bộ nhớ, dùng cho test và chạy thử không cần MongoDB
+func NewMemory() *Repositories {
+	users := newTable(func(u models.User) string { return u.ID }, cloneUser)
+	media := newTable(func(m models.Media) string { return m.ID }, cloneMedia)
+	failedLogins := newTable(func(f models.FailedLogin) string { return f.ID }, nil)
+	jobRuns := newTable(func(r models.JobRun) string { return r.ID }, nil)
+	return &Repositories{
+		Users:        &memoryUsers{table: users},
+		Albums:       &memoryAlbums{table: newTable(func(a models.Album) string { return a.ID }, nil)},
+		Media:        &memoryMedia{table: media},
+		ShareLinks:   &memoryShareLinks{table: newTable(func(l models.ShareLink) string { return l.ID }, nil)},
+		UserTokens:   &memoryUserTokens{table: newTable(func(t models.UserToken) string { return t.ID }, nil)},
+		Sessions:     &memorySessions{table: newTable(func(s models.Session) string { return s.ID }, nil)},
+		AccessTokens: &memoryAccessTokens{table: newTable(func(t models.AccessToken) string { return t.ID }, cloneAccessToken)},
+		Identities:   &memoryIdentities{table: newTable(func(i models.ExternalIdentity) string { return i.ID }, nil)},
+		OIDCStates:   &memoryOIDCStates{table: newTable(func(s models.OIDCState) string { return s.ID }, nil)},
+		FailedLogins: &memoryFailedLogins{table: failedLogins},
+		JobRuns:      &memoryJobRuns{table: jobRuns},
+		Stats:        &memoryStats{users: users, media: media, failedLogins: failedLogins, jobRuns: jobRuns},
+		Audit:        &memoryAudit{table: newTable(func(e models.AuditEvent) string { return e.ID }, cloneAuditEvent)},
+		Webhooks:     &memoryWebhooks{table: newTable(func(w models.Webhook) string { return w.ID }, cloneWebhook)},
+		WebhookDeliveries: &memoryWebhookDeliveries{
+			table: newTable(func(d models.WebhookDelivery) string { return d.ID }, cloneWebhookDelivery),
+		},
+		Events: &memoryEvents{table: newTable(func(e models.UserEvent) string { return e.ID }, nil)},
+	}
+}
+
+// table - Bảng in-memory an toàn cho nhiều goroutine; giữ thứ tự chèn để kết quả ổn định.
+// Giá trị được sao chép khi ghi và đọc nên caller không sửa được dữ liệu đã lưu
+type table[T any] struct {
+	mu    sync.RWMutex
+	items map[string]T
+	order []string
+	id    func(T) string
+	clone func(T) T
+}
+
+func newTable[T any](id func(T) string, clone func(T) T) *table[T] {
+	if clone == nil {
+		clone = func(v T) T { return v }
+	}
+	return &table[T]{items: map[string]T{}, id: id, clone: clone}
+}
+
+// insert - ErrDuplicate khi trùng ID hoặc khi conflict trả true với một bản ghi đã có
+func (t *table[T]) insert(item T, conflict func(existing T) bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.id(item)
+	if _, ok := t.items[id]; ok {
+		return ErrDuplicate
+	}
+	if conflict != nil {
+		for _, existing := range t.items {
+			if conflict(existing) {
+				return ErrDuplicate
+			}
+		}
+	}
+	t.items[id] = t.clone(item)
+	t.order = append(t.order, id)
+	return nil
+}
+
+// find - Bản ghi đầu tiên khớp, ErrNotFound nếu không có
+func (t *table[T]) find(match func(T) bool) (*T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, id := range t.order {
+		if item := t.items[id]; match(item) {
+			found := t.clone(item)
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// filter - Mọi bản ghi khớp theo thứ tự chèn; không có thì trả slice rỗng
+func (t *table[T]) filter(match func(T) bool) []T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := []T{}
+	for _, id := range t.order {
+		if item := t.items[id]; match(item) {
+			result = append(result, t.clone(item))
+		}
+	}
+	return result
+}
+
+// update - Sửa bản ghi khớp đầu tiên trong lock; apply lỗi hoặc conflict với bản ghi khác
+// (trả ErrDuplicate) thì bản ghi giữ nguyên
+func (t *table[T]) update(match func(T) bool, apply func(*T) error, conflict func(updated, existing T) bool) (*T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range t.order {
+		item := t.items[id]
+		if !match(item) {
+			continue
+		}
+		updated := t.clone(item)
+		if err := apply(&updated); err != nil {
+			return nil, err
+		}
+		if conflict != nil {
+			for otherID, other := range t.items {
+				if otherID != id && conflict(updated, other) {
+					return nil, ErrDuplicate
+				}
+			}
+		}
+		t.items[id] = updated
+		result := t.clone(updated)
+		return &result, nil
+	}
+	return nil, ErrNotFound
+}
+
+// remove - Xóa các bản ghi khớp (tối đa limit, 0 = không giới hạn) và trả về các bản ghi đã xóa
+func (t *table[T]) remove(match func(T) bool, limit int) []T {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var removed []T
+	kept := t.order[:0]
+	for _, id := range t.order {
+		item := t.items[id]
+		if (limit <= 0 || len(removed) < limit) && match(item) {
+			removed = append(removed, item)
+			delete(t.items, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	t.order = kept
+	return removed
+}
+
+// paginate - Trang [skip, skip+limit) của items (limit 0 = không giới hạn); ngoài phạm vi trả slice rỗng
+func paginate[T any](items []T, skip, limit int) []T {
+	if skip >= len(items) {
+		return []T{}
+	}
+	items = items[skip:]
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+func cloneUser(u models.User) models.User {
+	if u.EmailVerified != nil {
+		verified := *u.EmailVerified
+		u.EmailVerified = &verified
+	}
+	u.RecoveryCodes = cloneStrings(u.RecoveryCodes)
+	return u
+}
+
+func cloneMedia(m models.Media) models.Media {
+	m.Tags = cloneStrings(m.Tags)
+	if m.Metadata != nil {
+		metadata := *m.Metadata
+		metadata.Keywords = cloneStrings(metadata.Keywords)
+		m.Metadata = &metadata
+	}
+	return m
+}
+
+func cloneAccessToken(t models.AccessToken) models.AccessToken {
+	t.Scopes = cloneStrings(t.Scopes)
+	return t
+}
+
+func cloneAuditEvent(e models.AuditEvent) models.AuditEvent {
+	if e.Diff != nil {
+		diff := make(map[string]models.AuditChange, len(e.Diff))
+		for k, v := range e.Diff {
+			diff[k] = v
+		}
+		e.Diff = diff
+	}
+	if e.Details != nil {
+		details := make(map[string]string, len(e.Details))
+		for k, v := range e.Details {
+			details[k] = v
+		}
+		e.Details = details
+	}
+	return e
+}
+
+func cloneWebhook(w models.Webhook) models.Webhook {
+	w.Events = cloneStrings(w.Events)
+	return w
+}
+
+func cloneWebhookDelivery(d models.WebhookDelivery) models.WebhookDelivery {
+	if d.Attempts != nil {
+		d.Attempts = append([]models.WebhookAttempt(nil), d.Attempts...)
+	}
+	return d
+}
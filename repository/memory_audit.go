@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryAudit struct {
+	// appendMu - Giữ đọc event cuối và chèn event mới là một bước
+	appendMu sync.Mutex
+	table    *table[models.AuditEvent]
+}
+
+func (r *memoryAudit) Append(ctx context.Context, event *models.AuditEvent) error {
+	r.appendMu.Lock()
+	defer r.appendMu.Unlock()
+
+	event.Seq, event.PrevHash = 1, ""
+	if events := r.table.filter(func(models.AuditEvent) bool { return true }); len(events) > 0 {
+		last := events[len(events)-1]
+		event.Seq, event.PrevHash = last.Seq+1, last.Hash
+	}
+	event.Hash = event.ComputeHash()
+	return r.table.insert(*event, nil)
+}
+
+func (q AuditQuery) matches(e models.AuditEvent) bool {
+	return (q.Action == "" || e.Action == q.Action) &&
+		(q.ActorID == "" || e.ActorID == q.ActorID) &&
+		(q.TargetType == "" || e.TargetType == q.TargetType) &&
+		(q.TargetID == "" || e.TargetID == q.TargetID) &&
+		(q.From == 0 || e.CreatedAt >= q.From) &&
+		(q.To == 0 || e.CreatedAt < q.To)
+}
+
+func (r *memoryAudit) Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, int64, error) {
+	events := r.table.filter(query.matches)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Seq > events[j].Seq })
+	return paginate(events, query.Skip, query.Limit), int64(len(events)), nil
+}
+
+func (r *memoryAudit) Chain(ctx context.Context, after int64, limit int) ([]models.AuditEvent, error) {
+	events := r.table.filter(func(e models.AuditEvent) bool { return e.Seq > after })
+	return paginate(events, 0, limit), nil
+}
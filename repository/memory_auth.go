@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryUserTokens struct {
+	table *table[models.UserToken]
+}
+
+func (r *memoryUserTokens) Create(ctx context.Context, token *models.UserToken) error {
+	return r.table.insert(*token, func(existing models.UserToken) bool {
+		return existing.TokenHash == token.TokenHash
+	})
+}
+
+func (r *memoryUserTokens) DeleteUnused(ctx context.Context, userID, purpose string) error {
+	r.table.remove(func(t models.UserToken) bool {
+		return t.UserID == userID && t.Purpose == purpose && t.UsedAt == 0
+	}, 0)
+	return nil
+}
+
+func (r *memoryUserTokens) Consume(ctx context.Context, tokenHash, purpose string, now int64) (*models.UserToken, error) {
+	return r.table.update(func(t models.UserToken) bool {
+		return t.TokenHash == tokenHash && t.Purpose == purpose && t.UsedAt == 0 && t.ExpiresAt > now
+	}, func(t *models.UserToken) error {
+		t.UsedAt = now
+		return nil
+	}, nil)
+}
+
+type memorySessions struct {
+	table *table[models.Session]
+}
+
+func (r *memorySessions) Create(ctx context.Context, session *models.Session) error {
+	return r.table.insert(*session, nil)
+}
+
+func (r *memorySessions) Get(ctx context.Context, userID, id string) (*models.Session, error) {
+	return r.table.find(func(s models.Session) bool { return s.ID == id && s.UserID == userID })
+}
+
+func (r *memorySessions) ListActive(ctx context.Context, userID string, now int64) ([]models.Session, error) {
+	sessions := r.table.filter(func(s models.Session) bool { return s.UserID == userID && s.ExpiresAt > now })
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].LastSeenAt > sessions[j].LastSeenAt })
+	return sessions, nil
+}
+
+func (r *memorySessions) Touch(ctx context.Context, id, ip string, now int64) error {
+	_, err := r.table.update(func(s models.Session) bool { return s.ID == id }, func(s *models.Session) error {
+		s.LastSeenAt = now
+		s.IP = ip
+		return nil
+	}, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (r *memorySessions) Delete(ctx context.Context, userID, id string) error {
+	if len(r.table.remove(func(s models.Session) bool { return s.ID == id && s.UserID == userID }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memorySessions) DeleteAll(ctx context.Context, userID, exceptID string) (int64, error) {
+	removed := r.table.remove(func(s models.Session) bool { return s.UserID == userID && s.ID != exceptID }, 0)
+	return int64(len(removed)), nil
+}
+
+type memoryAccessTokens struct {
+	table *table[models.AccessToken]
+}
+
+func (r *memoryAccessTokens) Create(ctx context.Context, token *models.AccessToken) error {
+	return r.table.insert(*token, func(existing models.AccessToken) bool {
+		return existing.TokenHash == token.TokenHash
+	})
+}
+
+func (r *memoryAccessTokens) GetByHash(ctx context.Context, tokenHash string) (*models.AccessToken, error) {
+	return r.table.find(func(t models.AccessToken) bool { return t.TokenHash == tokenHash })
+}
+
+func (r *memoryAccessTokens) ListByUser(ctx context.Context, userID string) ([]models.AccessToken, error) {
+	tokens := r.table.filter(func(t models.AccessToken) bool { return t.UserID == userID })
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedAt > tokens[j].CreatedAt })
+	return tokens, nil
+}
+
+func (r *memoryAccessTokens) CountByUser(ctx context.Context, userID string) (int64, error) {
+	return int64(len(r.table.filter(func(t models.AccessToken) bool { return t.UserID == userID }))), nil
+}
+
+func (r *memoryAccessTokens) Touch(ctx context.Context, id, ip string, now int64) error {
+	_, err := r.table.update(func(t models.AccessToken) bool { return t.ID == id }, func(t *models.AccessToken) error {
+		t.LastUsedAt = now
+		t.LastUsedIP = ip
+		return nil
+	}, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (r *memoryAccessTokens) Delete(ctx context.Context, userID, id string) error {
+	if len(r.table.remove(func(t models.AccessToken) bool { return t.ID == id && t.UserID == userID }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryIdentities struct {
+	table *table[models.ExternalIdentity]
+}
+
+func (r *memoryIdentities) Create(ctx context.Context, identity *models.ExternalIdentity) error {
+	return r.table.insert(*identity, func(existing models.ExternalIdentity) bool {
+		return existing.Provider == identity.Provider && existing.Subject == identity.Subject
+	})
+}
+
+func (r *memoryIdentities) RecordLogin(ctx context.Context, provider, subject, email string, now int64) (*models.ExternalIdentity, error) {
+	return r.table.update(func(i models.ExternalIdentity) bool {
+		return i.Provider == provider && i.Subject == subject
+	}, func(i *models.ExternalIdentity) error {
+		i.LastLoginAt = now
+		i.Email = email
+		return nil
+	}, nil)
+}
+
+func (r *memoryIdentities) ListByUser(ctx context.Context, userID string) ([]models.ExternalIdentity, error) {
+	return r.table.filter(func(i models.ExternalIdentity) bool { return i.UserID == userID }), nil
+}
+
+func (r *memoryIdentities) CountByUser(ctx context.Context, userID string) (int64, error) {
+	return int64(len(r.table.filter(func(i models.ExternalIdentity) bool { return i.UserID == userID }))), nil
+}
+
+func (r *memoryIdentities) Delete(ctx context.Context, userID, id string) error {
+	if len(r.table.remove(func(i models.ExternalIdentity) bool { return i.ID == id && i.UserID == userID }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryOIDCStates struct {
+	table *table[models.OIDCState]
+}
+
+func (r *memoryOIDCStates) Create(ctx context.Context, state *models.OIDCState) error {
+	return r.table.insert(*state, nil)
+}
+
+func (r *memoryOIDCStates) Consume(ctx context.Context, id, provider string, now int64) (*models.OIDCState, error) {
+	removed := r.table.remove(func(s models.OIDCState) bool {
+		return s.ID == id && s.Provider == provider && s.ExpiresAt > now
+	}, 1)
+	if len(removed) == 0 {
+		return nil, ErrNotFound
+	}
+	return &removed[0], nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryEvents struct {
+	// appendMu - Giữ tăng seq và chèn sự kiện mới là một bước
+	appendMu sync.Mutex
+	// seq - Seq đã cấp cuối cùng; như counter của Mongo, không giảm khi sự kiện bị xóa
+	seq   int64
+	table *table[models.UserEvent]
+}
+
+func (r *memoryEvents) Append(ctx context.Context, event *models.UserEvent) error {
+	r.appendMu.Lock()
+	defer r.appendMu.Unlock()
+
+	event.Seq = r.seq + 1
+	if err := r.table.insert(*event, nil); err != nil {
+		return err
+	}
+	r.seq = event.Seq
+	return nil
+}
+
+func (r *memoryEvents) After(ctx context.Context, userID string, after int64, limit int) ([]models.UserEvent, error) {
+	events := r.table.filter(func(e models.UserEvent) bool {
+		return e.Seq > after && (userID == "" || e.UserID == userID)
+	})
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return paginate(events, 0, limit), nil
+}
+
+func (r *memoryEvents) LastSeq(ctx context.Context) (int64, error) {
+	r.appendMu.Lock()
+	defer r.appendMu.Unlock()
+	return r.seq, nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryAlbums struct {
+	table *table[models.Album]
+}
+
+func (r *memoryAlbums) Create(ctx context.Context, album *models.Album) error {
+	return r.table.insert(*album, nil)
+}
+
+func (r *memoryAlbums) Get(ctx context.Context, userID, id string) (*models.Album, error) {
+	return r.table.find(func(a models.Album) bool { return a.ID == id && a.UserID == userID })
+}
+
+func (r *memoryAlbums) ListByUser(ctx context.Context, userID string) ([]models.Album, error) {
+	return r.table.filter(func(a models.Album) bool { return a.UserID == userID }), nil
+}
+
+type memoryMedia struct {
+	table *table[models.Media]
+}
+
+func (r *memoryMedia) Create(ctx context.Context, media *models.Media) error {
+	return r.table.insert(*media, nil)
+}
+
+func (r *memoryMedia) Get(ctx context.Context, id string) (*models.Media, error) {
+	return r.table.find(func(m models.Media) bool { return m.ID == id })
+}
+
+func (r *memoryMedia) Delete(ctx context.Context, userID, id string) error {
+	if len(r.table.remove(func(m models.Media) bool { return m.ID == id && m.UserID == userID }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryMedia) Find(ctx context.Context, query MediaQuery) ([]models.Media, int64, error) {
+	match := func(m models.Media) bool { return m.UserID == query.UserID && m.AlbumID == query.AlbumID }
+	if query.Rules != nil {
+		if err := ValidateSmartRule(query.Rules); err != nil {
+			return nil, 0, err
+		}
+		match = func(m models.Media) bool { return m.UserID == query.UserID && matchSmartRule(&m, query.Rules) }
+	}
+
+	media := r.table.filter(match)
+
+	// Cùng thứ tự với Mongo: ảnh có ngày chụp mới hơn trước, không có ngày chụp xếp cuối, rồi theo ngày upload
+	sort.SliceStable(media, func(i, j int) bool {
+		ti, oki := mediaNumber(&media[i], "captured_at")
+		tj, okj := mediaNumber(&media[j], "captured_at")
+		if oki != okj {
+			return oki
+		}
+		if ti != tj {
+			return ti > tj
+		}
+		return media[i].CreatedAt > media[j].CreatedAt
+	})
+
+	return paginate(media, query.Skip, query.Limit), int64(len(media)), nil
+}
+
+// matchSmartRule - Đánh giá cây rule (đã validate) trên một media, cùng ngữ nghĩa với filter Mongo
+func matchSmartRule(m *models.Media, rule *models.SmartRule) bool {
+	if rule.Match != "" {
+		for i := range rule.Rules {
+			matched := matchSmartRule(m, &rule.Rules[i])
+			switch {
+			case rule.Match == "all" && !matched:
+				return false
+			case rule.Match == "any" && matched:
+				return true
+			case rule.Match == "none" && matched:
+				return false
+			}
+		}
+		return rule.Match != "any"
+	}
+
+	field := smartFields[rule.Field]
+	if rule.Op == "exists" {
+		want, _ := rule.Value.(bool)
+		return mediaHasField(m, rule.Field, field.kind) == want
+	}
+
+	switch field.kind {
+	case smartFieldString:
+		value, ok := mediaString(m, rule.Field)
+		return matchString(rule, value, ok)
+	case smartFieldTags:
+		return matchTags(rule, mediaTags(m, rule.Field))
+	default:
+		value, ok := mediaNumber(m, rule.Field)
+		return matchNumber(rule, field, value, ok)
+	}
+}
+
+func matchString(rule *models.SmartRule, value string, ok bool) bool {
+	switch rule.Op {
+	case "ne":
+		want, _ := smartString(rule)
+		return !ok || value != want
+	case "in":
+		values, _ := smartStringList(rule)
+		return ok && containsString(values, value)
+	case "contains":
+		want, _ := smartString(rule)
+		return ok && strings.Contains(strings.ToLower(value), strings.ToLower(want))
+	default:
+		want, _ := smartString(rule)
+		return ok && value == want
+	}
+}
+
+func matchTags(rule *models.SmartRule, tags []string) bool {
+	switch rule.Op {
+	case "in", "all":
+		values, _ := smartStringList(rule)
+		for _, value := range values {
+			found := containsString(tags, value)
+			if rule.Op == "in" && found {
+				return true
+			}
+			if rule.Op == "all" && !found {
+				return false
+			}
+		}
+		return rule.Op == "all"
+	default:
+		want, _ := smartString(rule)
+		return containsString(tags, want)
+	}
+}
+
+func matchNumber(rule *models.SmartRule, field smartField, value float64, ok bool) bool {
+	if rule.Op == "between" {
+		values, _ := smartList(rule.Value)
+		from, _ := smartNumber(values[0], field)
+		to, _ := smartNumber(values[1], field)
+		return ok && value >= from && value <= to
+	}
+
+	want, _ := smartNumber(rule.Value, field)
+	switch rule.Op {
+	case "ne":
+		return !ok || value != want
+	case "gt":
+		return ok && value > want
+	case "gte":
+		return ok && value >= want
+	case "lt":
+		return ok && value < want
+	case "lte":
+		return ok && value <= want
+	default:
+		return ok && value == want
+	}
+}
+
+// mediaHasField - Field có trong document hay không; giá trị rỗng của field omitempty không được lưu
+func mediaHasField(m *models.Media, name string, kind smartFieldKind) bool {
+	switch kind {
+	case smartFieldString:
+		_, ok := mediaString(m, name)
+		return ok
+	case smartFieldTags:
+		return len(mediaTags(m, name)) > 0
+	default:
+		_, ok := mediaNumber(m, name)
+		return ok
+	}
+}
+
+func mediaString(m *models.Media, name string) (string, bool) {
+	if name == "type" {
+		// type luôn được lưu, kể cả rỗng
+		return m.Type, true
+	}
+
+	var value string
+	metadata := m.Metadata
+	if metadata == nil {
+		metadata = &models.ImageMetadata{}
+	}
+	location := metadata.Location
+	if location == nil {
+		location = &models.LocationInfo{}
+	}
+
+	switch name {
+	case "title":
+		value = m.Title
+	case "camera_make":
+		value = metadata.CameraMake
+	case "camera_model":
+		value = metadata.CameraModel
+	case "lens_model":
+		value = metadata.LensModel
+	case "country":
+		value = location.Country
+	case "state":
+		value = location.State
+	case "city":
+		value = location.City
+	}
+	return value, value != ""
+}
+
+func mediaTags(m *models.Media, name string) []string {
+	if name == "keyword" {
+		if m.Metadata == nil {
+			return nil
+		}
+		return m.Metadata.Keywords
+	}
+	return m.Tags
+}
+
+func mediaNumber(m *models.Media, name string) (float64, bool) {
+	var value float64
+	switch name {
+	case "uploaded_at":
+		// created_at luôn được lưu
+		return float64(m.CreatedAt), true
+	case "duration":
+		value = m.Duration
+	case "size":
+		value = float64(m.Size)
+	case "rating", "iso", "captured_at":
+		if m.Metadata == nil {
+			return 0, false
+		}
+		switch name {
+		case "rating":
+			value = float64(m.Metadata.Rating)
+		case "iso":
+			value = float64(m.Metadata.ISO)
+		default:
+			value = float64(m.Metadata.DateTime)
+		}
+	}
+	return value, value != 0
+}
+
+type memoryShareLinks struct {
+	table *table[models.ShareLink]
+}
+
+func (r *memoryShareLinks) Create(ctx context.Context, link *models.ShareLink) error {
+	return r.table.insert(*link, nil)
+}
+
+func (r *memoryShareLinks) Get(ctx context.Context, id string) (*models.ShareLink, error) {
+	return r.table.find(func(l models.ShareLink) bool { return l.ID == id })
+}
+
+func (r *memoryShareLinks) Delete(ctx context.Context, userID, id string) error {
+	if len(r.table.remove(func(l models.ShareLink) bool { return l.ID == id && l.UserID == userID }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryFailedLogins struct {
+	table *table[models.FailedLogin]
+}
+
+func (r *memoryFailedLogins) Create(ctx context.Context, failure *models.FailedLogin) error {
+	return r.table.insert(*failure, nil)
+}
+
+type memoryJobRuns struct {
+	table *table[models.JobRun]
+}
+
+func (r *memoryJobRuns) Create(ctx context.Context, run *models.JobRun) error {
+	return r.table.insert(*run, nil)
+}
+
+// memoryStats - Tính thống kê trực tiếp trên các bảng in-memory, cùng kết quả với pipeline Mongo
+type memoryStats struct {
+	users        *table[models.User]
+	media        *table[models.Media]
+	failedLogins *table[models.FailedLogin]
+	jobRuns      *table[models.JobRun]
+}
+
+// day - Ngày UTC của created_at, cùng định dạng với $dateToString trong pipeline Mongo
+func day(createdAt int64) string {
+	return time.Unix(createdAt, 0).UTC().Format("2006-01-02")
+}
+
+func (r StatsRange) contains(createdAt int64) bool {
+	return createdAt >= r.From && createdAt < r.To
+}
+
+func (s *memoryStats) Users(ctx context.Context, r StatsRange) (*models.UserStats, error) {
+	stats := &models.UserStats{SignupsPerDay: []models.DailyCount{}}
+	perDay := map[string]int64{}
+	for _, u := range s.users.filter(func(u models.User) bool { return u.CreatedAt < r.To }) {
+		stats.Total++
+		if u.Role == "admin" {
+			stats.Admins++
+		}
+		if !u.IsEmailVerified() {
+			stats.Unverified++
+		}
+		if u.TwoFactorEnabled {
+			stats.TwoFactor++
+		}
+		if u.CreatedAt >= r.From {
+			stats.Signups++
+			perDay[day(u.CreatedAt)]++
+		}
+	}
+	for date, count := range perDay {
+		stats.SignupsPerDay = append(stats.SignupsPerDay, models.DailyCount{Date: date, Count: count})
+	}
+	sort.Slice(stats.SignupsPerDay, func(i, j int) bool { return stats.SignupsPerDay[i].Date < stats.SignupsPerDay[j].Date })
+	return stats, nil
+}
+
+func (s *memoryStats) Uploads(ctx context.Context, r StatsRange) ([]models.UploadsPerDay, error) {
+	type key struct{ date, kind string }
+	groups := map[key]*models.UploadsPerDay{}
+	for _, m := range s.media.filter(func(m models.Media) bool { return r.contains(m.CreatedAt) }) {
+		k := key{day(m.CreatedAt), m.Type}
+		if groups[k] == nil {
+			groups[k] = &models.UploadsPerDay{Date: k.date, Type: k.kind}
+		}
+		groups[k].Count++
+		groups[k].Bytes += m.Size
+	}
+	result := []models.UploadsPerDay{}
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date < result[j].Date
+		}
+		return result[i].Type < result[j].Type
+	})
+	return result, nil
+}
+
+func (s *memoryStats) Storage(ctx context.Context, r StatsRange, limit int) ([]models.StorageUsage, error) {
+	groups := map[string]*models.StorageUsage{}
+	for _, m := range s.media.filter(func(m models.Media) bool { return r.contains(m.CreatedAt) }) {
+		if groups[m.UserID] == nil {
+			groups[m.UserID] = &models.StorageUsage{UserID: m.UserID}
+		}
+		g := groups[m.UserID]
+		g.Files++
+		g.Bytes += m.Size
+		switch m.Type {
+		case "image":
+			g.Images++
+		case "video":
+			g.Videos++
+		}
+	}
+	result := []models.StorageUsage{}
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Bytes != result[j].Bytes {
+			return result[i].Bytes > result[j].Bytes
+		}
+		return result[i].UserID < result[j].UserID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	for i := range result {
+		if u, err := s.users.find(func(u models.User) bool { return u.ID == result[i].UserID }); err == nil {
+			result[i].Name, result[i].Email = u.Name, u.Email
+		}
+	}
+	return result, nil
+}
+
+func (s *memoryStats) Cameras(ctx context.Context, r StatsRange, limit int) ([]models.CameraCount, error) {
+	type key struct{ make, model string }
+	counts := map[key]int64{}
+	for _, m := range s.media.filter(func(m models.Media) bool {
+		return r.contains(m.CreatedAt) && m.Type == "image" && m.Metadata != nil && m.Metadata.CameraModel != ""
+	}) {
+		counts[key{m.Metadata.CameraMake, m.Metadata.CameraModel}]++
+	}
+	result := []models.CameraCount{}
+	for k, count := range counts {
+		result = append(result, models.CameraCount{Make: k.make, Model: k.model, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Make != result[j].Make {
+			return result[i].Make < result[j].Make
+		}
+		return result[i].Model < result[j].Model
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (s *memoryStats) FailedLogins(ctx context.Context, r StatsRange) ([]models.FailedLoginsPerDay, error) {
+	type key struct{ date, reason string }
+	counts := map[key]int64{}
+	for _, f := range s.failedLogins.filter(func(f models.FailedLogin) bool { return r.contains(f.CreatedAt) }) {
+		counts[key{day(f.CreatedAt), f.Reason}]++
+	}
+	result := []models.FailedLoginsPerDay{}
+	for k, count := range counts {
+		result = append(result, models.FailedLoginsPerDay{Date: k.date, Reason: k.reason, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date < result[j].Date
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result, nil
+}
+
+func (s *memoryStats) JobRuns(ctx context.Context, r StatsRange) ([]models.JobRunsPerDay, error) {
+	type key struct{ date, job string }
+	groups := map[key]*models.JobRunsPerDay{}
+	totals := map[key]float64{}
+	for _, run := range s.jobRuns.filter(func(run models.JobRun) bool { return r.contains(run.CreatedAt) }) {
+		k := key{day(run.CreatedAt), run.Job}
+		if groups[k] == nil {
+			groups[k] = &models.JobRunsPerDay{Date: k.date, Job: k.job}
+		}
+		g := groups[k]
+		g.Runs++
+		if run.Status == models.JobStatusFailed {
+			g.Failures++
+		}
+		totals[k] += run.DurationMs
+		if run.DurationMs > g.MaxDurationMs {
+			g.MaxDurationMs = run.DurationMs
+		}
+	}
+	result := []models.JobRunsPerDay{}
+	for k, g := range groups {
+		g.AvgDurationMs = totals[k] / float64(g.Runs)
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date < result[j].Date
+		}
+		return result[i].Job < result[j].Job
+	})
+	return result, nil
+}
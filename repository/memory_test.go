@@ -0,0 +1,9 @@
+package repository
+
+import "testing"
+
+func TestMemoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T) *Repositories {
+		return NewMemory()
+	})
+}
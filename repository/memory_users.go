@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryUsers struct {
+	table *table[models.User]
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	return r.table.insert(*user, func(existing models.User) bool {
+		return existing.Email == user.Email
+	})
+}
+
+func (r *memoryUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return r.table.find(func(u models.User) bool { return u.ID == id })
+}
+
+func (r *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.table.find(func(u models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUsers) List(ctx context.Context) ([]models.User, error) {
+	return r.table.filter(func(models.User) bool { return true }), nil
+}
+
+func (r *memoryUsers) Search(ctx context.Context, term string) ([]models.User, error) {
+	term = strings.ToLower(term)
+	return r.table.filter(func(u models.User) bool {
+		return strings.Contains(strings.ToLower(u.Name), term) || strings.Contains(strings.ToLower(u.Email), term)
+	}), nil
+}
+
+func (r *memoryUsers) Update(ctx context.Context, id string, changes UserChanges) error {
+	_, err := r.table.update(func(u models.User) bool { return u.ID == id }, func(u *models.User) error {
+		if changes.Name != nil {
+			u.Name = *changes.Name
+		}
+		if changes.Email != nil {
+			u.Email = *changes.Email
+		}
+		if changes.PendingEmail != nil {
+			u.PendingEmail = *changes.PendingEmail
+		}
+		if changes.Phone != nil {
+			u.Phone = *changes.Phone
+		}
+		if changes.Avatar != nil {
+			u.Avatar = *changes.Avatar
+		}
+		if changes.StripMetadata != nil {
+			u.StripMetadata = *changes.StripMetadata
+		}
+		if changes.Password != nil {
+			u.Password = *changes.Password
+		}
+		if changes.EmailVerified != nil {
+			verified := *changes.EmailVerified
+			u.EmailVerified = &verified
+		}
+		if changes.TwoFactorEnabled != nil {
+			u.TwoFactorEnabled = *changes.TwoFactorEnabled
+		}
+		if changes.TwoFactorSecret != nil {
+			u.TwoFactorSecret = *changes.TwoFactorSecret
+		}
+		if changes.TwoFactorPendingSecret != nil {
+			u.TwoFactorPendingSecret = *changes.TwoFactorPendingSecret
+		}
+		if changes.TwoFactorLastStep != nil {
+			u.TwoFactorLastStep = *changes.TwoFactorLastStep
+		}
+		if changes.RecoveryCodes != nil {
+			u.RecoveryCodes = cloneStrings(*changes.RecoveryCodes)
+		}
+		u.UpdatedAt = time.Now().Unix()
+		return nil
+	}, func(updated, existing models.User) bool {
+		return updated.Email == existing.Email
+	})
+	return err
+}
+
+func (r *memoryUsers) Delete(ctx context.Context, id string) error {
+	if len(r.table.remove(func(u models.User) bool { return u.ID == id }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryUsers) AdvanceTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	_, err := r.table.update(func(u models.User) bool {
+		return u.ID == id && u.TwoFactorLastStep < step
+	}, func(u *models.User) error {
+		u.TwoFactorLastStep = step
+		return nil
+	}, nil)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *memoryUsers) ConsumeRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	_, err := r.table.update(func(u models.User) bool {
+		return u.ID == id && containsString(u.RecoveryCodes, hash)
+	}, func(u *models.User) error {
+		kept := u.RecoveryCodes[:0]
+		for _, code := range u.RecoveryCodes {
+			if code != hash {
+				kept = append(kept, code)
+			}
+		}
+		u.RecoveryCodes = kept
+		return nil
+	}, nil)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+type memoryWebhooks struct {
+	table *table[models.Webhook]
+}
+
+func (r *memoryWebhooks) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.table.insert(*webhook, nil)
+}
+
+func (r *memoryWebhooks) Get(ctx context.Context, id string) (*models.Webhook, error) {
+	return r.table.find(func(w models.Webhook) bool { return w.ID == id })
+}
+
+func (r *memoryWebhooks) List(ctx context.Context) ([]models.Webhook, error) {
+	webhooks := r.table.filter(func(models.Webhook) bool { return true })
+	sort.SliceStable(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt > webhooks[j].CreatedAt })
+	return webhooks, nil
+}
+
+func (r *memoryWebhooks) ListForEvent(ctx context.Context, event string) ([]models.Webhook, error) {
+	return r.table.filter(func(w models.Webhook) bool {
+		return w.Enabled && slices.Contains(w.Events, event)
+	}), nil
+}
+
+func (r *memoryWebhooks) Update(ctx context.Context, id string, changes WebhookChanges) (*models.Webhook, error) {
+	return r.table.update(func(w models.Webhook) bool { return w.ID == id }, func(w *models.Webhook) error {
+		if changes.URL != nil {
+			w.URL = *changes.URL
+		}
+		if changes.Events != nil {
+			w.Events = cloneStrings(*changes.Events)
+		}
+		if changes.Description != nil {
+			w.Description = *changes.Description
+		}
+		if changes.Enabled != nil {
+			w.Enabled = *changes.Enabled
+			if w.Enabled {
+				w.DisabledReason = ""
+				w.ConsecutiveFailures = 0
+			}
+		}
+		w.UpdatedAt = time.Now().Unix()
+		return nil
+	}, nil)
+}
+
+func (r *memoryWebhooks) Delete(ctx context.Context, id string) error {
+	if len(r.table.remove(func(w models.Webhook) bool { return w.ID == id }, 1)) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryWebhooks) RecordSuccess(ctx context.Context, id string) error {
+	r.table.update(func(w models.Webhook) bool { return w.ID == id }, func(w *models.Webhook) error {
+		w.ConsecutiveFailures = 0
+		return nil
+	}, nil)
+	return nil
+}
+
+func (r *memoryWebhooks) RecordFailure(ctx context.Context, id string, disableAfter int, reason string) (bool, error) {
+	disabled := false
+	_, err := r.table.update(func(w models.Webhook) bool { return w.ID == id }, func(w *models.Webhook) error {
+		w.ConsecutiveFailures++
+		if w.Enabled && w.ConsecutiveFailures >= disableAfter {
+			w.Enabled = false
+			w.DisabledReason = reason
+			w.UpdatedAt = time.Now().Unix()
+			disabled = true
+		}
+		return nil
+	}, nil)
+	return disabled, err
+}
+
+type memoryWebhookDeliveries struct {
+	table *table[models.WebhookDelivery]
+}
+
+func (r *memoryWebhookDeliveries) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.Attempts == nil {
+		delivery.Attempts = []models.WebhookAttempt{}
+	}
+	return r.table.insert(*delivery, nil)
+}
+
+func (r *memoryWebhookDeliveries) Get(ctx context.Context, webhookID, id string) (*models.WebhookDelivery, error) {
+	return r.table.find(func(d models.WebhookDelivery) bool { return d.ID == id && d.WebhookID == webhookID })
+}
+
+func (r *memoryWebhookDeliveries) ListByWebhook(ctx context.Context, webhookID string, skip, limit int) ([]models.WebhookDelivery, int64, error) {
+	deliveries := r.table.filter(func(d models.WebhookDelivery) bool { return d.WebhookID == webhookID })
+	slices.Reverse(deliveries)
+	sort.SliceStable(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt > deliveries[j].CreatedAt })
+	return paginate(deliveries, skip, limit), int64(len(deliveries)), nil
+}
+
+func (r *memoryWebhookDeliveries) due(now int64) func(models.WebhookDelivery) bool {
+	return func(d models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliveryPending && d.NextAttemptAt <= now
+	}
+}
+
+func (r *memoryWebhookDeliveries) ClaimDue(ctx context.Context, now, leaseUntil int64) (*models.WebhookDelivery, error) {
+	due := r.table.filter(r.due(now))
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt < due[j].NextAttemptAt })
+	for _, candidate := range due {
+		// Kiểm tra lại trong lock: worker khác có thể vừa lấy delivery này
+		claimed, err := r.table.update(func(d models.WebhookDelivery) bool { return d.ID == candidate.ID && r.due(now)(d) },
+			func(d *models.WebhookDelivery) error {
+				d.NextAttemptAt = leaseUntil
+				return nil
+			}, nil)
+		if err != ErrNotFound {
+			return claimed, err
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryWebhookDeliveries) CountDue(ctx context.Context, now int64) (int64, error) {
+	return int64(len(r.table.filter(r.due(now)))), nil
+}
+
+func (r *memoryWebhookDeliveries) Record(ctx context.Context, id string, result WebhookDeliveryResult) error {
+	_, err := r.table.update(func(d models.WebhookDelivery) bool { return d.ID == id }, func(d *models.WebhookDelivery) error {
+		d.Status = result.Status
+		d.NextAttemptAt = result.NextAttemptAt
+		d.UpdatedAt = result.Now
+		d.Attempts = append(d.Attempts, result.Attempt)
+		if result.Status == models.WebhookDeliverySucceeded {
+			d.DeliveredAt = result.Now
+		}
+		return nil
+	}, nil)
+	return err
+}
+
+func (r *memoryWebhookDeliveries) DeleteByWebhook(ctx context.Context, webhookID string) error {
+	r.table.remove(func(d models.WebhookDelivery) bool { return d.WebhookID == webhookID }, 0)
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongo - Repository dùng MongoDB
+func NewMongo(db *mongo.Database) *Repositories {
+	return &Repositories{
+		Users:        &mongoUsers{col: db.Collection("users")},
+		Albums:       &mongoAlbums{col: db.Collection("albums")},
+		Media:        &mongoMedia{col: db.Collection("media")},
+		ShareLinks:   &mongoShareLinks{col: db.Collection("share_links")},
+		UserTokens:   &mongoUserTokens{col: db.Collection("user_tokens")},
+		Sessions:     &mongoSessions{col: db.Collection("sessions")},
+		AccessTokens: &mongoAccessTokens{col: db.Collection("access_tokens")},
+		Identities:   &mongoIdentities{col: db.Collection("external_identities")},
+		OIDCStates:   &mongoOIDCStates{col: db.Collection("oidc_states")},
+		FailedLogins: &mongoFailedLogins{col: db.Collection("failed_logins")},
+		JobRuns:      &mongoJobRuns{col: db.Collection("job_runs")},
+		Stats: &mongoStats{
+			users:        db.Collection("users"),
+			media:        db.Collection("media"),
+			failedLogins: db.Collection("failed_logins"),
+			jobRuns:      db.Collection("job_runs"),
+		},
+		Audit:             &mongoAudit{col: db.Collection("audit_events")},
+		Webhooks:          &mongoWebhooks{col: db.Collection("webhooks")},
+		WebhookDeliveries: &mongoWebhookDeliveries{col: db.Collection("webhook_deliveries")},
+		Events:            &mongoEvents{col: db.Collection("user_events"), counters: db.Collection("counters")},
+	}
+}
+
+// mapError - Chuyển lỗi của driver sang lỗi domain
+func mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return ErrDuplicate
+	default:
+		return err
+	}
+}
+
+// findOne - FindOne + Decode với lỗi đã được chuyển đổi
+func findOne[T any](ctx context.Context, col *mongo.Collection, filter bson.M) (*T, error) {
+	var doc T
+	if err := col.FindOne(ctx, filter).Decode(&doc); err != nil {
+		return nil, mapError(err)
+	}
+	return &doc, nil
+}
+
+// deleteOne - ErrNotFound khi không có document nào khớp
+func deleteOne(ctx context.Context, col *mongo.Collection, filter bson.M) error {
+	result, err := col.DeleteOne(ctx, filter)
+	if err != nil {
+		return mapError(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// containsPattern - Regex tìm chuỗi con, không phân biệt hoa thường, đã escape ký tự đặc biệt
+func containsPattern(term string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(term), Options: "i"}
+}
+
+// findAll - Find + cursor.All; kết quả rỗng là slice rỗng (không phải nil) để JSON trả []
+func findAll[T any](ctx context.Context, col *mongo.Collection, filter bson.M, opts ...*options.FindOptions) ([]T, error) {
+	cursor, err := col.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []T{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// aggregate - Aggregate + cursor.All; kết quả rỗng là slice rỗng (không phải nil) để JSON trả []
+func aggregate[T any](ctx context.Context, col *mongo.Collection, pipeline mongo.Pipeline) ([]T, error) {
+	cursor, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []T{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
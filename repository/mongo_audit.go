@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// auditRetryMax - Thời gian chờ tối đa giữa hai lần thử khi instance khác chèn cùng seq trước
+const auditRetryMax = 50 * time.Millisecond
+
+// mongoAudit - Unique index trên seq (migration 006) đảm bảo chuỗi không bị rẽ nhánh khi ghi đồng thời
+type mongoAudit struct {
+	col *mongo.Collection
+}
+
+// Append - Mỗi lần trùng seq nghĩa là một sự kiện khác vừa được ghi, nên thử lại (chờ ngẫu nhiên để các instance
+// không va nhau tiếp) tới khi ctx hết hạn thay vì bỏ sự kiện sau một số lần cố định; khi đó trả lỗi cho caller
+func (r *mongoAudit) Append(ctx context.Context, event *models.AuditEvent) error {
+	last := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}}).SetProjection(bson.M{"seq": 1, "hash": 1})
+	for attempt := 1; ; attempt++ {
+		var head models.AuditEvent
+		switch err := r.col.FindOne(ctx, bson.M{}, last).Decode(&head); {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			event.Seq, event.PrevHash = 1, ""
+		case err != nil:
+			return err
+		default:
+			event.Seq, event.PrevHash = head.Seq+1, head.Hash
+		}
+		event.Hash = event.ComputeHash()
+
+		_, err := r.col.InsertOne(ctx, event)
+		if !mongo.IsDuplicateKeyError(err) {
+			return mapError(err)
+		}
+
+		wait := min(time.Duration(attempt)*5*time.Millisecond, auditRetryMax)
+		timer := time.NewTimer(rand.N(wait) + time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("append audit event after %d attempts: %w", attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (q AuditQuery) filter() bson.M {
+	filter := bson.M{}
+	for field, value := range map[string]string{
+		"action": q.Action, "actor_id": q.ActorID, "target_type": q.TargetType, "target_id": q.TargetID,
+	} {
+		if value != "" {
+			filter[field] = value
+		}
+	}
+	createdAt := bson.M{}
+	if q.From != 0 {
+		createdAt["$gte"] = q.From
+	}
+	if q.To != 0 {
+		createdAt["$lt"] = q.To
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+	return filter
+}
+
+func (r *mongoAudit) Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, int64, error) {
+	filter := query.filter()
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: -1}}).SetSkip(int64(query.Skip))
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	events, err := findAll[models.AuditEvent](ctx, r.col, filter, opts)
+	return events, total, err
+}
+
+func (r *mongoAudit) Chain(ctx context.Context, after int64, limit int) ([]models.AuditEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return findAll[models.AuditEvent](ctx, r.col, bson.M{"seq": bson.M{"$gt": after}}, opts)
+}
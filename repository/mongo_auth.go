@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserTokens struct {
+	col *mongo.Collection
+}
+
+func (r *mongoUserTokens) Create(ctx context.Context, token *models.UserToken) error {
+	_, err := r.col.InsertOne(ctx, token)
+	return mapError(err)
+}
+
+func (r *mongoUserTokens) DeleteUnused(ctx context.Context, userID, purpose string) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{
+		"user_id": userID,
+		"purpose": purpose,
+		"used_at": bson.M{"$exists": false},
+	})
+	return mapError(err)
+}
+
+func (r *mongoUserTokens) Consume(ctx context.Context, tokenHash, purpose string, now int64) (*models.UserToken, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+
+	var token models.UserToken
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token); err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+type mongoSessions struct {
+	col *mongo.Collection
+}
+
+func (r *mongoSessions) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.col.InsertOne(ctx, session)
+	return mapError(err)
+}
+
+func (r *mongoSessions) Get(ctx context.Context, userID, id string) (*models.Session, error) {
+	return findOne[models.Session](ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
+
+func (r *mongoSessions) ListActive(ctx context.Context, userID string, now int64) ([]models.Session, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+	return findAll[models.Session](ctx, r.col, bson.M{"user_id": userID, "expires_at": bson.M{"$gt": now}}, opts)
+}
+
+func (r *mongoSessions) Touch(ctx context.Context, id, ip string, now int64) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_seen_at": now, "ip": ip}})
+	return mapError(err)
+}
+
+func (r *mongoSessions) Delete(ctx context.Context, userID, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
+
+func (r *mongoSessions) DeleteAll(ctx context.Context, userID, exceptID string) (int64, error) {
+	filter := bson.M{"user_id": userID}
+	if exceptID != "" {
+		filter["_id"] = bson.M{"$ne": exceptID}
+	}
+	result, err := r.col.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return result.DeletedCount, nil
+}
+
+type mongoAccessTokens struct {
+	col *mongo.Collection
+}
+
+func (r *mongoAccessTokens) Create(ctx context.Context, token *models.AccessToken) error {
+	_, err := r.col.InsertOne(ctx, token)
+	return mapError(err)
+}
+
+func (r *mongoAccessTokens) GetByHash(ctx context.Context, tokenHash string) (*models.AccessToken, error) {
+	return findOne[models.AccessToken](ctx, r.col, bson.M{"token_hash": tokenHash})
+}
+
+func (r *mongoAccessTokens) ListByUser(ctx context.Context, userID string) ([]models.AccessToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return findAll[models.AccessToken](ctx, r.col, bson.M{"user_id": userID}, opts)
+}
+
+func (r *mongoAccessTokens) CountByUser(ctx context.Context, userID string) (int64, error) {
+	count, err := r.col.CountDocuments(ctx, bson.M{"user_id": userID})
+	return count, mapError(err)
+}
+
+func (r *mongoAccessTokens) Touch(ctx context.Context, id, ip string, now int64) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": now, "last_used_ip": ip}})
+	return mapError(err)
+}
+
+func (r *mongoAccessTokens) Delete(ctx context.Context, userID, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
+
+type mongoIdentities struct {
+	col *mongo.Collection
+}
+
+func (r *mongoIdentities) Create(ctx context.Context, identity *models.ExternalIdentity) error {
+	_, err := r.col.InsertOne(ctx, identity)
+	return mapError(err)
+}
+
+func (r *mongoIdentities) RecordLogin(ctx context.Context, provider, subject, email string, now int64) (*models.ExternalIdentity, error) {
+	var identity models.ExternalIdentity
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"provider": provider, "subject": subject},
+		bson.M{"$set": bson.M{"last_login_at": now, "email": email}},
+		opts,
+	).Decode(&identity)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &identity, nil
+}
+
+func (r *mongoIdentities) ListByUser(ctx context.Context, userID string) ([]models.ExternalIdentity, error) {
+	return findAll[models.ExternalIdentity](ctx, r.col, bson.M{"user_id": userID})
+}
+
+func (r *mongoIdentities) CountByUser(ctx context.Context, userID string) (int64, error) {
+	count, err := r.col.CountDocuments(ctx, bson.M{"user_id": userID})
+	return count, mapError(err)
+}
+
+func (r *mongoIdentities) Delete(ctx context.Context, userID, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
+
+type mongoOIDCStates struct {
+	col *mongo.Collection
+}
+
+func (r *mongoOIDCStates) Create(ctx context.Context, state *models.OIDCState) error {
+	_, err := r.col.InsertOne(ctx, state)
+	return mapError(err)
+}
+
+func (r *mongoOIDCStates) Consume(ctx context.Context, id, provider string, now int64) (*models.OIDCState, error) {
+	var state models.OIDCState
+	err := r.col.FindOneAndDelete(ctx, bson.M{
+		"_id":        id,
+		"provider":   provider,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&state)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// eventAppendAttempts - Số lần thử lại khi seq lấy từ counter đã có sự kiện (counter chậm hơn dữ liệu)
+	eventAppendAttempts = 10
+	// eventCounterID - Document trong collection counters giữ seq đã cấp cuối cùng
+	eventCounterID = "user_events"
+)
+
+// mongoEvents - Seq lấy từ document counter (migration 009) không bao giờ bị TTL xóa, nên seq tiếp tục tăng
+// cả khi mọi sự kiện cũ đã hết hạn; client nối lại bằng Last-Event-ID cũ không bỏ lỡ sự kiện mới.
+// Unique index trên seq (migration 008) chặn hai sự kiện cùng seq
+type mongoEvents struct {
+	col      *mongo.Collection
+	counters *mongo.Collection
+}
+
+func (r *mongoEvents) Append(ctx context.Context, event *models.UserEvent) error {
+	var err error
+	for attempt := 0; attempt < eventAppendAttempts; attempt++ {
+		var counter struct {
+			Seq int64 `bson:"seq"`
+		}
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		err = r.counters.FindOneAndUpdate(ctx, bson.M{"_id": eventCounterID}, bson.M{"$inc": bson.M{"seq": 1}}, opts).
+			Decode(&counter)
+		if err != nil {
+			return err
+		}
+		event.Seq = counter.Seq
+
+		_, err = r.col.InsertOne(ctx, event)
+		if !mongo.IsDuplicateKeyError(err) {
+			return mapError(err)
+		}
+		// Counter bị tạo lại khi đã có sự kiện: đưa lên bằng seq lớn nhất rồi thử lại
+		var last int64
+		if last, err = r.maxEventSeq(ctx); err != nil {
+			return err
+		}
+		if _, err = r.counters.UpdateOne(ctx, bson.M{"_id": eventCounterID}, bson.M{"$max": bson.M{"seq": last}}); err != nil {
+			return err
+		}
+		err = ErrDuplicate
+	}
+	return err
+}
+
+func (r *mongoEvents) After(ctx context.Context, userID string, after int64, limit int) ([]models.UserEvent, error) {
+	filter := bson.M{"seq": bson.M{"$gt": after}}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return findAll[models.UserEvent](ctx, r.col, filter, opts)
+}
+
+func (r *mongoEvents) LastSeq(ctx context.Context) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := r.counters.FindOne(ctx, bson.M{"_id": eventCounterID}).Decode(&counter)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return r.maxEventSeq(ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// maxEventSeq - Seq lớn nhất còn trong collection, 0 khi trống
+func (r *mongoEvents) maxEventSeq(ctx context.Context) (int64, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}}).SetProjection(bson.M{"seq": 1})
+	var last models.UserEvent
+	err := r.col.FindOne(ctx, bson.M{}, opts).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last.Seq, nil
+}
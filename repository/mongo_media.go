@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoAlbums struct {
+	col *mongo.Collection
+}
+
+func (r *mongoAlbums) Create(ctx context.Context, album *models.Album) error {
+	_, err := r.col.InsertOne(ctx, album)
+	return mapError(err)
+}
+
+func (r *mongoAlbums) Get(ctx context.Context, userID, id string) (*models.Album, error) {
+	return findOne[models.Album](ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
+
+func (r *mongoAlbums) ListByUser(ctx context.Context, userID string) ([]models.Album, error) {
+	return findAll[models.Album](ctx, r.col, bson.M{"user_id": userID})
+}
+
+type mongoMedia struct {
+	col *mongo.Collection
+}
+
+func (r *mongoMedia) Create(ctx context.Context, media *models.Media) error {
+	_, err := r.col.InsertOne(ctx, media)
+	return mapError(err)
+}
+
+func (r *mongoMedia) Get(ctx context.Context, id string) (*models.Media, error) {
+	return findOne[models.Media](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoMedia) Delete(ctx context.Context, userID, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
+
+func (r *mongoMedia) Find(ctx context.Context, query MediaQuery) ([]models.Media, int64, error) {
+	filter := bson.M{"user_id": query.UserID, "album_id": query.AlbumID}
+	if query.Rules != nil {
+		var err error
+		if filter, err = smartAlbumFilter(query.UserID, query.Rules); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "metadata.date_time", Value: -1}, {Key: "created_at", Value: -1}}).
+		SetSkip(int64(query.Skip))
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	media, err := findAll[models.Media](ctx, r.col, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return media, total, nil
+}
+
+type mongoShareLinks struct {
+	col *mongo.Collection
+}
+
+func (r *mongoShareLinks) Create(ctx context.Context, link *models.ShareLink) error {
+	_, err := r.col.InsertOne(ctx, link)
+	return mapError(err)
+}
+
+func (r *mongoShareLinks) Get(ctx context.Context, id string) (*models.ShareLink, error) {
+	return findOne[models.ShareLink](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoShareLinks) Delete(ctx context.Context, userID, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id, "user_id": userID})
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoFailedLogins struct {
+	col *mongo.Collection
+}
+
+func (r *mongoFailedLogins) Create(ctx context.Context, failure *models.FailedLogin) error {
+	_, err := r.col.InsertOne(ctx, failure)
+	return mapError(err)
+}
+
+type mongoJobRuns struct {
+	col *mongo.Collection
+}
+
+func (r *mongoJobRuns) Create(ctx context.Context, run *models.JobRun) error {
+	_, err := r.col.InsertOne(ctx, run)
+	return mapError(err)
+}
+
+// mongoStats - Thống kê bằng aggregation pipeline; mọi pipeline bắt đầu bằng $match trên created_at để dùng index
+type mongoStats struct {
+	users        *mongo.Collection
+	media        *mongo.Collection
+	failedLogins *mongo.Collection
+	jobRuns      *mongo.Collection
+}
+
+// dayExpr - Ngày UTC (YYYY-MM-DD) của created_at (unix giây)
+var dayExpr = bson.M{"$dateToString": bson.M{
+	"format": "%Y-%m-%d",
+	"date":   bson.M{"$toDate": bson.M{"$multiply": bson.A{"$created_at", 1000}}},
+}}
+
+func (r StatsRange) match() bson.M {
+	return bson.M{"created_at": bson.M{"$gte": r.From, "$lt": r.To}}
+}
+
+// countIf - Biểu thức $sum đếm document thỏa điều kiện
+func countIf(cond bson.M) bson.M {
+	return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+}
+
+func (s *mongoStats) Users(ctx context.Context, r StatsRange) (*models.UserStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$lt": r.To}}}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":        nil,
+					"total":      bson.M{"$sum": 1},
+					"admins":     countIf(bson.M{"$eq": bson.A{"$role", "admin"}}),
+					"unverified": countIf(bson.M{"$eq": bson.A{"$email_verified", false}}),
+					"two_factor": countIf(bson.M{"$eq": bson.A{"$two_factor_enabled", true}}),
+				}},
+			},
+			"signups": bson.A{
+				bson.M{"$match": bson.M{"created_at": bson.M{"$gte": r.From}}},
+				bson.M{"$group": bson.M{"_id": dayExpr, "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}}},
+	}
+	type facets struct {
+		Totals []struct {
+			Total      int64 `bson:"total"`
+			Admins     int64 `bson:"admins"`
+			Unverified int64 `bson:"unverified"`
+			TwoFactor  int64 `bson:"two_factor"`
+		} `bson:"totals"`
+		Signups []models.DailyCount `bson:"signups"`
+	}
+	docs, err := aggregate[facets](ctx, s.users, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.UserStats{SignupsPerDay: []models.DailyCount{}}
+	if len(docs) == 0 {
+		return stats, nil
+	}
+	if totals := docs[0].Totals; len(totals) > 0 {
+		stats.Total, stats.Admins = totals[0].Total, totals[0].Admins
+		stats.Unverified, stats.TwoFactor = totals[0].Unverified, totals[0].TwoFactor
+	}
+	if docs[0].Signups != nil {
+		stats.SignupsPerDay = docs[0].Signups
+	}
+	for _, d := range stats.SignupsPerDay {
+		stats.Signups += d.Count
+	}
+	return stats, nil
+}
+
+func (s *mongoStats) Uploads(ctx context.Context, r StatsRange) ([]models.UploadsPerDay, error) {
+	return aggregate[models.UploadsPerDay](ctx, s.media, mongo.Pipeline{
+		{{Key: "$match", Value: r.match()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"date": dayExpr, "type": "$type"},
+			"count": bson.M{"$sum": 1},
+			"bytes": bson.M{"$sum": "$size"},
+		}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "date": "$_id.date", "type": "$_id.type", "count": 1, "bytes": 1}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "type", Value: 1}}}},
+	})
+}
+
+func (s *mongoStats) Storage(ctx context.Context, r StatsRange, limit int) ([]models.StorageUsage, error) {
+	byBytes := bson.D{{Key: "bytes", Value: -1}, {Key: "_id", Value: 1}}
+	return aggregate[models.StorageUsage](ctx, s.media, mongo.Pipeline{
+		{{Key: "$match", Value: r.match()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$user_id",
+			"files":  bson.M{"$sum": 1},
+			"images": countIf(bson.M{"$eq": bson.A{"$type", "image"}}),
+			"videos": countIf(bson.M{"$eq": bson.A{"$type", "video"}}),
+			"bytes":  bson.M{"$sum": "$size"},
+		}}},
+		{{Key: "$sort", Value: byBytes}},
+		{{Key: "$limit", Value: limit}},
+		// Chỉ join user cho top limit; user đã xóa thì name/email rỗng
+		{{Key: "$lookup", Value: bson.M{"from": s.users.Name(), "localField": "_id", "foreignField": "_id", "as": "user"}}},
+		{{Key: "$set", Value: bson.M{
+			"name":  bson.M{"$arrayElemAt": bson.A{"$user.name", 0}},
+			"email": bson.M{"$arrayElemAt": bson.A{"$user.email", 0}},
+		}}},
+		{{Key: "$project", Value: bson.M{"user": 0}}},
+		{{Key: "$sort", Value: byBytes}},
+	})
+}
+
+func (s *mongoStats) Cameras(ctx context.Context, r StatsRange, limit int) ([]models.CameraCount, error) {
+	match := r.match()
+	match["type"] = "image"
+	match["metadata.camera_model"] = bson.M{"$exists": true, "$ne": ""}
+	return aggregate[models.CameraCount](ctx, s.media, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"make": bson.M{"$ifNull": bson.A{"$metadata.camera_make", ""}}, "model": "$metadata.camera_model"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "make": "$_id.make", "model": "$_id.model", "count": 1}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "make", Value: 1}, {Key: "model", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+}
+
+func (s *mongoStats) FailedLogins(ctx context.Context, r StatsRange) ([]models.FailedLoginsPerDay, error) {
+	return aggregate[models.FailedLoginsPerDay](ctx, s.failedLogins, mongo.Pipeline{
+		{{Key: "$match", Value: r.match()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"date": dayExpr, "reason": "$reason"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "date": "$_id.date", "reason": "$_id.reason", "count": 1}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "reason", Value: 1}}}},
+	})
+}
+
+func (s *mongoStats) JobRuns(ctx context.Context, r StatsRange) ([]models.JobRunsPerDay, error) {
+	return aggregate[models.JobRunsPerDay](ctx, s.jobRuns, mongo.Pipeline{
+		{{Key: "$match", Value: r.match()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             bson.M{"date": dayExpr, "job": "$job"},
+			"runs":            bson.M{"$sum": 1},
+			"failures":        countIf(bson.M{"$eq": bson.A{"$status", models.JobStatusFailed}}),
+			"avg_duration_ms": bson.M{"$avg": "$duration_ms"},
+			"max_duration_ms": bson.M{"$max": "$duration_ms"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id": 0, "date": "$_id.date", "job": "$_id.job",
+			"runs": 1, "failures": 1, "avg_duration_ms": 1, "max_duration_ms": 1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "job", Value: 1}}}},
+	})
+}
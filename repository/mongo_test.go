@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/migrations"
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestMongoContract - Chạy contract trên MongoDB thật; bỏ qua nếu không đặt MONGODB_TEST_URI.
+// Mỗi subtest dùng một database riêng (đã chạy migration để có unique index) và xóa sau khi xong.
+func TestMongoContract(t *testing.T) {
+	client := connectMongo(t)
+	runContract(t, func(t *testing.T) *Repositories {
+		return newMongoRepos(t, client)
+	})
+}
+
+// TestMongoEventsConcurrentAppend - Nhiều instance ghi sự kiện cùng lúc trong khi một hub đọc theo feed:
+// seq được cấp trước khi sự kiện được lưu nên có thể lưu không theo thứ tự, feed vẫn phải thấy đủ mọi seq đúng một lần
+func TestMongoEventsConcurrentAppend(t *testing.T) {
+	repos := newMongoRepos(t, connectMongo(t))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				errs <- repos.Events.Append(ctx, &models.UserEvent{
+					ID: fmt.Sprintf("evt_%d_%d", w, i), UserID: fmt.Sprintf("uid_%d", w), Type: models.EventMediaProcessed,
+					Data: `{}`, CreatedAt: 100,
+				})
+			}
+		}(w)
+	}
+	written := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(written)
+	}()
+
+	feed := NewEventFeed(repos.Events, 0, 10*time.Second)
+	var seen []int64
+	for done := false; !done; {
+		select {
+		case <-written:
+			done = true
+		default:
+		}
+		events, err := feed.Next(ctx, 20)
+		if err != nil {
+			t.Fatalf("read events: %v", err)
+		}
+		for _, event := range events {
+			seen = append(seen, event.Seq)
+		}
+	}
+	// Đọc nốt phần còn lại sau khi mọi lần ghi đã xong
+	for {
+		events, err := feed.Next(ctx, 20)
+		if err != nil {
+			t.Fatalf("read events: %v", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+		for _, event := range events {
+			seen = append(seen, event.Seq)
+		}
+	}
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	if len(seen) != writers*perWriter {
+		t.Fatalf("expected %d events, feed saw %d", writers*perWriter, len(seen))
+	}
+	for i, seq := range seen {
+		if seq != int64(i+1) {
+			t.Fatalf("expected seq %d at %d, got %d", i+1, i, seq)
+		}
+	}
+}
+
+// connectMongo - Client tới MONGODB_TEST_URI; bỏ qua test nếu không đặt
+func connectMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect(context.Background())
+	})
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	return client
+}
+
+var mongoDatabases atomic.Int64
+
+// newMongoRepos - Repository trên một database mới đã chạy migration, xóa khi test xong
+func newMongoRepos(t *testing.T, client *mongo.Client) *Repositories {
+	t.Helper()
+	db := client.Database(fmt.Sprintf("media_store_test_%d_%d", time.Now().UnixNano(), mongoDatabases.Add(1)))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return NewMongo(db)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoUsers struct {
+	col *mongo.Collection
+}
+
+func (r *mongoUsers) Create(ctx context.Context, user *models.User) error {
+	_, err := r.col.InsertOne(ctx, user)
+	return mapError(err)
+}
+
+func (r *mongoUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return findOne[models.User](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return findOne[models.User](ctx, r.col, bson.M{"email": email})
+}
+
+func (r *mongoUsers) List(ctx context.Context) ([]models.User, error) {
+	return findAll[models.User](ctx, r.col, bson.M{})
+}
+
+func (r *mongoUsers) Search(ctx context.Context, term string) ([]models.User, error) {
+	pattern := containsPattern(term)
+	return findAll[models.User](ctx, r.col, bson.M{
+		"$or": bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}},
+	})
+}
+
+func (r *mongoUsers) Update(ctx context.Context, id string, changes UserChanges) error {
+	set := bson.M{"updated_at": time.Now().Unix()}
+	unset := bson.M{}
+
+	// Field tùy chọn (omitempty) nhận giá trị rỗng thì bị xóa khỏi document
+	optional := func(key string, value interface{}, empty bool) {
+		if empty {
+			unset[key] = ""
+		} else {
+			set[key] = value
+		}
+	}
+	if changes.Name != nil {
+		set["name"] = *changes.Name
+	}
+	if changes.Email != nil {
+		set["email"] = *changes.Email
+	}
+	if changes.Password != nil {
+		set["password"] = *changes.Password
+	}
+	if changes.PendingEmail != nil {
+		optional("pending_email", *changes.PendingEmail, *changes.PendingEmail == "")
+	}
+	if changes.Phone != nil {
+		optional("phone", *changes.Phone, *changes.Phone == "")
+	}
+	if changes.Avatar != nil {
+		optional("avatar", *changes.Avatar, *changes.Avatar == "")
+	}
+	if changes.StripMetadata != nil {
+		optional("strip_metadata", *changes.StripMetadata, *changes.StripMetadata == "")
+	}
+	if changes.EmailVerified != nil {
+		set["email_verified"] = *changes.EmailVerified
+	}
+	if changes.TwoFactorEnabled != nil {
+		set["two_factor_enabled"] = *changes.TwoFactorEnabled
+	}
+	if changes.TwoFactorSecret != nil {
+		optional("two_factor_secret", *changes.TwoFactorSecret, *changes.TwoFactorSecret == "")
+	}
+	if changes.TwoFactorPendingSecret != nil {
+		optional("two_factor_pending_secret", *changes.TwoFactorPendingSecret, *changes.TwoFactorPendingSecret == "")
+	}
+	if changes.TwoFactorLastStep != nil {
+		optional("two_factor_last_step", *changes.TwoFactorLastStep, *changes.TwoFactorLastStep == 0)
+	}
+	if changes.RecoveryCodes != nil {
+		optional("recovery_codes", *changes.RecoveryCodes, len(*changes.RecoveryCodes) == 0)
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return mapError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUsers) Delete(ctx context.Context, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoUsers) AdvanceTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	result, err := r.col.UpdateOne(ctx, bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"two_factor_last_step": bson.M{"$exists": false}},
+			bson.M{"two_factor_last_step": bson.M{"$lt": step}},
+		},
+	}, bson.M{"$set": bson.M{"two_factor_last_step": step}})
+	if err != nil {
+		return false, mapError(err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoUsers) ConsumeRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, mapError(err)
+	}
+	return result.ModifiedCount == 1, nil
+}
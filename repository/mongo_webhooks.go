@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoWebhooks struct {
+	col *mongo.Collection
+}
+
+func (r *mongoWebhooks) Create(ctx context.Context, webhook *models.Webhook) error {
+	_, err := r.col.InsertOne(ctx, webhook)
+	return mapError(err)
+}
+
+func (r *mongoWebhooks) Get(ctx context.Context, id string) (*models.Webhook, error) {
+	return findOne[models.Webhook](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoWebhooks) List(ctx context.Context) ([]models.Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return findAll[models.Webhook](ctx, r.col, bson.M{}, opts)
+}
+
+func (r *mongoWebhooks) ListForEvent(ctx context.Context, event string) ([]models.Webhook, error) {
+	return findAll[models.Webhook](ctx, r.col, bson.M{"enabled": true, "events": event})
+}
+
+func (r *mongoWebhooks) Update(ctx context.Context, id string, changes WebhookChanges) (*models.Webhook, error) {
+	set := bson.M{"updated_at": time.Now().Unix()}
+	unset := bson.M{}
+	if changes.URL != nil {
+		set["url"] = *changes.URL
+	}
+	if changes.Events != nil {
+		set["events"] = *changes.Events
+	}
+	if changes.Description != nil {
+		if *changes.Description == "" {
+			unset["description"] = ""
+		} else {
+			set["description"] = *changes.Description
+		}
+	}
+	if changes.Enabled != nil {
+		set["enabled"] = *changes.Enabled
+		if *changes.Enabled {
+			set["consecutive_failures"] = 0
+			unset["disabled_reason"] = ""
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var webhook models.Webhook
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&webhook); err != nil {
+		return nil, mapError(err)
+	}
+	return &webhook, nil
+}
+
+func (r *mongoWebhooks) Delete(ctx context.Context, id string) error {
+	return deleteOne(ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoWebhooks) RecordSuccess(ctx context.Context, id string) error {
+	// Chỉ ghi khi có lỗi cần xóa: phần lớn delivery thành công không cần ghi gì
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "consecutive_failures": bson.M{"$ne": 0}},
+		bson.M{"$set": bson.M{"consecutive_failures": 0}},
+	)
+	return mapError(err)
+}
+
+func (r *mongoWebhooks) RecordFailure(ctx context.Context, id string, disableAfter int, reason string) (bool, error) {
+	var webhook models.Webhook
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"consecutive_failures": 1}}, opts).Decode(&webhook)
+	if err != nil {
+		return false, mapError(err)
+	}
+	if !webhook.Enabled || webhook.ConsecutiveFailures < disableAfter {
+		return false, nil
+	}
+
+	// Điều kiện enabled: true để chỉ một worker tắt subscription (và ghi log) khi nhiều delivery lỗi cùng lúc
+	result, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "enabled": true},
+		bson.M{"$set": bson.M{"enabled": false, "disabled_reason": reason, "updated_at": time.Now().Unix()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+type mongoWebhookDeliveries struct {
+	col *mongo.Collection
+}
+
+func (r *mongoWebhookDeliveries) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	// attempts phải là mảng (không phải null) để $push được
+	if delivery.Attempts == nil {
+		delivery.Attempts = []models.WebhookAttempt{}
+	}
+	_, err := r.col.InsertOne(ctx, delivery)
+	return mapError(err)
+}
+
+func (r *mongoWebhookDeliveries) Get(ctx context.Context, webhookID, id string) (*models.WebhookDelivery, error) {
+	return findOne[models.WebhookDelivery](ctx, r.col, bson.M{"_id": id, "webhook_id": webhookID})
+}
+
+func (r *mongoWebhookDeliveries) ListByWebhook(ctx context.Context, webhookID string, skip, limit int) ([]models.WebhookDelivery, int64, error) {
+	filter := bson.M{"webhook_id": webhookID}
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(int64(skip))
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	deliveries, err := findAll[models.WebhookDelivery](ctx, r.col, filter, opts)
+	return deliveries, total, err
+}
+
+func dueFilter(now int64) bson.M {
+	return bson.M{"status": models.WebhookDeliveryPending, "next_attempt_at": bson.M{"$lte": now}}
+}
+
+func (r *mongoWebhookDeliveries) ClaimDue(ctx context.Context, now, leaseUntil int64) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	err := r.col.FindOneAndUpdate(ctx, dueFilter(now), bson.M{"$set": bson.M{"next_attempt_at": leaseUntil}}, opts).Decode(&delivery)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &delivery, nil
+}
+
+func (r *mongoWebhookDeliveries) CountDue(ctx context.Context, now int64) (int64, error) {
+	return r.col.CountDocuments(ctx, dueFilter(now))
+}
+
+func (r *mongoWebhookDeliveries) Record(ctx context.Context, id string, result WebhookDeliveryResult) error {
+	set := bson.M{"status": result.Status, "updated_at": result.Now}
+	update := bson.M{"$set": set, "$push": bson.M{"attempts": result.Attempt}}
+	if result.NextAttemptAt != 0 {
+		set["next_attempt_at"] = result.NextAttemptAt
+	} else {
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+	}
+	if result.Status == models.WebhookDeliverySucceeded {
+		set["delivered_at"] = result.Now
+	}
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return mapError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoWebhookDeliveries) DeleteByWebhook(ctx context.Context, webhookID string) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"webhook_id": webhookID})
+	return err
+}
@@ -0,0 +1,259 @@
+// Package repository tách truy cập dữ liệu khỏi HTTP handler. Mỗi collection có một interface,
+// với bản Mongo (production) và bản in-memory (test); cả hai phải qua cùng bộ contract test.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+// Lỗi domain trả về bởi mọi implementation; handler không cần biết lỗi của driver
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+)
+
+// Repositories - Tập hợp repository được inject vào handler và middleware
+type Repositories struct {
+	Users             UserRepository
+	Albums            AlbumRepository
+	Media             MediaRepository
+	ShareLinks        ShareLinkRepository
+	UserTokens        UserTokenRepository
+	Sessions          SessionRepository
+	AccessTokens      AccessTokenRepository
+	Identities        IdentityRepository
+	OIDCStates        OIDCStateRepository
+	FailedLogins      FailedLoginRepository
+	JobRuns           JobRunRepository
+	Stats             StatsRepository
+	Audit             AuditRepository
+	Webhooks          WebhookRepository
+	WebhookDeliveries WebhookDeliveryRepository
+	Events            EventRepository
+}
+
+// Ptr - Tiện ích tạo con trỏ cho các field của UserChanges
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// UserChanges - Các field cần cập nhật; nil là giữ nguyên, giá trị rỗng của field tùy chọn là xóa field
+type UserChanges struct {
+	Name                   *string
+	Email                  *string
+	PendingEmail           *string
+	Phone                  *string
+	Avatar                 *string
+	StripMetadata          *string
+	Password               *string
+	EmailVerified          *bool
+	TwoFactorEnabled       *bool
+	TwoFactorSecret        *string
+	TwoFactorPendingSecret *string
+	TwoFactorLastStep      *int64
+	RecoveryCodes          *[]string
+}
+
+type UserRepository interface {
+	// Create - ErrDuplicate khi ID hoặc email đã tồn tại
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	List(ctx context.Context) ([]models.User, error)
+	// Search - Tìm theo chuỗi con (không phân biệt hoa thường) trong tên hoặc email
+	Search(ctx context.Context, term string) ([]models.User, error)
+	// Update - Áp dụng changes và cập nhật updated_at; ErrDuplicate khi email mới đã được dùng
+	Update(ctx context.Context, id string, changes UserChanges) error
+	Delete(ctx context.Context, id string) error
+	// AdvanceTOTPStep - Ghi nhận bước TOTP nếu lớn hơn bước đã dùng; false nghĩa là mã đã được dùng
+	AdvanceTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	// ConsumeRecoveryCode - Xóa hash mã khôi phục khỏi danh sách; false nếu không có
+	ConsumeRecoveryCode(ctx context.Context, id, hash string) (bool, error)
+}
+
+type AlbumRepository interface {
+	Create(ctx context.Context, album *models.Album) error
+	// Get - Album của đúng owner; album của người khác trả ErrNotFound
+	Get(ctx context.Context, userID, id string) (*models.Album, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Album, error)
+}
+
+// MediaQuery - Điều kiện lấy media; Rules khác nil thì lọc theo smart rules thay vì AlbumID
+type MediaQuery struct {
+	UserID  string
+	AlbumID string
+	Rules   *models.SmartRule
+	Skip    int
+	Limit   int
+}
+
+type MediaRepository interface {
+	Create(ctx context.Context, media *models.Media) error
+	Get(ctx context.Context, id string) (*models.Media, error)
+	// Find - Trả trang kết quả (mới chụp trước, rồi mới upload trước) và tổng số bản ghi khớp
+	Find(ctx context.Context, query MediaQuery) ([]models.Media, int64, error)
+	// Delete - Xóa media của user; ErrNotFound khi không có hoặc thuộc user khác
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *models.ShareLink) error
+	Get(ctx context.Context, id string) (*models.ShareLink, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	// DeleteUnused - Hủy các token chưa dùng cùng mục đích của user
+	DeleteUnused(ctx context.Context, userID, purpose string) error
+	// Consume - Đánh dấu token đã dùng một cách atomic; token sai, hết hạn hoặc đã dùng trả ErrNotFound
+	Consume(ctx context.Context, tokenHash, purpose string, now int64) (*models.UserToken, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	Get(ctx context.Context, userID, id string) (*models.Session, error)
+	// ListActive - Phiên chưa hết hạn, phiên dùng gần nhất trước
+	ListActive(ctx context.Context, userID string, now int64) ([]models.Session, error)
+	Touch(ctx context.Context, id, ip string, now int64) error
+	Delete(ctx context.Context, userID, id string) error
+	// DeleteAll - Xóa mọi phiên của user trừ exceptID (rỗng = xóa tất cả); trả số phiên đã xóa
+	DeleteAll(ctx context.Context, userID, exceptID string) (int64, error)
+}
+
+type AccessTokenRepository interface {
+	Create(ctx context.Context, token *models.AccessToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.AccessToken, error)
+	// ListByUser - Token mới tạo trước
+	ListByUser(ctx context.Context, userID string) ([]models.AccessToken, error)
+	CountByUser(ctx context.Context, userID string) (int64, error)
+	Touch(ctx context.Context, id, ip string, now int64) error
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type IdentityRepository interface {
+	// Create - ErrDuplicate khi provider + subject đã được liên kết
+	Create(ctx context.Context, identity *models.ExternalIdentity) error
+	// RecordLogin - Tìm identity theo provider + subject và cập nhật email, thời điểm đăng nhập
+	RecordLogin(ctx context.Context, provider, subject, email string, now int64) (*models.ExternalIdentity, error)
+	ListByUser(ctx context.Context, userID string) ([]models.ExternalIdentity, error)
+	CountByUser(ctx context.Context, userID string) (int64, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *models.OIDCState) error
+	// Consume - Lấy và xóa state (chỉ dùng một lần); sai provider hoặc hết hạn trả ErrNotFound
+	Consume(ctx context.Context, id, provider string, now int64) (*models.OIDCState, error)
+}
+
+type FailedLoginRepository interface {
+	Create(ctx context.Context, failure *models.FailedLogin) error
+}
+
+type JobRunRepository interface {
+	Create(ctx context.Context, run *models.JobRun) error
+}
+
+// StatsRange - Khoảng thời gian [From, To) theo created_at (unix giây); thống kê theo ngày dùng ngày UTC
+type StatsRange struct {
+	From int64
+	To   int64
+}
+
+// StatsRepository - Thống kê cho admin; kết quả theo ngày sắp xếp theo ngày rồi theo nhóm
+type StatsRepository interface {
+	// Users - Số user tạo trước To và số đăng ký mới trong khoảng
+	Users(ctx context.Context, r StatsRange) (*models.UserStats, error)
+	Uploads(ctx context.Context, r StatsRange) ([]models.UploadsPerDay, error)
+	// Storage - Top limit user upload nhiều dung lượng nhất trong khoảng
+	Storage(ctx context.Context, r StatsRange, limit int) ([]models.StorageUsage, error)
+	// Cameras - Top limit model máy ảnh theo số ảnh upload trong khoảng
+	Cameras(ctx context.Context, r StatsRange, limit int) ([]models.CameraCount, error)
+	FailedLogins(ctx context.Context, r StatsRange) ([]models.FailedLoginsPerDay, error)
+	JobRuns(ctx context.Context, r StatsRange) ([]models.JobRunsPerDay, error)
+}
+
+// AuditQuery - Điều kiện lọc audit log; field rỗng (hoặc 0) là không lọc. From/To là khoảng [From, To) theo created_at
+type AuditQuery struct {
+	Action     string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	From       int64
+	To         int64
+	Skip       int
+	Limit      int
+}
+
+// AuditRepository - Audit log chỉ ghi thêm: không có Update/Delete
+type AuditRepository interface {
+	// Append - Nối event vào cuối chuỗi: gán Seq, PrevHash và Hash. An toàn khi nhiều instance ghi đồng thời
+	Append(ctx context.Context, event *models.AuditEvent) error
+	// Find - Event mới nhất trước và tổng số event khớp
+	Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, int64, error)
+	// Chain - Tối đa limit event có Seq > after theo thứ tự Seq tăng dần, dùng để kiểm tra chuỗi hash
+	Chain(ctx context.Context, after int64, limit int) ([]models.AuditEvent, error)
+}
+
+// WebhookChanges - Các field cần cập nhật; nil là giữ nguyên. Bật lại subscription thì xóa lý do tắt và đếm lỗi
+type WebhookChanges struct {
+	URL         *string
+	Events      *[]string
+	Description *string
+	Enabled     *bool
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Get(ctx context.Context, id string) (*models.Webhook, error)
+	// List - Subscription mới tạo trước
+	List(ctx context.Context) ([]models.Webhook, error)
+	// ListForEvent - Subscription đang bật có đăng ký event
+	ListForEvent(ctx context.Context, event string) ([]models.Webhook, error)
+	// Update - Áp dụng changes, trả về subscription sau khi cập nhật
+	Update(ctx context.Context, id string, changes WebhookChanges) (*models.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	// RecordSuccess - Đặt lại số delivery thất bại liên tiếp về 0; subscription đã bị xóa thì bỏ qua
+	RecordSuccess(ctx context.Context, id string) error
+	// RecordFailure - Tăng số delivery thất bại liên tiếp; khi đạt disableAfter thì tắt subscription với reason.
+	// Trả về true nếu lần gọi này tắt subscription
+	RecordFailure(ctx context.Context, id string, disableAfter int, reason string) (bool, error)
+}
+
+// WebhookDeliveryResult - Trạng thái delivery sau một lần xử lý và lần gửi được ghi thêm vào lịch sử
+type WebhookDeliveryResult struct {
+	Status        string
+	Attempt       models.WebhookAttempt
+	NextAttemptAt int64
+	Now           int64
+}
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	// Get - Delivery của đúng subscription; của subscription khác trả ErrNotFound
+	Get(ctx context.Context, webhookID, id string) (*models.WebhookDelivery, error)
+	// ListByWebhook - Delivery mới tạo trước và tổng số delivery của subscription
+	ListByWebhook(ctx context.Context, webhookID string, skip, limit int) ([]models.WebhookDelivery, int64, error)
+	// ClaimDue - Lấy một delivery pending đến hạn (NextAttemptAt <= now, sớm nhất trước) và dời NextAttemptAt tới
+	// leaseUntil một cách atomic để worker khác không lấy trùng; ErrNotFound khi không có delivery đến hạn
+	ClaimDue(ctx context.Context, now, leaseUntil int64) (*models.WebhookDelivery, error)
+	// CountDue - Số delivery pending đã đến hạn
+	CountDue(ctx context.Context, now int64) (int64, error)
+	// Record - Lưu kết quả xử lý: trạng thái mới, lần gửi và lịch gửi lại
+	Record(ctx context.Context, id string, result WebhookDeliveryResult) error
+	DeleteByWebhook(ctx context.Context, webhookID string) error
+}
+
+// EventRepository - Sự kiện real-time của user, giữ tới PurgeAt để client nối lại không mất sự kiện
+type EventRepository interface {
+	// Append - Gán Seq lớn hơn mọi Seq đã cấp (kể cả của sự kiện đã bị xóa) rồi lưu. An toàn khi nhiều instance ghi đồng thời
+	Append(ctx context.Context, event *models.UserEvent) error
+	// After - Tối đa limit sự kiện có Seq > after theo thứ tự Seq tăng dần; userID rỗng là sự kiện của mọi user
+	After(ctx context.Context, userID string, after int64, limit int) ([]models.UserEvent, error)
+	// LastSeq - Seq đã cấp cuối cùng, 0 khi chưa có sự kiện; không giảm khi sự kiện cũ hết hạn
+	LastSeq(ctx context.Context) (int64, error)
+}
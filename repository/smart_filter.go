@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxSmartRuleDepth = 5
+	maxSmartRuleNodes = 50
+)
+
+type smartFieldKind int
+
+const (
+	smartFieldString smartFieldKind = iota
+	smartFieldNumber
+	smartFieldTime
+	smartFieldTags
+)
+
+type smartField struct {
+	path string
+	kind smartFieldKind
+}
+
+// smartFields - Các field được phép dùng trong rule và đường dẫn tương ứng trong collection media
+var smartFields = map[string]smartField{
+	"type":         {path: "type", kind: smartFieldString},
+	"title":        {path: "title", kind: smartFieldString},
+	"camera_make":  {path: "metadata.camera_make", kind: smartFieldString},
+	"camera_model": {path: "metadata.camera_model", kind: smartFieldString},
+	"lens_model":   {path: "metadata.lens_model", kind: smartFieldString},
+	"country":      {path: "metadata.location.country", kind: smartFieldString},
+	"state":        {path: "metadata.location.state", kind: smartFieldString},
+	"city":         {path: "metadata.location.city", kind: smartFieldString},
+	"tag":          {path: "tags", kind: smartFieldTags},
+	"keyword":      {path: "metadata.keywords", kind: smartFieldTags},
+	"rating":       {path: "metadata.rating", kind: smartFieldNumber},
+	"duration":     {path: "duration", kind: smartFieldNumber},
+	"iso":          {path: "metadata.iso", kind: smartFieldNumber},
+	"size":         {path: "size", kind: smartFieldNumber},
+	"captured_at":  {path: "metadata.date_time", kind: smartFieldTime},
+	"uploaded_at":  {path: "created_at", kind: smartFieldTime},
+}
+
+var smartOps = map[smartFieldKind]map[string]bool{
+	smartFieldString: {"eq": true, "ne": true, "in": true, "contains": true, "exists": true},
+	smartFieldNumber: {"eq": true, "ne": true, "gt": true, "gte": true, "lt": true, "lte": true, "between": true, "exists": true},
+	smartFieldTime:   {"gt": true, "gte": true, "lt": true, "lte": true, "between": true, "exists": true},
+	smartFieldTags:   {"eq": true, "in": true, "all": true, "exists": true},
+}
+
+// ValidateSmartRule - Kiểm tra cây rule hợp lệ trước khi lưu
+func ValidateSmartRule(rule *models.SmartRule) error {
+	nodes := 0
+	_, err := buildSmartFilter(rule, 0, &nodes)
+	return err
+}
+
+// smartAlbumFilter - Dịch cây rule của smart album thành filter Mongo, giới hạn trong media của owner
+func smartAlbumFilter(userID string, rule *models.SmartRule) (bson.M, error) {
+	nodes := 0
+	filter, err := buildSmartFilter(rule, 0, &nodes)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$and": bson.A{bson.M{"user_id": userID}, filter}}, nil
+}
+
+func buildSmartFilter(rule *models.SmartRule, depth int, nodes *int) (bson.M, error) {
+	if rule == nil {
+		return nil, errors.New("rule is required")
+	}
+	if depth > maxSmartRuleDepth {
+		return nil, fmt.Errorf("rules are nested deeper than %d levels", maxSmartRuleDepth)
+	}
+	*nodes++
+	if *nodes > maxSmartRuleNodes {
+		return nil, fmt.Errorf("rules contain more than %d conditions", maxSmartRuleNodes)
+	}
+
+	if rule.Match != "" {
+		if rule.Field != "" || rule.Op != "" || rule.Value != nil {
+			return nil, errors.New("a rule group cannot also set field, op or value")
+		}
+		if len(rule.Rules) == 0 {
+			return nil, fmt.Errorf("rule group %q has no rules", rule.Match)
+		}
+
+		children := bson.A{}
+		for i := range rule.Rules {
+			child, err := buildSmartFilter(&rule.Rules[i], depth+1, nodes)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+
+		switch rule.Match {
+		case "all":
+			return bson.M{"$and": children}, nil
+		case "any":
+			return bson.M{"$or": children}, nil
+		case "none":
+			return bson.M{"$nor": children}, nil
+		default:
+			return nil, fmt.Errorf("unknown match %q, expected all, any or none", rule.Match)
+		}
+	}
+
+	if len(rule.Rules) > 0 {
+		return nil, errors.New("nested rules require match")
+	}
+
+	field, ok := smartFields[rule.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", rule.Field)
+	}
+	if !smartOps[field.kind][rule.Op] {
+		return nil, fmt.Errorf("operator %q is not supported for field %q", rule.Op, rule.Field)
+	}
+
+	if rule.Op == "exists" {
+		exists, ok := rule.Value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("field %q: exists expects true or false", rule.Field)
+		}
+		return bson.M{field.path: bson.M{"$exists": exists}}, nil
+	}
+
+	switch field.kind {
+	case smartFieldString:
+		return buildStringCondition(rule, field.path)
+	case smartFieldTags:
+		return buildTagCondition(rule, field.path)
+	default:
+		return buildRangeCondition(rule, field)
+	}
+}
+
+func buildStringCondition(rule *models.SmartRule, path string) (bson.M, error) {
+	switch rule.Op {
+	case "in":
+		values, err := smartStringList(rule)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: bson.M{"$in": values}}, nil
+	case "contains":
+		value, err := smartString(rule)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}}, nil
+	case "ne":
+		value, err := smartString(rule)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: bson.M{"$ne": value}}, nil
+	default:
+		value, err := smartString(rule)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: value}, nil
+	}
+}
+
+func buildTagCondition(rule *models.SmartRule, path string) (bson.M, error) {
+	switch rule.Op {
+	case "in", "all":
+		values, err := smartStringList(rule)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: bson.M{"$" + rule.Op: values}}, nil
+	default:
+		value, err := smartString(rule)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: value}, nil
+	}
+}
+
+func buildRangeCondition(rule *models.SmartRule, field smartField) (bson.M, error) {
+	if rule.Op == "between" {
+		values, ok := smartList(rule.Value)
+		if !ok || len(values) != 2 {
+			return nil, fmt.Errorf("field %q: between expects [from, to]", rule.Field)
+		}
+		from, err := smartNumber(values[0], field)
+		if err != nil {
+			return nil, err
+		}
+		to, err := smartNumber(values[1], field)
+		if err != nil {
+			return nil, err
+		}
+		if from > to {
+			return nil, fmt.Errorf("field %q: from must not be after to", rule.Field)
+		}
+		return bson.M{field.path: bson.M{"$gte": from, "$lte": to}}, nil
+	}
+
+	value, err := smartNumber(rule.Value, field)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Op == "eq" {
+		return bson.M{field.path: value}, nil
+	}
+	return bson.M{field.path: bson.M{"$" + rule.Op: value}}, nil
+}
+
+func smartString(rule *models.SmartRule) (string, error) {
+	value, ok := rule.Value.(string)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("field %q expects a non-empty string", rule.Field)
+	}
+	return value, nil
+}
+
+func smartStringList(rule *models.SmartRule) ([]string, error) {
+	items, ok := smartList(rule.Value)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("field %q: %s expects a non-empty list", rule.Field, rule.Op)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("field %q: %s expects a list of strings", rule.Field, rule.Op)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// smartList - Value có thể đến từ JSON ([]interface{}) hoặc từ Mongo (bson.A)
+func smartList(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case bson.A:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// smartNumber - Chuyển value sang số; field thời gian nhận cả unix timestamp lẫn chuỗi RFC3339/YYYY-MM-DD
+func smartNumber(value interface{}, field smartField) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case string:
+		if field.kind == smartFieldTime {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return float64(t.Unix()), nil
+			}
+			if t, err := time.Parse("2006-01-02", v); err == nil {
+				return float64(t.Unix()), nil
+			}
+			return 0, fmt.Errorf("invalid date %q, expected RFC3339 or YYYY-MM-DD", v)
+		}
+	}
+	return 0, errors.New("expected a numeric value")
+}
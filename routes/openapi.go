@@ -0,0 +1,354 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/hieu9721/media-store-backend/health"
+	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/openapi"
+)
+
+// Mẫu các body lặp lại nhiều lần
+var (
+	messageBody = openapi.Object{"message": ""}
+	loginBody   = openapi.Object{"message": "", "token": "", "user": models.User{}}
+	// Login và OIDC callback trả token, hoặc challenge token khi tài khoản bật 2FA
+	loginOrChallengeBody = openapi.OneOf{
+		loginBody,
+		openapi.Object{"message": "", "two_factor_required": true, "challenge_token": ""},
+	}
+	userBody      = openapi.Object{"message": "", "data": models.User{}}
+	userListBody  = openapi.Object{"message": "", "count": 0, "data": []models.User{}}
+	uploadedImage = openapi.Object{
+		"message":  "",
+		"user_id":  "",
+		"filename": "",
+		"url":      "",
+		"size":     int64(0),
+		"metadata": openapi.Optional(models.ImageMetadata{}),
+	}
+	mediaFiles = []string{"image/*", "video/*", "application/octet-stream"}
+)
+
+// OpenAPI - Tài liệu OpenAPI của mọi route đăng ký trong SetupRoutes (contract test kiểm tra không thiếu route nào)
+func OpenAPI() *openapi3.T {
+	ops := apiOperations()
+	for i := range ops {
+		if ops[i].Auth == openapi.User && ops[i].Scope == "" {
+			ops[i].Scope = middleware.MethodScope(ops[i].Method)
+		}
+	}
+
+	return openapi.Build(openapi3.Info{
+		Title:       "Media Store API",
+		Description: "Media upload, albums and sharing. Errors are returned as application/problem+json.",
+		Version:     health.Build().Version,
+	}, ops)
+}
+
+func apiOperations() []openapi.Operation {
+	return []openapi.Operation{
+		// System
+		{Method: http.MethodGet, Path: "/health", Tag: "System", Summary: "Legacy health check, always OK",
+			Response: openapi.Object{"status": "", "message": ""}},
+		{Method: http.MethodGet, Path: "/livez", Tag: "System", Summary: "Liveness probe",
+			Response: openapi.Object{"status": ""}},
+		{Method: http.MethodGet, Path: "/readyz", Tag: "System", Summary: "Readiness probe with dependency checks",
+			Description: "Returns 503 with the same body when a critical check fails.",
+			Response:    health.Report{}, Also: map[int]any{http.StatusServiceUnavailable: health.Report{}}},
+		{Method: http.MethodGet, Path: "/version", Tag: "System", Summary: "Build information",
+			Response: health.BuildInfo{}},
+		{Method: http.MethodGet, Path: "/metrics", Tag: "System", Summary: "Prometheus metrics",
+			Description: "Requires Authorization: Bearer <METRICS_TOKEN> when a token is configured.",
+			Produces:    []string{"text/plain"}, Errors: []int{http.StatusUnauthorized}},
+		{Method: http.MethodGet, Path: "/openapi.json", Tag: "System", Summary: "This OpenAPI document",
+			Produces: []string{"application/json"}},
+		{Method: http.MethodGet, Path: "/docs", Tag: "System", Summary: "API documentation (Swagger UI)",
+			Produces: []string{"text/html"}},
+
+		// Files
+		{Method: http.MethodGet, Path: "/uploads/*filepath", Tag: "Files", Summary: "Download an uploaded file",
+			Description: "Images are served without GPS or all metadata depending on the owner's strip_metadata setting.",
+			Produces:    mediaFiles, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodHead, Path: "/uploads/*filepath", Tag: "Files", Summary: "Uploaded file headers",
+			Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/s/:token", Tag: "Files", Summary: "Download media through a share link",
+			Produces: mediaFiles, Errors: []int{http.StatusNotFound, http.StatusGone}},
+		{Method: http.MethodGet, Path: "/media/:id/render", Tag: "Files", Summary: "Download a resized or converted image",
+			Description: "Only URLs signed by POST /api/v1/media/{id}/render are accepted. Renders are cached, " +
+				"so the response never changes for the same URL.",
+			Query: []openapi.Param{
+				{Name: "w", Description: "Width in pixels", Schema: 0},
+				{Name: "h", Description: "Height in pixels", Schema: 0},
+				{Name: "fit", Description: "contain, cover or fill when both w and h are set", Schema: ""},
+				{Name: "gravity", Description: "Part kept when fit is cover: center, north, southeast, ...", Schema: ""},
+				{Name: "rotate", Description: "Clockwise rotation: 90, 180 or 270", Schema: 0},
+				{Name: "format", Description: "jpeg or png", Schema: ""},
+				{Name: "q", Description: "JPEG quality, 1-100", Schema: 0},
+				{Name: "sig", Description: "Signature of the other parameters", Required: true, Schema: ""},
+			},
+			Produces: []string{"image/jpeg", "image/png"},
+			Errors:   []int{http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusServiceUnavailable}},
+
+		// Auth
+		{Method: http.MethodPost, Path: "/api/v1/auth/register", Tag: "Auth", Summary: "Register an account",
+			Body: models.RegisterInput{}, Status: http.StatusCreated,
+			Response: openapi.Object{"message": "", "user": models.User{}, "verification_email_sent": true},
+			Errors:   []int{http.StatusConflict}, RateLimited: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/login", Tag: "Auth", Summary: "Log in with email and password",
+			Body: models.LoginInput{}, Response: loginOrChallengeBody,
+			Errors: []int{http.StatusUnauthorized, http.StatusForbidden}, RateLimited: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/verify-email", Tag: "Auth", Summary: "Confirm an email address",
+			Body: models.VerifyEmailInput{}, Response: messageBody,
+			Errors: []int{http.StatusNotFound, http.StatusConflict}, RateLimited: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/resend-verification", Tag: "Auth", Summary: "Resend the verification email",
+			Body: models.EmailInput{}, Response: messageBody, RateLimited: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/forgot-password", Tag: "Auth", Summary: "Send a password reset link",
+			Body: models.EmailInput{}, Response: messageBody, RateLimited: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/reset-password", Tag: "Auth", Summary: "Set a new password with a reset token",
+			Body: models.ResetPasswordInput{}, Response: messageBody,
+			Errors: []int{http.StatusNotFound}, RateLimited: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/2fa/verify", Tag: "Auth", Summary: "Complete a two-factor login",
+			Body: models.TwoFactorLoginInput{}, Response: loginBody,
+			Errors: []int{http.StatusUnauthorized}, RateLimited: true},
+		{Method: http.MethodGet, Path: "/api/v1/auth/oidc/providers", Tag: "Auth", Summary: "Configured OpenID Connect providers",
+			Response: openapi.Object{"data": []string{}}},
+		{Method: http.MethodGet, Path: "/api/v1/auth/oidc/:provider/login", Tag: "Auth", Summary: "Start an OpenID Connect login",
+			Description: "Redirects to the identity provider.",
+			Status:      http.StatusFound, Errors: []int{http.StatusNotFound, http.StatusBadGateway}, RateLimited: true},
+		{Method: http.MethodGet, Path: "/api/v1/auth/oidc/:provider/callback", Tag: "Auth", Summary: "OpenID Connect callback",
+			Query: []openapi.Param{
+				{Name: "code", Description: "Authorization code"},
+				{Name: "state", Description: "Login state from the login redirect"},
+				{Name: "error", Description: "Error returned by the identity provider"},
+			},
+			Response:    loginOrChallengeBody,
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusBadGateway},
+			RateLimited: true},
+
+		// Current user and account security
+		{Method: http.MethodGet, Path: "/api/v1/me", Tag: "Account", Summary: "Current user", Auth: openapi.User,
+			Response: openapi.Object{"user": models.User{}}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/events", Tag: "Account", Summary: "Real-time event stream (server-sent events)",
+			Description: "Pushes media.processed, media.metadata_extracted, album.media_added and share_link.accessed events " +
+				"for the current user. Each event has an id; reconnect with the Last-Event-ID header (or last_event_id) " +
+				"to receive the events missed while disconnected. Streams are closed after EVENTS_MAX_STREAM.",
+			Auth: openapi.User, Query: []openapi.Param{
+				{Name: "last_event_id", Description: "Resume after this event id, for clients that cannot send Last-Event-ID", Schema: int64(0)},
+			},
+			Produces: []string{"text/event-stream"}, Errors: []int{http.StatusServiceUnavailable}},
+		{Method: http.MethodPost, Path: "/api/v1/me/password", Tag: "Account", Summary: "Change password and revoke other sessions",
+			Auth: openapi.JWTOnly, Body: models.ChangePasswordInput{}, Response: messageBody},
+		{Method: http.MethodGet, Path: "/api/v1/me/identities", Tag: "Account", Summary: "Linked external identities",
+			Auth: openapi.JWTOnly, Response: openapi.Object{"count": 0, "data": []models.ExternalIdentity{}}},
+		{Method: http.MethodDelete, Path: "/api/v1/me/identities/:id", Tag: "Account", Summary: "Unlink an external identity",
+			Auth: openapi.JWTOnly, Response: messageBody, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+
+		// Two-factor authentication
+		{Method: http.MethodPost, Path: "/api/v1/me/2fa/setup", Tag: "Two-factor", Summary: "Start TOTP setup",
+			Auth:     openapi.JWTOnly,
+			Response: openapi.Object{"message": "", "secret": "", "otpauth_url": ""},
+			Errors:   []int{http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPost, Path: "/api/v1/me/2fa/enable", Tag: "Two-factor", Summary: "Confirm TOTP setup and get recovery codes",
+			Auth: openapi.JWTOnly, Body: models.TwoFactorCodeInput{},
+			Response: openapi.Object{"message": "", "recovery_codes": []string{}},
+			Errors:   []int{http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPost, Path: "/api/v1/me/2fa/disable", Tag: "Two-factor", Summary: "Disable two-factor authentication",
+			Auth: openapi.JWTOnly, Body: models.DisableTwoFactorInput{}, Response: messageBody,
+			Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/me/2fa/recovery-codes", Tag: "Two-factor", Summary: "Regenerate recovery codes",
+			Auth: openapi.JWTOnly, Body: models.TwoFactorCodeInput{},
+			Response: openapi.Object{"message": "", "recovery_codes": []string{}},
+			Errors:   []int{http.StatusNotFound}},
+
+		// Sessions
+		{Method: http.MethodGet, Path: "/api/v1/me/sessions", Tag: "Sessions", Summary: "Active sessions (devices)",
+			Auth: openapi.JWTOnly, Response: openapi.Object{"count": 0, "data": []models.Session{}}},
+		{Method: http.MethodDelete, Path: "/api/v1/me/sessions", Tag: "Sessions", Summary: "Log out all other sessions",
+			Auth: openapi.JWTOnly, Response: openapi.Object{"message": "", "revoked": int64(0)}},
+		{Method: http.MethodDelete, Path: "/api/v1/me/sessions/:id", Tag: "Sessions", Summary: "Log out a session",
+			Auth: openapi.JWTOnly, Response: messageBody, Errors: []int{http.StatusNotFound}},
+
+		// Personal access tokens
+		{Method: http.MethodGet, Path: "/api/v1/me/tokens", Tag: "Access tokens", Summary: "Personal access tokens",
+			Auth: openapi.JWTOnly, Response: openapi.Object{"count": 0, "data": []models.AccessToken{}}},
+		{Method: http.MethodPost, Path: "/api/v1/me/tokens", Tag: "Access tokens", Summary: "Create a personal access token",
+			Description: "The token is only returned once.",
+			Auth:        openapi.JWTOnly, Body: models.CreateAccessTokenInput{}, Status: http.StatusCreated,
+			Response: openapi.Object{"message": "", "token": "", "data": models.AccessToken{}},
+			Errors:   []int{http.StatusConflict}},
+		{Method: http.MethodDelete, Path: "/api/v1/me/tokens/:id", Tag: "Access tokens", Summary: "Revoke a personal access token",
+			Auth: openapi.JWTOnly, Response: messageBody, Errors: []int{http.StatusNotFound}},
+
+		// Uploads
+		{Method: http.MethodPost, Path: "/api/v1/upload/avatar", Tag: "Uploads", Summary: "Upload an avatar",
+			Auth: openapi.User, Scope: models.ScopeUpload, Form: openapi.Object{"image": openapi.File},
+			Response: uploadedImage},
+		{Method: http.MethodPost, Path: "/api/v1/upload/image", Tag: "Uploads", Summary: "Upload an image to the gallery",
+			Auth: openapi.User, Scope: models.ScopeUpload,
+			Form: openapi.Object{
+				"image":       openapi.File,
+				"album_id":    openapi.Optional(""),
+				"title":       openapi.Optional(""),
+				"description": openapi.Optional(""),
+				"tags":        openapi.Optional(""),
+			},
+			Response: withMediaID(uploadedImage)},
+		{Method: http.MethodPost, Path: "/api/v1/upload/video", Tag: "Uploads", Summary: "Upload a video",
+			Auth: openapi.User, Scope: models.ScopeUpload,
+			Form: openapi.Object{
+				"video":       openapi.File,
+				"album_id":    openapi.Optional(""),
+				"title":       openapi.Optional(""),
+				"description": openapi.Optional(""),
+				"tags":        openapi.Optional(""),
+			},
+			Response: openapi.Object{
+				"message":  "",
+				"user_id":  "",
+				"media_id": "",
+				"filename": "",
+				"url":      "",
+				"size":     int64(0),
+				"duration": float64(0),
+			}},
+
+		// Albums and sharing
+		{Method: http.MethodPost, Path: "/api/v1/albums", Tag: "Albums", Summary: "Create a regular or smart album",
+			Auth: openapi.User, Body: models.CreateAlbumInput{}, Status: http.StatusCreated, Response: models.Album{}},
+		{Method: http.MethodGet, Path: "/api/v1/albums", Tag: "Albums", Summary: "Albums of the current user",
+			Auth: openapi.User, Response: []models.Album{}},
+		{Method: http.MethodGet, Path: "/api/v1/albums/:id/media", Tag: "Albums", Summary: "Media in an album",
+			Auth: openapi.User,
+			Query: []openapi.Param{
+				{Name: "page", Description: "Page number, from 1", Schema: 0},
+				{Name: "limit", Description: "Page size, up to 100", Schema: 0},
+			},
+			Response: openapi.Object{"album": models.Album{}, "page": 0, "limit": 0, "total": int64(0), "data": []models.Media{}},
+			Errors:   []int{http.StatusNotFound, http.StatusUnprocessableEntity}},
+		{Method: http.MethodDelete, Path: "/api/v1/media/:id", Tag: "Albums", Summary: "Delete media",
+			Description: "Removes the file, its cached renders and its share links stop working.",
+			Auth:        openapi.User, Response: messageBody, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/media/:id/share", Tag: "Albums", Summary: "Create a share link",
+			Auth: openapi.User, Body: models.CreateShareLinkInput{}, BodyOptional: true, Status: http.StatusCreated,
+			Response: openapi.Object{"message": "", "url": "", "data": models.ShareLink{}},
+			Errors:   []int{http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/media/:id/render", Tag: "Albums", Summary: "Create a signed render URL",
+			Description: "Returns a public URL of GET /media/{id}/render for the given size, crop, rotation and format.",
+			Auth:        openapi.User, Body: models.RenderInput{}, Response: openapi.Object{"message": "", "url": ""},
+			Errors: []int{http.StatusNotFound, http.StatusUnprocessableEntity}},
+		{Method: http.MethodDelete, Path: "/api/v1/shares/:id", Tag: "Albums", Summary: "Revoke a share link",
+			Auth: openapi.User, Response: messageBody, Errors: []int{http.StatusNotFound}},
+
+		// Users
+		{Method: http.MethodGet, Path: "/api/v1/users", Tag: "Users", Summary: "List users",
+			Auth: openapi.User, Response: userListBody},
+		{Method: http.MethodGet, Path: "/api/v1/users/search", Tag: "Users", Summary: "Search users by name or email",
+			Auth: openapi.User, Query: []openapi.Param{{Name: "q", Description: "Search term", Required: true}},
+			Response: userListBody},
+		{Method: http.MethodGet, Path: "/api/v1/users/:id", Tag: "Users", Summary: "Get a user",
+			Auth: openapi.User, Response: userBody, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPut, Path: "/api/v1/users/:id", Tag: "Users", Summary: "Update a user",
+			Description: "Only the account owner or an admin. Changing the email sends a verification link; the new address applies once verified.",
+			Auth:        openapi.User, Body: models.UpdateUser{}, Response: userBody,
+			Errors: []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPost, Path: "/api/v1/users", Tag: "Admin", Summary: "Create a user",
+			Auth: openapi.Admin, Body: models.User{}, Status: http.StatusCreated, Response: userBody,
+			Errors: []int{http.StatusConflict}},
+		{Method: http.MethodDelete, Path: "/api/v1/users/:id", Tag: "Admin", Summary: "Delete a user",
+			Auth: openapi.Admin, Response: messageBody, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: "/api/v1/users/:id/2fa", Tag: "Admin", Summary: "Reset a user's two-factor authentication",
+			Auth: openapi.Admin, Response: messageBody, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		statsOperation("users", "User totals and signups per day", models.UserStats{}, false, nil),
+		statsOperation("uploads", "Uploads per day by media type", []models.UploadsPerDay{}, false, nil),
+		statsOperation("storage", "Top uploaders by storage used", []models.StorageUsage{}, true, nil),
+		statsOperation("cameras", "Camera model distribution", []models.CameraCount{}, true, nil),
+		statsOperation("logins", "Failed logins per day by reason", []models.FailedLoginsPerDay{}, false, nil),
+		statsOperation("jobs", "Background job runs per day", []models.JobRunsPerDay{}, false, openapi.Object{
+			"queues": openapi.Object{
+				"mail":    openapi.Object{"pending": 0, "last_activity": int64(0)},
+				"webhook": openapi.Object{"pending": 0, "last_activity": int64(0)},
+			},
+		}),
+		{Method: http.MethodGet, Path: "/api/v1/admin/audit", Tag: "Admin", Summary: "Query the audit log",
+			Description: "Newest events first. Each event carries the hash of the previous one, so edits and deletions break the chain.",
+			Auth:        openapi.Admin, Query: []openapi.Param{
+				{Name: "action", Description: "Event action, e.g. user.delete"},
+				{Name: "actor_id", Description: "User who performed the action"},
+				{Name: "target_type", Description: "Type of the affected object, e.g. user or media"},
+				{Name: "target_id", Description: "ID of the affected object"},
+				{Name: "from", Description: "Start date (YYYY-MM-DD, UTC) or RFC 3339 timestamp"},
+				{Name: "to", Description: "End date, inclusive (YYYY-MM-DD, UTC), or exclusive RFC 3339 timestamp"},
+				{Name: "page", Description: "Page number, from 1", Schema: 0},
+				{Name: "limit", Description: "Page size, up to 100", Schema: 0},
+			},
+			Response: openapi.Object{"page": 0, "limit": 0, "total": int64(0), "data": []models.AuditEvent{}}},
+		{Method: http.MethodGet, Path: "/api/v1/admin/audit/verify", Tag: "Admin", Summary: "Verify the audit log hash chain",
+			Auth: openapi.Admin, Response: openapi.Object{
+				"valid": true, "checked": int64(0), "head_seq": int64(0),
+				"head_hash": openapi.Optional(""), "broken_at": openapi.Optional(int64(0)), "reason": openapi.Optional(""),
+			}},
+
+		// Webhooks
+		{Method: http.MethodGet, Path: "/api/v1/admin/webhooks", Tag: "Webhooks", Summary: "Webhook subscriptions",
+			Description: "Newest first. events lists every event type that can be subscribed to.",
+			Auth:        openapi.Admin, Response: openapi.Object{"count": 0, "data": []models.Webhook{}, "events": []string{}}},
+		{Method: http.MethodPost, Path: "/api/v1/admin/webhooks", Tag: "Webhooks", Summary: "Create a webhook subscription",
+			Description: "Events are POSTed to url as JSON, signed with the returned secret: " +
+				"X-Webhook-Signature is t=<unix time>,v1=<hex HMAC-SHA256 of \"<t>.<body>\">. The secret is only returned once. " +
+				"url must use https and resolve to a public address.",
+			Auth: openapi.Admin, Body: models.CreateWebhookInput{}, Status: http.StatusCreated,
+			Response: openapi.Object{"message": "", "secret": "", "data": models.Webhook{}}},
+		{Method: http.MethodPut, Path: "/api/v1/admin/webhooks/:id", Tag: "Webhooks", Summary: "Update a webhook subscription",
+			Description: "Setting enabled to true re-enables a subscription that was disabled after repeated failures.",
+			Auth:        openapi.Admin, Body: models.UpdateWebhookInput{},
+			Response: openapi.Object{"message": "", "data": models.Webhook{}}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: "/api/v1/admin/webhooks/:id", Tag: "Webhooks", Summary: "Delete a webhook subscription and its delivery log",
+			Auth: openapi.Admin, Response: messageBody, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id/deliveries", Tag: "Webhooks", Summary: "Webhook delivery log",
+			Description: "Newest first, with every attempt. Deliveries are kept for 30 days.",
+			Auth:        openapi.Admin, Query: []openapi.Param{
+				{Name: "page", Description: "Page number, from 1", Schema: 0},
+				{Name: "limit", Description: "Page size, up to 100", Schema: 0},
+			},
+			Response: openapi.Object{"page": 0, "limit": 0, "total": int64(0), "data": []models.WebhookDelivery{}},
+			Errors:   []int{http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/admin/webhooks/:id/deliveries/:delivery_id/redeliver", Tag: "Webhooks",
+			Summary:     "Redeliver a webhook event",
+			Description: "Queues the original payload again as a new delivery with the same event ID.",
+			Auth:        openapi.Admin, Status: http.StatusAccepted,
+			Response: openapi.Object{"message": "", "data": models.WebhookDelivery{}},
+			Errors:   []int{http.StatusNotFound, http.StatusConflict}},
+	}
+}
+
+// statsOperation - Endpoint thống kê admin: cùng tham số khoảng thời gian, trả JSON hoặc CSV
+func statsOperation(name, summary string, data any, limited bool, extra openapi.Object) openapi.Operation {
+	query := []openapi.Param{
+		{Name: "from", Description: "Start date (YYYY-MM-DD, UTC) or RFC 3339 timestamp; defaults to 30 days before to"},
+		{Name: "to", Description: "End date, inclusive (YYYY-MM-DD, UTC), or exclusive RFC 3339 timestamp; defaults to today. Ranges are limited to 366 days"},
+		{Name: "format", Description: "json (default) or csv; Accept: text/csv also selects CSV"},
+	}
+	if limited {
+		query = append(query, openapi.Param{Name: "limit", Description: "Number of rows, up to 100 (default 10)", Schema: 0})
+	}
+	response := openapi.Object{"from": "", "to": "", "data": data}
+	for key, value := range extra {
+		response[key] = value
+	}
+	return openapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/admin/stats/" + name, Tag: "Admin", Summary: summary,
+		Auth: openapi.Admin, Query: query, Response: response, Produces: []string{"text/csv"},
+	}
+}
+
+// withMediaID - Body upload ảnh vào thư viện: như avatar, thêm media_id
+func withMediaID(body openapi.Object) openapi.Object {
+	out := openapi.Object{"media_id": ""}
+	for key, value := range body {
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/openapi"
+	"github.com/hieu9721/media-store-backend/repository"
+)
+
+// TestOpenAPICoversRoutes - Mọi route đăng ký trong SetupRoutes phải có trong tài liệu OpenAPI và ngược lại
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	// Bật mọi route tuỳ chọn để tài liệu được so với bảng route đầy đủ
+	cfg.Metrics.Enabled = true
+	cfg.API.Docs = true
+	router := SetupRoutes(repository.NewMemory(), cfg)
+	doc := OpenAPI()
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path := openapi.Path(route.Path)
+		registered[route.Method+" "+path] = true
+		item := doc.Paths.Value(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("%s %s is registered but missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is documented but not registered", method, path)
+			}
+		}
+	}
+}
+
+// TestOpenAPIValid - Tài liệu sinh ra phải hợp lệ theo đặc tả OpenAPI 3
+func TestOpenAPIValid(t *testing.T) {
+	if err := OpenAPI().Validate(context.Background()); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.API.Docs = true
+	router := SetupRoutes(repository.NewMemory(), cfg)
+
+	for _, path := range []string{"/openapi.json", "/docs"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, w.Code)
+		}
+	}
+}
@@ -1,19 +1,40 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/hieu9721/media-store-backend/api"
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/metrics"
 	"github.com/hieu9721/media-store-backend/middleware"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/openapi"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/repository"
 )
 
-func SetupRoutes() *gin.Engine {
-    router := gin.Default()
+func SetupRoutes(repos *repository.Repositories, cfg *config.Config) *gin.Engine {
+    router := gin.New()
+    // Client IP (rate limits, sessions, audit) only comes from X-Forwarded-For when the
+    // connection is from a configured proxy; validated by config, so this cannot fail
+    if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+        panic(err)
+    }
+    h := api.NewHandler(repos, cfg)
 
-    // Middleware
+    // Middleware: request ID first so access logs, panics and error bodies carry it,
+    // then the server span so logs and downstream calls share its trace ID
+    router.Use(middleware.RequestID())
+    router.Use(middleware.Tracing(cfg.Tracing.ServiceName))
+    router.Use(middleware.AccessLog())
+    router.Use(middleware.Metrics())
+    router.Use(middleware.Recovery())
     router.Use(middleware.CORS())
     router.Use(middleware.ErrorHandler())
+    router.NoRoute(middleware.NotFound())
 
-    // Health check
+    // Health check (legacy, always OK; use /livez and /readyz for probes)
     router.GET("/health", func(c *gin.Context) {
         c.JSON(200, gin.H{
             "status": "OK",
@@ -21,49 +42,160 @@ func SetupRoutes() *gin.Engine {
         })
     })
 
-    // Serve static files (uploaded images)
-    router.Static("/uploads", "./uploads")
+    // Liveness, readiness (dependency checks) and build info
+    router.GET("/livez", h.Livez)
+    router.GET("/readyz", h.Readyz)
+    router.GET("/version", h.Version)
+
+    // Prometheus metrics, optionally protected by a bearer token (METRICS_TOKEN)
+    if cfg.Metrics.Enabled {
+        router.GET("/metrics", middleware.MetricsAuth(cfg.Metrics.Token), gin.WrapH(metrics.Handler()))
+    }
+
+    // OpenAPI document and Swagger UI
+    spec := OpenAPI()
+    if cfg.API.Docs {
+        router.GET("/openapi.json", gin.WrapH(openapi.SpecHandler(spec)))
+        router.GET("/docs", gin.WrapH(openapi.DocsHandler("/openapi.json")))
+    }
+
+    // Serve uploaded files (images are stripped of GPS/metadata per owner settings)
+    router.GET("/uploads/*filepath", h.ServeUpload)
+    router.HEAD("/uploads/*filepath", h.ServeUpload)
+
+    // Public share links
+    router.GET("/s/:token", h.GetSharedMedia)
+
+    // Signed on-the-fly image renders (resize, crop, rotate, re-encode), cached on disk
+    router.GET("/media/:id/render", h.RenderMedia)
 
     // API v1 routes
     v1 := router.Group("/api/v1")
     {
+        // Requests are checked against the OpenAPI document after authentication,
+        // so anonymous callers get 401 rather than validation details
+        validate := func(c *gin.Context) { c.Next() }
+        if cfg.API.ValidateRequests {
+            validate = middleware.ValidateRequest(spec)
+        }
+
         // Auth routes (public)
         auth := v1.Group("/auth")
+        auth.Use(validate)
+        {
+            auth.POST("/register", middleware.RateLimit("register", ratelimit.Per(5, time.Hour)), h.Register)
+            auth.POST("/login", middleware.RateLimit("login", ratelimit.Per(20, time.Minute)), h.Login)
+            auth.POST("/verify-email", middleware.RateLimit("verify", ratelimit.Per(10, 15*time.Minute)), h.VerifyEmail)
+            auth.POST("/resend-verification", middleware.RateLimit("mail", ratelimit.Per(5, 15*time.Minute)), h.ResendVerification)
+            auth.POST("/forgot-password", middleware.RateLimit("mail", ratelimit.Per(5, 15*time.Minute)), h.ForgotPassword)
+            auth.POST("/reset-password", middleware.RateLimit("verify", ratelimit.Per(10, 15*time.Minute)), h.ResetPassword)
+            auth.POST("/2fa/verify", middleware.RateLimit("login", ratelimit.Per(20, time.Minute)), h.VerifyTwoFactorLogin)
+
+            // OpenID Connect login
+            auth.GET("/oidc/providers", h.GetOIDCProviders)
+            auth.GET("/oidc/:provider/login", middleware.RateLimit("login", ratelimit.Per(20, time.Minute)), h.OIDCLogin)
+            auth.GET("/oidc/:provider/callback", middleware.RateLimit("login", ratelimit.Per(20, time.Minute)), h.OIDCCallback)
+        }
+
+        // Upload routes: personal access tokens need the upload scope (or write)
+        upload := v1.Group("/upload")
+        upload.Use(middleware.AuthRequired(repos, models.ScopeUpload), validate, middleware.InFlight(metrics.UploadsInFlight))
         {
-            auth.POST("/register", api.Register)
-            auth.POST("/login", api.Login)
+            upload.POST("/avatar", h.UploadAvatar)           // Upload avatar
+            upload.POST("/image", h.UploadUserImage)         // Upload image to user gallery
+            upload.POST("/video", h.UploadVideo)             // Upload video
         }
 
         // Protected routes (require authentication)
         protected := v1.Group("")
-        protected.Use(middleware.AuthRequired())
+        protected.Use(middleware.AuthRequired(repos, ""), validate)
         {
             // Current user
-            protected.GET("/me", api.GetCurrentUser)
+            protected.GET("/me", h.GetCurrentUser)
+
+            // Real-time events of the current user (server-sent events)
+            protected.GET("/events", middleware.InFlight(metrics.EventStreams), h.GetEvents)
+
+            // Account security settings (not available to personal access tokens)
+            account := protected.Group("/me")
+            account.Use(middleware.JWTOnly())
+            {
+                // Linked external identities (OIDC)
+                account.GET("/identities", h.GetMyIdentities)
+                account.DELETE("/identities/:id", h.DeleteMyIdentity)
+
+                // Two-factor authentication (TOTP)
+                account.POST("/2fa/setup", h.SetupTwoFactor)
+                account.POST("/2fa/enable", h.EnableTwoFactor)
+                account.POST("/2fa/disable", h.DisableTwoFactor)
+                account.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+
+                // Active sessions (devices)
+                account.GET("/sessions", h.GetSessions)
+                account.DELETE("/sessions", h.DeleteOtherSessions)
+                account.DELETE("/sessions/:id", h.DeleteSession)
+                account.POST("/password", h.ChangePassword)
+
+                // Personal access tokens
+                account.GET("/tokens", h.GetAccessTokens)
+                account.POST("/tokens", h.CreateAccessToken)
+                account.DELETE("/tokens/:id", h.DeleteAccessToken)
+            }
 
-            // Upload routes
-            upload := protected.Group("/upload")
+            // Album routes (regular + smart albums)
+            albums := protected.Group("/albums")
             {
-                upload.POST("/avatar", api.UploadAvatar)           // Upload avatar
-                upload.POST("/image", api.UploadUserImage)         // Upload image to user gallery
-                upload.POST("/video", api.UploadVideo)             // Upload video
+                albums.POST("", h.CreateAlbum)
+                albums.GET("", h.GetAlbums)
+                albums.GET("/:id/media", h.GetAlbumMedia)
             }
 
+            // Media sharing
+            protected.DELETE("/media/:id", h.DeleteMedia)
+            protected.POST("/media/:id/share", h.CreateShareLink)
+            protected.POST("/media/:id/render", h.CreateRenderURL)
+            protected.DELETE("/shares/:id", h.DeleteShareLink)
+
             // User routes (protected)
             users := protected.Group("/users")
             {
-                users.GET("", api.GetUsers)
-                users.GET("/search", api.SearchUsers)
-                users.GET("/:id", api.GetUser)
-                users.PUT("/:id", api.UpdateUser)
+                users.GET("", h.GetUsers)
+                users.GET("/search", h.SearchUsers)
+                users.GET("/:id", h.GetUser)
+                users.PUT("/:id", h.UpdateUser)
             }
 
-            // Admin only routes
+            // Admin only routes; personal access tokens inherit the role, so they are refused here
             admin := users.Group("")
-            admin.Use(middleware.AdminRequired())
+            admin.Use(middleware.JWTOnly(), middleware.AdminRequired())
             {
-                admin.POST("", api.CreateUser)
-                admin.DELETE("/:id", api.DeleteUser)
+                admin.POST("", h.CreateUser)
+                admin.DELETE("/:id", h.DeleteUser)
+                admin.DELETE("/:id/2fa", h.ResetUserTwoFactor)
+            }
+
+            // Admin reports: statistics (JSON hoặc CSV), audit log và webhooks
+            reports := protected.Group("/admin")
+            reports.Use(middleware.JWTOnly(), middleware.AdminRequired())
+            {
+                stats := reports.Group("/stats")
+                stats.GET("/users", h.StatsUsers)
+                stats.GET("/uploads", h.StatsUploads)
+                stats.GET("/storage", h.StatsStorage)
+                stats.GET("/cameras", h.StatsCameras)
+                stats.GET("/logins", h.StatsFailedLogins)
+                stats.GET("/jobs", h.StatsJobs)
+
+                reports.GET("/audit", h.GetAuditEvents)
+                reports.GET("/audit/verify", h.VerifyAuditLog)
+
+                // Outgoing webhooks: subscriptions, delivery log and manual redelivery
+                reports.GET("/webhooks", h.GetWebhooks)
+                reports.POST("/webhooks", h.CreateWebhook)
+                reports.PUT("/webhooks/:id", h.UpdateWebhook)
+                reports.DELETE("/webhooks/:id", h.DeleteWebhook)
+                reports.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+                reports.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
             }
         }
     }
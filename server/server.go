@@ -0,0 +1,113 @@
+// Package server chạy HTTP server với timeout rõ ràng và tắt êm (graceful shutdown) khi nhận tín hiệu
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Options - Thông số của HTTP server (lấy từ config.ServerConfig); upload lớn cần ReadTimeout/WriteTimeout đủ dài
+type Options struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ShutdownTimeout - Thời hạn chung để request đang chạy và các worker nền hoàn tất khi tắt
+	ShutdownTimeout time.Duration
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Server - HTTP server kèm các bước dọn dẹp chạy theo thứ tự sau khi ngừng nhận request
+type Server struct {
+	http  *http.Server
+	opts  Options
+	hooks []shutdownHook
+	drain []shutdownHook
+}
+
+// New - Tạo server cho handler với opts
+func New(handler http.Handler, opts Options) *Server {
+	return &Server{
+		opts: opts,
+		http: &http.Server{
+			Addr:              opts.Addr,
+			Handler:           handler,
+			ReadHeaderTimeout: opts.ReadHeaderTimeout,
+			ReadTimeout:       opts.ReadTimeout,
+			WriteTimeout:      opts.WriteTimeout,
+			IdleTimeout:       opts.IdleTimeout,
+			MaxHeaderBytes:    opts.MaxHeaderBytes,
+		},
+	}
+}
+
+// OnShutdown - Đăng ký bước dọn dẹp (drain worker, đóng database); chạy theo thứ tự đăng ký
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// BeforeDrain - Đăng ký bước chạy ngay khi bắt đầu tắt, trước khi chờ request đang chạy: dùng để kết thúc
+// các kết nối kéo dài (stream SSE) mà nếu không sẽ giữ server tới hết ShutdownTimeout
+func (s *Server) BeforeDrain(name string, fn func(ctx context.Context) error) {
+	s.drain = append(s.drain, shutdownHook{name: name, fn: fn})
+}
+
+// Run - Phục vụ tới khi ctx bị hủy (SIGTERM/SIGINT), rồi ngừng nhận kết nối mới, chờ request đang chạy
+// và các hook hoàn tất trong ShutdownTimeout. Lỗi listen (ví dụ port đã bị chiếm) được trả về ngay.
+func (s *Server) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			runHooks(context.Background(), s.hooks)
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", s.opts.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+	defer cancel()
+
+	runHooks(shutdownCtx, s.drain)
+	err := s.http.Shutdown(shutdownCtx)
+	if err != nil {
+		slog.Warn("shutdown deadline exceeded, closing remaining connections", "error", err)
+		s.http.Close()
+	}
+
+	if hookErr := runHooks(shutdownCtx, s.hooks); err == nil {
+		err = hookErr
+	}
+	if err == nil {
+		slog.Info("server stopped cleanly")
+	}
+	return err
+}
+
+func runHooks(ctx context.Context, hooks []shutdownHook) error {
+	var first error
+	for _, hook := range hooks {
+		if err := hook.fn(ctx); err != nil {
+			slog.Error("shutdown step failed", "step", hook.name, "error", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,38 @@
+package sso
+
+import (
+	"log/slog"
+
+	"github.com/hieu9721/media-store-backend/config"
+)
+
+var registry = NewRegistry()
+
+// Setup - Tạo registry từ các provider trong cấu hình (oidc.providers hoặc OIDC_PROVIDERS và OIDC_<NAME>_*),
+// đã được kiểm tra khi đọc cấu hình
+func Setup(cfg config.OIDCConfig) {
+	var configs []ProviderConfig
+	for _, provider := range cfg.Providers {
+		configs = append(configs, ProviderConfig{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		})
+		slog.Info("OIDC provider configured", "provider", provider.Name, "issuer", provider.Issuer)
+	}
+
+	registry = NewRegistry(configs...)
+}
+
+// SetRegistry - Thay registry hiện tại, dùng cho test với mock OIDC server
+func SetRegistry(r *Registry) {
+	registry = r
+}
+
+// Default - Registry hiện tại
+func Default() *Registry {
+	return registry
+}
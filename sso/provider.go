@@ -0,0 +1,185 @@
+// Package sso triển khai OpenID Connect relying party (discovery, PKCE, state/nonce, xác thực ID token qua JWKS)
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrMissingIDToken  = errors.New("token response does not contain an id_token")
+	ErrNonceMismatch   = errors.New("id_token nonce does not match")
+)
+
+// ProviderConfig - Cấu hình một identity provider
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// HTTPClient - Tùy chọn, dùng để trỏ tới mock OIDC server khi test
+	HTTPClient *http.Client
+}
+
+// Identity - Thông tin user lấy từ ID token đã xác thực
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider - Một identity provider đã discovery xong
+type Provider struct {
+	config   ProviderConfig
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewProvider - Gọi discovery (/.well-known/openid-configuration) và chuẩn bị verifier dùng JWKS của provider
+func NewProvider(ctx context.Context, config ProviderConfig) (*Provider, error) {
+	ctx = config.context(ctx)
+
+	discovered, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", config.Name, err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+
+	return &Provider{
+		config: config,
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: discovered.VerifierContext(ctx, &oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+func (c ProviderConfig) context(ctx context.Context) context.Context {
+	if c.HTTPClient != nil {
+		return oidc.ClientContext(ctx, c.HTTPClient)
+	}
+	return ctx
+}
+
+// Name - Tên provider trong cấu hình
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL - URL chuyển user tới trang đăng nhập của provider (authorization code + PKCE S256)
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange - Đổi authorization code lấy token, xác thực chữ ký/issuer/audience/expiry của ID token và nonce
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	ctx = p.config.context(ctx)
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id_token verification: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("id_token claims: %w", err)
+	}
+
+	return &Identity{
+		Provider:      p.config.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}, nil
+}
+
+// Registry - Danh sách provider; discovery được thực hiện lần đầu provider được dùng
+type Registry struct {
+	mu        sync.Mutex
+	configs   map[string]ProviderConfig
+	providers map[string]*Provider
+}
+
+func NewRegistry(configs ...ProviderConfig) *Registry {
+	r := &Registry{
+		configs:   map[string]ProviderConfig{},
+		providers: map[string]*Provider{},
+	}
+	for _, config := range configs {
+		r.configs[config.Name] = config
+	}
+	return r
+}
+
+// Get - Lấy provider theo tên, discovery nếu chưa có (thử lại ở lần gọi sau nếu discovery lỗi)
+func (r *Registry) Get(ctx context.Context, name string) (*Provider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if provider, ok := r.providers[name]; ok {
+		return provider, nil
+	}
+	config, ok := r.configs[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	provider, err := NewProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	r.providers[name] = provider
+	return provider, nil
+}
+
+// Names - Tên các provider đã cấu hình
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.configs))
+	for name := range r.configs {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,199 @@
+package testkit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// Password - Mật khẩu của mọi user tạo bởi CreateUser
+const Password = "testkit-password"
+
+var (
+	passwordHashOnce sync.Once
+	passwordHash     string
+	sequence         atomic.Int64
+)
+
+// hashedPassword - Hash bcrypt của Password, tính một lần cho cả lần chạy test
+func hashedPassword() string {
+	passwordHashOnce.Do(func() {
+		hash, err := utils.HashPassword(Password)
+		if err != nil {
+			panic(err)
+		}
+		passwordHash = hash
+	})
+	return passwordHash
+}
+
+// UserOption - Tùy chỉnh user trước khi lưu
+type UserOption func(*models.User)
+
+// WithRole - Role của user: "user" (mặc định) hoặc "admin"
+func WithRole(role string) UserOption {
+	return func(u *models.User) { u.Role = role }
+}
+
+// WithEmail - Email cố định thay cho email sinh tự động
+func WithEmail(email string) UserOption {
+	return func(u *models.User) { u.Email = email }
+}
+
+// WithName - Tên hiển thị thay cho tên sinh tự động
+func WithName(name string) UserOption {
+	return func(u *models.User) { u.Name = name }
+}
+
+// Unverified - User chưa xác thực email (không đăng nhập được)
+func Unverified() UserOption {
+	return func(u *models.User) { u.EmailVerified = repository.Ptr(false) }
+}
+
+// CreateUser - Lưu user đã xác thực email với mật khẩu Password; email là duy nhất trong lần chạy test
+func (k *Kit) CreateUser(opts ...UserOption) *models.User {
+	k.t.Helper()
+	n := sequence.Add(1)
+	now := time.Now().Unix()
+	user := &models.User{
+		ID:            utils.GenerateUserID(),
+		Name:          fmt.Sprintf("Test User %d", n),
+		Email:         fmt.Sprintf("user%d@example.test", n),
+		EmailVerified: repository.Ptr(true),
+		Password:      hashedPassword(),
+		Role:          "user",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	for _, opt := range opts {
+		opt(user)
+	}
+	if err := k.Repos.Users.Create(k.Context(), user); err != nil {
+		k.t.Fatalf("testkit: create user: %v", err)
+	}
+	return user
+}
+
+// Token - JWT của user kèm một phiên đăng nhập, giống token cấp khi đăng nhập
+func (k *Kit) Token(user *models.User) string {
+	k.t.Helper()
+	now := time.Now()
+	session := models.Session{
+		ID:         utils.GenerateID("ses"),
+		UserID:     user.ID,
+		DeviceName: "testkit",
+		CreatedAt:  now.Unix(),
+		LastSeenAt: now.Unix(),
+		ExpiresAt:  now.Add(utils.TokenTTL).Unix(),
+		PurgeAt:    now.Add(utils.TokenTTL),
+	}
+	if err := k.Repos.Sessions.Create(k.Context(), &session); err != nil {
+		k.t.Fatalf("testkit: create session: %v", err)
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Email, user.Role, session.ID)
+	if err != nil {
+		k.t.Fatalf("testkit: generate token: %v", err)
+	}
+	return token
+}
+
+// Login - Tạo user với role cho trước và trả về user cùng JWT của user đó
+func (k *Kit) Login(role string, opts ...UserOption) (*models.User, string) {
+	k.t.Helper()
+	user := k.CreateUser(append([]UserOption{WithRole(role)}, opts...)...)
+	return user, k.Token(user)
+}
+
+// AlbumOption - Tùy chỉnh album trước khi lưu
+type AlbumOption func(*models.Album)
+
+// Smart - Album thông minh với cây điều kiện rules
+func Smart(rules models.SmartRule) AlbumOption {
+	return func(a *models.Album) {
+		a.Type = models.AlbumTypeSmart
+		a.Rules = &rules
+	}
+}
+
+// CreateAlbum - Lưu album thường của owner
+func (k *Kit) CreateAlbum(owner *models.User, name string, opts ...AlbumOption) *models.Album {
+	k.t.Helper()
+	now := time.Now().Unix()
+	album := &models.Album{
+		ID:        utils.GenerateID("alb"),
+		UserID:    owner.ID,
+		Name:      name,
+		Type:      models.AlbumTypeRegular,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, opt := range opts {
+		opt(album)
+	}
+	if err := k.Repos.Albums.Create(k.Context(), album); err != nil {
+		k.t.Fatalf("testkit: create album: %v", err)
+	}
+	return album
+}
+
+// MediaOption - Tùy chỉnh media trước khi lưu
+type MediaOption func(*models.Media)
+
+// InAlbum - Media thuộc album
+func InAlbum(album *models.Album) MediaOption {
+	return func(m *models.Media) { m.AlbumID = album.ID }
+}
+
+// WithMetadata - Metadata như khi được trích xuất lúc upload
+func WithMetadata(metadata models.ImageMetadata) MediaOption {
+	return func(m *models.Media) { m.Metadata = &metadata }
+}
+
+// WithTags - Tag của media
+func WithTags(tags ...string) MediaOption {
+	return func(m *models.Media) { m.Tags = tags }
+}
+
+// CreateMedia - Lưu ảnh của owner, kèm file JPEG thật trong thư mục upload để /uploads phục vụ được
+func (k *Kit) CreateMedia(owner *models.User, opts ...MediaOption) *models.Media {
+	k.t.Helper()
+	now := time.Now().Unix()
+	filename := utils.GenerateID("img") + ".jpg"
+	rel := fmt.Sprintf("uid_%s/gallery/%s", owner.ID, filename)
+	media := &models.Media{
+		ID:        utils.GenerateID("med"),
+		UserID:    owner.ID,
+		Type:      "image",
+		URL:       k.Config.Server.BaseURL + "/uploads/" + rel,
+		Filename:  filename,
+		Path:      rel,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, opt := range opts {
+		opt(media)
+	}
+
+	data := JPEG(16, 16, nil)
+	fullPath := filepath.Join(k.Config.Storage.UploadDir, filepath.FromSlash(media.Path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		k.t.Fatalf("testkit: create upload dir: %v", err)
+	}
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		k.t.Fatalf("testkit: write media file: %v", err)
+	}
+	media.Size = int64(len(data))
+
+	if err := k.Repos.Media.Create(k.Context(), media); err != nil {
+		k.t.Fatalf("testkit: create media: %v", err)
+	}
+	return media
+}
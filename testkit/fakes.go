@@ -0,0 +1,139 @@
+package testkit
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/mailer"
+	"github.com/hieu9721/media-store-backend/models"
+)
+
+// Outbox - Mailer giữ email trong bộ nhớ thay vì gửi đi
+type Outbox struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+	read     map[string]int
+}
+
+// Send - Lưu email vào Outbox
+func (o *Outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages - Các email đã gửi, theo thứ tự gửi
+func (o *Outbox) Messages() []mailer.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]mailer.Message(nil), o.messages...)
+}
+
+// Wait - Chờ email tiếp theo gửi tới địa chỉ to (email gửi ở background nên có độ trễ).
+// Mỗi lần gọi trả về một email chưa được Wait trả về trước đó.
+func (o *Outbox) Wait(t testing.TB, to string) mailer.Message {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if msg, ok := o.next(to); ok {
+			return msg
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no email sent to %s", to)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (o *Outbox) next(to string) (mailer.Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.read == nil {
+		o.read = map[string]int{}
+	}
+	seen := 0
+	for _, msg := range o.messages {
+		if msg.To != to {
+			continue
+		}
+		if seen == o.read[to] {
+			o.read[to]++
+			return msg, true
+		}
+		seen++
+	}
+	return mailer.Message{}, false
+}
+
+// LinkToken - Giá trị tham số token trong link của email (xác thực email, đặt lại mật khẩu)
+func LinkToken(t testing.TB, msg mailer.Message) string {
+	t.Helper()
+	for _, field := range strings.Fields(msg.Body) {
+		u, err := url.Parse(field)
+		if err != nil {
+			continue
+		}
+		if token := u.Query().Get("token"); token != "" {
+			return token
+		}
+	}
+	t.Fatalf("no token link in email %q", msg.Subject)
+	return ""
+}
+
+// Coordinates - Tọa độ đã được tra cứu
+type Coordinates struct {
+	Latitude, Longitude float64
+}
+
+// StubGeocoder - Geocoder trả địa chỉ cố định và ghi lại tọa độ được tra cứu
+type StubGeocoder struct {
+	mu       sync.Mutex
+	location *models.LocationInfo
+	err      error
+	calls    []Coordinates
+}
+
+// NewStubGeocoder - Geocoder luôn trả về một địa chỉ ở Hà Nội
+func NewStubGeocoder() *StubGeocoder {
+	return &StubGeocoder{location: &models.LocationInfo{
+		Country:     "Vietnam",
+		City:        "Hanoi",
+		District:    "Hoan Kiem",
+		DisplayName: "Hoan Kiem, Hanoi, Vietnam",
+	}}
+}
+
+// Respond - Đổi kết quả trả về: location cho mọi tọa độ, hoặc lỗi nếu err khác nil
+func (g *StubGeocoder) Respond(location *models.LocationInfo, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.location, g.err = location, err
+}
+
+// Reverse - Trả kết quả đã đặt và ghi lại tọa độ
+func (g *StubGeocoder) Reverse(ctx context.Context, lat, lon float64) (*models.LocationInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = append(g.calls, Coordinates{Latitude: lat, Longitude: lon})
+	if g.err != nil {
+		return nil, g.err
+	}
+	if g.location == nil {
+		return nil, nil
+	}
+	location := *g.location
+	return &location, nil
+}
+
+// Calls - Các tọa độ đã được tra cứu
+func (g *StubGeocoder) Calls() []Coordinates {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Coordinates(nil), g.calls...)
+}
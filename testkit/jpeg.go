@@ -0,0 +1,176 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"time"
+)
+
+// EXIF - Tag EXIF ghi vào ảnh tổng hợp; field rỗng/0 được bỏ qua
+type EXIF struct {
+	Make  string
+	Model string
+	// Taken - DateTimeOriginal (giờ địa phương, không có offset)
+	Taken time.Time
+	// Latitude, Longitude - Tọa độ GPS theo độ; cả hai bằng 0 nghĩa là không có GPS
+	Latitude  float64
+	Longitude float64
+}
+
+// JPEG - Ảnh JPEG tổng hợp kích thước width x height, kèm block EXIF (APP1) nếu exif khác nil
+func JPEG(width, height int, exif *EXIF) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: 128, A: 255})
+		}
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, nil); err != nil {
+		panic(err)
+	}
+	if exif == nil {
+		return out.Bytes()
+	}
+
+	// Chèn APP1 "Exif\0\0" + TIFF ngay sau SOI
+	payload := append([]byte("Exif\x00\x00"), exif.tiff()...)
+	data := out.Bytes()
+	var result bytes.Buffer
+	result.Write(data[:2])
+	result.Write([]byte{0xFF, 0xE1})
+	binary.Write(&result, binary.BigEndian, uint16(len(payload)+2))
+	result.Write(payload)
+	result.Write(data[2:])
+	return result.Bytes()
+}
+
+// Kiểu dữ liệu TIFF
+const (
+	tiffByte     = 1
+	tiffASCII    = 2
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+)
+
+type tiffEntry struct {
+	tag   uint16
+	kind  uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, value string) tiffEntry {
+	data := append([]byte(value), 0)
+	return tiffEntry{tag: tag, kind: tiffASCII, count: uint32(len(data)), data: data}
+}
+
+func longEntry(tag uint16, value uint32) tiffEntry {
+	return tiffEntry{tag: tag, kind: tiffLong, count: 1, data: binary.LittleEndian.AppendUint32(nil, value)}
+}
+
+// degreesEntry - Tọa độ dạng độ/phút/giây, mỗi phần là một RATIONAL
+func degreesEntry(tag uint16, value float64) tiffEntry {
+	value = math.Abs(value)
+	degrees := math.Floor(value)
+	minutes := math.Floor((value - degrees) * 60)
+	seconds := ((value-degrees)*60 - minutes) * 60
+	var data []byte
+	for _, r := range [][2]uint32{{uint32(degrees), 1}, {uint32(minutes), 1}, {uint32(math.Round(seconds * 10000)), 10000}} {
+		data = binary.LittleEndian.AppendUint32(data, r[0])
+		data = binary.LittleEndian.AppendUint32(data, r[1])
+	}
+	return tiffEntry{tag: tag, kind: tiffRational, count: 3, data: data}
+}
+
+// encodeIFD - IFD bắt đầu tại offset start (tính từ đầu TIFF); giá trị dài hơn 4 byte nằm ngay sau IFD
+func encodeIFD(entries []tiffEntry, start uint32) []byte {
+	extraOffset := start + uint32(2+12*len(entries)+4)
+	var ifd, extra []byte
+	ifd = binary.LittleEndian.AppendUint16(ifd, uint16(len(entries)))
+	for _, e := range entries {
+		ifd = binary.LittleEndian.AppendUint16(ifd, e.tag)
+		ifd = binary.LittleEndian.AppendUint16(ifd, e.kind)
+		ifd = binary.LittleEndian.AppendUint32(ifd, e.count)
+		if len(e.data) <= 4 {
+			value := make([]byte, 4)
+			copy(value, e.data)
+			ifd = append(ifd, value...)
+			continue
+		}
+		ifd = binary.LittleEndian.AppendUint32(ifd, extraOffset+uint32(len(extra)))
+		extra = append(extra, e.data...)
+		if len(extra)%2 == 1 {
+			extra = append(extra, 0)
+		}
+	}
+	ifd = binary.LittleEndian.AppendUint32(ifd, 0)
+	return append(ifd, extra...)
+}
+
+// tiff - Block TIFF little-endian: IFD0 (Make, Model) trỏ tới Exif IFD (DateTimeOriginal) và GPS IFD
+func (e *EXIF) tiff() []byte {
+	var exifEntries, gpsEntries []tiffEntry
+	if !e.Taken.IsZero() {
+		exifEntries = append(exifEntries, asciiEntry(0x9003, e.Taken.Format("2006:01:02 15:04:05")))
+	}
+	if e.Latitude != 0 || e.Longitude != 0 {
+		latRef, lonRef := "N", "E"
+		if e.Latitude < 0 {
+			latRef = "S"
+		}
+		if e.Longitude < 0 {
+			lonRef = "W"
+		}
+		gpsEntries = []tiffEntry{
+			{tag: 0x0000, kind: tiffByte, count: 4, data: []byte{2, 3, 0, 0}},
+			asciiEntry(0x0001, latRef),
+			degreesEntry(0x0002, e.Latitude),
+			asciiEntry(0x0003, lonRef),
+			degreesEntry(0x0004, e.Longitude),
+		}
+	}
+
+	ifd0 := func(exifOffset, gpsOffset uint32) []tiffEntry {
+		var entries []tiffEntry
+		if e.Make != "" {
+			entries = append(entries, asciiEntry(0x010F, e.Make))
+		}
+		if e.Model != "" {
+			entries = append(entries, asciiEntry(0x0110, e.Model))
+		}
+		entries = append(entries, tiffEntry{tag: 0x0112, kind: tiffShort, count: 1, data: []byte{1, 0}})
+		if exifEntries != nil {
+			entries = append(entries, longEntry(0x8769, exifOffset))
+		}
+		if gpsEntries != nil {
+			entries = append(entries, longEntry(0x8825, gpsOffset))
+		}
+		return entries
+	}
+
+	// Kích thước IFD0 không phụ thuộc giá trị offset nên tính trước rồi mã hóa lại với offset thật
+	const header = 8
+	exifOffset := uint32(header + len(encodeIFD(ifd0(0, 0), header)))
+	exifIFD := encodeIFD(exifEntries, exifOffset)
+	gpsOffset := exifOffset
+	if exifEntries != nil {
+		gpsOffset += uint32(len(exifIFD))
+	}
+
+	out := []byte("II*\x00")
+	out = binary.LittleEndian.AppendUint32(out, header)
+	out = append(out, encodeIFD(ifd0(exifOffset, gpsOffset), header)...)
+	if exifEntries != nil {
+		out = append(out, exifIFD...)
+	}
+	if gpsEntries != nil {
+		out = append(out, encodeIFD(gpsEntries, gpsOffset)...)
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package testkit
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+)
+
+type multipartFile struct {
+	field, filename string
+	data            []byte
+}
+
+// Multipart - Body multipart/form-data cho các route upload
+type Multipart struct {
+	fields [][2]string
+	files  []multipartFile
+}
+
+// NewMultipart - Form rỗng
+func NewMultipart() *Multipart {
+	return &Multipart{}
+}
+
+// Field - Thêm field text (title, description, tags, album_id...)
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.fields = append(m.fields, [2]string{name, value})
+	return m
+}
+
+// File - Thêm file; phần mở rộng của filename quyết định định dạng được chấp nhận
+func (m *Multipart) File(field, filename string, data []byte) *Multipart {
+	m.files = append(m.files, multipartFile{field: field, filename: filename, data: data})
+	return m
+}
+
+// Encode - Body và Content-Type (kèm boundary) của form
+func (m *Multipart) Encode() (io.Reader, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, f := range m.fields {
+		w.WriteField(f[0], f[1])
+	}
+	for _, f := range m.files {
+		part, _ := w.CreateFormFile(f.field, f.filename)
+		part.Write(f.data)
+	}
+	w.Close()
+	return &body, w.FormDataContentType()
+}
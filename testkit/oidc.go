@@ -0,0 +1,169 @@
+package testkit
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/sso"
+)
+
+// OIDCClaims - Thông tin user mà OIDCProvider đưa vào ID token
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCProvider - Identity provider giả (discovery, JWKS, token endpoint) ký ID token RS256 bằng khóa tạo khi test chạy
+type OIDCProvider struct {
+	Name     string
+	ClientID string
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]oidcGrant
+}
+
+type oidcGrant struct {
+	claims OIDCClaims
+	nonce  string
+}
+
+// NewOIDCProvider - Chạy provider giả và đăng ký nó vào registry của package sso dưới tên name
+func NewOIDCProvider(t testing.TB, name string) *OIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("testkit: generate OIDC key: %v", err)
+	}
+	p := &OIDCProvider{Name: name, ClientID: "media-store-test", key: key, codes: map[string]oidcGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("POST /token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	sso.SetRegistry(sso.NewRegistry(sso.ProviderConfig{
+		Name:        name,
+		Issuer:      p.server.URL,
+		ClientID:    p.ClientID,
+		RedirectURL: "http://media.test/api/v1/auth/oidc/" + name + "/callback",
+		HTTPClient:  p.server.Client(),
+	}))
+	t.Cleanup(func() { sso.SetRegistry(sso.NewRegistry()) })
+	return p
+}
+
+// Login - Đăng nhập qua provider với claims: bắt đầu từ /login, "người dùng đồng ý" rồi gọi callback như trình duyệt
+func (p *OIDCProvider) Login(k *Kit, claims OIDCClaims) *Response {
+	k.t.Helper()
+	start := k.JSON(http.MethodGet, "/api/v1/auth/oidc/"+p.Name+"/login", "", nil).Status(http.StatusFound)
+	location, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		k.t.Fatalf("testkit: parse OIDC redirect: %v", err)
+	}
+	query := location.Query()
+
+	code := "code-" + query.Get("state")
+	p.mu.Lock()
+	p.codes[code] = oidcGrant{claims: claims, nonce: query.Get("nonce")}
+	p.mu.Unlock()
+
+	callback := url.Values{"code": {code}, "state": {query.Get("state")}}
+	return k.JSON(http.MethodGet, "/api/v1/auth/oidc/"+p.Name+"/callback?"+callback.Encode(), "", nil)
+}
+
+func (p *OIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *OIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "test",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}})
+}
+
+// token - Đổi code lấy ID token; mỗi code dùng được một lần
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	code := r.PostFormValue("code")
+	p.mu.Lock()
+	grant, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.sign(map[string]any{
+		"iss":            p.server.URL,
+		"aud":            p.ClientID,
+		"sub":            grant.claims.Subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.claims.Email,
+		"email_verified": grant.claims.EmailVerified,
+		"name":           grant.claims.Name,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{
+		"access_token": "access-" + code,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// sign - JWT RS256 của claims
+func (p *OIDCProvider) sign(claims map[string]any) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signing))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
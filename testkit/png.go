@@ -0,0 +1,62 @@
+package testkit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// PNGText - Chunk text ghi vào ảnh PNG tổng hợp: tEXt, hoặc zTXt khi Compressed
+type PNGText struct {
+	Keyword    string
+	Text       string
+	Compressed bool
+}
+
+// PNG - Ảnh PNG tổng hợp kích thước width x height, các chunk text được chèn ngay sau IHDR theo thứ tự
+func PNG(width, height int, texts ...PNGText) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: 128, A: 255})
+		}
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		panic(err)
+	}
+
+	// Chữ ký 8 byte + IHDR (4 length + 4 type + 13 data + 4 CRC)
+	data := out.Bytes()
+	const afterIHDR = 8 + 25
+	var result bytes.Buffer
+	result.Write(data[:afterIHDR])
+	for _, text := range texts {
+		payload := append([]byte(text.Keyword), 0)
+		kind := "tEXt"
+		if text.Compressed {
+			kind = "zTXt"
+			var compressed bytes.Buffer
+			w := zlib.NewWriter(&compressed)
+			w.Write([]byte(text.Text))
+			w.Close()
+			payload = append(append(payload, 0), compressed.Bytes()...)
+		} else {
+			payload = append(payload, text.Text...)
+		}
+		writePNGChunk(&result, kind, payload)
+	}
+	result.Write(data[afterIHDR:])
+	return result.Bytes()
+}
+
+func writePNGChunk(w *bytes.Buffer, kind string, payload []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(payload)))
+	typed := append([]byte(kind), payload...)
+	w.Write(typed)
+	binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(typed))
+}
@@ -0,0 +1,250 @@
+// Package testkit dựng router trong process với repository in-memory (hoặc MongoDB tạm), thư mục upload tạm
+// và các driver giả (mailer, geocoder, rate limit) để viết test ở mức HTTP. Mỗi Kit chạy một webhook worker
+// và một hub sự kiện real-time riêng.
+//
+// Mailer, geocoder, rate limit, webhook worker, hub sự kiện và khóa JWT là biến toàn cục của package tương ứng,
+// nên mỗi Kit thay chúng khi được tạo (registry OIDC do NewOIDCProvider thay): không chạy song song (t.Parallel)
+// các test dùng Kit.
+package testkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hieu9721/media-store-backend/api"
+	"github.com/hieu9721/media-store-backend/apperr"
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/geocoder"
+	"github.com/hieu9721/media-store-backend/mailer"
+	"github.com/hieu9721/media-store-backend/migrations"
+	"github.com/hieu9721/media-store-backend/ratelimit"
+	"github.com/hieu9721/media-store-backend/realtime"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/routes"
+	"github.com/hieu9721/media-store-backend/utils"
+	"github.com/hieu9721/media-store-backend/webhook"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kit - Router đã dựng cùng các dependency để test kiểm tra trực tiếp
+type Kit struct {
+	t        testing.TB
+	Router   *gin.Engine
+	Repos    *repository.Repositories
+	Config   *config.Config
+	Mail     *Outbox
+	Geocoder *StubGeocoder
+	Webhooks *webhook.Worker
+	Events   *realtime.Hub
+}
+
+// Option - Tùy chỉnh Kit trước khi dựng router
+type Option func(t testing.TB, k *Kit)
+
+// WithConfig - Sửa cấu hình mặc định của Kit (giới hạn upload, tắt validate request...)
+func WithConfig(fn func(cfg *config.Config)) Option {
+	return func(t testing.TB, k *Kit) {
+		fn(k.Config)
+	}
+}
+
+// WithMongo - Dùng MongoDB thật: mỗi Kit một database riêng đã chạy migration, xóa khi test xong.
+// Bỏ qua test nếu không đặt MONGODB_TEST_URI.
+func WithMongo() Option {
+	return func(t testing.TB, k *Kit) {
+		uri := os.Getenv("MONGODB_TEST_URI")
+		if uri == "" {
+			t.Skip("MONGODB_TEST_URI not set")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			t.Fatalf("testkit: connect mongo: %v", err)
+		}
+		db := client.Database(fmt.Sprintf("media_store_testkit_%d", time.Now().UnixNano()))
+		t.Cleanup(func() {
+			db.Drop(context.Background())
+			client.Disconnect(context.Background())
+		})
+		if _, err := migrations.Up(ctx, db); err != nil {
+			t.Fatalf("testkit: migrate: %v", err)
+		}
+		k.Repos = repository.NewMongo(db)
+	}
+}
+
+// New - Dựng Kit: repository in-memory, upload vào t.TempDir(), email giữ trong Outbox,
+// geocoder trả địa chỉ cố định và rate limit riêng cho từng Kit
+func New(t testing.TB, opts ...Option) *Kit {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Server.BaseURL = "http://media.test"
+	cfg.Server.FrontendURL = "http://app.media.test"
+	cfg.JWT.Secret = "testkit-jwt-secret-not-for-production"
+	cfg.Storage.UploadDir = t.TempDir()
+	cfg.Storage.MinFreeSpace = 0
+	cfg.Render.CacheDir = t.TempDir()
+	// Gửi lại webhook gần như ngay để test retry không phải chờ
+	cfg.Webhooks.RetryBackoff = config.Duration{Duration: time.Millisecond}
+	cfg.Webhooks.PollInterval = config.Duration{Duration: 20 * time.Millisecond}
+	// Subscriber giả là httptest server http://127.0.0.1
+	cfg.Webhooks.AllowPrivate = true
+	cfg.Events.PollInterval = config.Duration{Duration: 20 * time.Millisecond}
+
+	k := &Kit{
+		t:        t,
+		Repos:    repository.NewMemory(),
+		Config:   cfg,
+		Mail:     &Outbox{},
+		Geocoder: NewStubGeocoder(),
+	}
+	for _, opt := range opts {
+		opt(t, k)
+	}
+	if err := k.Config.Validate(); err != nil {
+		t.Fatalf("testkit: invalid config: %v", err)
+	}
+
+	utils.ConfigureJWT(k.Config.JWT.Secret, k.Config.JWT.TTL.Duration)
+	mailer.SetMailer(k.Mail)
+	mailer.Observe(api.JobRecorder(k.Repos))
+	geocoder.SetGeocoder(k.Geocoder)
+	ratelimit.SetStore(ratelimit.NewMemoryStore())
+
+	k.Webhooks = webhook.NewWorker(k.Repos, k.Config.Webhooks)
+	k.Webhooks.Observe(api.JobRecorder(k.Repos))
+	webhook.SetWorker(k.Webhooks)
+	k.Webhooks.Start()
+	t.Cleanup(func() {
+		webhook.SetWorker(nil)
+		k.Webhooks.Stop(context.Background())
+	})
+
+	k.Events = realtime.NewHub(k.Repos.Events, k.Config.Events)
+	realtime.SetHub(k.Events)
+	k.Events.Start()
+	t.Cleanup(func() {
+		realtime.SetHub(nil)
+		k.Events.Stop(context.Background())
+	})
+
+	k.Router = routes.SetupRoutes(k.Repos, k.Config)
+	return k
+}
+
+// Context - Context cho thao tác trực tiếp trên repository trong test
+func (k *Kit) Context() context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	k.t.Cleanup(cancel)
+	return ctx
+}
+
+// Do - Gửi request qua router
+func (k *Kit) Do(req *http.Request) *Response {
+	k.t.Helper()
+	w := httptest.NewRecorder()
+	k.Router.ServeHTTP(w, req)
+	return &Response{ResponseRecorder: w, t: k.t}
+}
+
+// JSON - Gửi request với body JSON (nil nếu không có body); token rỗng là request không đăng nhập
+func (k *Kit) JSON(method, path, token string, body any) *Response {
+	k.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			k.t.Fatalf("testkit: encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return k.Do(req)
+}
+
+// Upload - Gửi form multipart/form-data bằng POST
+func (k *Kit) Upload(path, token string, form *Multipart) *Response {
+	k.t.Helper()
+	body, contentType := form.Encode()
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return k.Do(req)
+}
+
+// Response - Response đã ghi lại, kèm helper kiểm tra status và đọc body
+type Response struct {
+	*httptest.ResponseRecorder
+	t testing.TB
+}
+
+// Status - Dừng test nếu status khác want, in kèm body để dễ tìm lỗi
+func (r *Response) Status(want int) *Response {
+	r.t.Helper()
+	if r.Code != want {
+		r.t.Fatalf("status = %d, want %d; body: %s", r.Code, want, r.Body.String())
+	}
+	return r
+}
+
+// Decode - Giải mã body JSON vào v
+func (r *Response) Decode(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("decode response: %v; body: %s", err, r.Body.String())
+	}
+}
+
+// Map - Body JSON dạng object
+func (r *Response) Map() map[string]any {
+	r.t.Helper()
+	var m map[string]any
+	r.Decode(&m)
+	return m
+}
+
+// Problem - Body lỗi application/problem+json
+func (r *Response) Problem() apperr.Problem {
+	r.t.Helper()
+	if ct := r.Header().Get("Content-Type"); ct != apperr.ContentType {
+		r.t.Fatalf("Content-Type = %q, want %q; body: %s", ct, apperr.ContentType, r.Body.String())
+	}
+	var p apperr.Problem
+	r.Decode(&p)
+	return p
+}
+
+// Fails - Dừng test nếu response không phải lỗi có status và code mong đợi
+func (r *Response) Fails(status int, code string) apperr.Problem {
+	r.t.Helper()
+	r.Status(status)
+	p := r.Problem()
+	if p.Code != code {
+		r.t.Fatalf("error code = %q, want %q; body: %s", p.Code, code, r.Body.String())
+	}
+	return p
+}
@@ -1,13 +1,15 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 
 	"github.com/google/uuid"
 )
 
 func GenerateID(pre string) string {
-	return fmt.Sprintf(pre, "_%s", uuid.New().String())
+	return fmt.Sprintf("%s_%s", pre, uuid.New().String())
 }
 
 func GenerateUserID() string {
@@ -20,3 +22,12 @@ func IsValidUserID(id string) bool {
 	}
 	return id[:4] == "uid_"
 }
+
+// GenerateRandomToken - Sinh chuỗi ngẫu nhiên an toàn (base64 URL) từ n byte
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
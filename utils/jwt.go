@@ -2,7 +2,6 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,28 +11,47 @@ type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// Purpose - Rỗng với access token; "2fa" với challenge token chờ nhập mã TOTP
+	Purpose string `json:"purpose,omitempty"`
+	// SessionID - Phiên đăng nhập cấp token; token bị từ chối khi phiên bị thu hồi
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID string, email string, role string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return "", errors.New("JWT_SECRET not set in environment")
+// TokenTTL - Thời hạn của access token, cũng là thời hạn của phiên đăng nhập
+var TokenTTL = 24 * time.Hour
+
+var (
+	jwtSecret          []byte
+	errSecretNotLoaded = errors.New("JWT secret not configured")
+)
+
+// ConfigureJWT - Đặt khóa ký và thời hạn token từ cấu hình lúc khởi động
+func ConfigureJWT(secret string, ttl time.Duration) {
+	jwtSecret = []byte(secret)
+	if ttl > 0 {
+		TokenTTL = ttl
+	}
+}
+
+func GenerateToken(userID string, email string, role string, sessionID string) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", errSecretNotLoaded
 	}
 
-	expiryHours := 24
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiryHours))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := token.SignedString(jwtSecret)
 	if err != nil {
 		return "", err
 	}
@@ -41,17 +59,62 @@ func GenerateToken(userID string, email string, role string) (string, error) {
 	return tokenString, nil
 }
 
+// TwoFactorChallengePurpose - Purpose của challenge token cấp sau khi đúng mật khẩu nhưng chưa nhập mã 2FA
+const TwoFactorChallengePurpose = "2fa"
+
+// GenerateChallengeToken - Token ngắn hạn (5 phút) chỉ dùng để hoàn tất đăng nhập 2FA
+func GenerateChallengeToken(userID string) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", errSecretNotLoaded
+	}
+
+	claims := Claims{
+		UserID:  userID,
+		Purpose: TwoFactorChallengePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ValidateToken - Xác thực access token; challenge token 2FA bị từ chối
 func ValidateToken(tokenString string) (*Claims, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET not set in environment")
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
+}
+
+// ValidateChallengeToken - Xác thực challenge token 2FA
+func ValidateChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != TwoFactorChallengePurpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
+}
+
+func parseToken(tokenString string) (*Claims, error) {
+	if len(jwtSecret) == 0 {
+		return nil, errSecretNotLoaded
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(jwtSecret), nil
+		return jwtSecret, nil
 	})
 
 	if err != nil {
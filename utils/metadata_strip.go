@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Các chế độ xử lý metadata khi trả file ảnh
+const (
+	StripMetadataNone = "none"
+	StripMetadataGPS  = "gps"
+	StripMetadataAll  = "all"
+)
+
+var (
+	jpegSOI      = []byte{0xFF, 0xD8}
+	pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+	exifHeader   = []byte("Exif\x00\x00")
+	xmpHeader    = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader    = []byte("ICC_PROFILE\x00")
+	adobeHeader  = []byte("Adobe")
+
+	errMalformedImage = errors.New("malformed image data")
+)
+
+// IsValidStripMode - Kiểm tra giá trị chế độ strip metadata
+func IsValidStripMode(mode string) bool {
+	return mode == StripMetadataNone || mode == StripMetadataGPS || mode == StripMetadataAll
+}
+
+// StripImageMetadata - Loại bỏ GPS hoặc toàn bộ metadata khỏi ảnh JPEG/PNG.
+// Định dạng khác được trả về nguyên vẹn.
+func StripImageMetadata(data []byte, mode string) ([]byte, error) {
+	if mode == StripMetadataNone {
+		return data, nil
+	}
+	switch {
+	case bytes.HasPrefix(data, jpegSOI):
+		return stripJPEG(data, mode)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNG(data, mode)
+	default:
+		return data, nil
+	}
+}
+
+func stripJPEG(data []byte, mode string) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(jpegSOI)
+
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF || pos+1 >= len(data) {
+			return nil, errMalformedImage
+		}
+		marker := data[pos+1]
+
+		// Padding byte 0xFF giữa các segment
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		// Các marker không có phần length
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out.Write(data[pos : pos+2])
+			pos += 2
+			continue
+		}
+		if marker == 0xD9 {
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		}
+
+		if pos+4 > len(data) {
+			return nil, errMalformedImage
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errMalformedImage
+		}
+		segment := data[pos:end]
+		payload := data[pos+4 : end]
+
+		// SOS: phần còn lại là dữ liệu ảnh nén, copy nguyên
+		if marker == 0xDA {
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		}
+
+		switch {
+		case mode == StripMetadataAll && isMetadataSegment(marker, payload):
+			// bỏ segment
+		case mode == StripMetadataGPS && marker == 0xE1 && bytes.HasPrefix(payload, xmpHeader):
+			// XMP có thể chứa exif:GPSLatitude/GPSLongitude
+		case mode == StripMetadataGPS && marker == 0xE1 && bytes.HasPrefix(payload, exifHeader):
+			scrubbed := append([]byte(nil), segment...)
+			scrubGPSIFD(scrubbed[4+len(exifHeader):])
+			out.Write(scrubbed)
+		default:
+			out.Write(segment)
+		}
+		pos = end
+	}
+
+	return out.Bytes(), nil
+}
+
+// isMetadataSegment - APPn/COM chứa metadata; giữ lại JFIF (APP0), ICC profile và Adobe vì ảnh hưởng hiển thị
+func isMetadataSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == 0xFE:
+		return true
+	case marker == 0xE0:
+		return false
+	case marker == 0xE2 && bytes.HasPrefix(payload, iccHeader):
+		return false
+	case marker == 0xEE && bytes.HasPrefix(payload, adobeHeader):
+		return false
+	default:
+		return marker >= 0xE1 && marker <= 0xEF
+	}
+}
+
+// scrubGPSIFD - Xóa toàn bộ entry và giá trị của GPS IFD trong block TIFF, giữ nguyên các tag EXIF khác
+func scrubGPSIFD(tiff []byte) {
+	if len(tiff) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	ifd0 := int(order.Uint32(tiff[4:8]))
+	if ifd0+2 > len(tiff) {
+		return
+	}
+	count := int(order.Uint16(tiff[ifd0 : ifd0+2]))
+	gpsOffset := -1
+	for i := 0; i < count; i++ {
+		entry := ifd0 + 2 + i*12
+		if entry+12 > len(tiff) {
+			return
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x8825 {
+			gpsOffset = int(order.Uint32(tiff[entry+8 : entry+12]))
+			break
+		}
+	}
+	if gpsOffset < 0 || gpsOffset+2 > len(tiff) {
+		return
+	}
+
+	gpsCount := int(order.Uint16(tiff[gpsOffset : gpsOffset+2]))
+	for i := 0; i < gpsCount; i++ {
+		entry := gpsOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		size := tiffTypeSize(order.Uint16(tiff[entry+2:entry+4])) * int(order.Uint32(tiff[entry+4:entry+8]))
+		if size > 4 {
+			valueOffset := int(order.Uint32(tiff[entry+8 : entry+12]))
+			if valueOffset >= 0 && size <= len(tiff) && valueOffset <= len(tiff)-size {
+				clear(tiff[valueOffset : valueOffset+size])
+			}
+		}
+		clear(tiff[entry : entry+12])
+	}
+	order.PutUint16(tiff[gpsOffset:gpsOffset+2], 0)
+}
+
+func tiffTypeSize(t uint16) int {
+	switch t {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	default:
+		return 0
+	}
+}
+
+func stripPNG(data []byte, mode string) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformedImage
+		}
+		chunk := data[pos:end]
+
+		drop := false
+		switch chunkType {
+		case "eXIf":
+			drop = true
+		case "iTXt":
+			drop = mode == StripMetadataAll || bytes.HasPrefix(chunk[8:], []byte("XML:com.adobe.xmp\x00"))
+		case "tEXt", "zTXt", "tIME":
+			drop = mode == StripMetadataAll
+		}
+		if !drop {
+			out.Write(chunk)
+		}
+
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrDestinationNotAllowed - Địa chỉ đích là loopback, mạng nội bộ, link-local (gồm metadata cloud) hoặc không định tuyến công khai
+var ErrDestinationNotAllowed = errors.New("destination address is not allowed")
+
+// blockedPrefixes - Dải không được netip phân loại sẵn nhưng vẫn không phải Internet công khai
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT, gồm metadata của một số cloud (100.100.100.200)
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 trỏ tới được mọi địa chỉ IPv4, kể cả nội bộ
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// publicAddr - Địa chỉ định tuyến trên Internet công khai
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL - Kiểm tra URL subscriber trước khi lưu và trước mỗi lần gửi: bắt buộc https, host là IP phải công khai.
+// Host là tên miền được kiểm tra khi kết nối (sau khi phân giải). allowPrivate (chỉ dùng khi phát triển) bỏ qua cả hai
+func CheckURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if allowPrivate {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return errors.New("url must use https")
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !publicAddr(addr) {
+		return ErrDestinationNotAllowed
+	}
+	return nil
+}
+
+// transport - Transport chỉ kết nối tới địa chỉ công khai. Địa chỉ được kiểm tra sau khi phân giải DNS, ngay trước
+// khi kết nối, nên tên miền trỏ vào mạng nội bộ (kể cả DNS rebinding giữa lúc kiểm tra và lúc gửi) đều bị chặn.
+// Không dùng proxy từ biến môi trường vì kết nối tới proxy sẽ bỏ qua kiểm tra này
+func transport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addrPort.Addr()) {
+				return ErrDestinationNotAllowed
+			}
+			return nil
+		}
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if errors.Is(err, ErrDestinationNotAllowed) {
+			// Không trả lỗi gốc: nó chứa địa chỉ nội bộ đã phân giải và sẽ hiện trong log delivery
+			return nil, ErrDestinationNotAllowed
+		}
+		return conn, err
+	}
+	return t
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header gửi kèm mỗi delivery
+const (
+	// SignatureHeader - "t=<unix giây>,v1=<hex HMAC-SHA256 của "<t>.<body>">"
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	// EventIDHeader - Giống nhau giữa các lần gửi lại cùng một sự kiện
+	EventIDHeader    = "X-Webhook-ID"
+	DeliveryIDHeader = "X-Webhook-Delivery"
+)
+
+// Lỗi khi kiểm tra chữ ký
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Sign - Giá trị SignatureHeader cho body gửi lúc t. Timestamp nằm trong phần được ký để subscriber
+// từ chối request cũ bị phát lại
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+// Verify - Kiểm tra header do Sign tạo; tolerance > 0 thì từ chối chữ ký lệch quá tolerance so với now.
+// Header có thể chứa nhiều v1 (khi đổi secret), chỉ cần một giá trị khớp
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expected := mac(secret, ts, body)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if skew := now.Sub(time.Unix(sec, 0)); skew > tolerance || skew < -tolerance {
+			return ErrExpiredSignature
+		}
+	}
+	return nil
+}
+
+func mac(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,91 @@
+// Package webhook gửi sự kiện tới subscriber qua HTTP: payload ký HMAC-SHA256, delivery lưu trong
+// repository và được worker nền gửi lại với backoff lũy thừa cho tới khi thành công hoặc hết lượt thử.
+package webhook
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/utils"
+)
+
+// Retention - Thời gian giữ log delivery trước khi TTL index xóa
+const Retention = 30 * 24 * time.Hour
+
+// Event - Body JSON gửi tới subscriber
+type Event struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	CreatedAt int64  `json:"created_at"`
+	Data      any    `json:"data"`
+}
+
+// NewSecret - Khóa ký ngẫu nhiên cho subscription mới
+func NewSecret() (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// NewEvent - Sự kiện mới với ID riêng; payload được mã hóa một lần và dùng chung cho mọi subscriber
+func NewEvent(eventType string, data any) (*Event, string, error) {
+	event := &Event{ID: utils.GenerateID("evt"), Type: eventType, CreatedAt: time.Now().Unix(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return event, string(payload), nil
+}
+
+// NewDelivery - Delivery pending gửi payload tới webhook ngay khi worker rảnh
+func NewDelivery(webhookID, eventID, eventType, payload string) *models.WebhookDelivery {
+	now := time.Now()
+	return &models.WebhookDelivery{
+		ID:            utils.GenerateID("whd"),
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		Event:         eventType,
+		Payload:       payload,
+		Status:        models.WebhookDeliveryPending,
+		Attempts:      []models.WebhookAttempt{},
+		NextAttemptAt: now.Unix(),
+		CreatedAt:     now.Unix(),
+		UpdatedAt:     now.Unix(),
+		PurgeAt:       now.Add(Retention),
+	}
+}
+
+// current - Worker đang chạy; handler chỉ cần đánh thức nó sau khi tạo delivery
+var current atomic.Pointer[Worker]
+
+// SetWorker - Đăng ký worker cho Notify, Pending và LastActivity; nil để bỏ
+func SetWorker(w *Worker) {
+	current.Store(w)
+}
+
+// Notify - Báo worker có delivery mới để gửi ngay thay vì chờ chu kỳ poll
+func Notify() {
+	if w := current.Load(); w != nil {
+		w.Notify()
+	}
+}
+
+// Pending - Số delivery đã đến hạn mà chưa gửi, theo lần poll gần nhất
+func Pending() int {
+	if w := current.Load(); w != nil {
+		return w.Pending()
+	}
+	return 0
+}
+
+// LastActivity - Heartbeat của worker, dùng cho readiness check
+func LastActivity() time.Time {
+	if w := current.Load(); w != nil {
+		return w.LastActivity()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,306 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hieu9721/media-store-backend/config"
+	"github.com/hieu9721/media-store-backend/models"
+	"github.com/hieu9721/media-store-backend/repository"
+	"github.com/hieu9721/media-store-backend/tracing"
+)
+
+const (
+	// concurrency - Số delivery gửi đồng thời; một subscriber chậm không chặn cả hàng đợi
+	concurrency = 4
+	// leaseMargin - Delivery đang gửi được giữ thêm ngoài timeout; worker chết giữa chừng thì delivery được gửi lại sau đó
+	leaseMargin = 30 * time.Second
+	// responseLimit - Số byte đầu của response lưu vào log delivery
+	responseLimit = 1024
+	userAgent     = "media-store-webhooks/1"
+)
+
+// Observer - Nhận kết quả mỗi lần gửi (job "webhook"), ví dụ để lưu thống kê
+type Observer func(ctx context.Context, job string, elapsed time.Duration, err error)
+
+// Worker - Lấy delivery đến hạn từ repository và gửi tới subscriber. Nhiều instance có thể chạy cùng lúc:
+// ClaimDue bảo đảm mỗi delivery chỉ được một worker gửi tại một thời điểm
+type Worker struct {
+	repos    *repository.Repositories
+	cfg      config.WebhooksConfig
+	client   *http.Client
+	observer Observer
+
+	wake    chan struct{}
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	due     atomic.Int64
+	// lastActivity - Thời điểm (UnixNano) worker gửi xong một delivery hoặc poll xong hàng đợi
+	lastActivity atomic.Int64
+}
+
+// NewWorker - Worker dùng cấu hình webhooks; mỗi lần gửi là một span client và gửi kèm traceparent
+func NewWorker(repos *repository.Repositories, cfg config.WebhooksConfig) *Worker {
+	w := &Worker{
+		repos: repos,
+		cfg:   cfg,
+		client: tracing.HTTPClient(http.Client{
+			Transport: transport(cfg.AllowPrivate),
+			Timeout:   cfg.Timeout.Duration,
+			// Redirect được tính là lỗi: subscriber phải đăng ký đúng URL nhận
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}),
+		wake: make(chan struct{}, concurrency),
+	}
+	w.lastActivity.Store(time.Now().UnixNano())
+	return w
+}
+
+// Observe - Đăng ký observer cho các lần gửi; gọi trước Start
+func (w *Worker) Observe(fn Observer) {
+	w.observer = fn
+}
+
+// Start - Chạy worker ở background tới khi Stop được gọi
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	for i := 0; i < concurrency; i++ {
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			w.run(ctx)
+		}()
+	}
+}
+
+// Stop - Ngừng lấy delivery mới và chờ các lần gửi đang chạy xong, tối đa tới khi ctx hết hạn.
+// Delivery chưa gửi vẫn nằm trong repository và được gửi khi worker chạy lại
+func (w *Worker) Stop(ctx context.Context) error {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify - Đánh thức worker đang chờ chu kỳ poll
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending - Số delivery đã đến hạn mà chưa gửi, theo lần poll gần nhất
+func (w *Worker) Pending() int {
+	return int(w.due.Load())
+}
+
+// LastActivity - Heartbeat của worker, dùng cho readiness check
+func (w *Worker) LastActivity() time.Time {
+	return time.Unix(0, w.lastActivity.Load())
+}
+
+func (w *Worker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval.Duration)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil && w.next(ctx) {
+		}
+		if ctx.Err() == nil {
+			if due, err := w.repos.WebhookDeliveries.CountDue(ctx, time.Now().Unix()); err == nil {
+				w.due.Store(due)
+			}
+			w.lastActivity.Store(time.Now().UnixNano())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// next - Gửi một delivery đến hạn; false khi không còn delivery nào hoặc không đọc được hàng đợi
+func (w *Worker) next(ctx context.Context) bool {
+	now := time.Now()
+	lease := now.Add(w.cfg.Timeout.Duration + leaseMargin)
+	delivery, err := w.repos.WebhookDeliveries.ClaimDue(ctx, now.Unix(), lease.Unix())
+	if err == repository.ErrNotFound {
+		return false
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to claim webhook delivery", "error", err)
+		}
+		return false
+	}
+
+	// Lần gửi đã bắt đầu thì chạy hết kể cả khi worker đang dừng
+	w.deliver(context.WithoutCancel(ctx), delivery)
+	w.lastActivity.Store(time.Now().UnixNano())
+	return true
+}
+
+// deliver - Gửi một lần, lưu kết quả và lên lịch gửi lại hoặc kết thúc delivery
+func (w *Worker) deliver(ctx context.Context, d *models.WebhookDelivery) {
+	hook, err := w.repos.Webhooks.Get(ctx, d.WebhookID)
+	if err != nil && err != repository.ErrNotFound {
+		// Để lease hết hạn rồi thử lại
+		slog.ErrorContext(ctx, "failed to load webhook", "webhook_id", d.WebhookID, "delivery_id", d.ID, "error", err)
+		return
+	}
+	if err == repository.ErrNotFound || !hook.Enabled {
+		reason := "webhook deleted; not sent"
+		if hook != nil {
+			reason = "webhook disabled; not sent"
+		}
+		w.record(ctx, d, repository.WebhookDeliveryResult{
+			Status:  models.WebhookDeliveryFailed,
+			Attempt: models.WebhookAttempt{At: time.Now().Unix(), Error: reason},
+		})
+		return
+	}
+
+	start := time.Now()
+	attempt, err := w.send(ctx, hook, d)
+	if w.observer != nil {
+		w.observer(ctx, "webhook", time.Since(start), err)
+	}
+
+	result := repository.WebhookDeliveryResult{Attempt: attempt}
+	attempts := len(d.Attempts) + 1
+	switch {
+	case err == nil:
+		result.Status = models.WebhookDeliverySucceeded
+	case attempts < w.cfg.MaxAttempts:
+		result.Status = models.WebhookDeliveryPending
+		result.NextAttemptAt = time.Now().Add(w.backoff(attempts)).Unix()
+	default:
+		result.Status = models.WebhookDeliveryFailed
+	}
+	w.record(ctx, d, result)
+
+	if err != nil {
+		slog.WarnContext(ctx, "webhook delivery failed",
+			"webhook_id", hook.ID, "delivery_id", d.ID, "event", d.Event, "attempt", attempts, "status", result.Status, "error", err)
+	}
+	w.track(ctx, hook, result.Status)
+}
+
+func (w *Worker) record(ctx context.Context, d *models.WebhookDelivery, result repository.WebhookDeliveryResult) {
+	result.Now = time.Now().Unix()
+	if err := w.repos.WebhookDeliveries.Record(ctx, d.ID, result); err != nil {
+		slog.ErrorContext(ctx, "failed to record webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// track - Cập nhật số delivery thất bại liên tiếp; tắt subscription khi đạt DisableAfter
+func (w *Worker) track(ctx context.Context, hook *models.Webhook, status string) {
+	switch status {
+	case models.WebhookDeliverySucceeded:
+		if err := w.repos.Webhooks.RecordSuccess(ctx, hook.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to reset webhook failures", "webhook_id", hook.ID, "error", err)
+		}
+	case models.WebhookDeliveryFailed:
+		reason := fmt.Sprintf("%d consecutive deliveries failed after %d attempts each", w.cfg.DisableAfter, w.cfg.MaxAttempts)
+		disabled, err := w.repos.Webhooks.RecordFailure(ctx, hook.ID, w.cfg.DisableAfter, reason)
+		if err != nil && err != repository.ErrNotFound {
+			slog.ErrorContext(ctx, "failed to record webhook failure", "webhook_id", hook.ID, "error", err)
+		}
+		if disabled {
+			slog.WarnContext(ctx, "webhook disabled after repeated failures", "webhook_id", hook.ID, "url", hook.URL)
+		}
+	}
+}
+
+// send - POST payload đã ký tới subscriber; chỉ response 2xx là thành công
+func (w *Worker) send(ctx context.Context, hook *models.Webhook, d *models.WebhookDelivery) (models.WebhookAttempt, error) {
+	start := time.Now()
+	attempt := models.WebhookAttempt{At: start.Unix()}
+	body := []byte(d.Payload)
+	var remote netip.Addr
+
+	// URL lưu trước khi có kiểm tra (hoặc khi cấu hình đổi) vẫn phải qua kiểm tra trước khi gửi
+	err := CheckURL(hook.URL, w.cfg.AllowPrivate)
+	var req *http.Request
+	if err == nil {
+		req, err = http.NewRequestWithContext(w.traceRemote(ctx, &remote), http.MethodPost, hook.URL, bytes.NewReader(body))
+	}
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set(EventHeader, d.Event)
+		req.Header.Set(EventIDHeader, d.EventID)
+		req.Header.Set(DeliveryIDHeader, d.ID)
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, start, body))
+
+		var resp *http.Response
+		resp, err = w.client.Do(req)
+		if err == nil {
+			snippet, _ := io.ReadAll(io.LimitReader(resp.Body, responseLimit))
+			// Đọc hết phần còn lại (có giới hạn) để kết nối được dùng lại
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+
+			attempt.StatusCode = resp.StatusCode
+			attempt.Response = strings.ToValidUTF8(string(snippet), "")
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = fmt.Errorf("HTTP %d", resp.StatusCode)
+				// Khi allow_private cho phép gửi vào mạng nội bộ, body lỗi của dịch vụ nội bộ không được lộ qua log delivery
+				if !publicAddr(remote) {
+					attempt.Response = ""
+				}
+			}
+		}
+	}
+
+	attempt.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	return attempt, err
+}
+
+// traceRemote - Ghi địa chỉ đã kết nối vào remote (kết nối dùng lại cũng được báo)
+func (w *Worker) traceRemote(ctx context.Context, remote *netip.Addr) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if addrPort, err := netip.ParseAddrPort(info.Conn.RemoteAddr().String()); err == nil {
+				*remote = addrPort.Addr()
+			}
+		},
+	})
+}
+
+// backoff - RetryBackoff * 2^(attempts-1), tối đa RetryBackoffMax
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.cfg.RetryBackoff.Duration
+	for i := 1; i < attempts && delay < w.cfg.RetryBackoffMax.Duration; i++ {
+		delay *= 2
+	}
+	return min(delay, w.cfg.RetryBackoffMax.Duration)
+}